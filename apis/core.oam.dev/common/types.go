@@ -55,6 +55,15 @@ type Schematic struct {
 	CUE *CUE `json:"cue,omitempty"`
 
 	Terraform *Terraform `json:"terraform,omitempty"`
+
+	JSONSchema *JSONSchema `json:"jsonSchema,omitempty"`
+}
+
+// JSONSchema declares the parameters of the capability with a JSON Schema, which is used as the
+// parameter schema directly without CUE compilation
+type JSONSchema struct {
+	// Schema is the JSON Schema of the parameters
+	Schema string `json:"schema"`
 }
 
 // Terraform is the struct to describe cloud resources managed by Hashicorp Terraform
@@ -278,6 +278,21 @@ func (in *DefinitionReference) DeepCopy() *DefinitionReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JSONSchema) DeepCopyInto(out *JSONSchema) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JSONSchema.
+func (in *JSONSchema) DeepCopy() *JSONSchema {
+	if in == nil {
+		return nil
+	}
+	out := new(JSONSchema)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OAMObjectReference) DeepCopyInto(out *OAMObjectReference) {
 	*out = *in
@@ -399,6 +414,11 @@ func (in *Schematic) DeepCopyInto(out *Schematic) {
 		*out = new(Terraform)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.JSONSchema != nil {
+		in, out := &in.JSONSchema, &out.JSONSchema
+		*out = new(JSONSchema)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Schematic.
@@ -66,6 +66,73 @@ type ComponentDefinitionStatus struct {
 	// LatestRevision of the component definition
 	// +optional
 	LatestRevision *common.Revision `json:"latestRevision,omitempty"`
+	// SchemaConfigMapRef refers to the ConfigMap which contains OpenAPI V3 JSON schema of the latest revision.
+	// +optional
+	SchemaConfigMapRef *SchemaConfigMapReference `json:"schemaConfigMapRef,omitempty"`
+	// SchemaStorageRef refers to the storage backend and the location of the schema of the latest revision
+	// +optional
+	SchemaStorageRef *SchemaStorageReference `json:"schemaStorageRef,omitempty"`
+	// CollectedRevisions is the number of DefinitionRevisions deleted by the garbage collection
+	// +optional
+	CollectedRevisions int64 `json:"collectedRevisions,omitempty"`
+	// RevisionHistory lists the DefinitionRevisions of the component definition from the newest to the oldest,
+	// it's capped at the revision limit of the definition
+	// +optional
+	RevisionHistory []DefinitionRevisionHistory `json:"revisionHistory,omitempty"`
+	// SchemaSize is the size in bytes of the OpenAPI V3 JSON schema of the parameters before compression
+	// +optional
+	SchemaSize int64 `json:"schemaSize,omitempty"`
+	// TraitCompatibility is the trait compatibility declared by the annotations of the definition,
+	// it's only set when the declaration is well-formed
+	// +optional
+	TraitCompatibility *TraitCompatibility `json:"traitCompatibility,omitempty"`
+	// Alias is the alias of the definition accepted by the controller, which is unique among
+	// the ComponentDefinitions in the namespace
+	// +optional
+	Alias string `json:"alias,omitempty"`
+}
+
+// TraitCompatibility lists the traits which can or cannot be attached to the components of a definition
+type TraitCompatibility struct {
+	// CompatibleTraits are the names of the traits known to work with the definition
+	// +optional
+	CompatibleTraits []string `json:"compatibleTraits,omitempty"`
+	// IncompatibleTraits are the names of the traits which must not be attached to the definition
+	// +optional
+	IncompatibleTraits []string `json:"incompatibleTraits,omitempty"`
+}
+
+// DefinitionRevisionHistory is a compact record of a DefinitionRevision
+type DefinitionRevisionHistory struct {
+	// Name of the DefinitionRevision
+	Name string `json:"name"`
+	// Revision number of the DefinitionRevision
+	Revision int64 `json:"revision"`
+	// RevisionHash of the DefinitionRevision
+	RevisionHash string `json:"revisionHash,omitempty"`
+	// CreationTimestamp of the DefinitionRevision
+	CreationTimestamp metav1.Time `json:"creationTimestamp,omitempty"`
+}
+
+// SchemaConfigMapReference refers to a ConfigMap storing the OpenAPI V3 JSON schema of a DefinitionRevision
+type SchemaConfigMapReference struct {
+	// Name of the ConfigMap
+	Name string `json:"name"`
+	// Namespace of the ConfigMap, it's the namespace of the definition if empty
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Revision is the name of the DefinitionRevision which the schema corresponds to
+	Revision string `json:"revision"`
+}
+
+// SchemaStorageReference refers to the OpenAPI V3 JSON schema of a DefinitionRevision stored in a storage backend
+type SchemaStorageReference struct {
+	// Backend is the storage backend of the schema, e.g. configmap or s3
+	Backend string `json:"backend"`
+	// Location of the schema in the backend
+	Location string `json:"location"`
+	// Revision is the name of the DefinitionRevision which the schema corresponds to
+	Revision string `json:"revision"`
 }
 
 // +kubebuilder:object:root=true
@@ -30,6 +30,10 @@ type DefinitionRevisionSpec struct {
 	// RevisionHash record the hash value of the spec of DefinitionRevision object.
 	RevisionHash string `json:"revisionHash"`
 
+	// Signature records the base64 encoded detached Ed25519 signature over the RevisionHash, it's set by the
+	// controller configured with a signing key so that the integrity of the revision can be verified before use.
+	Signature string `json:"signature,omitempty"`
+
 	// DefinitionType
 	DefinitionType common.DefinitionType `json:"definitionType"`
 
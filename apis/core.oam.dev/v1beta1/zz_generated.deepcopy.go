@@ -461,6 +461,28 @@ func (in *ComponentDefinitionStatus) DeepCopyInto(out *ComponentDefinitionStatus
 		*out = new(common.Revision)
 		**out = **in
 	}
+	if in.SchemaConfigMapRef != nil {
+		in, out := &in.SchemaConfigMapRef, &out.SchemaConfigMapRef
+		*out = new(SchemaConfigMapReference)
+		**out = **in
+	}
+	if in.SchemaStorageRef != nil {
+		in, out := &in.SchemaStorageRef, &out.SchemaStorageRef
+		*out = new(SchemaStorageReference)
+		**out = **in
+	}
+	if in.RevisionHistory != nil {
+		in, out := &in.RevisionHistory, &out.RevisionHistory
+		*out = make([]DefinitionRevisionHistory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TraitCompatibility != nil {
+		in, out := &in.TraitCompatibility, &out.TraitCompatibility
+		*out = new(TraitCompatibility)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentDefinitionStatus.
@@ -499,6 +521,22 @@ func (in *DefinitionRevision) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DefinitionRevisionHistory) DeepCopyInto(out *DefinitionRevisionHistory) {
+	*out = *in
+	in.CreationTimestamp.DeepCopyInto(&out.CreationTimestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefinitionRevisionHistory.
+func (in *DefinitionRevisionHistory) DeepCopy() *DefinitionRevisionHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(DefinitionRevisionHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DefinitionRevisionList) DeepCopyInto(out *DefinitionRevisionList) {
 	*out = *in
@@ -770,6 +808,61 @@ func (in *ResourceTrackerSpec) DeepCopy() *ResourceTrackerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchemaConfigMapReference) DeepCopyInto(out *SchemaConfigMapReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchemaConfigMapReference.
+func (in *SchemaConfigMapReference) DeepCopy() *SchemaConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SchemaConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchemaStorageReference) DeepCopyInto(out *SchemaStorageReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchemaStorageReference.
+func (in *SchemaStorageReference) DeepCopy() *SchemaStorageReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SchemaStorageReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraitCompatibility) DeepCopyInto(out *TraitCompatibility) {
+	*out = *in
+	if in.CompatibleTraits != nil {
+		in, out := &in.CompatibleTraits, &out.CompatibleTraits
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncompatibleTraits != nil {
+		in, out := &in.IncompatibleTraits, &out.IncompatibleTraits
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraitCompatibility.
+func (in *TraitCompatibility) DeepCopy() *TraitCompatibility {
+	if in == nil {
+		return nil
+	}
+	out := new(TraitCompatibility)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TraitDefinition) DeepCopyInto(out *TraitDefinition) {
 	*out = *in
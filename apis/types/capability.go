@@ -54,6 +54,17 @@ const CapabilityConfigMapNamePrefix = "schema-"
 const (
 	// OpenapiV3JSONSchema is the key to store OpenAPI v3 JSON schema in ConfigMap
 	OpenapiV3JSONSchema string = "openapi-v3-json-schema"
+	// OpenapiV3JSONSchemaEncoding is the key to mark how the OpenAPI v3 JSON schema is encoded in ConfigMap,
+	// the schema is stored as plain text if it's absent
+	OpenapiV3JSONSchemaEncoding string = "openapi-v3-json-schema-encoding"
+	// OpenapiV3Document is the key to store the OpenAPI v3 document wrapping the OpenAPI v3 JSON schema as the
+	// parameter component in ConfigMap, it's optional and encoded the same as the schema
+	OpenapiV3Document string = "openapi-v3-document"
+	// ParameterDefaults is the key to store the default values of the parameters applied by the OpenAPI v3 JSON schema
+	// in ConfigMap, it's a JSON object of the parameters with defaults and encoded the same as the schema
+	ParameterDefaults string = "parameter-defaults"
+	// SchemaEncodingGzipBase64 means the OpenAPI v3 JSON schema is gzip compressed and then base64 encoded
+	SchemaEncodingGzipBase64 string = "gzip+base64"
 	// UISchema is the key to store ui custom schema
 	UISchema string = "ui-schema"
 	// VelaQLConfigmapKey is the key to store velaql view
@@ -35,6 +35,7 @@ import (
 	oamcontroller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/resourcekeeper"
+	"github.com/oam-dev/kubevela/version"
 )
 
 // CoreOptions contains everything necessary to create and run vela-core
@@ -63,6 +64,15 @@ type CoreOptions struct {
 	ClusterMetricsInterval     time.Duration
 }
 
+// defaultDefRevisionIgnoredMetadataPrefixes are the metadata key prefixes of common GitOps tools, which are not
+// recorded into the definition revisions by default
+var defaultDefRevisionIgnoredMetadataPrefixes = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"argocd.argoproj.io/",
+	"kustomize.toolkit.fluxcd.io/",
+	"helm.toolkit.fluxcd.io/",
+}
+
 // NewCoreOptions creates a new NewVelaCoreOptions object with default parameters
 func NewCoreOptions() *CoreOptions {
 	s := &CoreOptions{
@@ -79,10 +89,27 @@ func NewCoreOptions() *CoreOptions {
 			RevisionLimit:                                50,
 			AppRevisionLimit:                             10,
 			DefRevisionLimit:                             20,
+			DefRevisionNamingStrategy:                    "sequential",
+			SharedSchemaNamespace:                        "",
+			SchemaStorageNamespace:                       "",
+			SchemaStorageBackend:                         "configmap",
+			SchemaStorageS3Endpoint:                      "",
+			SchemaStorageS3Bucket:                        "",
+			SchemaStorageS3Region:                        "",
+			DefSchemaGenerationTimeout:                   30 * time.Second,
+			DefSchemaSizeWarningThreshold:                512 * 1024,
+			DefSchemaCache:                               "none",
+			DefinitionPolicyConfigMap:                    "",
+			DefRevisionIgnoredMetadataPrefixes:           defaultDefRevisionIgnoredMetadataPrefixes,
+			DefReconcileBaseDelay:                        5 * time.Millisecond,
+			DefReconcileQPS:                              10,
+			DefReconcileBurst:                            100,
+			DefCircuitBreakerOpenInterval:                30 * time.Minute,
 			AutoGenWorkloadDefinition:                    true,
 			ConcurrentReconciles:                         4,
 			IgnoreAppWithoutControllerRequirement:        false,
 			IgnoreDefinitionWithoutControllerRequirement: false,
+			ControllerVersion:                            version.VelaVersion,
 		},
 		HealthAddr:                 ":9440",
 		StorageDriver:              "Local",
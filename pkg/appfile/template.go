@@ -42,8 +42,101 @@ const (
 	UsageTag = "+usage="
 	// ShortTag is the short alias annotation
 	ShortTag = "+short"
+	// DeprecatedTag is the deprecation comment annotation of a parameter, the text after `=` is the deprecation message
+	DeprecatedTag = "+deprecated"
+	// DeprecationMessageExtension is the OpenAPI schema extension carrying the deprecation message of a parameter
+	DeprecationMessageExtension = "x-deprecation-message"
+	// RequiresTag is the comment annotation listing the sibling parameters required by a parameter, separated by `,`
+	RequiresTag = "+requires="
+	// ConflictsTag is the comment annotation listing the sibling parameters conflicting with a parameter, separated by `,`
+	ConflictsTag = "+conflicts="
+	// RequiresExtension is the OpenAPI schema extension carrying the parameters required by a parameter
+	RequiresExtension = "x-requires"
+	// ConflictsExtension is the OpenAPI schema extension carrying the parameters conflicting with a parameter
+	ConflictsExtension = "x-conflicts"
+	// AccessTag is the comment annotation of the access level of a parameter, see the AccessLevel constants
+	AccessTag = "+access="
+	// AccessLevelExtension is the OpenAPI schema extension carrying the access level of a parameter
+	AccessLevelExtension = "x-access-level"
 )
 
+const (
+	// AccessLevelBasic marks the parameters shown by the forms by default
+	AccessLevelBasic = "basic"
+	// AccessLevelAdvanced marks the parameters hidden by the forms by default
+	AccessLevelAdvanced = "advanced"
+	// AccessLevelInternal marks the parameters not meant to be set by the users, they can be excluded from the
+	// stored schema
+	AccessLevelInternal = "internal"
+)
+
+// ExtractDeprecatedTag removes the line of DeprecatedTag from the comment of a parameter, and reports whether
+// the parameter is deprecated along with the deprecation message
+func ExtractDeprecatedTag(comment string) (rest string, deprecated bool, message string) {
+	if !strings.Contains(comment, DeprecatedTag) {
+		return comment, false, ""
+	}
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, DeprecatedTag) {
+			lines = append(lines, line)
+			continue
+		}
+		deprecated = true
+		message = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(trimmed, DeprecatedTag), "="))
+	}
+	return strings.Join(lines, "\n"), deprecated, message
+}
+
+// ExtractDependencyTags removes the lines of RequiresTag and ConflictsTag from the comment of a parameter, and
+// returns the parameters listed by them
+func ExtractDependencyTags(comment string) (rest string, requires []string, conflicts []string) {
+	if !strings.Contains(comment, RequiresTag) && !strings.Contains(comment, ConflictsTag) {
+		return comment, nil, nil
+	}
+	split := func(list string) []string {
+		var names []string
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, RequiresTag):
+			requires = append(requires, split(strings.TrimPrefix(trimmed, RequiresTag))...)
+		case strings.HasPrefix(trimmed, ConflictsTag):
+			conflicts = append(conflicts, split(strings.TrimPrefix(trimmed, ConflictsTag))...)
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), requires, conflicts
+}
+
+// ExtractAccessTag removes the line of AccessTag from the comment of a parameter, and returns the access level of the
+// parameter, which is empty if the tag is absent. The level is not validated here.
+func ExtractAccessTag(comment string) (rest string, level string) {
+	if !strings.Contains(comment, AccessTag) {
+		return comment, ""
+	}
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, AccessTag) {
+			lines = append(lines, line)
+			continue
+		}
+		level = strings.TrimSpace(strings.TrimPrefix(trimmed, AccessTag))
+	}
+	return strings.Join(lines, "\n"), level
+}
+
 // Template is a helper struct for processing capability including
 // ComponentDefinition, TraitDefinition.
 // It mainly collects schematic and status data of a capability definition.
@@ -379,3 +379,81 @@ spec:
 		t.Fatal("failed load template of trait definition ", diff)
 	}
 }
+
+func TestExtractDeprecatedTag(t *testing.T) {
+	cases := map[string]struct {
+		comment    string
+		rest       string
+		deprecated bool
+		message    string
+	}{
+		"not deprecated": {comment: "+usage=Which image\n+short=i", rest: "+usage=Which image\n+short=i"},
+		"with message": {
+			comment:    "+usage=Which image\n+deprecated=use images instead\n+short=i",
+			rest:       "+usage=Which image\n+short=i",
+			deprecated: true,
+			message:    "use images instead",
+		},
+		"without message": {comment: "+deprecated", deprecated: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rest, deprecated, message := ExtractDeprecatedTag(tc.comment)
+			assert.Equal(t, tc.rest, rest)
+			assert.Equal(t, tc.deprecated, deprecated)
+			assert.Equal(t, tc.message, message)
+		})
+	}
+}
+
+func TestExtractDependencyTags(t *testing.T) {
+	cases := map[string]struct {
+		comment   string
+		rest      string
+		requires  []string
+		conflicts []string
+	}{
+		"no dependency": {comment: "+usage=Enable TLS", rest: "+usage=Enable TLS"},
+		"requires and conflicts": {
+			comment:   "+usage=Enable TLS\n+requires=cert, key\n+conflicts=insecure",
+			rest:      "+usage=Enable TLS",
+			requires:  []string{"cert", "key"},
+			conflicts: []string{"insecure"},
+		},
+		"repeated tags": {
+			comment:  "+requires=cert\n+requires=key,",
+			rest:     "",
+			requires: []string{"cert", "key"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rest, requires, conflicts := ExtractDependencyTags(tc.comment)
+			assert.Equal(t, tc.rest, rest)
+			assert.Equal(t, tc.requires, requires)
+			assert.Equal(t, tc.conflicts, conflicts)
+		})
+	}
+}
+
+func TestExtractAccessTag(t *testing.T) {
+	cases := map[string]struct {
+		comment string
+		rest    string
+		level   string
+	}{
+		"no access level": {comment: "+usage=Enable TLS", rest: "+usage=Enable TLS"},
+		"access level": {
+			comment: "+usage=Enable TLS\n+access= advanced ",
+			rest:    "+usage=Enable TLS",
+			level:   AccessLevelAdvanced,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rest, level := ExtractAccessTag(tc.comment)
+			assert.Equal(t, tc.rest, rest)
+			assert.Equal(t, tc.level, level)
+		})
+	}
+}
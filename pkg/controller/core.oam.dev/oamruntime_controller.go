@@ -17,7 +17,10 @@ limitations under the License.
 package core_oam_dev
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
+	"k8s.io/component-base/featuregate"
 )
 
 // Args args used by controller
@@ -35,6 +38,168 @@ type Args struct {
 	// The default value is 20.
 	DefRevisionLimit int
 
+	// DefRevisionNamingStrategy is the naming strategy of definition revisions, either sequential or hash-based.
+	// The default value is sequential.
+	DefRevisionNamingStrategy string
+
+	// SharedSchemaNamespace is the namespace where the system-scoped component definitions publish their schema
+	// ConfigMaps, so that the schema can be resolved from other namespaces. It's disabled if empty.
+	SharedSchemaNamespace string
+
+	// SchemaStorageNamespace is the namespace where the schema ConfigMaps of all component definitions are stored, the
+	// namespace of the definition is encoded into the name of the ConfigMap. The schema is stored along with the
+	// definition if empty.
+	SchemaStorageNamespace string
+
+	// SchemaStorageBackend is where the schemas of the component definitions are stored, either configmap or s3.
+	// The default value is configmap.
+	SchemaStorageBackend string
+
+	// SchemaStorageS3Endpoint is the URL of the S3 compatible object store if the schemas are stored in s3
+	SchemaStorageS3Endpoint string
+
+	// SchemaStorageS3Bucket is the bucket storing the schemas if they are stored in s3
+	SchemaStorageS3Bucket string
+
+	// SchemaStorageS3Region is the region of the bucket storing the schemas if they are stored in s3
+	SchemaStorageS3Region string
+
+	// DefSchemaGenerationTimeout is the time budget of evaluating the CUE template of a component definition to
+	// generate its parameter schema. The generation is unlimited if it's not positive.
+	DefSchemaGenerationTimeout time.Duration
+
+	// DefSchemaSizeWarningThreshold is the size in bytes of the parameter schema of a component definition above which
+	// a warning is reported. The warning is disabled if it's not positive.
+	DefSchemaSizeWarningThreshold int
+
+	// DefSchemaOpenAPIV3Document also stores the complete OpenAPI v3 document of the parameters into the schema
+	// ConfigMaps of component definitions, along with the OpenAPI v3 JSON schema kept for backward compatibility.
+	DefSchemaOpenAPIV3Document bool
+
+	// DefRequireParameterDescriptions rejects the component definitions whose top-level parameters have no description
+	// by the validating webhook.
+	DefRequireParameterDescriptions bool
+
+	// DefAllowedFamilies are the component families allowed in the family label of component definitions by the
+	// validating webhook. Any family is allowed if it's empty.
+	DefAllowedFamilies []string
+
+	// DefAllowedCUEImports are the packages the CUE templates of component definitions are allowed to import by the
+	// validating webhook. Any import is allowed if it's empty and DefAllowedCUEImportsConfigMap is not set.
+	DefAllowedCUEImports []string
+
+	// DefAllowedCUEImportsConfigMap is the namespace/name of the ConfigMap listing more packages the CUE templates of
+	// component definitions are allowed to import, it's read on every validation so the allowlist can be updated
+	// without restarting.
+	DefAllowedCUEImportsConfigMap string
+
+	// DefDetectUnusedParameters reports the parameters declared but never referenced by the CUE templates of
+	// component definitions in the ParametersUsed condition, which doesn't fail the definitions.
+	DefDetectUnusedParameters bool
+
+	// DefRevisionGCMaintenanceWindows are the windows formatted as <start>/<end> in RFC3339, during which the
+	// definition revisions are never garbage collected, e.g. the change freezes.
+	DefRevisionGCMaintenanceWindows []string
+
+	// DefRevisionGCGracePeriod is the minimum age of the definition revisions by their creation timestamps before
+	// they are garbage collected, so that the revisions just superseded are kept for a while to roll back to.
+	DefRevisionGCGracePeriod time.Duration
+
+	// DefNamespaceAllowlist and DefNamespaceDenylist scope the component definitions reconciled by the controller to
+	// the namespaces, so that the controllers sharing a cluster don't process the same definitions. All the namespaces
+	// are allowed if the allowlist is empty, and the namespaces of the denylist are never allowed.
+	DefNamespaceAllowlist []string
+	DefNamespaceDenylist  []string
+
+	// DefTracingEndpoint is the OTLP gRPC endpoint, e.g. otel-collector:4317, which the spans of the component
+	// definition reconciles are exported to. The tracing is disabled if it's empty.
+	DefTracingEndpoint string
+	// DefTracingInsecure exports the spans without TLS
+	DefTracingInsecure bool
+
+	// DefCircuitBreakerThreshold is the number of consecutive failures of generating the schema of the same generation
+	// of a component definition, after which the definition is retried at DefCircuitBreakerOpenInterval instead of the
+	// exponential backoff until it changes. The circuit breaker is disabled if either of them is not positive.
+	DefCircuitBreakerThreshold    int
+	DefCircuitBreakerOpenInterval time.Duration
+
+	// DefTerraformModuleCheckTimeout is the time budget of resolving the remote modules called by the Terraform
+	// component definitions, the unreachable modules are reported in the conditions. The check is disabled if it's not
+	// positive, so that the air-gapped clusters are not penalized.
+	DefTerraformModuleCheckTimeout time.Duration
+
+	// DefSpokeClusters are the clusters that the DefinitionRevisions and the schema ConfigMaps of component definitions
+	// are replicated to after the definitions are reconciled. The clusters are reached through the cluster gateway.
+	DefSpokeClusters []string
+
+	// DefSchemaCompilationConcurrency caps the concurrent evaluations of CUE templates generating the schemas of
+	// component definitions, independently of the concurrent reconciles. It's unlimited if not positive.
+	DefSchemaCompilationConcurrency int
+
+	// DefSchemaCache caches the parameter schemas generated from the CUE templates of component definitions by the hash
+	// of the schematic content, either none, memory or configmap. The configmap cache also reuses the schema ConfigMaps
+	// stored from the same content, e.g. after the controller restarts.
+	DefSchemaCache string
+
+	// DefSchemaDriftResyncPeriod is the period of checking the schema ConfigMaps of component definitions against the
+	// schemas of their latest revisions, the drifted ConfigMaps are restored. It's disabled if not positive.
+	DefSchemaDriftResyncPeriod time.Duration
+
+	// DefSchemaChecksum records the checksum of the schemas of component definitions on the schema ConfigMaps and the
+	// definition revisions, the schema drift resync compares the stored schemas with it instead of regenerating them.
+	DefSchemaChecksum bool
+
+	// DefExcludeInternalParameters removes the parameters of the internal access level from the stored schemas of
+	// component definitions.
+	DefExcludeInternalParameters bool
+
+	// DefSchemaFragmentMinSize stores the nested parameter schemas no smaller than the size in bytes once as the
+	// fragments shared by the schemas of component definitions, which refer to them by $ref. It's disabled if not positive.
+	DefSchemaFragmentMinSize int
+
+	// DefinitionPolicyConfigMap is the <namespace>/<name> of the ConfigMap holding the CUE policies that the component
+	// definitions are checked against. The policy check is disabled if empty.
+	DefinitionPolicyConfigMap string
+
+	// DefRevisionIgnoredMetadataPrefixes are the key prefixes of the labels and annotations that are not recorded into
+	// the definition revisions, e.g. the operational metadata added by GitOps tools.
+	DefRevisionIgnoredMetadataPrefixes []string
+
+	// DefRevisionNormalizeCUE ignores the comments and the formatting of the CUE templates when deciding whether a
+	// component definition is changed, so that such edits don't create new definition revisions.
+	DefRevisionNormalizeCUE bool
+
+	// DefTenantLabels are the labels set on the schema ConfigMaps and the definition revisions of component definitions,
+	// so that the label based RBAC and network policies of tenants apply to them.
+	DefTenantLabels map[string]string
+
+	// DefTenantLabelKeys are the keys of the component definition labels copied onto the schema ConfigMaps and the
+	// definition revisions, they take precedence over DefTenantLabels.
+	DefTenantLabelKeys []string
+
+	// DefRevisionSigningKeyFile is the path of the PEM encoded PKCS #8 Ed25519 private key signing the new definition
+	// revisions. The revisions are not signed if empty.
+	DefRevisionSigningKeyFile string
+
+	// DefRevisionVerificationKeyFile is the path of the PEM encoded PKIX Ed25519 public key verifying the signatures of
+	// the latest definition revisions. The verification is disabled if empty.
+	DefRevisionVerificationKeyFile string
+
+	// DefReconcileBaseDelay is the initial per-item delay of requeueing a component definition, which grows
+	// exponentially on the consecutive failures of the definition.
+	DefReconcileBaseDelay time.Duration
+
+	// DefReconcileQPS is the overall rate of the rate limited reconciles of component definitions, including the ones
+	// of all definitions listed when the controller starts. The rate is unlimited if it's not positive.
+	DefReconcileQPS float64
+
+	// DefReconcileBurst is the number of component definitions that can be reconciled at once before DefReconcileQPS applies
+	DefReconcileBurst int
+
+	// AuditOnly makes the component definition controller validate the definitions and report the conditions and
+	// events without any other writes, i.e. no finalizers, revisions, schema ConfigMaps or other status fields.
+	AuditOnly bool
+
 	// ConcurrentReconciles is the concurrent reconcile number of the controller
 	ConcurrentReconciles int
 
@@ -46,6 +211,14 @@ type Args struct {
 
 	// IgnoreDefinitionWithoutControllerRequirement indicates that trait/component/workflowstep definition controller will not process the definition without 'definition.oam.dev/controller-version-require' annotation.
 	IgnoreDefinitionWithoutControllerRequirement bool
+
+	// FeatureGates are the feature gates toggling the optional behaviors of the controllers, e.g.
+	// features.DefinitionSchemaCompression. The feature gates of the process set by --feature-gates are used if nil.
+	FeatureGates featuregate.FeatureGate
+
+	// ControllerVersion is the build version of the controller, which is stamped on the definition revisions whose
+	// schemas are generated by the controller. The revisions are not stamped if it's empty.
+	ControllerVersion string
 }
 
 // AddFlags adds flags to the specified FlagSet
@@ -56,6 +229,90 @@ func (a *Args) AddFlags(fs *pflag.FlagSet, c *Args) {
 		"application-revision-limit is the maximum number of application useless revisions that will be maintained, if the useless revisions exceed this number, older ones will be GCed first.The default value is 10.")
 	fs.IntVar(&a.DefRevisionLimit, "definition-revision-limit", c.DefRevisionLimit,
 		"definition-revision-limit is the maximum number of component/trait definition useless revisions that will be maintained, if the useless revisions exceed this number, older ones will be GCed first.The default value is 20.")
+	fs.StringVar(&a.DefRevisionNamingStrategy, "definition-revision-naming-strategy", c.DefRevisionNamingStrategy,
+		"definition-revision-naming-strategy decides how the component definition revisions are named, sequential names them with the incrementing revision number while hash-based names them with the revision hash. The default value is sequential.")
+	fs.StringVar(&a.SharedSchemaNamespace, "shared-schema-namespace", c.SharedSchemaNamespace,
+		"shared-schema-namespace is the namespace where the component definitions labeled with 'definition.oam.dev/system-scoped' publish their schema ConfigMaps. It's disabled if empty.")
+	fs.StringVar(&a.SchemaStorageNamespace, "schema-storage-namespace", c.SchemaStorageNamespace,
+		"schema-storage-namespace is the namespace where the schema ConfigMaps of all component definitions are stored, the ConfigMaps are named as component-schema-<namespace>.<name>. The schema is stored in the namespace of the definition if empty.")
+	fs.StringVar(&a.SchemaStorageBackend, "schema-storage-backend", c.SchemaStorageBackend,
+		"schema-storage-backend is where the schemas of the component definitions are stored, either configmap or s3. The s3 backend keeps the schemas out of etcd, its credentials are read from the environment variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, and the schemas are not published to the shared schema namespace. The default value is configmap.")
+	fs.StringVar(&a.SchemaStorageS3Endpoint, "schema-storage-s3-endpoint", c.SchemaStorageS3Endpoint,
+		"schema-storage-s3-endpoint is the URL of the S3 compatible object store if the schema storage backend is s3.")
+	fs.StringVar(&a.SchemaStorageS3Bucket, "schema-storage-s3-bucket", c.SchemaStorageS3Bucket,
+		"schema-storage-s3-bucket is the bucket storing the schemas if the schema storage backend is s3.")
+	fs.StringVar(&a.SchemaStorageS3Region, "schema-storage-s3-region", c.SchemaStorageS3Region,
+		"schema-storage-s3-region is the region of the bucket storing the schemas if the schema storage backend is s3. The default value is us-east-1.")
+	fs.DurationVar(&a.DefSchemaGenerationTimeout, "definition-schema-generation-timeout", c.DefSchemaGenerationTimeout,
+		"definition-schema-generation-timeout is the time budget of evaluating the CUE template of a component definition to generate its parameter schema, the definition exceeding it is rejected. The generation is unlimited if it's not positive. The default value is 30s.")
+	fs.IntVar(&a.DefSchemaSizeWarningThreshold, "definition-schema-size-warning-threshold", c.DefSchemaSizeWarningThreshold,
+		"definition-schema-size-warning-threshold is the size in bytes of the parameter schema of a component definition above which a warning is reported, so that the schema growing towards the size limit of ConfigMap is caught early. The warning is disabled if it's not positive. The default value is 524288.")
+	fs.BoolVar(&a.DefRequireParameterDescriptions, "definition-require-parameter-descriptions", c.DefRequireParameterDescriptions,
+		"definition-require-parameter-descriptions makes the validating webhook reject the component definitions whose top-level parameters have no description, the parameters missing descriptions are reported. The parameters of remote Terraform configurations are not checked.")
+	fs.StringSliceVar(&a.DefAllowedFamilies, "definition-allowed-families", c.DefAllowedFamilies,
+		"definition-allowed-families are the component families allowed in the definition.oam.dev/family label of component definitions, the validating webhook rejects the definitions of unknown families. The definitions without the label are always allowed. Any family is allowed if it's empty, which is the default.")
+	fs.StringSliceVar(&a.DefAllowedCUEImports, "definition-allowed-cue-imports", c.DefAllowedCUEImports,
+		"definition-allowed-cue-imports are the packages the CUE templates of component definitions are allowed to import, the validating webhook rejects the definitions importing other packages. An entry ending with /* allows all the packages under that path. Any import is allowed if it's empty and definition-allowed-cue-imports-configmap is not set, which is the default.")
+	fs.StringVar(&a.DefAllowedCUEImportsConfigMap, "definition-allowed-cue-imports-configmap", c.DefAllowedCUEImportsConfigMap,
+		"definition-allowed-cue-imports-configmap is the namespace/name of a ConfigMap whose imports key lists more packages allowed by definition-allowed-cue-imports, separated by commas or newlines. It's read on every validation, and only the packages of definition-allowed-cue-imports are allowed if the ConfigMap doesn't exist.")
+	fs.BoolVar(&a.DefDetectUnusedParameters, "definition-detect-unused-parameters", c.DefDetectUnusedParameters,
+		"definition-detect-unused-parameters reports the top-level parameters declared but never referenced by the CUE templates and the status templates of component definitions in the ParametersUsed condition along with a warning event, the definitions are not failed. The parameters referenced as a whole are treated as used.")
+	fs.StringSliceVar(&a.DefRevisionGCMaintenanceWindows, "definition-revision-gc-maintenance-windows", c.DefRevisionGCMaintenanceWindows,
+		"definition-revision-gc-maintenance-windows are the maintenance windows formatted as <start>/<end> in RFC3339, e.g. 2024-12-20T00:00:00Z/2025-01-06T00:00:00Z, during which the garbage collection of definition revisions is skipped, e.g. for the change freezes. The collection is deferred until the window closes.")
+	fs.DurationVar(&a.DefRevisionGCGracePeriod, "definition-revision-gc-grace-period", c.DefRevisionGCGracePeriod,
+		"definition-revision-gc-grace-period is the minimum age of the definition revisions by their creation timestamps before they are garbage collected, so that the revisions just superseded are kept for a while to roll back to. The revisions over the definition-revision-limit are collected once they age past the grace period. It's disabled if not positive, which is the default.")
+	fs.StringSliceVar(&a.DefNamespaceAllowlist, "definition-namespace-allowlist", c.DefNamespaceAllowlist,
+		"definition-namespace-allowlist are the namespaces of the component definitions reconciled by the controller, so that the controllers sharing a cluster are scoped to their own namespaces and don't process the same definitions. The definitions in all the namespaces are reconciled if it's empty, which is the default.")
+	fs.StringSliceVar(&a.DefNamespaceDenylist, "definition-namespace-denylist", c.DefNamespaceDenylist,
+		"definition-namespace-denylist are the namespaces of the component definitions never reconciled by the controller, it takes precedence over the definition-namespace-allowlist.")
+	fs.StringVar(&a.DefTracingEndpoint, "definition-tracing-endpoint", c.DefTracingEndpoint,
+		"definition-tracing-endpoint is the OTLP gRPC endpoint, e.g. otel-collector:4317, which the OpenTelemetry spans of the component definition reconciles are exported to. The spans of generating the definition revisions, storing the parameter schemas and collecting the revisions are the children of the reconcile span. The tracing is disabled if it's empty, which is the default.")
+	fs.BoolVar(&a.DefTracingInsecure, "definition-tracing-insecure", c.DefTracingInsecure,
+		"definition-tracing-insecure exports the spans to the definition-tracing-endpoint without TLS, e.g. to a collector in the cluster.")
+	fs.IntVar(&a.DefCircuitBreakerThreshold, "definition-circuit-breaker-threshold", c.DefCircuitBreakerThreshold,
+		"definition-circuit-breaker-threshold is the number of consecutive failures of generating the schema of the same generation of a component definition, after which the circuit of the definition opens and it's retried at the definition-circuit-breaker-open-interval instead of the exponential backoff. The CircuitOpen condition is set and a warning event is emitted. A new generation of the definition closes the circuit. It's disabled if not positive.")
+	fs.DurationVar(&a.DefCircuitBreakerOpenInterval, "definition-circuit-breaker-open-interval", c.DefCircuitBreakerOpenInterval,
+		"definition-circuit-breaker-open-interval is the interval of retrying the component definitions whose circuit is open, see definition-circuit-breaker-threshold.")
+	fs.DurationVar(&a.DefTerraformModuleCheckTimeout, "definition-terraform-module-check-timeout", c.DefTerraformModuleCheckTimeout,
+		"definition-terraform-module-check-timeout is the time budget of resolving the remote modules, i.e. git repositories, registry modules and http archives, called by the inline configurations of Terraform component definitions. The unreachable modules are reported in the TerraformModulesReachable condition. The check is disabled if it's not positive, which is the default for air-gapped clusters.")
+	fs.StringSliceVar(&a.DefSpokeClusters, "definition-spoke-clusters", c.DefSpokeClusters,
+		"definition-spoke-clusters are the clusters that the DefinitionRevisions and the schema ConfigMaps of component definitions are replicated to after the definitions are reconciled, so that the schemas can be resolved locally there. It requires the cluster gateway to be enabled, and the failure of a cluster is reported by an event without blocking the others.")
+	fs.IntVar(&a.DefSchemaCompilationConcurrency, "definition-schema-compilation-concurrency", c.DefSchemaCompilationConcurrency,
+		"definition-schema-compilation-concurrency caps the concurrent evaluations of CUE templates generating the parameter schemas of component definitions, so that a burst of new definitions doesn't take all the CPU of the controller. The reconciles waiting for the compilation don't count to the definition-schema-generation-timeout, and a template exceeding the timeout holds one slot at most until its evaluation finishes. It's unlimited if not positive, which is the default.")
+	fs.StringVar(&a.DefSchemaCache, "definition-schema-cache", c.DefSchemaCache,
+		"definition-schema-cache caches the parameter schemas generated from the CUE templates of component definitions by the hash of the schematic content, so that the reconciles triggered by metadata changes skip the CUE compilation, either none, memory or configmap. The configmap cache also reuses the schema ConfigMaps stored from the same content, e.g. after the controller restarts. The default value is none.")
+	fs.DurationVar(&a.DefSchemaDriftResyncPeriod, "definition-schema-drift-resync-period", c.DefSchemaDriftResyncPeriod,
+		"definition-schema-drift-resync-period is the period of checking the schema ConfigMaps of component definitions against the schemas generated from their latest revisions, the ConfigMaps modified out of band are restored and a SchemaDriftCorrected event is recorded. It doesn't apply to the s3 schema storage backend and the audit only mode. It's disabled if not positive, which is the default.")
+	fs.BoolVar(&a.DefSchemaChecksum, "definition-schema-checksum", c.DefSchemaChecksum,
+		"definition-schema-checksum records the sha256 checksum of the parameter schemas of component definitions in the definition.oam.dev/schema-checksum annotation of the schema ConfigMaps and the definition revisions, so that the readers can verify the integrity of the stored schemas. The schema drift resync compares the stored schemas with the checksums of the revisions instead of regenerating them. It doesn't apply to the s3 schema storage backend.")
+	fs.BoolVar(&a.DefExcludeInternalParameters, "definition-exclude-internal-parameters", c.DefExcludeInternalParameters,
+		"definition-exclude-internal-parameters removes the parameters marked by the +access=internal tag from the stored parameter schemas of component definitions, so that the clients never show them. The parameters of the basic and advanced access levels are kept with their levels in the x-access-level extension.")
+	fs.IntVar(&a.DefSchemaFragmentMinSize, "definition-schema-fragment-min-size", c.DefSchemaFragmentMinSize,
+		"definition-schema-fragment-min-size stores the nested parameter schemas of component definitions no smaller than the size in bytes once as shared fragments in the ConfigMaps named schema-fragment-<hash>, and refers to them by $ref from the schema ConfigMaps, so that the structures repeated by many definitions are stored once. The schemas read through the schema store have the references resolved. It's disabled if not positive, which is the default.")
+	fs.BoolVar(&a.DefSchemaOpenAPIV3Document, "definition-schema-openapi-v3-document", c.DefSchemaOpenAPIV3Document,
+		"definition-schema-openapi-v3-document also stores the complete OpenAPI v3 document of the parameters under the key openapi-v3-document of the schema ConfigMaps of component definitions, for the tools which only consume OpenAPI v3 documents. The OpenAPI v3 JSON schema is always stored under the key openapi-v3-json-schema. It doesn't apply to the s3 schema storage backend.")
+	fs.StringVar(&a.DefinitionPolicyConfigMap, "definition-policy-configmap", c.DefinitionPolicyConfigMap,
+		"definition-policy-configmap is the <namespace>/<name> of the ConfigMap holding the CUE policies that the component definitions are checked against, each .cue file of the ConfigMap declares the violations in its deny list. The policy check is disabled if empty.")
+	fs.StringSliceVar(&a.DefRevisionIgnoredMetadataPrefixes, "definition-revision-ignored-metadata-prefixes", c.DefRevisionIgnoredMetadataPrefixes,
+		"definition-revision-ignored-metadata-prefixes are the key prefixes of the definition labels and annotations that are not recorded into the definition revisions, e.g. the ones added by GitOps tools.")
+	fs.BoolVar(&a.DefRevisionNormalizeCUE, "definition-revision-normalize-cue", c.DefRevisionNormalizeCUE,
+		"definition-revision-normalize-cue ignores the comments and the formatting of the CUE templates when deciding whether a component definition is changed, so that the edits without semantic changes don't create new definition revisions. The revisions still record the templates as they are. Toggling it creates a new revision for each definition on the next reconcile as the revision hash changes.")
+	fs.StringToStringVar(&a.DefTenantLabels, "definition-tenant-labels", c.DefTenantLabels,
+		"definition-tenant-labels are the labels set on the schema ConfigMaps and the definition revisions of component definitions, so that the label based RBAC and network policies of tenants apply to them, e.g. tenant.example.com/name=platform.")
+	fs.StringSliceVar(&a.DefTenantLabelKeys, "definition-tenant-label-keys", c.DefTenantLabelKeys,
+		"definition-tenant-label-keys are the keys of the component definition labels copied onto their schema ConfigMaps and definition revisions, which take precedence over definition-tenant-labels.")
+	fs.StringVar(&a.DefRevisionSigningKeyFile, "definition-revision-signing-key", c.DefRevisionSigningKeyFile,
+		"definition-revision-signing-key is the path of the PEM encoded PKCS #8 Ed25519 private key which signs the revision hash of the new component definition revisions. The revisions are not signed if empty.")
+	fs.StringVar(&a.DefRevisionVerificationKeyFile, "definition-revision-verification-key", c.DefRevisionVerificationKeyFile,
+		"definition-revision-verification-key is the path of the PEM encoded PKIX Ed25519 public key which verifies the signature of the latest component definition revisions, the unsigned or tampered revisions are reported in the RevisionSignatureValid condition of the definition. The verification is disabled if empty.")
+	fs.DurationVar(&a.DefReconcileBaseDelay, "definition-reconcile-base-delay", c.DefReconcileBaseDelay,
+		"definition-reconcile-base-delay is the initial per-item delay of requeueing a component definition, which grows exponentially on the consecutive failures of the definition. The default value is 5ms.")
+	fs.Float64Var(&a.DefReconcileQPS, "definition-reconcile-qps", c.DefReconcileQPS,
+		"definition-reconcile-qps is the overall rate of the rate limited reconciles of component definitions, the definitions listed when the controller starts are enqueued at this rate to avoid the load spike of compiling all CUE templates at once. The rate is unlimited if it's not positive. The default value is 10.")
+	fs.IntVar(&a.DefReconcileBurst, "definition-reconcile-burst", c.DefReconcileBurst,
+		"definition-reconcile-burst is the number of component definitions that can be reconciled at once before definition-reconcile-qps applies. The default value is 100.")
+	fs.BoolVar(&a.AuditOnly, "audit-only", c.AuditOnly,
+		"If true, component definition controller only validates the definitions and reports the problems in their conditions and events, no finalizers, definition revisions, schema ConfigMaps or other status fields are written. It's meant for auditing the definitions without side effects.")
 	fs.BoolVar(&a.AutoGenWorkloadDefinition, "autogen-workload-definition", c.AutoGenWorkloadDefinition, "Automatic generated workloadDefinition which componentDefinition refers to.")
 	fs.IntVar(&a.ConcurrentReconciles, "concurrent-reconciles", c.ConcurrentReconciles, "concurrent-reconciles is the concurrent reconcile number of the controller. The default value is 4")
 	fs.BoolVar(&a.IgnoreAppWithoutControllerRequirement, "ignore-app-without-controller-version", c.IgnoreAppWithoutControllerRequirement, "If true, application controller will not process the app without 'app.oam.dev/controller-version-require' annotation")
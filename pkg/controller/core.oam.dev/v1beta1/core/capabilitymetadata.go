@@ -0,0 +1,97 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	velaerrors "github.com/oam-dev/kubevela/pkg/utils/errors"
+)
+
+// TypeCapabilityMetadataValid indicates whether the category and the icon declared by the definition are valid
+const TypeCapabilityMetadataValid = "CapabilityMetadataValid"
+
+// CapabilityCategories are the known categories the definitions are grouped by in UI
+var CapabilityCategories = sets.NewString(
+	"application", "database", "messaging", "networking", "storage", "observability", "security", "cloud-resource", "other")
+
+var (
+	iconFormats     = sets.NewString(".png", ".svg", ".jpg", ".jpeg", ".gif", ".webp")
+	iconDataURLExpr = regexp.MustCompile(`^data:image/(png|svg\+xml|jpeg|gif|webp);base64,[A-Za-z0-9+/]+=*$`)
+)
+
+// ParseCapabilityMetadata validates the category and the icon declared by the annotations types.AnnoDefinitionCategory
+// and types.AnnoDefinitionIcon of the definition, and returns them to be stored along with the schema. It returns nil
+// if neither annotation is set. The category must be one of CapabilityCategories, and the icon must be either an
+// http(s) URL of an image or a base64 data URL of an image.
+func ParseCapabilityMetadata(def metav1.Object) (map[string]string, error) {
+	annotations := def.GetAnnotations()
+	category, categorySet := annotations[types.AnnoDefinitionCategory]
+	icon, iconSet := annotations[types.AnnoDefinitionIcon]
+	if !categorySet && !iconSet {
+		return nil, nil
+	}
+	metadata := map[string]string{}
+	var errs velaerrors.ErrorList
+	if categorySet {
+		if !CapabilityCategories.Has(category) {
+			errs = append(errs, fmt.Errorf("unknown category %q in %s, it must be one of %s",
+				category, types.AnnoDefinitionCategory, strings.Join(CapabilityCategories.List(), ",")))
+		} else {
+			metadata[types.AnnoDefinitionCategory] = category
+		}
+	}
+	if iconSet {
+		if err := validateIcon(icon); err != nil {
+			errs = append(errs, fmt.Errorf("invalid icon in %s: %w", types.AnnoDefinitionIcon, err))
+		} else {
+			metadata[types.AnnoDefinitionIcon] = icon
+		}
+	}
+	if errs.HasError() {
+		return nil, errs
+	}
+	return metadata, nil
+}
+
+func validateIcon(icon string) error {
+	if strings.HasPrefix(icon, "data:") {
+		if !iconDataURLExpr.MatchString(icon) {
+			return fmt.Errorf("the data URL must be a base64 encoded image of format png, svg+xml, jpeg, gif or webp")
+		}
+		return nil
+	}
+	u, err := url.Parse(icon)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is neither an http(s) URL nor a data URL", icon)
+	}
+	if ext := strings.ToLower(path.Ext(u.Path)); !iconFormats.Has(ext) {
+		return fmt.Errorf("the format of %q is not one of %s", icon, strings.Join(iconFormats.List(), ","))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestParseCapabilityMetadata(t *testing.T) {
+	testCases := map[string]struct {
+		annotations map[string]string
+		expected    map[string]string
+		errContains string
+	}{
+		"not declared": {
+			annotations: map[string]string{oam.AnnotationDefinitionDeprecated: "true"},
+		},
+		"declared": {
+			annotations: map[string]string{
+				types.AnnoDefinitionCategory: "database",
+				types.AnnoDefinitionIcon:     "https://example.com/icons/mysql.SVG?v=1",
+			},
+			expected: map[string]string{
+				types.AnnoDefinitionCategory: "database",
+				types.AnnoDefinitionIcon:     "https://example.com/icons/mysql.SVG?v=1",
+			},
+		},
+		"data URL icon": {
+			annotations: map[string]string{types.AnnoDefinitionIcon: "data:image/png;base64,iVBORw0KGgo="},
+			expected:    map[string]string{types.AnnoDefinitionIcon: "data:image/png;base64,iVBORw0KGgo="},
+		},
+		"unknown category": {
+			annotations: map[string]string{types.AnnoDefinitionCategory: "games"},
+			errContains: `unknown category "games" in definition.oam.dev/category`,
+		},
+		"icon not a URL": {
+			annotations: map[string]string{types.AnnoDefinitionIcon: "mysql.svg"},
+			errContains: `"mysql.svg" is neither an http(s) URL nor a data URL`,
+		},
+		"icon of unknown format": {
+			annotations: map[string]string{types.AnnoDefinitionIcon: "https://example.com/icons/mysql.exe"},
+			errContains: `the format of "https://example.com/icons/mysql.exe" is not one of`,
+		},
+		"icon data URL not an image": {
+			annotations: map[string]string{types.AnnoDefinitionIcon: "data:text/html;base64,PGh0bWw+"},
+			errContains: "the data URL must be a base64 encoded image",
+		},
+		"both invalid": {
+			annotations: map[string]string{
+				types.AnnoDefinitionCategory: "",
+				types.AnnoDefinitionIcon:     "ftp://example.com/mysql.png",
+			},
+			errContains: "unknown category",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			def := &v1beta1.ComponentDefinition{ObjectMeta: metav1.ObjectMeta{Name: "worker", Annotations: tc.annotations}}
+			metadata, err := ParseCapabilityMetadata(def)
+			if tc.errContains != "" {
+				require.ErrorContains(t, err, tc.errContains)
+				require.Nil(t, metadata)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, metadata)
+		})
+	}
+}
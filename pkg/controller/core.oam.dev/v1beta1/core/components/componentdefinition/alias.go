@@ -0,0 +1,115 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+)
+
+// getAlias returns the alias declared by the annotation of the definition, the empty alias is ignored
+func getAlias(def client.Object) string {
+	return strings.TrimSpace(def.GetAnnotations()[types.AnnoDefinitionAlias])
+}
+
+// claimsAliasBefore checks whether the alias of the definition a takes precedence over the one of b,
+// the definition created earlier wins and the name breaks the tie
+func claimsAliasBefore(a, b *v1beta1.ComponentDefinition) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// checkAlias validates the alias of the definition against the other ComponentDefinitions in the namespace. The alias
+// is accepted if it's neither the name of another definition nor declared by a definition created earlier, otherwise
+// the AliasAccepted condition of the later definition is set to False. It returns the accepted alias.
+func (r *Reconciler) checkAlias(ctx context.Context, def *v1beta1.ComponentDefinition) (string, []condition.Condition) {
+	current := def.GetCondition(coredef.TypeAliasAccepted)
+	alias := getAlias(def)
+	if alias == "" {
+		if current.Status == corev1.ConditionUnknown {
+			return "", nil
+		}
+		return "", []condition.Condition{condition.ReadyCondition(coredef.TypeAliasAccepted)}
+	}
+	err := r.validateAlias(ctx, def, alias)
+	if err == nil {
+		return alias, []condition.Condition{condition.ReadyCondition(coredef.TypeAliasAccepted)}
+	}
+	if current.Status != corev1.ConditionFalse {
+		r.recorder().Event(def, event.Warning("the alias is not accepted", err))
+	}
+	return "", []condition.Condition{condition.ErrorCondition(coredef.TypeAliasAccepted, err)}
+}
+
+func (r *Reconciler) validateAlias(ctx context.Context, def *v1beta1.ComponentDefinition, alias string) error {
+	if msgs := validation.IsDNS1123Subdomain(alias); len(msgs) != 0 {
+		return fmt.Errorf("invalid alias %q: %s", alias, strings.Join(msgs, ","))
+	}
+	defList := &v1beta1.ComponentDefinitionList{}
+	if err := r.List(ctx, defList, client.InNamespace(def.Namespace)); err != nil {
+		return fmt.Errorf("cannot list ComponentDefinitions to check the alias %s: %w", alias, err)
+	}
+	for i := range defList.Items {
+		other := &defList.Items[i]
+		if other.Name == def.Name || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if other.Name == alias {
+			return fmt.Errorf("the alias %s is the name of ComponentDefinition %s", alias, other.Name)
+		}
+		if getAlias(other) == alias && claimsAliasBefore(other, def) {
+			return fmt.Errorf("the alias %s is already taken by ComponentDefinition %s", alias, other.Name)
+		}
+	}
+	return nil
+}
+
+// findDefinitionsSharingAlias finds the other ComponentDefinitions declaring the same alias with the changed one,
+// so that the alias released by a definition is taken over by the next one in order
+func (r *Reconciler) findDefinitionsSharingAlias(obj client.Object) []reconcile.Request {
+	alias := getAlias(obj)
+	if alias == "" {
+		return nil
+	}
+	defList := &v1beta1.ComponentDefinitionList{}
+	if err := r.List(context.Background(), defList, client.InNamespace(obj.GetNamespace())); err != nil {
+		klog.ErrorS(err, "Could not list ComponentDefinitions sharing the alias", "alias", alias)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, other := range defList.Items {
+		if other.Name != obj.GetName() && getAlias(&other) == alias {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&other)})
+		}
+	}
+	return requests
+}
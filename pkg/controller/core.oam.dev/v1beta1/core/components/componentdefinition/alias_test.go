@@ -0,0 +1,103 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+)
+
+func newAliasedComponentDefinition(name, alias string, created time.Time) *v1beta1.ComponentDefinition {
+	cd := newFakeComponentDefinition(name, "default")
+	cd.Annotations = map[string]string{types.AnnoDefinitionAlias: alias}
+	cd.CreationTimestamp = metav1.NewTime(created)
+	return cd
+}
+
+func TestAliasCollision(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	first := newAliasedComponentDefinition("webservice-v2", "webservice", now)
+	second := newAliasedComponentDefinition("webservice-v3", "webservice", now.Add(time.Minute))
+	named := newAliasedComponentDefinition("worker-v2", "worker", now)
+	worker := newFakeComponentDefinition("worker", "default")
+	invalid := newAliasedComponentDefinition("invalid-alias", "Web Service", now)
+	noAlias := newAliasedComponentDefinition("no-alias", "", now)
+	r := newFakeReconciler(t, first, second, named, worker, invalid, noAlias)
+	counter := &countingRecorder{}
+	r.record = counter
+
+	// the later definition is reconciled first, which doesn't matter
+	for _, cd := range []*v1beta1.ComponentDefinition{second, first, named, invalid, noAlias, second} {
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(first), got))
+	require.Equal(t, "webservice", got.Status.Alias)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeAliasAccepted).Status)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(second), got))
+	require.Empty(t, got.Status.Alias)
+	rejected := got.GetCondition(coredef.TypeAliasAccepted)
+	require.Equal(t, corev1.ConditionFalse, rejected.Status)
+	require.Contains(t, rejected.Message, "the alias webservice is already taken by ComponentDefinition webservice-v2")
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(named), got))
+	require.Contains(t, got.GetCondition(coredef.TypeAliasAccepted).Message, "the alias worker is the name of ComponentDefinition worker")
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(invalid), got))
+	require.Contains(t, got.GetCondition(coredef.TypeAliasAccepted).Message, `invalid alias "Web Service"`)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(noAlias), got))
+	require.Equal(t, corev1.ConditionUnknown, got.GetCondition(coredef.TypeAliasAccepted).Status)
+	// one warning for each rejected definition, not repeated by the later reconciles
+	require.Equal(t, 3, counter.warnings)
+
+	name, err := utils.ResolveComponentDefinitionAlias(ctx, r.Client, "default", "webservice")
+	require.NoError(t, err)
+	require.Equal(t, "webservice-v2", name)
+	name, err = utils.ResolveComponentDefinitionAlias(ctx, r.Client, "default", "worker")
+	require.NoError(t, err)
+	require.Equal(t, "worker", name)
+	_, err = utils.ResolveComponentDefinitionAlias(ctx, r.Client, "default", "not-exist")
+	require.Error(t, err)
+
+	// the alias released by the earlier definition is taken over by the later one
+	require.Equal(t, []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(second)}}, r.findDefinitionsSharingAlias(first))
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(first), got))
+	got.Annotations[types.AnnoDefinitionAlias] = "webservice-legacy"
+	require.NoError(t, r.Update(ctx, got))
+	for _, cd := range []*v1beta1.ComponentDefinition{first, second} {
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(second), got))
+	require.Equal(t, "webservice", got.Status.Alias)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeAliasAccepted).Status)
+}
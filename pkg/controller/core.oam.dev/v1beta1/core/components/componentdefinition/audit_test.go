@@ -0,0 +1,148 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+)
+
+// writeCountingClient counts the writes except the patches of status
+type writeCountingClient struct {
+	client.Client
+	writes        int
+	deletes       int
+	statusPatches int
+}
+
+func (c *writeCountingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.writes++
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *writeCountingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.writes++
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *writeCountingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.writes++
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *writeCountingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.writes++
+	c.deletes++
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *writeCountingClient) Status() client.SubResourceWriter {
+	return &writeCountingStatusWriter{SubResourceWriter: c.Client.Status(), client: c}
+}
+
+type writeCountingStatusWriter struct {
+	client.SubResourceWriter
+	client *writeCountingClient
+}
+
+func (w *writeCountingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	w.client.writes++
+	return w.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+func (w *writeCountingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	w.client.statusPatches++
+	return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func TestAuditOnly(t *testing.T) {
+	ctx := context.Background()
+	valid := newFakeComponentDefinition("audit-valid", "default")
+	invalid := newFakeComponentDefinition("audit-invalid", "default")
+	invalid.Spec.Schematic.CUE.Template = "parameter: {image: string\n"
+	r := newFakeReconciler(t, valid, invalid)
+	cli := &writeCountingClient{Client: r.Client}
+	r.Client = cli
+	r.auditOnly = true
+
+	for _, cd := range []*v1beta1.ComponentDefinition{valid, invalid} {
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	require.Zero(t, cli.writes)
+	require.Equal(t, 2, cli.statusPatches)
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(valid), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+	require.Empty(t, got.Finalizers)
+	require.Nil(t, got.Status.LatestRevision)
+	require.Empty(t, got.Status.ConfigMapRef)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(invalid), got))
+	require.Equal(t, corev1.ConditionFalse, got.GetCondition(coredef.TypeSchemaReady).Status)
+	require.Empty(t, got.Finalizers)
+
+	revs := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, r.List(ctx, revs))
+	require.Empty(t, revs.Items)
+	cms := &corev1.ConfigMapList{}
+	require.NoError(t, r.List(ctx, cms))
+	require.Empty(t, cms.Items)
+
+	// the unchanged conditions are not patched again
+	_, err := reconcileFake(t, r, valid.Name, valid.Namespace)
+	require.NoError(t, err)
+	require.Zero(t, cli.writes)
+	require.Equal(t, 2, cli.statusPatches)
+}
+
+func TestAuditOnlyReleasesFinalizer(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("audit-deleted", "default")
+	r := newFakeReconciler(t, cd)
+
+	// the definition is registered with the finalizer before the controller switches to the audit mode
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	r.auditOnly = true
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.NoError(t, r.Delete(ctx, got))
+	cli := &writeCountingClient{Client: r.Client}
+	r.Client = cli
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	// only the finalizer is released, the revision and the ConfigMap are left to the owner references
+	require.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(cd), &v1beta1.ComponentDefinition{})))
+	require.Zero(t, cli.deletes)
+	revs := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, r.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	require.Len(t, revs.Items, 1)
+	cms := &corev1.ConfigMapList{}
+	require.NoError(t, r.List(ctx, cms, client.InNamespace(cd.Namespace)))
+	require.NotEmpty(t, cms.Items)
+}
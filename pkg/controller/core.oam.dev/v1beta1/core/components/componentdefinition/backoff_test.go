@@ -0,0 +1,93 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// unavailableClient fails to create ConfigMaps until the given number of attempts is used up
+type unavailableClient struct {
+	client.Client
+	mu       sync.Mutex
+	failures int
+}
+
+func (c *unavailableClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if _, ok := obj.(*corev1.ConfigMap); ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.failures > 0 {
+			c.failures--
+			return apierrors.NewServiceUnavailable("the server is not ready")
+		}
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestRequeueWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("backoff-cd", "default")
+	r := newFakeReconciler(t, cd)
+	// the schema can only be stored on the third reconcile, the ConfigMaps of the definition
+	// and its revision are written together in each reconcile
+	r.Client = &unavailableClient{Client: r.Client, failures: 4}
+	r.schemaBackoff = workqueue.NewItemExponentialFailureRateLimiter(schemaBackoffBaseDelay, schemaBackoffMaxDelay)
+	key := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cd)}.String()
+
+	result, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, schemaBackoffBaseDelay, result.RequeueAfter)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, condition.ReasonReconcileError, got.GetCondition(condition.TypeSynced).Reason)
+
+	result, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, 2*schemaBackoffBaseDelay, result.RequeueAfter)
+
+	result, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Zero(t, result.RequeueAfter)
+	require.Zero(t, r.schemaBackoff.NumRequeues(key))
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, condition.ReasonReconcileSuccess, got.GetCondition(condition.TypeSynced).Reason)
+	require.NotNil(t, got.Status.SchemaConfigMapRef)
+}
+
+func TestRequeueBackoffCapped(t *testing.T) {
+	r := &Reconciler{schemaBackoff: workqueue.NewItemExponentialFailureRateLimiter(schemaBackoffBaseDelay, schemaBackoffMaxDelay)}
+	req := ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "default", Name: "capped"}}
+	for i := 0; i < 20; i++ {
+		r.requeueWithBackoff(req)
+	}
+	require.Equal(t, schemaBackoffMaxDelay, r.requeueWithBackoff(req).RequeueAfter)
+	r.forgetBackoff(req)
+	require.Equal(t, schemaBackoffBaseDelay, r.requeueWithBackoff(req).RequeueAfter)
+}
@@ -0,0 +1,123 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+)
+
+// circuitBreaker counts the consecutive failures of reconciling each definition. Once the failures of the same
+// generation reach the threshold, the circuit of the definition opens and it's retried at the open interval
+// instead of the exponential backoff. A new generation of the definition closes the circuit, so does a success.
+type circuitBreaker struct {
+	threshold    int
+	openInterval time.Duration
+
+	mu       sync.Mutex
+	failures map[string]consecutiveFailures
+}
+
+type consecutiveFailures struct {
+	generation int64
+	count      int
+}
+
+// newCircuitBreaker returns nil if either the threshold or the open interval is not positive, which disables it
+func newCircuitBreaker(threshold int, openInterval time.Duration) *circuitBreaker {
+	if threshold <= 0 || openInterval <= 0 {
+		return nil
+	}
+	return &circuitBreaker{threshold: threshold, openInterval: openInterval, failures: map[string]consecutiveFailures{}}
+}
+
+// fail records a failure of the given generation of the definition and returns the number of consecutive failures,
+// the failures of the former generations are not counted
+func (b *circuitBreaker) fail(key string, generation int64) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f := b.failures[key]
+	if f.generation != generation {
+		f = consecutiveFailures{generation: generation}
+	}
+	f.count++
+	b.failures[key] = f
+	return f.count
+}
+
+// reset forgets the failures of the definition
+func (b *circuitBreaker) reset(key string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, key)
+}
+
+// tripCircuit records the failure of the definition, and opens the circuit once the consecutive failures reach the
+// threshold. The requeue result is backed off to the open interval if the circuit is open, otherwise the given
+// result is kept. A warning event is emitted when the circuit opens.
+func (r *Reconciler) tripCircuit(req ctrl.Request, def *v1beta1.ComponentDefinition, result ctrl.Result) (ctrl.Result, []condition.Condition) {
+	if r.circuitBreaker == nil {
+		return result, nil
+	}
+	failures := r.circuitBreaker.fail(req.String(), def.Generation)
+	if failures < r.circuitBreaker.threshold {
+		return result, r.closeCircuit(req, def, false)
+	}
+	cond := condition.Condition{
+		Type:               coredef.TypeCircuitOpen,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             coredef.ReasonCircuitOpen,
+		Message: fmt.Sprintf("failed %d consecutive times at generation %d, retried every %s until the definition changes",
+			failures, def.Generation, r.circuitBreaker.openInterval),
+	}
+	if def.GetCondition(coredef.TypeCircuitOpen).Status != corev1.ConditionTrue {
+		r.recorder().Event(def, event.Warning("circuit opened", errors.New(cond.Message)))
+	}
+	return ctrl.Result{RequeueAfter: r.circuitBreaker.openInterval}, []condition.Condition{cond}
+}
+
+// closeCircuit returns the closed CircuitOpen condition if the circuit of the definition was open. The failures of
+// the definition are forgotten if it's reconciled successfully.
+func (r *Reconciler) closeCircuit(req ctrl.Request, def *v1beta1.ComponentDefinition, succeeded bool) []condition.Condition {
+	if succeeded {
+		r.circuitBreaker.reset(req.String())
+	}
+	if def.GetCondition(coredef.TypeCircuitOpen).Status != corev1.ConditionTrue {
+		return nil
+	}
+	return []condition.Condition{{
+		Type:               coredef.TypeCircuitOpen,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             coredef.ReasonCircuitClosed,
+	}}
+}
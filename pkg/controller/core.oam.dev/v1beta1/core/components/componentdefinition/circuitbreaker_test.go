@@ -0,0 +1,100 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("circuit-cd", "default")
+	cd.Generation = 1
+	r := newFakeReconciler(t, cd)
+	cli := &unavailableClient{Client: r.Client, failures: 100}
+	r.Client = cli
+	recorder := &reasonRecorder{}
+	r.record = recorder
+	r.schemaBackoff = workqueue.NewItemExponentialFailureRateLimiter(schemaBackoffBaseDelay, schemaBackoffMaxDelay)
+	r.circuitBreaker = newCircuitBreaker(2, time.Hour)
+	got := &v1beta1.ComponentDefinition{}
+
+	result, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, schemaBackoffBaseDelay, result.RequeueAfter)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionUnknown, got.GetCondition(coredef.TypeCircuitOpen).Status)
+
+	// the circuit opens on the second consecutive failure
+	result, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, result.RequeueAfter)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeCircuitOpen).Status)
+	require.Equal(t, coredef.ReasonCircuitOpen, got.GetCondition(coredef.TypeCircuitOpen).Reason)
+	require.Contains(t, recorder.reasons, "circuit opened")
+
+	result, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, result.RequeueAfter)
+	opened := 0
+	for _, reason := range recorder.reasons {
+		if reason == "circuit opened" {
+			opened++
+		}
+	}
+	require.Equal(t, 1, opened, "the event is only emitted when the circuit opens")
+
+	// a new generation closes the circuit even though it still fails
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	got.Generation = 2
+	require.NoError(t, r.Update(ctx, got))
+	result, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Less(t, result.RequeueAfter, time.Hour)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionFalse, got.GetCondition(coredef.TypeCircuitOpen).Status)
+	require.Equal(t, coredef.ReasonCircuitClosed, got.GetCondition(coredef.TypeCircuitOpen).Reason)
+
+	// the circuit opens again and is closed by the success
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeCircuitOpen).Status)
+	cli.failures = 0
+	result, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Zero(t, result.RequeueAfter)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionFalse, got.GetCondition(coredef.TypeCircuitOpen).Status)
+	require.Equal(t, 1, r.circuitBreaker.fail("default/circuit-cd", got.Generation))
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	require.Nil(t, newCircuitBreaker(0, time.Hour))
+	require.Nil(t, newCircuitBreaker(3, 0))
+}
@@ -21,15 +21,37 @@ package componentdefinition
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	ctrlrec "github.com/kubevela/pkg/controller/reconciler"
+	monitorContext "github.com/kubevela/pkg/monitor/context"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/semaphore"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrlEvent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
@@ -37,86 +59,659 @@ import (
 	oamctrl "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/features"
+	"github.com/oam-dev/kubevela/pkg/monitor/metrics"
+	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/version"
 )
 
+const (
+	errUpdateComponentDefinitionFinalizer = "cannot update componentDefinition finalizer"
+)
+
+const (
+	// schemaBackoffBaseDelay is the initial delay to requeue a ComponentDefinition whose schema cannot be generated
+	schemaBackoffBaseDelay = time.Second
+	// schemaBackoffMaxDelay caps the exponential backoff of requeueing
+	schemaBackoffMaxDelay = 5 * time.Minute
+)
+
 // Reconciler reconciles a ComponentDefinition object
 type Reconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	record event.Recorder
+	// schemaBackoff computes the requeue delay per definition when the schema cannot be generated,
+	// e.g. the CRD of the workload is not ready yet
+	schemaBackoff workqueue.RateLimiter
+	// circuitBreaker backs off the definitions failing the schema persistently to a long interval if it's set
+	circuitBreaker *circuitBreaker
 	options
 }
 
 type options struct {
-	defRevLimit          int
-	concurrentReconciles int
-	ignoreDefNoCtrlReq   bool
-	controllerVersion    string
+	defRevLimit             int
+	concurrentReconciles    int
+	ignoreDefNoCtrlReq      bool
+	controllerVersion       string
+	sharedSchemaNamespace   string
+	schemaStorageNamespace  string
+	schemaGenerationTimeout time.Duration
+	schemaSizeThreshold     int
+	schemaOpenAPIV3Document bool
+	policyEvaluator         PolicyEvaluator
+	// schemaStore stores the schemas outside the ConfigMaps if it's set
+	schemaStore utils.SchemaStore
+	// schemaCache reuses the schemas generated from the unchanged CUE schematics if it's set
+	schemaCache *utils.SchemaCache
+	// reconcileBaseDelay, reconcileQPS and reconcileBurst configure the rate limiter of the reconcile queue
+	reconcileBaseDelay time.Duration
+	reconcileQPS       float64
+	reconcileBurst     int
+	// disableSchemaCompression and disableRevisionGCByUsage are parsed from the feature gates, the zero values
+	// keep the default behaviors
+	disableSchemaCompression bool
+	disableRevisionGCByUsage bool
+	// auditOnly validates the definitions without any writes except the conditions
+	auditOnly bool
+	// terraformModuleResolver resolves the remote modules of the Terraform definitions within the
+	// terraformModuleCheckTimeout if it's set
+	terraformModuleResolver     TerraformModuleResolver
+	terraformModuleCheckTimeout time.Duration
+	// spokeClusters are the clusters the DefinitionRevisions and the schema ConfigMaps are replicated to
+	spokeClusters []string
+	// schemaFragmentMinSize stores the nested parameter schemas no smaller than the size as shared fragments if positive
+	schemaFragmentMinSize int
+	// schemaDriftResyncPeriod is the period of checking the schema ConfigMaps against the latest revisions if positive
+	schemaDriftResyncPeriod time.Duration
+	// tenantLabels and the definition labels of tenantLabelKeys are set on the schema ConfigMaps and the revisions
+	tenantLabels    map[string]string
+	tenantLabelKeys []string
+	// schemaCompilationSemaphore bounds the concurrent evaluations of CUE templates if it's set
+	schemaCompilationSemaphore *semaphore.Weighted
+	// detectUnusedParameters reports the parameters never referenced by the CUE templates in the ParametersUsed condition
+	detectUnusedParameters bool
+	// revisionOptions are the options of reconciling the DefinitionRevisions shared by all the definitions, the
+	// options of each definition are added to them on reconcile
+	revisionOptions []coredef.DefinitionRevisionOption
+	// revisionGCMaintenanceWindows are the windows during which the revisions are never garbage collected, and
+	// revisionGCGracePeriod is the minimum age of the revisions before they are garbage collected. They are among
+	// the revisionOptions, and requeue the definitions once the revisions can be collected.
+	revisionGCMaintenanceWindows coredef.RevisionGCMaintenanceWindows
+	revisionGCGracePeriod        coredef.RevisionGCGracePeriod
+	// schemaChecksum records the checksum of the stored schema on the schema ConfigMaps and the revisions
+	schemaChecksum bool
+	// excludeInternalParameters removes the internal parameters from the stored schema
+	excludeInternalParameters bool
+	// namespaces filters the namespaces of the definitions reconciled by the controller
+	namespaces namespaceFilter
+	// tracerProvider creates the spans of the reconciles if it's set
+	tracerProvider trace.TracerProvider
 }
 
 // Reconcile is the main logic for ComponentDefinition controller
-func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, retErr error) {
 	ctx, cancel := ctrlrec.NewReconcileContext(ctx)
 	defer cancel()
 
-	klog.InfoS("Reconcile componentDefinition", "componentDefinition", klog.KRef(req.Namespace, req.Name))
-
 	var componentDefinition v1beta1.ComponentDefinition
+	defer timeReconcile(&componentDefinition, &retErr)()
+	ctx, endSpan := r.traceReconcile(ctx, req, &componentDefinition, &res, &retErr)
+	defer endSpan()
+
+	logCtx := monitorContext.NewTraceContext(ctx, "").AddTag("componentDefinition", klog.KRef(req.Namespace, req.Name),
+		"controller", "componentDefinition")
+	logCtx.Info("Reconcile componentDefinition")
+
 	if err := r.Get(ctx, req.NamespacedName, &componentDefinition); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.forgetDefinition(req)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	logCtx.AddTag("generation", componentDefinition.Generation)
+
+	if !r.namespaces.allows(componentDefinition.Namespace) {
+		// the definition is cleaned up by the controller the namespace is allowed to with its own storage settings,
+		// the finalizer registered before the namespace was excluded is released only
+		logCtx.Info("skip definition: the namespace is not allowed")
+		return ctrl.Result{}, r.releaseFinalizer(logCtx, &componentDefinition)
+	}
+
 	if !coredef.MatchControllerRequirement(&componentDefinition, r.controllerVersion, r.ignoreDefNoCtrlReq) {
-		klog.InfoS("skip definition: not match the controller requirement of definition", "componentDefinition", klog.KObj(&componentDefinition))
+		// the resources belong to the controller matching the requirement, a definition registered with the finalizer
+		// before it stopped matching is released only, otherwise it could never be deleted
+		logCtx.Info("skip definition: not match the controller requirement of definition")
+		return ctrl.Result{}, r.releaseFinalizer(logCtx, &componentDefinition)
+	}
+
+	if r.auditOnly {
+		// nothing is written in the audit mode, the resources registered before switching to it are left to the
+		// garbage collection by the owner references
+		if !componentDefinition.DeletionTimestamp.IsZero() {
+			return ctrl.Result{}, r.releaseFinalizer(logCtx, &componentDefinition)
+		}
+		return r.audit(logCtx, &componentDefinition)
+	}
+
+	if !componentDefinition.DeletionTimestamp.IsZero() {
+		if err := r.handleDeletion(logCtx, &componentDefinition); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.forgetDefinition(req)
 		return ctrl.Result{}, nil
 	}
 
-	defRev, result, err := coredef.ReconcileDefinitionRevision(ctx, r.Client, r.record, &componentDefinition, r.defRevLimit, func(revision *common.Revision) error {
+	if !meta.FinalizerExists(&componentDefinition, oam.FinalizerComponentDefinition) {
+		meta.AddFinalizer(&componentDefinition, oam.FinalizerComponentDefinition)
+		if err := r.Update(ctx, &componentDefinition); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, errUpdateComponentDefinitionFinalizer)
+		}
+		logCtx.Info("Register new finalizer for componentDefinition", "finalizer", oam.FinalizerComponentDefinition)
+	}
+
+	if done, err := r.rollback(logCtx, &componentDefinition); done || err != nil {
+		return ctrl.Result{}, err
+	}
+
+	revisionOperation := "reuse"
+	defRev, result, err := coredef.ReconcileDefinitionRevision(ctx, r.Client, r.recorder(), &componentDefinition, r.defRevLimit, func(revision *common.Revision) error {
+		revisionOperation = "create"
 		componentDefinition.Status.LatestRevision = revision
 		return r.UpdateStatus(ctx, &componentDefinition)
-	})
+	}, append(r.revisionOptions[:len(r.revisionOptions):len(r.revisionOptions)],
+		coredef.RevisionLabels(r.tenantLabelsOf(&componentDefinition)),
+		coredef.RevisionsCollectedHook(r.cleanUpCollectedRevisions(logCtx, &componentDefinition)))...)
+	if until, ok := r.revisionGCMaintenanceWindows.ActiveUntil(time.Now()); ok {
+		// the garbage collection skipped in the maintenance window is done once the window closes
+		defer func() {
+			if retErr == nil && res == (ctrl.Result{}) {
+				res = ctrl.Result{RequeueAfter: time.Until(until)}
+			}
+		}()
+	}
 	if result != nil {
 		return *result, err
 	}
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	logCtx.AddTag("revision", defRev.Name, "revisionHash", defRev.Spec.RevisionHash)
+	metrics.ComponentDefinitionRevisionCounter.WithLabelValues(revisionOperation).Inc()
+	history := componentDefinition.Status.RevisionHistory
+	revisions, listErr := r.recordRevisionNumber(ctx, &componentDefinition)
+	if listErr == nil {
+		limit, _ := coredef.GetDefinitionRevisionLimit(&componentDefinition, r.defRevLimit)
+		history = revisionHistory(revisions, limit)
+		if next, ok := r.revisionGCGracePeriod.NextCollection(revisions, limit, defRev.Name, time.Now()); ok {
+			// the revisions retained by the grace period are collected once they age past it
+			defer func() {
+				if retErr == nil && res == (ctrl.Result{}) {
+					res = ctrl.Result{RequeueAfter: time.Until(next)}
+				}
+			}()
+		}
+	}
 
-	def := utils.NewCapabilityComponentDef(&componentDefinition)
+	statusTemplateCondition := condition.ReadyCondition(coredef.TypeStatusTemplateValid)
+	if err := coredef.ValidateStatusTemplates(componentDefinition.Spec.Status); err != nil {
+		logCtx.Info("Invalid status templates of componentDefinition", "err", err)
+		r.recorder().Event(&componentDefinition, event.Warning("invalid status templates", err))
+		statusTemplateCondition = condition.ErrorCondition(coredef.TypeStatusTemplateValid, err)
+	}
+
+	deprecatedConditions := r.checkDeprecation(&componentDefinition)
+	policyConditions := r.checkPolicies(ctx, &componentDefinition)
+	terraformModuleConditions := r.checkTerraformModules(ctx, &componentDefinition)
+	traitCompatibility, traitCompatibilityConditions := r.checkTraitCompatibility(&componentDefinition)
+	alias, aliasConditions := r.checkAlias(ctx, &componentDefinition)
+	capabilityMetadataConditions := r.checkCapabilityMetadata(&componentDefinition)
+
+	def := r.newCapabilityDefinition(&componentDefinition, defRev)
 	// Store the parameter of componentDefinition to configMap
-	cmName, err := def.StoreOpenAPISchema(ctx, r.Client, req.Namespace, req.Name, defRev.Name)
+	storeCtx, span := coredef.StartSpan(ctx, "StoreOpenAPISchema", coredef.SpanAttributeDefinitionName.String(req.Name),
+		coredef.SpanAttributeDefinitionNamespace.String(req.Namespace), coredef.SpanAttributeDefinitionRevision.String(defRev.Name))
+	cmName, err := def.StoreOpenAPISchema(storeCtx, r.Client, req.Namespace, req.Name, defRev.Name)
+	coredef.EndSpan(span, err)
+	if err != nil && ctx.Err() != nil {
+		// the reconcile is cancelled or times out, which says nothing about the schema of the definition
+		logCtx.Info("Reconcile is cancelled while storing the schema", "err", err)
+		return ctrl.Result{}, err
+	}
 	if err != nil {
-		klog.InfoS("Could not store capability in ConfigMap", "err", err)
-		r.record.Event(&(componentDefinition), event.Warning("Could not store capability in ConfigMap", err))
-		return ctrl.Result{}, util.PatchCondition(ctx, r, &(componentDefinition),
-			condition.ReconcileError(fmt.Errorf(util.ErrStoreCapabilityInConfigMap, def.Name, err)))
+		logCtx.Info("Could not store capability in ConfigMap", "err", err)
+		r.recorder().Event(&(componentDefinition), event.Warning("Could not store capability in ConfigMap", err))
+		checkConditions := []condition.Condition{statusTemplateCondition}
+		checkConditions = append(checkConditions, deprecatedConditions...)
+		checkConditions = append(checkConditions, policyConditions...)
+		checkConditions = append(checkConditions, terraformModuleConditions...)
+		checkConditions = append(checkConditions, traitCompatibilityConditions...)
+		checkConditions = append(checkConditions, aliasConditions...)
+		checkConditions = append(checkConditions, capabilityMetadataConditions...)
+		// the other problems are reported along with the schema error, so that they are fixed at once
+		issues := append([]coredef.ValidationIssue{{Type: coredef.TypeSchemaReady, Err: coredef.NewDefinitionError(coredef.ErrSchemaStorage, def.Name, err)}},
+			coredef.ValidationIssues(checkConditions...)...)
+		conditions := []condition.Condition{
+			condition.ReconcileError(coredef.NewValidationError(issues...)),
+			condition.ErrorCondition(coredef.TypeSchemaReady, err),
+		}
+		conditions = append(conditions, checkConditions...)
+		var tfErr *utils.TerraformVariableError
+		if errors.As(err, &tfErr) {
+			conditions = append(conditions, condition.ErrorCondition(coredef.TypeTerraformVariablesValid, tfErr))
+		}
+		if errors.Is(err, utils.ErrSchemaGenerationTimeout) {
+			conditions = append(conditions, condition.ErrorCondition(coredef.TypeSchemaGenerationWithinBudget, err))
+		}
+		result, circuitConditions := r.tripCircuit(req, &componentDefinition, r.requeueWithBackoff(req))
+		conditions = append(conditions, circuitConditions...)
+		return result, util.PatchCondition(ctx, r, &(componentDefinition), conditions...)
+	}
+	r.forgetBackoff(req)
+	metrics.ComponentDefinitionSchemaSizeGauge.WithLabelValues(req.Namespace, req.Name).Set(float64(def.SchemaSize))
+	var schemaRef *v1beta1.SchemaConfigMapReference
+	// the ConfigMaps are neither pruned nor published if the schema is stored by an external store
+	if r.schemaStore == nil {
+		if listErr == nil {
+			if err := pruneStoredSchemas(ctx, r.Client, r.schemaStorageNamespace, &componentDefinition, revisions); err != nil {
+				logCtx.Info("Could not prune the schema ConfigMaps of collected revisions", "err", err, "namespace", r.schemaStorageNamespace)
+			}
+		}
+		schemaKey := utils.SchemaConfigMapKey(r.schemaStorageNamespace, componentDefinition.Namespace, componentDefinition.Name)
+		if err := syncSharedSchema(ctx, r.Client, r.sharedSchemaNamespace, &componentDefinition, schemaKey); err != nil {
+			logCtx.Info("Could not publish the schema to the shared namespace", "err", err, "namespace", r.sharedSchemaNamespace)
+			r.recorder().Event(&componentDefinition, event.Warning("cannot publish the schema to the shared namespace", err))
+			return ctrl.Result{}, util.PatchCondition(ctx, r, &componentDefinition, condition.ReconcileError(err))
+		}
+		schemaRef = &v1beta1.SchemaConfigMapReference{Name: utils.ComponentDefinitionConfigMapName(defRev.Name), Revision: defRev.Name}
+		if r.schemaStorageNamespace != "" {
+			revSchemaKey := utils.SchemaConfigMapKey(r.schemaStorageNamespace, componentDefinition.Namespace, defRev.Name)
+			schemaRef.Name, schemaRef.Namespace = revSchemaKey.Name, revSchemaKey.Namespace
+		}
+	}
+	store := def.GetSchemaStore(r.Client)
+	storageRef := &v1beta1.SchemaStorageReference{
+		Backend:  store.Backend(),
+		Location: store.Location(componentDefinition.Namespace, defRev.Name),
+		Revision: defRev.Name,
+	}
+	// Override the synced condition, which maybe include the error info.
+	conditions := []condition.Condition{condition.ReconcileSuccess(), condition.ReadyCondition(coredef.TypeSchemaReady), statusTemplateCondition}
+	if def.WorkloadType == util.TerraformDef {
+		conditions = append(conditions, condition.ReadyCondition(coredef.TypeTerraformVariablesValid))
+	}
+	if componentDefinition.GetCondition(coredef.TypeSchemaGenerationWithinBudget).Status == corev1.ConditionFalse {
+		conditions = append(conditions, condition.ReadyCondition(coredef.TypeSchemaGenerationWithinBudget))
 	}
-	if componentDefinition.Status.ConfigMapRef != cmName {
+	conditions = append(conditions, deprecatedConditions...)
+	conditions = append(conditions, policyConditions...)
+	conditions = append(conditions, terraformModuleConditions...)
+	conditions = append(conditions, traitCompatibilityConditions...)
+	conditions = append(conditions, aliasConditions...)
+	conditions = append(conditions, capabilityMetadataConditions...)
+	conditions = append(conditions, r.checkSchemaSize(&componentDefinition, def.SchemaSize)...)
+	conditions = append(conditions, r.checkUnusedParameters(&componentDefinition)...)
+	conditions = append(conditions, r.closeCircuit(req, &componentDefinition, true)...)
+	schemaSize := int64(def.SchemaSize)
+	if componentDefinition.Status.ConfigMapRef != cmName || !reflect.DeepEqual(componentDefinition.Status.SchemaConfigMapRef, schemaRef) ||
+		!reflect.DeepEqual(componentDefinition.Status.SchemaStorageRef, storageRef) ||
+		!apiequality.Semantic.DeepEqual(componentDefinition.Status.RevisionHistory, history) ||
+		componentDefinition.Status.SchemaSize != schemaSize ||
+		!reflect.DeepEqual(componentDefinition.Status.TraitCompatibility, traitCompatibility) ||
+		componentDefinition.Status.Alias != alias ||
+		util.IsConditionChanged(conditions, &componentDefinition) {
 		componentDefinition.Status.ConfigMapRef = cmName
-		// Override the conditions, which maybe include the error info.
-		componentDefinition.Status.Conditions = []condition.Condition{condition.ReconcileSuccess()}
+		componentDefinition.Status.SchemaConfigMapRef = schemaRef
+		componentDefinition.Status.SchemaStorageRef = storageRef
+		componentDefinition.Status.RevisionHistory = history
+		componentDefinition.Status.SchemaSize = schemaSize
+		componentDefinition.Status.TraitCompatibility = traitCompatibility
+		componentDefinition.Status.Alias = alias
+		componentDefinition.SetConditions(conditions...)
 
 		if err := r.UpdateStatus(ctx, &componentDefinition); err != nil {
-			klog.InfoS("Could not update componentDefinition Status", "err", err)
-			r.record.Event(&componentDefinition, event.Warning("cannot update ComponentDefinition Status", err))
+			logCtx.Info("Could not update componentDefinition Status", "err", err)
+			r.recorder().Event(&componentDefinition, event.Warning("cannot update ComponentDefinition Status", err))
 			return ctrl.Result{}, util.PatchCondition(ctx, r, &componentDefinition,
-				condition.ReconcileError(fmt.Errorf(util.ErrUpdateComponentDefinition, componentDefinition.Name, err)))
+				condition.ReconcileError(coredef.NewDefinitionError(coredef.ErrDefinitionUpdate, componentDefinition.Name, err)))
 		}
-		klog.InfoS("Successfully updated the status.configMapRef of the ComponentDefinition", "componentDefinition",
-			klog.KRef(req.Namespace, req.Name), "status.configMapRef", cmName)
+		logCtx.Info("Successfully updated the status.schemaStorageRef of the ComponentDefinition",
+			"status.configMapRef", cmName, "status.schemaStorageRef", storageRef.Location)
 	}
+	if len(r.spokeClusters) != 0 {
+		if failed := r.replicateToSpokes(ctx, &componentDefinition, defRev); len(failed) != 0 {
+			logCtx.Info("Could not replicate to the spoke clusters", "clusters", failed)
+			return r.requeueWithBackoff(req), nil
+		}
+	}
+	r.resetEventThrottle(req.NamespacedName)
 	return ctrl.Result{}, nil
 }
 
-// UpdateStatus updates v1beta1.ComponentDefinition's Status with retry.RetryOnConflict
+// newCapabilityDefinition creates the capability storing the schema of the definition with the options of the
+// reconciler. The schema follows the frozen revision rather than the pending changes of the definition.
+func (r *Reconciler) newCapabilityDefinition(componentDefinition *v1beta1.ComponentDefinition, defRev *v1beta1.DefinitionRevision) utils.CapabilityComponentDefinition {
+	schemaSource := componentDefinition
+	if coredef.IsRevisionFrozen(componentDefinition) {
+		schemaSource = componentDefinition.DeepCopy()
+		schemaSource.Spec = defRev.Spec.ComponentDefinition.Spec
+	}
+	def := utils.NewCapabilityComponentDef(schemaSource)
+	def.SchemaStorageNamespace = r.schemaStorageNamespace
+	def.SchemaGenerationTimeout = r.schemaGenerationTimeout
+	def.SchemaCompilationSemaphore = r.schemaCompilationSemaphore
+	def.SchemaStore = r.schemaStore
+	def.SchemaCache = r.schemaCache
+	def.DisableSchemaCompression = r.disableSchemaCompression
+	def.OpenAPIV3Document = r.schemaOpenAPIV3Document
+	def.SchemaFragmentMinSize = r.schemaFragmentMinSize
+	def.SchemaLabels = r.tenantLabelsOf(componentDefinition)
+	def.SchemaChecksum = r.schemaChecksum
+	def.ExcludeInternalParameters = r.excludeInternalParameters
+	// the invalid category and icon are reported by checkCapabilityMetadata and never stored
+	def.SchemaAnnotations, _ = coredef.ParseCapabilityMetadata(componentDefinition)
+	return def
+}
+
+// cleanUpCollectedRevisions returns the hook deleting the external schemas and the spoke replicas of the revisions of
+// the definition once they're garbage collected
+func (r *Reconciler) cleanUpCollectedRevisions(logCtx monitorContext.Context, componentDefinition *v1beta1.ComponentDefinition) func(metav1.Object, []string) {
+	ctx := logCtx.GetContext()
+	return func(def metav1.Object, collected []string) {
+		if err := deleteCollectedExternalSchemas(ctx, r.schemaStore, def.GetNamespace(), collected); err != nil {
+			logCtx.Info("Could not delete the external schemas of collected revisions", "err", err)
+		}
+		if failed := r.pruneSpokeReplicas(ctx, componentDefinition, collected); len(failed) != 0 {
+			logCtx.Info("Could not prune the replicas of collected revisions in the spoke clusters", "clusters", failed)
+		}
+	}
+}
+
+// tenantLabelsOf returns the tenant labels of the definition, which are the configured tenant labels overridden by
+// the labels of the definition of the tenant label keys
+func (r *Reconciler) tenantLabelsOf(componentDefinition *v1beta1.ComponentDefinition) map[string]string {
+	labels := util.MergeMapOverrideWithDst(r.tenantLabels, nil)
+	for _, key := range r.tenantLabelKeys {
+		if value, ok := componentDefinition.Labels[key]; ok {
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// audit validates the definition like Reconcile and reports the problems in the conditions and events, but nothing
+// else is written. Neither the revision nor the schema is stored, and the finalizer is not registered.
+func (r *Reconciler) audit(ctx monitorContext.Context, componentDefinition *v1beta1.ComponentDefinition) (ctrl.Result, error) {
+	statusTemplateCondition := condition.ReadyCondition(coredef.TypeStatusTemplateValid)
+	if err := coredef.ValidateStatusTemplates(componentDefinition.Spec.Status); err != nil {
+		r.recorder().Event(componentDefinition, event.Warning("invalid status templates", err))
+		statusTemplateCondition = condition.ErrorCondition(coredef.TypeStatusTemplateValid, err)
+	}
+	_, traitCompatibilityConditions := r.checkTraitCompatibility(componentDefinition)
+	_, aliasConditions := r.checkAlias(ctx, componentDefinition)
+
+	checkConditions := []condition.Condition{statusTemplateCondition}
+	checkConditions = append(checkConditions, r.checkDeprecation(componentDefinition)...)
+	checkConditions = append(checkConditions, r.checkPolicies(ctx, componentDefinition)...)
+	checkConditions = append(checkConditions, traitCompatibilityConditions...)
+	checkConditions = append(checkConditions, aliasConditions...)
+	checkConditions = append(checkConditions, r.checkCapabilityMetadata(componentDefinition)...)
+
+	def := utils.NewCapabilityComponentDef(componentDefinition)
+	def.SchemaGenerationTimeout = r.schemaGenerationTimeout
+	var conditions []condition.Condition
+	jsonSchema, err := def.GenerateOpenAPISchema(ctx, r.Client, componentDefinition.Namespace, componentDefinition.Name)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctrl.Result{}, err
+		}
+		ctx.Info("Invalid schematic of componentDefinition in audit", "err", err)
+		r.recorder().Event(componentDefinition, event.Warning("Could not generate the parameter schema", err))
+		issues := append([]coredef.ValidationIssue{{Type: coredef.TypeSchemaReady, Err: coredef.NewDefinitionError(coredef.ErrSchemaStorage, componentDefinition.Name, err)}},
+			coredef.ValidationIssues(checkConditions...)...)
+		conditions = append(conditions,
+			condition.ReconcileError(coredef.NewValidationError(issues...)),
+			condition.ErrorCondition(coredef.TypeSchemaReady, err))
+	} else {
+		conditions = append(conditions, condition.ReconcileSuccess(), condition.ReadyCondition(coredef.TypeSchemaReady))
+		conditions = append(conditions, r.checkSchemaSize(componentDefinition, len(jsonSchema))...)
+	}
+	conditions = append(conditions, checkConditions...)
+	if !util.IsConditionChanged(conditions, componentDefinition) {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, util.PatchCondition(ctx, r, componentDefinition, conditions...)
+}
+
+// checkDeprecation computes the Deprecated condition of the definition, and emits a warning event
+// when the definition becomes deprecated
+func (r *Reconciler) checkDeprecation(def *v1beta1.ComponentDefinition) []condition.Condition {
+	current := def.GetCondition(coredef.TypeDeprecated)
+	if coredef.IsDeprecated(def) && current.Status != corev1.ConditionTrue {
+		r.recorder().Event(def, event.Event{
+			Type:    event.TypeWarning,
+			Reason:  event.Reason(coredef.ReasonDeprecated),
+			Message: coredef.GetDeprecationMessage(def),
+		})
+	}
+	return coredef.DeprecatedCondition(def, current)
+}
+
+// checkTraitCompatibility parses the trait compatibility declared by the definition and computes the
+// TraitCompatibilityValid condition, a warning event is emitted when the declaration becomes malformed.
+// The declaration is only validated and recorded here, it's enforced by the consumers of the definition.
+func (r *Reconciler) checkTraitCompatibility(def *v1beta1.ComponentDefinition) (*v1beta1.TraitCompatibility, []condition.Condition) {
+	current := def.GetCondition(coredef.TypeTraitCompatibilityValid)
+	compatibility, err := coredef.ParseTraitCompatibility(def)
+	if err != nil {
+		if current.Status != corev1.ConditionFalse {
+			r.recorder().Event(def, event.Warning("invalid trait compatibility", err))
+		}
+		return nil, []condition.Condition{condition.ErrorCondition(coredef.TypeTraitCompatibilityValid, err)}
+	}
+	if compatibility == nil && current.Status == corev1.ConditionUnknown {
+		return nil, nil
+	}
+	return compatibility, []condition.Condition{condition.ReadyCondition(coredef.TypeTraitCompatibilityValid)}
+}
+
+// checkCapabilityMetadata validates the category and the icon declared by the definition and computes the
+// CapabilityMetadataValid condition, a warning event is emitted when the declaration becomes invalid
+func (r *Reconciler) checkCapabilityMetadata(def *v1beta1.ComponentDefinition) []condition.Condition {
+	current := def.GetCondition(coredef.TypeCapabilityMetadataValid)
+	metadata, err := coredef.ParseCapabilityMetadata(def)
+	if err != nil {
+		if current.Status != corev1.ConditionFalse {
+			r.recorder().Event(def, event.Warning("invalid capability metadata", err))
+		}
+		return []condition.Condition{condition.ErrorCondition(coredef.TypeCapabilityMetadataValid, err)}
+	}
+	if metadata == nil && current.Status == corev1.ConditionUnknown {
+		return nil
+	}
+	return []condition.Condition{condition.ReadyCondition(coredef.TypeCapabilityMetadataValid)}
+}
+
+// checkSchemaSize computes the SchemaSizeWithinThreshold condition of the definition, and emits a warning event
+// when the size of the schema crosses the threshold. No condition is returned if the threshold is not set.
+func (r *Reconciler) checkSchemaSize(def *v1beta1.ComponentDefinition, size int) []condition.Condition {
+	if r.schemaSizeThreshold <= 0 {
+		return nil
+	}
+	if size <= r.schemaSizeThreshold {
+		return []condition.Condition{condition.ReadyCondition(coredef.TypeSchemaSizeWithinThreshold)}
+	}
+	err := fmt.Errorf("the size of the parameter schema %d bytes exceeds the warning threshold %d bytes", size, r.schemaSizeThreshold)
+	if def.GetCondition(coredef.TypeSchemaSizeWithinThreshold).Status != corev1.ConditionFalse {
+		r.recorder().Event(def, event.Warning("the parameter schema is too large", err))
+	}
+	return []condition.Condition{condition.ErrorCondition(coredef.TypeSchemaSizeWithinThreshold, err)}
+}
+
+// checkUnusedParameters computes the ParametersUsed condition of the definition, and emits a warning event when
+// parameters become unused. The unused parameters don't fail the definition. No condition is returned if the
+// detection is not enabled or the definition has no CUE template.
+func (r *Reconciler) checkUnusedParameters(def *v1beta1.ComponentDefinition) []condition.Condition {
+	if !r.detectUnusedParameters || def.Spec.Schematic == nil || def.Spec.Schematic.CUE == nil {
+		return nil
+	}
+	unused, err := coredef.FindUnusedParameters(def)
+	if err != nil {
+		// the invalid template is reported by the schema generation
+		return nil
+	}
+	if len(unused) == 0 {
+		return []condition.Condition{condition.ReadyCondition(coredef.TypeParametersUsed)}
+	}
+	err = fmt.Errorf("the parameters are declared but never used by the template: %s", strings.Join(unused, ", "))
+	current := def.GetCondition(coredef.TypeParametersUsed)
+	if current.Status != corev1.ConditionFalse || current.Message != err.Error() {
+		r.recorder().Event(def, event.Warning("unused parameters", err))
+	}
+	return []condition.Condition{condition.ErrorCondition(coredef.TypeParametersUsed, err)}
+}
+
+// requeueWithBackoff requeues the definition after an exponentially growing delay, so that transient
+// failures heal without waiting for the next watch event
+func (r *Reconciler) requeueWithBackoff(req ctrl.Request) ctrl.Result {
+	if r.schemaBackoff == nil {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: r.schemaBackoff.When(req.String())}
+}
+
+// forgetBackoff resets the backoff of the definition after it's reconciled successfully or it's deleted
+func (r *Reconciler) forgetBackoff(req ctrl.Request) {
+	if r.schemaBackoff != nil {
+		r.schemaBackoff.Forget(req.String())
+	}
+}
+
+// timeReconcile returns a function observing the reconcile duration, the result is regarded as error
+// if an error is returned or the reconcile error is patched into the conditions of the definition.
+func timeReconcile(def *v1beta1.ComponentDefinition, retErr *error) func() {
+	startTime := time.Now()
+	return func() {
+		result := "success"
+		if *retErr != nil || def.GetCondition(condition.TypeSynced).Reason == condition.ReasonReconcileError {
+			result = "error"
+		}
+		metrics.ComponentDefinitionReconcileTimeHistogram.WithLabelValues(result).Observe(time.Since(startTime).Seconds())
+	}
+}
+
+// recordRevisionNumber reports the number of DefinitionRevisions left after the garbage collection,
+// the DefinitionRevisions are returned for building the revision history
+func (r *Reconciler) recordRevisionNumber(ctx context.Context, def *v1beta1.ComponentDefinition) ([]v1beta1.DefinitionRevision, error) {
+	defRevList := new(v1beta1.DefinitionRevisionList)
+	if err := r.List(ctx, defRevList, client.InNamespace(def.Namespace),
+		client.MatchingLabels{oam.LabelComponentDefinitionName: def.Name}); err != nil {
+		klog.ErrorS(err, "Could not list DefinitionRevisions", "componentDefinition", klog.KObj(def))
+		return nil, err
+	}
+	metrics.ComponentDefinitionRevisionGauge.WithLabelValues(def.Namespace, def.Name).Set(float64(len(defRevList.Items)))
+	return defRevList.Items, nil
+}
+
+// handleDeletion cleans up the DefinitionRevisions and the schema ConfigMaps of the ComponentDefinition together with
+// their replicas in the spoke clusters, and removes the finalizer once the cleanup is done.
+func (r *Reconciler) handleDeletion(logCtx monitorContext.Context, def *v1beta1.ComponentDefinition) error {
+	if !meta.FinalizerExists(def, oam.FinalizerComponentDefinition) {
+		return nil
+	}
+	ctx := logCtx.GetContext()
+	logCtx.Info("The ComponentDefinition is being deleted")
+	err := deleteExternalSchemas(ctx, r.Client, r.schemaStore, def)
+	if err == nil {
+		err = cleanUpComponentDefinitionResources(ctx, r.Client, def)
+	}
+	if err == nil {
+		err = pruneStoredSchemas(ctx, r.Client, r.schemaStorageNamespace, def, nil)
+	}
+	if err == nil {
+		err = deleteSharedSchema(ctx, r.Client, r.sharedSchemaNamespace, def)
+	}
+	if err != nil {
+		logCtx.Error(err, "Could not clean up resources of ComponentDefinition")
+		r.recorder().Event(def, event.Warning("cannot clean up resources of ComponentDefinition", err))
+		return err
+	}
+	// the replicas left in the spoke clusters failed are reported by the events, they don't block the deletion as the
+	// spoke clusters may be gone for good
+	if failed := r.deleteSpokeReplicas(ctx, def); len(failed) != 0 {
+		logCtx.Info("Could not delete the replicas in the spoke clusters", "clusters", failed)
+	}
+	if r.schemaCache != nil {
+		r.schemaCache.Delete(client.ObjectKeyFromObject(def))
+	}
+	meta.RemoveFinalizer(def, oam.FinalizerComponentDefinition)
+	return errors.Wrap(r.Update(ctx, def), errUpdateComponentDefinitionFinalizer)
+}
+
+// forgetDefinition drops the metrics and the state tracked by the reconciler for the definition once it's deleted, so
+// that they don't pile up with the definitions coming and going
+func (r *Reconciler) forgetDefinition(req ctrl.Request) {
+	metrics.ComponentDefinitionRevisionGauge.DeleteLabelValues(req.Namespace, req.Name)
+	metrics.ComponentDefinitionSchemaSizeGauge.DeleteLabelValues(req.Namespace, req.Name)
+	r.circuitBreaker.reset(req.String())
+	r.resetEventThrottle(req.NamespacedName)
+	r.forgetBackoff(req)
+}
+
+// releaseFinalizer removes the finalizer of the definition being deleted without cleaning up its resources, which is
+// left to the garbage collection by the owner references
+func (r *Reconciler) releaseFinalizer(logCtx monitorContext.Context, def *v1beta1.ComponentDefinition) error {
+	if def.DeletionTimestamp.IsZero() || !meta.FinalizerExists(def, oam.FinalizerComponentDefinition) {
+		return nil
+	}
+	logCtx.Info("Release the finalizer of the ComponentDefinition being deleted without cleanup")
+	meta.RemoveFinalizer(def, oam.FinalizerComponentDefinition)
+	return errors.Wrap(r.Update(logCtx.GetContext(), def), errUpdateComponentDefinitionFinalizer)
+}
+
+// cleanUpComponentDefinitionResources deletes all DefinitionRevisions of the ComponentDefinition together with
+// the ConfigMaps storing the OpenAPI schema of the definition and its revisions. Resources already gone are ignored.
+func cleanUpComponentDefinitionResources(ctx context.Context, cli client.Client, def *v1beta1.ComponentDefinition) error {
+	defRevList := new(v1beta1.DefinitionRevisionList)
+	if err := cli.List(ctx, defRevList, client.InNamespace(def.Namespace),
+		client.MatchingLabels{oam.LabelComponentDefinitionName: def.Name}); err != nil {
+		return errors.Wrapf(err, "cannot list DefinitionRevisions of ComponentDefinition %s", def.Name)
+	}
+	cmNames := []string{utils.ComponentDefinitionConfigMapName(def.Name)}
+	for i := range defRevList.Items {
+		rev := defRevList.Items[i]
+		if err := cli.Delete(ctx, &rev); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot delete DefinitionRevision %s", rev.Name)
+		}
+		cmNames = append(cmNames, utils.ComponentDefinitionConfigMapName(rev.Name))
+	}
+	for _, name := range cmNames {
+		cm := &corev1.ConfigMap{}
+		cm.SetName(name)
+		cm.SetNamespace(def.Namespace)
+		if err := cli.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot delete ConfigMap %s", name)
+		}
+	}
+	return nil
+}
+
+// UpdateStatus updates v1beta1.ComponentDefinition's Status with retry.RetryOnConflict, the update is skipped if the
+// status is not changed to avoid the needless writes and watch events
 func (r *Reconciler) UpdateStatus(ctx context.Context, def *v1beta1.ComponentDefinition, opts ...client.SubResourceUpdateOption) error {
 	status := def.DeepCopy().Status
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() (err error) {
 		if err = r.Get(ctx, client.ObjectKey{Namespace: def.Namespace, Name: def.Name}, def); err != nil {
 			return
 		}
+		if apiequality.Semantic.DeepEqual(def.Status, status) {
+			return nil
+		}
 		def.Status = status
 		return r.Status().Update(ctx, def, opts...)
 	})
@@ -124,31 +719,216 @@ func (r *Reconciler) UpdateStatus(ctx context.Context, def *v1beta1.ComponentDef
 
 // SetupWithManager will setup with event recorder
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
-	r.record = event.NewAPIRecorder(mgr.GetEventRecorderFor("ComponentDefinition")).
-		WithAnnotations("controller", "ComponentDefinition")
+	r.record = newThrottledRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor("ComponentDefinition")).
+		WithAnnotations("controller", "ComponentDefinition"), eventThrottleInterval)
+	// index the definitions by the workload GVK to tell the definitions depending on a CRD
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1beta1.ComponentDefinition{},
+		utils.ComponentDefinitionWorkloadGVKIndex, utils.IndexComponentDefinitionByWorkloadGVK); err != nil {
+		return err
+	}
+	if r.schemaDriftResyncPeriod > 0 && r.schemaStore == nil && !r.auditOnly {
+		if err := mgr.Add(manager.RunnableFunc(r.runSchemaDriftResync)); err != nil {
+			return err
+		}
+	}
 	return ctrl.NewControllerManagedBy(mgr).
+		Named("componentdefinition").
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: r.concurrentReconciles,
+			RateLimiter:             newReconcileRateLimiter(r.reconcileBaseDelay, r.reconcileQPS, r.reconcileBurst),
 		}).
-		For(&v1beta1.ComponentDefinition{}).
+		// the definitions listed on startup are enqueued through the rate limiter to avoid the thundering herd
+		Watches(&source.Kind{Type: &v1beta1.ComponentDefinition{}}, &rateLimitedCreateHandler{},
+			builder.WithPredicates(definitionChangedPredicate(), r.namespaces.predicate())).
+		// the alias released by a definition is taken over by the next one declaring it
+		Watches(&source.Kind{Type: &v1beta1.ComponentDefinition{}}, handler.EnqueueRequestsFromMapFunc(r.findDefinitionsSharingAlias),
+			builder.WithPredicates(definitionChangedPredicate(), r.namespaces.predicate())).
+		// recreate the schema ConfigMaps once they are deleted by accident, the ConfigMaps centralized in the storage
+		// namespace are mapped to the definitions in the other namespaces
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.namespaces.filter(r.findDefinitionForSchemaConfigMap)),
+			builder.OnlyMetadata, builder.WithPredicates(schemaConfigMapDeletedPredicate())).
 		Complete(r)
 }
 
+// definitionChangedPredicate filters out the updates of ComponentDefinition which only change the status, e.g. the
+// ones made by the controller itself. The changes of labels and annotations are still reconciled as they affect the
+// revisions, so do the changes of finalizers and deletion which drive the cleanup.
+func definitionChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e ctrlEvent.UpdateEvent) bool {
+			oldDef, isOldDef := e.ObjectOld.(*v1beta1.ComponentDefinition)
+			newDef, isNewDef := e.ObjectNew.(*v1beta1.ComponentDefinition)
+			if !isOldDef || !isNewDef {
+				return true
+			}
+			// We think this event is triggered by resync
+			if oldDef.ResourceVersion == newDef.ResourceVersion {
+				return true
+			}
+			return oldDef.Generation != newDef.Generation ||
+				!reflect.DeepEqual(oldDef.Labels, newDef.Labels) ||
+				!reflect.DeepEqual(oldDef.Annotations, newDef.Annotations) ||
+				!reflect.DeepEqual(oldDef.Finalizers, newDef.Finalizers) ||
+				!reflect.DeepEqual(oldDef.DeletionTimestamp, newDef.DeletionTimestamp)
+		},
+	}
+}
+
+// namespaceFilter tells whether the definitions in a namespace are reconciled by the controller, so that the
+// controllers sharing a cluster are scoped to their own namespaces. All the namespaces are allowed if the allowlist
+// is empty, and the namespaces of the denylist are never allowed.
+type namespaceFilter struct {
+	allowed sets.String
+	denied  sets.String
+}
+
+func newNamespaceFilter(allowed, denied []string) namespaceFilter {
+	return namespaceFilter{allowed: sets.NewString(allowed...), denied: sets.NewString(denied...)}
+}
+
+func (f namespaceFilter) allows(namespace string) bool {
+	if f.denied.Has(namespace) {
+		return false
+	}
+	return f.allowed.Len() == 0 || f.allowed.Has(namespace)
+}
+
+// predicate filters out the events of the objects in the namespaces not allowed, including their deletions which
+// are cleaned up by the controllers the namespaces are allowed to
+func (f namespaceFilter) predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return f.allows(obj.GetNamespace())
+	})
+}
+
+// filter drops the requests mapped to the definitions in the namespaces not allowed
+func (f namespaceFilter) filter(mapFn handler.MapFunc) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		var requests []reconcile.Request
+		for _, req := range mapFn(obj) {
+			if f.allows(req.Namespace) {
+				requests = append(requests, req)
+			}
+		}
+		return requests
+	}
+}
+
 // Setup adds a controller that reconciles ComponentDefinition.
 func Setup(mgr ctrl.Manager, args oamctrl.Args) error {
 	r := Reconciler{
-		Client:  mgr.GetClient(),
-		Scheme:  mgr.GetScheme(),
-		options: parseOptions(args),
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		schemaBackoff:  workqueue.NewItemExponentialFailureRateLimiter(schemaBackoffBaseDelay, schemaBackoffMaxDelay),
+		circuitBreaker: newCircuitBreaker(args.DefCircuitBreakerThreshold, args.DefCircuitBreakerOpenInterval),
+		options:        parseOptions(args),
+	}
+	store, err := newSchemaStore(args)
+	if err != nil {
+		return err
+	}
+	r.schemaStore = store
+	if args.DefRevisionSigningKeyFile != "" {
+		key, err := coredef.LoadRevisionSigningKey(args.DefRevisionSigningKeyFile)
+		if err != nil {
+			return err
+		}
+		r.revisionOptions = append(r.revisionOptions, coredef.RevisionSigningKey(key))
+	}
+	windows, err := coredef.ParseMaintenanceWindows(args.DefRevisionGCMaintenanceWindows)
+	if err != nil {
+		return err
+	}
+	r.revisionGCMaintenanceWindows = windows
+	r.revisionOptions = append(r.revisionOptions, r.revisionGCMaintenanceWindows)
+	if args.DefTracingEndpoint != "" {
+		tp, err := newTracerProvider(context.Background(), args.DefTracingEndpoint, args.DefTracingInsecure)
+		if err != nil {
+			return errors.Wrap(err, "cannot create the tracer provider")
+		}
+		// the buffered spans are flushed when the manager stops
+		if err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return tp.Shutdown(context.Background())
+		})); err != nil {
+			return err
+		}
+		r.tracerProvider = tp
+	}
+	if args.DefRevisionVerificationKeyFile != "" {
+		key, err := coredef.LoadRevisionVerificationKey(args.DefRevisionVerificationKeyFile)
+		if err != nil {
+			return err
+		}
+		r.revisionOptions = append(r.revisionOptions, coredef.RevisionVerificationKey(key))
 	}
 	return r.SetupWithManager(mgr)
 }
 
 func parseOptions(args oamctrl.Args) options {
+	namingStrategy := coredef.RevisionNamingStrategy(args.DefRevisionNamingStrategy)
+	if namingStrategy != coredef.RevisionNamingHashBased {
+		namingStrategy = coredef.RevisionNamingSequential
+	}
+	gates := args.FeatureGates
+	if gates == nil {
+		gates = utilfeature.DefaultFeatureGate
+	}
+	var schemaCache *utils.SchemaCache
+	switch args.DefSchemaCache {
+	case "memory":
+		schemaCache = utils.NewSchemaCache(false)
+	case "configmap":
+		schemaCache = utils.NewSchemaCache(true)
+	}
+	var schemaCompilationSemaphore *semaphore.Weighted
+	if args.DefSchemaCompilationConcurrency > 0 {
+		schemaCompilationSemaphore = semaphore.NewWeighted(int64(args.DefSchemaCompilationConcurrency))
+	}
+	var terraformModuleResolver TerraformModuleResolver
+	if args.DefTerraformModuleCheckTimeout > 0 {
+		terraformModuleResolver = newRemoteModuleResolver()
+	}
+	revisionGCByUsage := gates.Enabled(features.DefinitionRevisionGCByUsage)
+	revisionGCGracePeriod := coredef.RevisionGCGracePeriod(args.DefRevisionGCGracePeriod)
 	return options{
-		defRevLimit:          args.DefRevisionLimit,
-		concurrentReconciles: args.ConcurrentReconciles,
-		ignoreDefNoCtrlReq:   args.IgnoreDefinitionWithoutControllerRequirement,
-		controllerVersion:    version.VelaVersion,
+		defRevLimit:              args.DefRevisionLimit,
+		concurrentReconciles:     args.ConcurrentReconciles,
+		ignoreDefNoCtrlReq:       args.IgnoreDefinitionWithoutControllerRequirement,
+		controllerVersion:        version.VelaVersion,
+		sharedSchemaNamespace:    args.SharedSchemaNamespace,
+		schemaStorageNamespace:   args.SchemaStorageNamespace,
+		schemaGenerationTimeout:  args.DefSchemaGenerationTimeout,
+		schemaSizeThreshold:      args.DefSchemaSizeWarningThreshold,
+		schemaOpenAPIV3Document:  args.DefSchemaOpenAPIV3Document,
+		schemaCache:              schemaCache,
+		auditOnly:                args.AuditOnly,
+		spokeClusters:            args.DefSpokeClusters,
+		policyEvaluator:          newConfigMapPolicyEvaluator(args.DefinitionPolicyConfigMap),
+		reconcileBaseDelay:       args.DefReconcileBaseDelay,
+		reconcileQPS:             args.DefReconcileQPS,
+		reconcileBurst:           args.DefReconcileBurst,
+		disableSchemaCompression: !gates.Enabled(features.DefinitionSchemaCompression),
+		disableRevisionGCByUsage: !revisionGCByUsage,
+		revisionOptions: []coredef.DefinitionRevisionOption{
+			namingStrategy,
+			coredef.IgnoredMetadataPrefixes(args.DefRevisionIgnoredMetadataPrefixes),
+			coredef.RevisionGCByUsage(revisionGCByUsage),
+			coredef.NormalizeCUETemplates(args.DefRevisionNormalizeCUE),
+			coredef.RevisionGeneratedBy(args.ControllerVersion),
+			revisionGCGracePeriod,
+		},
+		revisionGCGracePeriod:       revisionGCGracePeriod,
+		schemaFragmentMinSize:       max(args.DefSchemaFragmentMinSize, 0),
+		tenantLabels:                args.DefTenantLabels,
+		tenantLabelKeys:             args.DefTenantLabelKeys,
+		schemaCompilationSemaphore:  schemaCompilationSemaphore,
+		detectUnusedParameters:      args.DefDetectUnusedParameters,
+		schemaChecksum:              args.DefSchemaChecksum,
+		excludeInternalParameters:   args.DefExcludeInternalParameters,
+		namespaces:                  newNamespaceFilter(args.DefNamespaceAllowlist, args.DefNamespaceDenylist),
+		schemaDriftResyncPeriod:     max(args.DefSchemaDriftResyncPeriod, 0),
+		terraformModuleResolver:     terraformModuleResolver,
+		terraformModuleCheckTimeout: args.DefTerraformModuleCheckTimeout,
 	}
 }
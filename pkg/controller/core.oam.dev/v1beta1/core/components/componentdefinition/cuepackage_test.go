@@ -0,0 +1,80 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const cuePackageTemplate = `
+import "example.com/helpers"
+
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+}
+parameter: {
+	image: string
+	port:  helpers.#Port
+}
+`
+
+func TestCUEPackageFromConfigMap(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("cue-pkg-cd", "default")
+	cd.Spec.Schematic.CUE.Template = cuePackageTemplate
+	cd.Annotations = map[string]string{oam.AnnotationCUEPackageConfigMaps: "cue-helpers"}
+	r := newFakeReconciler(t, cd)
+
+	// the ConfigMap of the package is missing
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	synced := got.GetCondition(condition.TypeSynced)
+	require.Equal(t, condition.ReasonReconcileError, synced.Reason)
+	require.Contains(t, synced.Message, "the ConfigMap default/cue-helpers of the CUE package is not found")
+
+	require.NoError(t, r.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cue-helpers", Namespace: cd.Namespace},
+		Data: map[string]string{
+			utils.CUEPackagePathKey: "example.com/helpers",
+			"port.cue":              "package helpers\n\n#Port: int & >0 & <65536\n",
+		},
+	}))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, condition.ReasonReconcileSuccess, got.GetCondition(condition.TypeSynced).Reason)
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: got.Status.ConfigMapRef}, cm))
+	schema, err := utils.GetOpenAPISchemaFromConfigMap(cm)
+	require.NoError(t, err)
+	require.Contains(t, string(schema), `"port"`)
+	require.Contains(t, string(schema), `"maximum":65536`)
+}
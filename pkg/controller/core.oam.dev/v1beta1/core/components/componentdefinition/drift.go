@@ -0,0 +1,134 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// runSchemaDriftResync checks the schema ConfigMaps of all the definitions every schemaDriftResyncPeriod until the
+// context is done. It's a safety net besides the recreation of the deleted ConfigMaps, which catches the ConfigMaps
+// modified out of band.
+func (r *Reconciler) runSchemaDriftResync(ctx context.Context) error {
+	ticker := time.NewTicker(r.schemaDriftResyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.resyncSchemaDrift(ctx)
+		}
+	}
+}
+
+// resyncSchemaDrift corrects the drift of the schema ConfigMaps of all the definitions handled by the controller
+func (r *Reconciler) resyncSchemaDrift(ctx context.Context) {
+	defList := &v1beta1.ComponentDefinitionList{}
+	if err := r.List(ctx, defList); err != nil {
+		klog.ErrorS(err, "Could not list ComponentDefinitions to check the schema drift")
+		return
+	}
+	for i := range defList.Items {
+		def := &defList.Items[i]
+		if !def.DeletionTimestamp.IsZero() || def.Status.LatestRevision == nil || !r.namespaces.allows(def.Namespace) ||
+			!coredef.MatchControllerRequirement(def, r.controllerVersion, r.ignoreDefNoCtrlReq) {
+			continue
+		}
+		if _, err := r.correctSchemaDrift(ctx, def); err != nil {
+			klog.ErrorS(err, "Could not check the schema drift", "componentDefinition", klog.KObj(def))
+		}
+	}
+}
+
+// correctSchemaDrift compares the checksums of the schemas stored in the ConfigMaps of the definition and its latest
+// revision with the checksum recorded on the revision, or with the checksum of the schema generated from the revision
+// if none is recorded, and stores the schema again if they differ. Whether the drift is corrected is returned.
+func (r *Reconciler) correctSchemaDrift(ctx context.Context, componentDefinition *v1beta1.ComponentDefinition) (bool, error) {
+	defRev := &v1beta1.DefinitionRevision{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: componentDefinition.Namespace, Name: componentDefinition.Status.LatestRevision.Name}, defRev); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	def := r.newCapabilityDefinition(componentDefinition, defRev)
+	// the ConfigMap backed cache would return the drifted schema, so the expected schema is always generated
+	def.SchemaCache = nil
+	expectedHash := defRev.GetAnnotations()[oam.AnnotationSchemaChecksum]
+	if expectedHash == "" {
+		expected, err := def.GenerateOpenAPISchema(ctx, r.Client, componentDefinition.Namespace, componentDefinition.Name)
+		if err != nil {
+			return false, err
+		}
+		if expectedHash, err = utils.SchemaChecksum(expected); err != nil {
+			return false, err
+		}
+	}
+	var drifted []string
+	for _, name := range []string{componentDefinition.Name, defRev.Name} {
+		key := utils.SchemaConfigMapKey(r.schemaStorageNamespace, componentDefinition.Namespace, name)
+		hash, err := storedSchemaHash(ctx, r.Client, key)
+		if err != nil {
+			return false, err
+		}
+		if hash != expectedHash {
+			drifted = append(drifted, key.String())
+		}
+	}
+	if len(drifted) == 0 {
+		return false, nil
+	}
+	if _, err := def.StoreOpenAPISchema(ctx, r.Client, componentDefinition.Namespace, componentDefinition.Name, defRev.Name); err != nil {
+		return false, errors.Wrapf(err, "cannot restore the drifted schema ConfigMaps %v", drifted)
+	}
+	schemaKey := utils.SchemaConfigMapKey(r.schemaStorageNamespace, componentDefinition.Namespace, componentDefinition.Name)
+	if err := syncSharedSchema(ctx, r.Client, r.sharedSchemaNamespace, componentDefinition, schemaKey); err != nil {
+		return true, err
+	}
+	r.recorder().Event(componentDefinition, event.Normal("SchemaDriftCorrected",
+		fmt.Sprintf("the schema ConfigMaps %v drifted from the revision %s and are restored", drifted, defRev.Name)))
+	return true, nil
+}
+
+// storedSchemaHash returns the checksum of the schema stored in the ConfigMap with the fragments resolved, it's empty
+// if the ConfigMap doesn't exist or the schema can't be read
+func storedSchemaHash(ctx context.Context, cli client.Client, key client.ObjectKey) (string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := cli.Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "cannot get the schema ConfigMap %s", key)
+	}
+	hash, err := utils.StoredSchemaChecksum(ctx, cli, cm)
+	if err != nil {
+		return "", nil
+	}
+	return hash, nil
+}
@@ -0,0 +1,116 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// reasonRecorder records the reasons of the events
+type reasonRecorder struct {
+	reasons []string
+}
+
+func (r *reasonRecorder) Event(_ runtime.Object, e event.Event) {
+	r.reasons = append(r.reasons, string(e.Reason))
+}
+
+func (r *reasonRecorder) WithAnnotations(_ ...string) event.Recorder { return r }
+
+func TestCorrectSchemaDrift(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("drift", "default")
+	r := newFakeReconciler(t, cd)
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	recorder := &reasonRecorder{}
+	r.record = recorder
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.NotNil(t, got.Status.LatestRevision)
+	key := utils.SchemaConfigMapKey("", cd.Namespace, got.Status.LatestRevision.Name)
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, r.Get(ctx, key, cm))
+	original := cm.Data[types.OpenapiV3JSONSchema]
+
+	// the stored schema is untouched
+	corrected, err := r.correctSchemaDrift(ctx, got)
+	require.NoError(t, err)
+	require.False(t, corrected)
+	require.Empty(t, recorder.reasons)
+
+	// the schema ConfigMap of the revision is tampered
+	cm.Data[types.OpenapiV3JSONSchema] = `{"properties":{"tampered":{"type":"string"}},"type":"object"}`
+	require.NoError(t, r.Update(ctx, cm))
+	r.resyncSchemaDrift(ctx)
+	require.NoError(t, r.Get(ctx, key, cm))
+	require.JSONEq(t, original, cm.Data[types.OpenapiV3JSONSchema])
+	require.Equal(t, []string{"SchemaDriftCorrected"}, recorder.reasons)
+
+	// the corrected schema is not drifted anymore
+	corrected, err = r.correctSchemaDrift(ctx, got)
+	require.NoError(t, err)
+	require.False(t, corrected)
+	require.Len(t, recorder.reasons, 1)
+}
+
+func TestCorrectSchemaDriftByChecksum(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("drift-checksum", "default")
+	r := newFakeReconciler(t, cd)
+	r.schemaChecksum = true
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	defRev := &v1beta1.DefinitionRevision{}
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: got.Status.LatestRevision.Name}, defRev))
+	require.NotEmpty(t, defRev.Annotations[oam.AnnotationSchemaChecksum])
+
+	// the stored schemas are compared with the checksum of the revision rather than the regenerated schema
+	changed := got.DeepCopy()
+	changed.Spec.Schematic.CUE.Template = "parameter: {\n\treplicas: int\n}\noutput: {}\n"
+	corrected, err := r.correctSchemaDrift(ctx, changed)
+	require.NoError(t, err)
+	require.False(t, corrected)
+
+	key := utils.SchemaConfigMapKey("", cd.Namespace, defRev.Name)
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, r.Get(ctx, key, cm))
+	cm.Data[types.OpenapiV3JSONSchema] = `{"properties":{"tampered":{"type":"string"}},"type":"object"}`
+	require.NoError(t, r.Update(ctx, cm))
+	require.ErrorIs(t, utils.VerifySchemaChecksum(ctx, r.Client, cm), utils.ErrSchemaChecksumMismatch)
+	corrected, err = r.correctSchemaDrift(ctx, got)
+	require.NoError(t, err)
+	require.True(t, corrected)
+	require.NoError(t, r.Get(ctx, key, cm))
+	require.NoError(t, utils.VerifySchemaChecksum(ctx, r.Client, cm))
+}
@@ -0,0 +1,200 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// DryRun validates the ComponentDefinition by running the revision generation and schema storage logic
+// of the controller against an in-memory client, so nothing is persisted into the cluster.
+// The ConfigMaps of the CUE packages imported by the definition are read by the packageReader, which can be nil if
+// the definition imports no such package. It returns the generated OpenAPI v3 JSON schema of the parameters.
+func DryRun(ctx context.Context, cd *v1beta1.ComponentDefinition, packageReader client.Reader) ([]byte, error) {
+	def := cd.DeepCopy()
+	def.ResourceVersion = ""
+	if def.Namespace == "" {
+		def.Namespace = metav1.NamespaceDefault
+	}
+	if err := checkDryRunCUEPackages(ctx, packageReader, def); err != nil {
+		return nil, coredef.NewDefinitionError(coredef.ErrSchemaStorage, def.Name, err)
+	}
+	cli := newDryRunClient(packageReader)
+	if err := cli.Create(ctx, def); err != nil {
+		return nil, err
+	}
+
+	defRev, _, err := coredef.GenerateDefinitionRevision(ctx, cli, def)
+	if err != nil {
+		return nil, coredef.NewDefinitionError(coredef.ErrRevisionGeneration, def.Name, err)
+	}
+	if err = coredef.CreateDefinitionRevision(ctx, cli, def, defRev); err != nil {
+		return nil, coredef.NewDefinitionError(coredef.ErrRevisionCreation, defRev.Name, err)
+	}
+
+	capability := utils.NewCapabilityComponentDef(def)
+	cmName, err := capability.StoreOpenAPISchema(ctx, cli, def.Namespace, def.Name, defRev.Name)
+	if err != nil {
+		return nil, coredef.NewDefinitionError(coredef.ErrSchemaStorage, def.Name, err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err = cli.Get(ctx, client.ObjectKey{Namespace: def.Namespace, Name: cmName}, cm); err != nil {
+		return nil, err
+	}
+	return utils.GetOpenAPISchemaFromConfigMap(cm)
+}
+
+// checkDryRunCUEPackages checks that the ConfigMaps of the CUE packages imported by the definition can be read by the
+// reader, which DryRun reads them from
+func checkDryRunCUEPackages(ctx context.Context, reader client.Reader, def *v1beta1.ComponentDefinition) error {
+	for _, name := range strings.Split(def.GetAnnotations()[oam.AnnotationCUEPackageConfigMaps], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if reader == nil {
+			return fmt.Errorf("the CUE package in ConfigMap %s/%s is unavailable in dry run", def.Namespace, name)
+		}
+		if err := reader.Get(ctx, client.ObjectKey{Namespace: def.Namespace, Name: name}, &corev1.ConfigMap{}); err != nil {
+			return fmt.Errorf("cannot read the CUE package in ConfigMap %s/%s for dry run: %w", def.Namespace, name, err)
+		}
+	}
+	return nil
+}
+
+// dryRunClient keeps the objects written by DryRun in memory, and reads the other objects from the reader if it's
+// set. The listed objects only come from the reader, and the status is written along with the object.
+type dryRunClient struct {
+	reader  client.Reader
+	objects map[dryRunObjectKey]client.Object
+}
+
+type dryRunObjectKey struct {
+	kind string
+	key  client.ObjectKey
+}
+
+var _ client.Client = &dryRunClient{}
+
+func newDryRunClient(reader client.Reader) *dryRunClient {
+	return &dryRunClient{reader: reader, objects: map[dryRunObjectKey]client.Object{}}
+}
+
+func objectKeyOf(obj client.Object) dryRunObjectKey {
+	return dryRunObjectKey{kind: reflect.TypeOf(obj).String(), key: client.ObjectKeyFromObject(obj)}
+}
+
+func (c *dryRunClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if stored, ok := c.objects[dryRunObjectKey{kind: reflect.TypeOf(obj).String(), key: key}]; ok {
+		reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(stored.DeepCopyObject()).Elem())
+		return nil
+	}
+	if c.reader == nil {
+		return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	return c.reader.Get(ctx, key, obj, opts...)
+}
+
+func (c *dryRunClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if c.reader == nil {
+		return nil
+	}
+	return c.reader.List(ctx, list, opts...)
+}
+
+func (c *dryRunClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	key := objectKeyOf(obj)
+	if _, ok := c.objects[key]; ok {
+		return apierrors.NewAlreadyExists(schema.GroupResource{}, obj.GetName())
+	}
+	c.objects[key] = obj.DeepCopyObject().(client.Object)
+	return nil
+}
+
+func (c *dryRunClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	c.objects[objectKeyOf(obj)] = obj.DeepCopyObject().(client.Object)
+	return nil
+}
+
+// Patch stores the patched object as it is, which is the result of the merge patches DryRun runs into
+func (c *dryRunClient) Patch(ctx context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	return c.Update(ctx, obj)
+}
+
+func (c *dryRunClient) Delete(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	delete(c.objects, objectKeyOf(obj))
+	return nil
+}
+
+func (c *dryRunClient) DeleteAllOf(_ context.Context, _ client.Object, _ ...client.DeleteAllOfOption) error {
+	return nil
+}
+
+func (c *dryRunClient) Status() client.SubResourceWriter {
+	return c.SubResource("status")
+}
+
+func (c *dryRunClient) SubResource(string) client.SubResourceClient {
+	return dryRunSubResourceClient{c: c}
+}
+
+func (c *dryRunClient) Scheme() *runtime.Scheme {
+	return common.Scheme
+}
+
+func (c *dryRunClient) RESTMapper() meta.RESTMapper {
+	return nil
+}
+
+// dryRunSubResourceClient writes the subresources along with the objects of the dryRunClient
+type dryRunSubResourceClient struct {
+	c *dryRunClient
+}
+
+func (s dryRunSubResourceClient) Get(ctx context.Context, obj, _ client.Object, _ ...client.SubResourceGetOption) error {
+	return s.c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+}
+
+func (s dryRunSubResourceClient) Create(_ context.Context, _, _ client.Object, _ ...client.SubResourceCreateOption) error {
+	return nil
+}
+
+func (s dryRunSubResourceClient) Update(ctx context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+	return s.c.Update(ctx, obj)
+}
+
+func (s dryRunSubResourceClient) Patch(ctx context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+	return s.c.Update(ctx, obj)
+}
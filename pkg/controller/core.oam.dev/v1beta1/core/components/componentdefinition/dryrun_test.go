@@ -0,0 +1,114 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+
+	cd := newFakeComponentDefinition("dry-run", "")
+	schema, err := DryRun(ctx, cd, nil)
+	require.NoError(t, err)
+	require.Contains(t, string(schema), `"image"`)
+
+	unknownPkg := newFakeComponentDefinition("dry-run-unknown-pkg", "")
+	unknownPkg.Spec.Schematic.CUE.Template = "import \"not-exist.io/pkg\"\n" + fakeCDTemplate
+	_, err = DryRun(ctx, unknownPkg, nil)
+	require.ErrorIs(t, err, coredef.ErrSchemaStorage)
+	require.Contains(t, err.Error(), "not-exist.io/pkg")
+	var defErr *coredef.DefinitionError
+	require.ErrorAs(t, err, &defErr)
+	require.Equal(t, unknownPkg.Name, defErr.Name)
+
+	tf := newFakeComponentDefinition("dry-run-tf", "")
+	tf.Spec.Schematic = &common.Schematic{Terraform: &common.Terraform{Configuration: `
+variable "bucket" {
+  description = "OSS bucket name"
+  type = string
+}
+`}}
+	schema, err = DryRun(ctx, tf, nil)
+	require.NoError(t, err)
+	require.Contains(t, string(schema), `"bucket"`)
+
+	tf.Spec.Schematic.Terraform.Configuration = `
+resource "alicloud_oss_bucket" "bucket-acl" {
+  bucket = "my-bucket"
+}
+`
+	// the configuration declaring no variable has an empty schema like in the controller
+	schema, err = DryRun(ctx, tf, nil)
+	require.NoError(t, err)
+	require.NotContains(t, string(schema), `"bucket"`)
+
+	tf.Spec.Schematic.Terraform.Configuration = `
+resource "alicloud_oss_bucket" "bucket-acl" {
+  bucket = var.bucket
+}
+
+variable "bukcet" {
+  type = string
+}
+`
+	_, err = DryRun(ctx, tf, nil)
+	require.ErrorIs(t, err, coredef.ErrSchemaStorage)
+	var tfErr *utils.TerraformVariableError
+	require.ErrorAs(t, err, &tfErr)
+	require.Contains(t, err.Error(), "var.bucket is referenced but not a declared variable")
+}
+
+func TestDryRunCUEPackages(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("dry-run-cue-pkg", "default")
+	cd.Spec.Schematic.CUE.Template = cuePackageTemplate
+	cd.Annotations = map[string]string{oam.AnnotationCUEPackageConfigMaps: "cue-helpers"}
+
+	_, err := DryRun(ctx, cd, nil)
+	require.ErrorIs(t, err, coredef.ErrSchemaStorage)
+	require.Contains(t, err.Error(), "the CUE package in ConfigMap default/cue-helpers is unavailable in dry run")
+
+	reader := fake.NewClientBuilder().WithScheme(velacommon.Scheme).Build()
+	_, err = DryRun(ctx, cd, reader)
+	require.ErrorIs(t, err, coredef.ErrSchemaStorage)
+	require.Contains(t, err.Error(), "cannot read the CUE package in ConfigMap default/cue-helpers")
+
+	require.NoError(t, reader.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cue-helpers", Namespace: cd.Namespace},
+		Data: map[string]string{
+			utils.CUEPackagePathKey: "example.com/helpers",
+			"port.cue":              "package helpers\n\n#Port: int & >0 & <65536\n",
+		},
+	}))
+	schema, err := DryRun(ctx, cd, reader)
+	require.NoError(t, err)
+	require.Contains(t, string(schema), `"maximum":65536`)
+}
@@ -0,0 +1,111 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// eventThrottleInterval is the interval in which identical warning events of a definition are emitted at most once
+const eventThrottleInterval = 5 * time.Minute
+
+// eventThrottle tracks the last time each warning event of a definition was emitted
+type eventThrottle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	now      func() time.Time
+	lastSeen map[types.NamespacedName]map[string]time.Time
+}
+
+// allow returns true if the warning event hasn't been emitted for the definition within the interval
+func (t *eventThrottle) allow(key types.NamespacedName, e event.Event) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	signature := strings.Join([]string{string(e.Reason), e.Message}, "/")
+	now := t.now()
+	events, ok := t.lastSeen[key]
+	if !ok {
+		events = make(map[string]time.Time)
+		t.lastSeen[key] = events
+	}
+	if last, ok := events[signature]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+	events[signature] = now
+	return true
+}
+
+// reset forgets the emitted warning events of the definition
+func (t *eventThrottle) reset(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.lastSeen, key)
+}
+
+// throttledRecorder coalesces identical warning events of the same definition, so that a persistently
+// broken definition doesn't flood the events on every reconcile
+type throttledRecorder struct {
+	event.Recorder
+	throttle *eventThrottle
+}
+
+func newThrottledRecorder(r event.Recorder, interval time.Duration) *throttledRecorder {
+	return &throttledRecorder{
+		Recorder: r,
+		throttle: &eventThrottle{interval: interval, now: time.Now, lastSeen: map[types.NamespacedName]map[string]time.Time{}},
+	}
+}
+
+// Event records the event unless it's a warning emitted for the same object within the throttle interval
+func (r *throttledRecorder) Event(obj runtime.Object, e event.Event) {
+	if e.Type == event.TypeWarning {
+		if accessor, err := meta.Accessor(obj); err == nil &&
+			!r.throttle.allow(types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}, e) {
+			return
+		}
+	}
+	r.Recorder.Event(obj, e)
+}
+
+// WithAnnotations returns a new throttledRecorder sharing the same throttle
+func (r *throttledRecorder) WithAnnotations(keysAndValues ...string) event.Recorder {
+	return &throttledRecorder{Recorder: r.Recorder.WithAnnotations(keysAndValues...), throttle: r.throttle}
+}
+
+// resetEventThrottle resets the throttled warning events of the definition after it's reconciled successfully or
+// it's deleted
+func (r *Reconciler) resetEventThrottle(key types.NamespacedName) {
+	if t, ok := r.record.(*throttledRecorder); ok {
+		t.throttle.reset(key)
+	}
+}
+
+// recorder returns the event recorder of the reconciler, the events are discarded if no recorder is set, e.g. the
+// reconciler is used without a manager
+func (r *Reconciler) recorder() event.Recorder {
+	if r.record == nil {
+		return event.NewNopRecorder()
+	}
+	return r.record
+}
@@ -0,0 +1,149 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// countingRecorder counts the recorded warning events
+type countingRecorder struct {
+	warnings int
+}
+
+func (r *countingRecorder) Event(_ runtime.Object, e event.Event) {
+	if e.Type == event.TypeWarning {
+		r.warnings++
+	}
+}
+
+func (r *countingRecorder) WithAnnotations(_ ...string) event.Recorder { return r }
+
+func TestThrottleRepeatedWarningEvents(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("throttle-cd", "default")
+	cd.Spec.Schematic.CUE.Template = cuePackageTemplate
+	cd.Annotations = map[string]string{oam.AnnotationCUEPackageConfigMaps: "cue-helpers"}
+	r := newFakeReconciler(t, cd)
+	counter := &countingRecorder{}
+	recorder := newThrottledRecorder(counter, eventThrottleInterval)
+	now := time.Now()
+	recorder.throttle.now = func() time.Time { return now }
+	r.record = recorder.WithAnnotations("controller", "ComponentDefinition")
+
+	for i := 0; i < 5; i++ {
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, counter.warnings)
+
+	now = now.Add(eventThrottleInterval)
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, 2, counter.warnings)
+
+	// a successful reconcile resets the throttle
+	helpers := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cue-helpers", Namespace: cd.Namespace},
+		Data: map[string]string{
+			utils.CUEPackagePathKey: "example.com/helpers",
+			"port.cue":              "package helpers\n\n#Port: int & >0 & <65536\n",
+		},
+	}
+	require.NoError(t, r.Create(ctx, helpers))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, 2, counter.warnings)
+
+	require.NoError(t, r.Delete(ctx, helpers))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, 3, counter.warnings)
+
+	// normal events and warnings of other objects are not throttled
+	other := newFakeComponentDefinition("other-cd", "default")
+	r.record.Event(other, event.Warning("Could not store capability in ConfigMap", context.Canceled))
+	r.record.Event(other, event.Normal("Synced", "ok"))
+	r.record.Event(other, event.Normal("Synced", "ok"))
+	require.Equal(t, 4, counter.warnings)
+}
+
+func TestForgetDeletedDefinition(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("forget-cd", "default")
+	cd.Spec.Schematic.CUE.Template = cuePackageTemplate
+	cd.Annotations = map[string]string{oam.AnnotationCUEPackageConfigMaps: "cue-helpers"}
+	r := newFakeReconciler(t, cd)
+	recorder := newThrottledRecorder(&countingRecorder{}, eventThrottleInterval)
+	r.record = recorder
+	r.schemaBackoff = workqueue.NewItemExponentialFailureRateLimiter(schemaBackoffBaseDelay, schemaBackoffMaxDelay)
+	key := client.ObjectKeyFromObject(cd)
+
+	// the broken definition is throttled and backed off until it's deleted
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Len(t, recorder.throttle.lastSeen, 1)
+	require.Equal(t, 1, r.schemaBackoff.NumRequeues(key.String()))
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, key, got))
+	require.NoError(t, r.Delete(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Empty(t, recorder.throttle.lastSeen)
+	require.Zero(t, r.schemaBackoff.NumRequeues(key.String()))
+
+	// so is the definition deleted without the finalizer, which is only seen as not found
+	recorder.Event(cd, event.Warning("Could not store capability in ConfigMap", context.Canceled))
+	r.schemaBackoff.When(key.String())
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Empty(t, recorder.throttle.lastSeen)
+	require.Zero(t, r.schemaBackoff.NumRequeues(key.String()))
+}
+
+func TestReconcileWithoutRecorder(t *testing.T) {
+	valid := newFakeComponentDefinition("valid-cd", "default")
+	broken := newFakeComponentDefinition("broken-cd", "default")
+	broken.Spec.Schematic.CUE.Template = cuePackageTemplate
+	broken.Annotations = map[string]string{oam.AnnotationCUEPackageConfigMaps: "cue-helpers"}
+	r := newFakeReconciler(t, valid, broken)
+	r.record = nil
+
+	require.NotPanics(t, func() {
+		_, err := reconcileFake(t, r, valid.Name, valid.Namespace)
+		require.NoError(t, err)
+	})
+	// the broken definition emits warning events, which are discarded
+	require.NotPanics(t, func() {
+		_, _ = reconcileFake(t, r, broken.Name, broken.Namespace)
+	})
+}
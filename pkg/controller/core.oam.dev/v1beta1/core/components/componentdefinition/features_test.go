@@ -0,0 +1,80 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/types"
+	oamctrl "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/features"
+)
+
+func TestFeatureGates(t *testing.T) {
+	opts := parseOptions(oamctrl.Args{})
+	require.False(t, opts.disableSchemaCompression)
+	require.False(t, opts.disableRevisionGCByUsage)
+
+	gates := utilfeature.DefaultMutableFeatureGate.DeepCopy()
+	require.NoError(t, gates.SetFromMap(map[string]bool{
+		string(features.DefinitionSchemaCompression): false,
+		string(features.DefinitionRevisionGCByUsage): false,
+	}))
+	opts = parseOptions(oamctrl.Args{FeatureGates: gates})
+	require.True(t, opts.disableSchemaCompression)
+	require.True(t, opts.disableRevisionGCByUsage)
+}
+
+func TestSchemaCompressionFeatureGate(t *testing.T) {
+	// the description makes the schema exceed the compression threshold
+	largeSchema := fmt.Sprintf(`{"type": "object", "description": %q, "properties": {"image": {"type": "string"}}}`,
+		strings.Repeat("x", 600*1024))
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("compression %t", enabled), func(t *testing.T) {
+			ctx := context.Background()
+			cd := newFakeComponentDefinition("large-schema", "default")
+			cd.Spec.Schematic = &common.Schematic{JSONSchema: &common.JSONSchema{Schema: largeSchema}}
+			r := newFakeReconciler(t, cd)
+			gates := utilfeature.DefaultMutableFeatureGate.DeepCopy()
+			require.NoError(t, gates.SetFromMap(map[string]bool{string(features.DefinitionSchemaCompression): enabled}))
+			r.options = parseOptions(oamctrl.Args{DefRevisionLimit: defRevisionLimit, FeatureGates: gates})
+
+			_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+			require.NoError(t, err)
+			cm := &corev1.ConfigMap{}
+			require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: utils.ComponentDefinitionConfigMapName(cd.Name)}, cm))
+			if enabled {
+				require.Equal(t, types.SchemaEncodingGzipBase64, cm.Data[types.OpenapiV3JSONSchemaEncoding])
+			} else {
+				require.NotContains(t, cm.Data, types.OpenapiV3JSONSchemaEncoding)
+			}
+			schema, err := utils.GetOpenAPISchemaFromConfigMap(cm)
+			require.NoError(t, err)
+			require.JSONEq(t, largeSchema, string(schema))
+		})
+	}
+}
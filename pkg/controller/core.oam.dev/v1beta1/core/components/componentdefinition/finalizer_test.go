@@ -0,0 +1,154 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const fakeCDTemplate = `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: template: spec: containers: [{
+		name:  context.name
+		image: parameter.image
+	}]
+}
+parameter: {
+	// +usage=Which image would you like to use for your service
+	image: string
+}
+`
+
+func newFakeReconciler(t *testing.T, objs ...client.Object) *Reconciler {
+	s := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(s))
+	require.NoError(t, oamcore.AddToScheme(s))
+	return &Reconciler{
+		Client:  fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build(),
+		Scheme:  s,
+		record:  event.NewNopRecorder(),
+		options: options{defRevLimit: defRevisionLimit},
+	}
+}
+
+func newFakeComponentDefinition(name, namespace string) *v1beta1.ComponentDefinition {
+	return &v1beta1.ComponentDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1beta1.SchemeGroupVersion.String(),
+			Kind:       v1beta1.ComponentDefinitionKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Workload: common.WorkloadTypeDescriptor{
+				Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"},
+			},
+			Schematic: &common.Schematic{CUE: &common.CUE{Template: fakeCDTemplate}},
+		},
+	}
+}
+
+func reconcileFake(t *testing.T, r *Reconciler, name, namespace string) (ctrl.Result, error) {
+	t.Helper()
+	return r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: name, Namespace: namespace}})
+}
+
+func TestComponentDefinitionFinalizer(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("finalizer-cd", "default")
+	r := newFakeReconciler(t, cd)
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.True(t, meta.FinalizerExists(got, oam.FinalizerComponentDefinition))
+	require.NotNil(t, got.Status.LatestRevision)
+
+	revs := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, r.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	require.Len(t, revs.Items, 1)
+	for _, name := range []string{utils.ComponentDefinitionConfigMapName(cd.Name), utils.ComponentDefinitionConfigMapName(revs.Items[0].Name)} {
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: name}, &corev1.ConfigMap{}))
+	}
+
+	require.NoError(t, r.Delete(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	require.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(cd), &v1beta1.ComponentDefinition{})))
+	require.NoError(t, r.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	require.Empty(t, revs.Items)
+	cms := &corev1.ConfigMapList{}
+	require.NoError(t, r.List(ctx, cms, client.InNamespace(cd.Namespace)))
+	require.Empty(t, cms.Items)
+}
+
+func TestCleanUpComponentDefinitionResourcesIdempotent(t *testing.T) {
+	cd := newFakeComponentDefinition("gone-cd", "default")
+	r := newFakeReconciler(t)
+	require.NoError(t, cleanUpComponentDefinitionResources(context.Background(), r.Client, cd))
+	require.NoError(t, cleanUpComponentDefinitionResources(context.Background(), r.Client, cd))
+}
+
+func TestReleaseFinalizerOfUnmatchedComponentDefinition(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("unmatched-cd", "default")
+	r := newFakeReconciler(t, cd)
+	r.controllerVersion = "v1.0.0"
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	// the definition is handed over to another controller version after the finalizer is registered
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.True(t, meta.FinalizerExists(got, oam.FinalizerComponentDefinition))
+	got.SetAnnotations(map[string]string{oam.AnnotationControllerRequirement: "v2.0.0"})
+	require.NoError(t, r.Update(ctx, got))
+
+	require.NoError(t, r.Delete(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	// the finalizer is released without cleaning up the resources of the controller matching the requirement
+	require.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(cd), &v1beta1.ComponentDefinition{})))
+	revs := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, r.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	require.Len(t, revs.Items, 1)
+}
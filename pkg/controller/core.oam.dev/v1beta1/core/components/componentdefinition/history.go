@@ -0,0 +1,48 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"sort"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// revisionHistory builds the revision history of the definition from its DefinitionRevisions, the newest revision
+// comes first. Like the garbage collection, the history keeps the latest revision plus at most revisionLimit older
+// ones, and the DefinitionRevisions collected by the garbage collection drop out of the history.
+func revisionHistory(revisions []v1beta1.DefinitionRevision, revisionLimit int) []v1beta1.DefinitionRevisionHistory {
+	if len(revisions) == 0 {
+		return nil
+	}
+	history := make([]v1beta1.DefinitionRevisionHistory, 0, len(revisions))
+	for _, rev := range revisions {
+		history = append(history, v1beta1.DefinitionRevisionHistory{
+			Name:              rev.Name,
+			Revision:          rev.Spec.Revision,
+			RevisionHash:      rev.Spec.RevisionHash,
+			CreationTimestamp: rev.CreationTimestamp,
+		})
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Revision > history[j].Revision
+	})
+	if len(history) > revisionLimit+1 {
+		history = history[:revisionLimit+1]
+	}
+	return history
+}
@@ -0,0 +1,132 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestRevisionHistory(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("history", "default")
+	cd.Annotations = map[string]string{oam.AnnotationDefinitionRevisionLimit: "2"}
+	r := newFakeReconciler(t, cd)
+	got := &v1beta1.ComponentDefinition{}
+
+	names := func() []string {
+		var names []string
+		for _, h := range got.Status.RevisionHistory {
+			names = append(names, h.Name)
+		}
+		return names
+	}
+	for i := 1; i <= 4; i++ {
+		if i > 1 {
+			got.Spec.Schematic.CUE.Template += fmt.Sprintf("\nparameter: p%d: *%d | int\n", i, i)
+			require.NoError(t, r.Update(ctx, got))
+		}
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+		require.Equal(t, got.Status.LatestRevision.Name, got.Status.RevisionHistory[0].Name)
+		require.Equal(t, got.Status.LatestRevision.RevisionHash, got.Status.RevisionHistory[0].RevisionHash)
+	}
+	// history-v1 is collected by the garbage collection and trimmed from the history
+	require.Equal(t, []string{"history-v4", "history-v3", "history-v2"}, names())
+	require.Equal(t, int64(4), got.Status.RevisionHistory[0].Revision)
+
+	require.Nil(t, revisionHistory(nil, 2))
+	revs := []v1beta1.DefinitionRevision{{}, {}, {}}
+	for i := range revs {
+		revs[i].Name = fmt.Sprintf("history-v%d", i+1)
+		revs[i].Spec.Revision = int64(i + 1)
+	}
+	history := revisionHistory(revs, 0)
+	require.Len(t, history, 1)
+	require.Equal(t, "history-v3", history[0].Name)
+	require.Len(t, revisionHistory(revs, 10), 3)
+}
+
+func TestRequeueAfterRevisionGCMaintenanceWindow(t *testing.T) {
+	cd := newFakeComponentDefinition("maintenance", "default")
+	r := newFakeReconciler(t, cd)
+	now := time.Now()
+	r.revisionGCMaintenanceWindows = coredef.RevisionGCMaintenanceWindows{{Start: now.Add(-time.Hour), End: now.Add(time.Hour)}}
+	r.revisionOptions = append(r.revisionOptions, r.revisionGCMaintenanceWindows)
+
+	// the definition is reconciled again when the window closes to collect the revisions
+	res, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Greater(t, res.RequeueAfter, 59*time.Minute)
+	require.LessOrEqual(t, res.RequeueAfter, time.Hour)
+
+	r.revisionGCMaintenanceWindows = nil
+	r.revisionOptions = append(r.revisionOptions, r.revisionGCMaintenanceWindows)
+	res, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Zero(t, res.RequeueAfter)
+}
+
+// setRevisionGCGracePeriod sets the grace period of the reconciler like parseOptions, the later option overrides the
+// earlier one
+func setRevisionGCGracePeriod(r *Reconciler, period time.Duration) {
+	r.revisionGCGracePeriod = coredef.RevisionGCGracePeriod(period)
+	r.revisionOptions = append(r.revisionOptions, r.revisionGCGracePeriod)
+}
+
+func TestRequeueAfterRevisionGCGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("grace", "default")
+	cd.Annotations = map[string]string{oam.AnnotationDefinitionRevisionLimit: "0"}
+	r := newFakeReconciler(t, cd)
+	setRevisionGCGracePeriod(r, time.Hour)
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	v1 := &v1beta1.DefinitionRevision{}
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: "grace-v1"}, v1))
+	v1.CreationTimestamp = metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	require.NoError(t, r.Update(ctx, v1))
+
+	// the superseded revision is retained, and the definition is reconciled again once it ages past the grace period
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	got.Spec.Schematic.CUE.Template += "\nparameter: replicas: *1 | int\n"
+	require.NoError(t, r.Update(ctx, got))
+	res, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Greater(t, res.RequeueAfter, 49*time.Minute)
+	require.LessOrEqual(t, res.RequeueAfter, 50*time.Minute)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(v1), v1))
+
+	setRevisionGCGracePeriod(r, 5*time.Minute)
+	res, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Zero(t, res.RequeueAfter)
+	require.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(v1), v1)))
+}
@@ -0,0 +1,90 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/monitor/metrics"
+)
+
+func reconcileSampleCount(t *testing.T, result string) uint64 {
+	m := &dto.Metric{}
+	require.NoError(t, metrics.ComponentDefinitionReconcileTimeHistogram.WithLabelValues(result).(prometheus.Histogram).Write(m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestReconcileMetrics(t *testing.T) {
+	cd := newFakeComponentDefinition("metrics-cd", "default")
+	r := newFakeReconciler(t, cd)
+
+	created := testutil.ToFloat64(metrics.ComponentDefinitionRevisionCounter.WithLabelValues("create"))
+	reused := testutil.ToFloat64(metrics.ComponentDefinitionRevisionCounter.WithLabelValues("reuse"))
+	observed := reconcileSampleCount(t, "success")
+
+	for i := 0; i < 2; i++ {
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	require.Equal(t, created+1, testutil.ToFloat64(metrics.ComponentDefinitionRevisionCounter.WithLabelValues("create")))
+	require.Equal(t, reused+1, testutil.ToFloat64(metrics.ComponentDefinitionRevisionCounter.WithLabelValues("reuse")))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.ComponentDefinitionRevisionGauge.WithLabelValues(cd.Namespace, cd.Name)))
+	require.Equal(t, observed+2, reconcileSampleCount(t, "success"))
+
+	_, err := reconcileFake(t, r, "not-exist", cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, observed+3, reconcileSampleCount(t, "success"))
+}
+
+func TestSchemaSizeMetrics(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("large-schema-cd", "default")
+	r := newFakeReconciler(t, cd)
+	r.schemaSizeThreshold = 64
+	counter := &countingRecorder{}
+	r.record = counter
+	got := &v1beta1.ComponentDefinition{}
+
+	for i := 0; i < 2; i++ {
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Greater(t, got.Status.SchemaSize, int64(64))
+	require.Equal(t, float64(got.Status.SchemaSize), testutil.ToFloat64(metrics.ComponentDefinitionSchemaSizeGauge.WithLabelValues(cd.Namespace, cd.Name)))
+	withinThreshold := got.GetCondition(coredef.TypeSchemaSizeWithinThreshold)
+	require.Equal(t, corev1.ConditionFalse, withinThreshold.Status)
+	require.Contains(t, withinThreshold.Message, "exceeds the warning threshold 64 bytes")
+	// the warning is emitted once the threshold is crossed rather than on every reconcile
+	require.Equal(t, 1, counter.warnings)
+
+	r.schemaSizeThreshold = 1024 * 1024
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaSizeWithinThreshold).Status)
+}
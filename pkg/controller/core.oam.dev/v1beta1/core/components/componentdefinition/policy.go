@@ -0,0 +1,146 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+)
+
+// policyDenyField is the field of a definition policy listing the violations
+const policyDenyField = "deny"
+
+// PolicyEvaluator checks the ComponentDefinition against the organizational policies, e.g. forbidding hostPath
+// volumes in the rendered output, and returns the violations
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, cli client.Client, def *v1beta1.ComponentDefinition) ([]string, error)
+}
+
+// configMapPolicyEvaluator evaluates the CUE policies loaded from a ConfigMap. Each .cue file of the ConfigMap is a
+// policy evaluated in the scope of the CUE template of the definition, so it can refer to `output`, `outputs` and
+// `parameter` of the template, and declares the violations as strings in its `deny` list, for example
+//
+//	deny: [for v in output.spec.template.spec.volumes if v.hostPath != _|_ {"hostPath volume \(v.name) is forbidden"}]
+//
+// The ConfigMap is loaded on every reconcile, so the changes of policies apply from the next reconcile.
+type configMapPolicyEvaluator struct {
+	configMap types.NamespacedName
+}
+
+// newConfigMapPolicyEvaluator returns the evaluator of the policies in the ConfigMap named as <namespace>/<name>,
+// nil is returned if the name is empty so that the policy check is disabled
+func newConfigMapPolicyEvaluator(namespacedName string) PolicyEvaluator {
+	if namespacedName == "" {
+		return nil
+	}
+	key := types.NamespacedName{Name: namespacedName}
+	if ns, name, found := strings.Cut(namespacedName, "/"); found {
+		key = types.NamespacedName{Namespace: ns, Name: name}
+	}
+	return &configMapPolicyEvaluator{configMap: key}
+}
+
+// Evaluate implements PolicyEvaluator, only the definitions of CUE schematic are checked as the others have no
+// rendered output at registration
+func (e *configMapPolicyEvaluator) Evaluate(ctx context.Context, cli client.Client, def *v1beta1.ComponentDefinition) ([]string, error) {
+	if def.Spec.Schematic == nil || def.Spec.Schematic.CUE == nil {
+		return nil, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := cli.Get(ctx, e.configMap, cm); err != nil {
+		return nil, errors.Wrapf(err, "cannot load the definition policies from ConfigMap %s", e.configMap)
+	}
+	cueCtx := cuecontext.New()
+	template := cueCtx.CompileString(def.Spec.Schematic.CUE.Template + coredef.StatusTemplateRuntimeContext)
+	if template.Err() != nil {
+		return nil, errors.WithMessage(template.Err(), "compile the template")
+	}
+	policies := make([]string, 0, len(cm.Data))
+	for key := range cm.Data {
+		if strings.HasSuffix(key, ".cue") {
+			policies = append(policies, key)
+		}
+	}
+	sort.Strings(policies)
+	var violations []string
+	for _, key := range policies {
+		denied, err := evaluatePolicy(cueCtx, template, cm.Data[key])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "evaluate policy %s", key)
+		}
+		for _, msg := range denied {
+			violations = append(violations, fmt.Sprintf("%s: %s", key, msg))
+		}
+	}
+	return violations, nil
+}
+
+// evaluatePolicy compiles the policy in the scope of the template and returns the messages of its deny list
+func evaluatePolicy(cueCtx *cue.Context, template cue.Value, policy string) ([]string, error) {
+	v := cueCtx.CompileString(policy, cue.Scope(template))
+	if v.Err() != nil {
+		return nil, v.Err()
+	}
+	deny := v.LookupPath(cue.ParsePath(policyDenyField))
+	if !deny.Exists() {
+		return nil, nil
+	}
+	iter, err := deny.List()
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for iter.Next() {
+		msg, err := iter.Value().String()
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// checkPolicies computes the PolicyCompliant condition of the definition, the violations are reported in the
+// condition and a warning event. No condition is returned if the policy check is disabled.
+func (r *Reconciler) checkPolicies(ctx context.Context, def *v1beta1.ComponentDefinition) []condition.Condition {
+	if r.policyEvaluator == nil {
+		return nil
+	}
+	violations, err := r.policyEvaluator.Evaluate(ctx, r.Client, def)
+	if err == nil && len(violations) > 0 {
+		err = errors.Errorf("violate the definition policies: %s", strings.Join(violations, "; "))
+	}
+	if err != nil {
+		r.recorder().Event(def, event.Warning("definition policies are not complied", err))
+		return []condition.Condition{condition.ErrorCondition(coredef.TypePolicyCompliant, err)}
+	}
+	return []condition.Condition{condition.ReadyCondition(coredef.TypePolicyCompliant)}
+}
@@ -0,0 +1,93 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+)
+
+const hostPathTemplate = `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: template: spec: {
+		containers: [{
+			name:  context.name
+			image: parameter.image
+		}]
+		volumes: [{
+			name: "data"
+			hostPath: path: parameter.path
+		}]
+	}
+}
+parameter: {
+	image: string
+	path:  *"/data" | string
+}
+`
+
+const noHostPathPolicy = `
+_volumes: *output.spec.template.spec.volumes | []
+deny: [ for v in _volumes if v.hostPath != _|_ {"hostPath volume \(v.name) is forbidden"}]
+`
+
+func TestDefinitionPolicies(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("policy-cd", "default")
+	cd.Spec.Schematic.CUE.Template = hostPathTemplate
+	policies := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "definition-policies", Namespace: "vela-system"},
+		Data:       map[string]string{"no-hostpath.cue": noHostPathPolicy, "README": "not a policy"},
+	}
+	r := newFakeReconciler(t, cd, policies)
+	r.policyEvaluator = newConfigMapPolicyEvaluator("vela-system/definition-policies")
+	got := &v1beta1.ComponentDefinition{}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	compliant := got.GetCondition(coredef.TypePolicyCompliant)
+	require.Equal(t, corev1.ConditionFalse, compliant.Status)
+	require.Contains(t, compliant.Message, "no-hostpath.cue: hostPath volume data is forbidden")
+
+	got.Spec.Schematic.CUE.Template = fakeCDTemplate
+	require.NoError(t, r.Update(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypePolicyCompliant).Status)
+
+	// a broken policy is reported rather than regarded as compliant
+	policies.Data["broken.cue"] = "deny: [1]"
+	require.NoError(t, r.Update(ctx, policies))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	compliant = got.GetCondition(coredef.TypePolicyCompliant)
+	require.Equal(t, corev1.ConditionFalse, compliant.Status)
+	require.Contains(t, compliant.Message, "evaluate policy broken.cue")
+}
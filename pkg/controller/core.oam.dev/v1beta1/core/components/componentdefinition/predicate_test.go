@@ -0,0 +1,163 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlEvent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestDefinitionChangedPredicate(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("predicate-cd", "default")
+	r := newFakeReconciler(t, cd)
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	old := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), old))
+	require.Equal(t, "predicate-cd-v1", old.Status.LatestRevision.Name)
+
+	p := definitionChangedPredicate()
+	update := func(mutate func(def *v1beta1.ComponentDefinition)) bool {
+		newDef := old.DeepCopy()
+		newDef.ResourceVersion += "1"
+		mutate(newDef)
+		return p.Update(ctrlEvent.UpdateEvent{ObjectOld: old, ObjectNew: newDef})
+	}
+
+	// a pure status update doesn't trigger reconcile
+	require.False(t, update(func(def *v1beta1.ComponentDefinition) {
+		def.SetConditions(condition.ReconcileError(context.Canceled))
+		def.Status.ConfigMapRef = "changed"
+	}))
+	// nor does it create a new revision if reconciled anyway
+	newDef := old.DeepCopy()
+	newDef.SetConditions(condition.ReconcileError(context.Canceled))
+	require.NoError(t, r.Status().Update(ctx, newDef))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	revs := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, r.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	require.Len(t, revs.Items, 1)
+
+	require.True(t, update(func(def *v1beta1.ComponentDefinition) { def.Generation++ }))
+	require.True(t, update(func(def *v1beta1.ComponentDefinition) {
+		def.Annotations = map[string]string{oam.AnnotationDefinitionRevisionName: "1.0.0"}
+	}))
+	require.True(t, update(func(def *v1beta1.ComponentDefinition) { def.Labels = map[string]string{"team": "a"} }))
+	require.True(t, update(func(def *v1beta1.ComponentDefinition) { def.Finalizers = nil }))
+	require.True(t, update(func(def *v1beta1.ComponentDefinition) { def.DeletionTimestamp = &metav1.Time{} }))
+	// resync
+	require.True(t, p.Update(ctrlEvent.UpdateEvent{ObjectOld: old, ObjectNew: old.DeepCopy()}))
+}
+
+func TestNamespaceFilter(t *testing.T) {
+	inTeamA := newFakeComponentDefinition("worker", "team-a")
+	inTeamB := newFakeComponentDefinition("worker", "team-b")
+	inSystem := newFakeComponentDefinition("worker", "vela-system")
+
+	all := newNamespaceFilter(nil, nil)
+	require.True(t, all.allows("team-a"))
+	require.True(t, all.allows("vela-system"))
+
+	p := newNamespaceFilter([]string{"team-a", "vela-system"}, []string{"vela-system"}).predicate()
+	require.True(t, p.Create(ctrlEvent.CreateEvent{Object: inTeamA}))
+	require.True(t, p.Update(ctrlEvent.UpdateEvent{ObjectOld: inTeamA, ObjectNew: inTeamA}))
+	require.False(t, p.Create(ctrlEvent.CreateEvent{Object: inTeamB}))
+	require.False(t, p.Update(ctrlEvent.UpdateEvent{ObjectOld: inTeamB, ObjectNew: inTeamB}))
+	require.False(t, p.Delete(ctrlEvent.DeleteEvent{Object: inTeamB}))
+	// the deletions are filtered out as well, they are cleaned up by the controllers the namespaces are allowed to
+	deleting := inTeamB.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleting.Finalizers = []string{oam.FinalizerComponentDefinition}
+	require.False(t, p.Update(ctrlEvent.UpdateEvent{ObjectOld: inTeamB, ObjectNew: deleting}))
+	// the denylist takes precedence
+	require.False(t, p.Create(ctrlEvent.CreateEvent{Object: inSystem}))
+	require.True(t, newNamespaceFilter(nil, []string{"vela-system"}).allows("team-b"))
+
+	mapFn := newNamespaceFilter([]string{"team-a"}, nil).filter(func(client.Object) []reconcile.Request {
+		return []reconcile.Request{
+			{NamespacedName: client.ObjectKeyFromObject(inTeamA)},
+			{NamespacedName: client.ObjectKeyFromObject(inTeamB)},
+		}
+	})
+	require.Equal(t, []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(inTeamA)}}, mapFn(inTeamA))
+}
+
+func TestSchemaDriftResyncOutsideAllowlist(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("worker", "team-b")
+	r := newFakeReconciler(t, cd)
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	key := utils.SchemaConfigMapKey("", cd.Namespace, got.Status.LatestRevision.Name)
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, r.Get(ctx, key, cm))
+	tampered := `{"properties":{"tampered":{"type":"string"}},"type":"object"}`
+	cm.Data[types.OpenapiV3JSONSchema] = tampered
+	require.NoError(t, r.Update(ctx, cm))
+
+	// the definitions outside the allowlist are ignored
+	r.namespaces = newNamespaceFilter([]string{"team-a"}, nil)
+	r.resyncSchemaDrift(ctx)
+	require.NoError(t, r.Get(ctx, key, cm))
+	require.Equal(t, tampered, cm.Data[types.OpenapiV3JSONSchema])
+
+	r.namespaces = newNamespaceFilter([]string{"team-a", "team-b"}, nil)
+	r.resyncSchemaDrift(ctx)
+	require.NoError(t, r.Get(ctx, key, cm))
+	require.NotEqual(t, tampered, cm.Data[types.OpenapiV3JSONSchema])
+}
+
+func TestDeleteDefinitionOutsideAllowlist(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("worker", "team-b")
+	r := newFakeReconciler(t, cd)
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	// the namespace is excluded after the definition is registered with the finalizer
+	r.namespaces = newNamespaceFilter([]string{"team-a"}, nil)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.NoError(t, r.Delete(ctx, got))
+
+	// the finalizer is released without cleaning up the resources with the storage settings of this controller
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(cd), &v1beta1.ComponentDefinition{})))
+	revs := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, r.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	require.Len(t, revs.Items, 1)
+}
@@ -0,0 +1,63 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlEvent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileMaxDelay caps the per-item exponential delay of the reconcile queue
+const reconcileMaxDelay = 1000 * time.Second
+
+// newReconcileRateLimiter returns the rate limiter of the reconcile queue, which delays each definition exponentially
+// from the base delay and bounds the overall rate by a token bucket of the qps and the burst. The overall rate is
+// unlimited if the qps is not positive.
+func newReconcileRateLimiter(baseDelay time.Duration, qps float64, burst int) workqueue.RateLimiter {
+	limit := rate.Inf
+	if qps > 0 {
+		limit = rate.Limit(qps)
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(baseDelay, reconcileMaxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(limit, burst)},
+	)
+}
+
+// rateLimitedCreateHandler enqueues the ComponentDefinitions like handler.EnqueueRequestForObject, except that the
+// create events go through the rate limiter of the queue. All definitions are listed as created when the controller
+// starts, so they are spread out instead of compiling every CUE template at once.
+type rateLimitedCreateHandler struct {
+	handler.EnqueueRequestForObject
+}
+
+// Create implements handler.EventHandler
+func (h *rateLimitedCreateHandler) Create(evt ctrlEvent.CreateEvent, q workqueue.RateLimitingInterface) {
+	if evt.Object == nil {
+		return
+	}
+	q.AddRateLimited(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(evt.Object)})
+}
@@ -0,0 +1,76 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/util/workqueue"
+	ctrlEvent "sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// drainQueue counts the items of the queue as they become available
+func drainQueue(q workqueue.RateLimitingInterface, received chan<- struct{}) {
+	for {
+		item, shutdown := q.Get()
+		if shutdown {
+			return
+		}
+		q.Forget(item)
+		q.Done(item)
+		received <- struct{}{}
+	}
+}
+
+func TestRateLimitedMassEnqueue(t *testing.T) {
+	const definitions, qps, burst = 30, 50, 5
+	q := workqueue.NewRateLimitingQueue(newReconcileRateLimiter(time.Millisecond, qps, burst))
+	defer q.ShutDown()
+	received := make(chan struct{}, definitions)
+	go drainQueue(q, received)
+
+	h := &rateLimitedCreateHandler{}
+	start := time.Now()
+	for i := 0; i < definitions; i++ {
+		h.Create(ctrlEvent.CreateEvent{Object: newFakeComponentDefinition(fmt.Sprintf("cd-%d", i), "default")}, q)
+	}
+	h.Create(ctrlEvent.CreateEvent{}, q)
+
+	time.Sleep(100 * time.Millisecond)
+	// the burst plus the tokens refilled in the elapsed time, whatever the speed of the test machine
+	bound := burst + int(time.Since(start).Seconds()*qps) + 1
+	require.LessOrEqual(t, len(received), bound)
+	require.Less(t, len(received), definitions)
+	require.Eventually(t, func() bool { return len(received) == definitions }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestUnlimitedReconcileRate(t *testing.T) {
+	const definitions = 30
+	q := workqueue.NewRateLimitingQueue(newReconcileRateLimiter(time.Millisecond, 0, 0))
+	defer q.ShutDown()
+	received := make(chan struct{}, definitions)
+	go drainQueue(q, received)
+
+	h := &rateLimitedCreateHandler{}
+	for i := 0; i < definitions; i++ {
+		h.Create(ctrlEvent.CreateEvent{Object: newFakeComponentDefinition(fmt.Sprintf("cd-%d", i), "default")}, q)
+	}
+	require.Eventually(t, func() bool { return len(received) == definitions }, 500*time.Millisecond, 5*time.Millisecond)
+}
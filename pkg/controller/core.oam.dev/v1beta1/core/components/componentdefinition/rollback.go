@@ -0,0 +1,118 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// rollback replaces the spec of the definition with the spec stored in the DefinitionRevision requested by the
+// annotation oam.AnnotationDefinitionRollbackTo, and clears the annotation in the same update. The rolled back spec
+// is revisioned like any other change, so the revision history is preserved. It returns true if the reconcile
+// should stop here, i.e. the definition is updated and reconciled again, or the target is invalid. The invalid
+// target is reported by the RolledBack condition until the annotation is fixed or removed.
+func (r *Reconciler) rollback(ctx context.Context, def *v1beta1.ComponentDefinition) (bool, error) {
+	target, ok := def.GetAnnotations()[oam.AnnotationDefinitionRollbackTo]
+	if !ok {
+		if def.GetCondition(coredef.TypeRolledBack).Reason != coredef.ReasonInvalidRollbackTarget {
+			return false, nil
+		}
+		// the invalid rollback is withdrawn
+		return false, util.PatchCondition(ctx, r, def, condition.Condition{
+			Type:               coredef.TypeRolledBack,
+			Status:             corev1.ConditionFalse,
+			LastTransitionTime: metav1.Now(),
+			Reason:             coredef.ReasonRollbackWithdrawn,
+		})
+	}
+	defRev, err := r.getRollbackTarget(ctx, def, target)
+	if err != nil {
+		var invalid *invalidRollbackTargetError
+		if !errors.As(err, &invalid) {
+			return true, err
+		}
+		if current := def.GetCondition(coredef.TypeRolledBack); current.Reason != coredef.ReasonInvalidRollbackTarget || current.Message != err.Error() {
+			r.recorder().Event(def, event.Warning("cannot roll back", err))
+		}
+		return true, util.PatchCondition(ctx, r, def, condition.Condition{
+			Type:               coredef.TypeRolledBack,
+			Status:             corev1.ConditionFalse,
+			LastTransitionTime: metav1.Now(),
+			Reason:             coredef.ReasonInvalidRollbackTarget,
+			Message:            err.Error(),
+		})
+	}
+
+	def.Spec = *defRev.Spec.ComponentDefinition.Spec.DeepCopy()
+	delete(def.Annotations, oam.AnnotationDefinitionRollbackTo)
+	if err = r.Update(ctx, def); err != nil {
+		return true, errors.Wrapf(err, "cannot roll back to DefinitionRevision %s", defRev.Name)
+	}
+	r.recorder().Event(def, event.Normal("rolled back", fmt.Sprintf("the spec is rolled back to DefinitionRevision %s", defRev.Name)))
+	return true, util.PatchCondition(ctx, r, def, condition.Condition{
+		Type:               coredef.TypeRolledBack,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             coredef.ReasonRolledBack,
+		Message:            fmt.Sprintf("the spec is rolled back to DefinitionRevision %s", defRev.Name),
+	})
+}
+
+type invalidRollbackTargetError struct {
+	revision string
+	reason   string
+}
+
+func (e *invalidRollbackTargetError) Error() string {
+	return fmt.Sprintf("cannot roll back to DefinitionRevision %s: %s", e.revision, e.reason)
+}
+
+// getRollbackTarget gets the DefinitionRevision to roll back to, an *invalidRollbackTargetError is returned if it
+// doesn't exist or isn't a revision of the definition
+func (r *Reconciler) getRollbackTarget(ctx context.Context, def *v1beta1.ComponentDefinition, name string) (*v1beta1.DefinitionRevision, error) {
+	if name == "" {
+		return nil, &invalidRollbackTargetError{revision: name, reason: "the revision name is empty"}
+	}
+	defRev := &v1beta1.DefinitionRevision{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: def.Namespace, Name: name}, defRev); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, &invalidRollbackTargetError{revision: name, reason: "it doesn't exist"}
+		}
+		return nil, errors.Wrapf(err, "cannot get DefinitionRevision %s", name)
+	}
+	owner := defRev.GetLabels()[oam.LabelDefinitionUID]
+	if defRev.Spec.DefinitionType != common.ComponentType || defRev.GetLabels()[oam.LabelComponentDefinitionName] != def.Name ||
+		(owner != "" && def.UID != "" && owner != string(def.UID)) {
+		return nil, &invalidRollbackTargetError{revision: name, reason: fmt.Sprintf("it's not a revision of ComponentDefinition %s", def.Name)}
+	}
+	return defRev, nil
+}
@@ -0,0 +1,123 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("rollback-cd", "default")
+	other := newFakeComponentDefinition("other-cd", "default")
+	r := newFakeReconciler(t, cd, other)
+	counter := &countingRecorder{}
+	r.record = counter
+	got := &v1beta1.ComponentDefinition{}
+	update := func(mutate func(def *v1beta1.ComponentDefinition)) {
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+		mutate(got)
+		require.NoError(t, r.Update(ctx, got))
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	}
+	countRevisions := func() int {
+		revs := &v1beta1.DefinitionRevisionList{}
+		require.NoError(t, r.List(ctx, revs, client.InNamespace(cd.Namespace), client.MatchingLabels{oam.LabelComponentDefinitionName: cd.Name}))
+		return len(revs.Items)
+	}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	_, err = reconcileFake(t, r, other.Name, other.Namespace)
+	require.NoError(t, err)
+	update(func(def *v1beta1.ComponentDefinition) {
+		def.Spec.Schematic.CUE.Template += "\nparameter: replicas: *1 | int\n"
+	})
+	require.Equal(t, "rollback-cd-v2", got.Status.LatestRevision.Name)
+	require.Equal(t, 2, countRevisions())
+
+	// the target must be a revision of the definition
+	for target, msg := range map[string]string{
+		"not-exist":   "cannot roll back to DefinitionRevision not-exist: it doesn't exist",
+		"other-cd-v1": "cannot roll back to DefinitionRevision other-cd-v1: it's not a revision of ComponentDefinition rollback-cd",
+	} {
+		update(func(def *v1beta1.ComponentDefinition) {
+			def.Annotations = map[string]string{oam.AnnotationDefinitionRollbackTo: target}
+		})
+		rolledBack := got.GetCondition(coredef.TypeRolledBack)
+		require.Equal(t, corev1.ConditionFalse, rolledBack.Status)
+		require.Equal(t, coredef.ReasonInvalidRollbackTarget, rolledBack.Reason)
+		require.Equal(t, msg, rolledBack.Message)
+		require.Contains(t, got.Spec.Schematic.CUE.Template, "replicas")
+		require.Equal(t, target, got.Annotations[oam.AnnotationDefinitionRollbackTo])
+	}
+	require.Equal(t, 2, counter.warnings)
+
+	// the spec is rolled back and the annotation is cleared
+	update(func(def *v1beta1.ComponentDefinition) {
+		def.Annotations = map[string]string{oam.AnnotationDefinitionRollbackTo: "rollback-cd-v1"}
+	})
+	require.Equal(t, fakeCDTemplate, got.Spec.Schematic.CUE.Template)
+	require.NotContains(t, got.Annotations, oam.AnnotationDefinitionRollbackTo)
+	rolledBack := got.GetCondition(coredef.TypeRolledBack)
+	require.Equal(t, corev1.ConditionTrue, rolledBack.Status)
+	require.Equal(t, "the spec is rolled back to DefinitionRevision rollback-cd-v1", rolledBack.Message)
+
+	// the rolled back spec is revisioned as a new revision, and the history is preserved
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, "rollback-cd-v3", got.Status.LatestRevision.Name)
+	require.Equal(t, 3, countRevisions())
+	v1 := &v1beta1.DefinitionRevision{}
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: "rollback-cd-v1"}, v1))
+	require.Equal(t, v1.Spec.RevisionHash, got.Status.LatestRevision.RevisionHash)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+}
+
+func TestRollbackWithdrawn(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("withdrawn-cd", "default")
+	cd.Annotations = map[string]string{oam.AnnotationDefinitionRollbackTo: "not-exist"}
+	r := newFakeReconciler(t, cd)
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, coredef.ReasonInvalidRollbackTarget, got.GetCondition(coredef.TypeRolledBack).Reason)
+	require.Nil(t, got.Status.LatestRevision)
+
+	delete(got.Annotations, oam.AnnotationDefinitionRollbackTo)
+	require.NoError(t, r.Update(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, coredef.ReasonRollbackWithdrawn, got.GetCondition(coredef.TypeRolledBack).Reason)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+}
@@ -0,0 +1,93 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// isSystemScoped checks whether the definition is labeled as system-scoped
+func isSystemScoped(def *v1beta1.ComponentDefinition) bool {
+	scoped, err := strconv.ParseBool(def.GetLabels()[oam.LabelSystemScopedDefinition])
+	return err == nil && scoped
+}
+
+// syncSharedSchema publishes the schema ConfigMap of a system-scoped definition to the shared namespace, so that the
+// schema can be resolved from other namespaces. The shared copy is removed once the definition is no longer system-scoped.
+func syncSharedSchema(ctx context.Context, cli client.Client, sharedNamespace string, def *v1beta1.ComponentDefinition, sourceKey client.ObjectKey) error {
+	if sharedNamespace == "" || sharedNamespace == def.Namespace {
+		return nil
+	}
+	if !isSystemScoped(def) {
+		return deleteSharedSchema(ctx, cli, sharedNamespace, def)
+	}
+	source := &corev1.ConfigMap{}
+	if err := cli.Get(ctx, sourceKey, source); err != nil {
+		return errors.Wrapf(err, "cannot get the schema ConfigMap %s", sourceKey)
+	}
+	cmName := utils.ComponentDefinitionConfigMapName(def.Name)
+	labels := util.MergeMapOverrideWithDst(source.Labels, map[string]string{oam.LabelSharedSchemaSourceNamespace: def.Namespace})
+	shared := &corev1.ConfigMap{}
+	err := cli.Get(ctx, client.ObjectKey{Namespace: sharedNamespace, Name: cmName}, shared)
+	if apierrors.IsNotFound(err) {
+		shared.SetName(cmName)
+		shared.SetNamespace(sharedNamespace)
+		shared.SetLabels(labels)
+		shared.Data = source.Data
+		return errors.Wrapf(cli.Create(ctx, shared), "cannot create the shared schema ConfigMap %s/%s", sharedNamespace, cmName)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "cannot get the shared schema ConfigMap %s/%s", sharedNamespace, cmName)
+	}
+	if owner := shared.Labels[oam.LabelSharedSchemaSourceNamespace]; owner != def.Namespace {
+		return fmt.Errorf("the shared schema ConfigMap %s/%s is published by the definition in namespace %q", sharedNamespace, cmName, owner)
+	}
+	shared.SetLabels(labels)
+	shared.Data = source.Data
+	return errors.Wrapf(cli.Update(ctx, shared), "cannot update the shared schema ConfigMap %s/%s", sharedNamespace, cmName)
+}
+
+// deleteSharedSchema deletes the schema ConfigMap published by the definition to the shared namespace
+func deleteSharedSchema(ctx context.Context, cli client.Client, sharedNamespace string, def *v1beta1.ComponentDefinition) error {
+	if sharedNamespace == "" || sharedNamespace == def.Namespace {
+		return nil
+	}
+	cmName := utils.ComponentDefinitionConfigMapName(def.Name)
+	shared := &corev1.ConfigMap{}
+	if err := cli.Get(ctx, client.ObjectKey{Namespace: sharedNamespace, Name: cmName}, shared); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if shared.Labels[oam.LabelSharedSchemaSourceNamespace] != def.Namespace {
+		return nil
+	}
+	if err := cli.Delete(ctx, shared); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "cannot delete the shared schema ConfigMap %s/%s", sharedNamespace, cmName)
+	}
+	return nil
+}
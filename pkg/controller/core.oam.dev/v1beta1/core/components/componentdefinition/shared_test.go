@@ -0,0 +1,87 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestPublishSharedSchema(t *testing.T) {
+	ctx := context.Background()
+	const sharedNamespace = "vela-system"
+	cd := newFakeComponentDefinition("shared-cd", "default")
+	cd.Labels = map[string]string{oam.LabelSystemScopedDefinition: "true"}
+	r := newFakeReconciler(t, cd)
+	r.sharedSchemaNamespace = sharedNamespace
+	got := &v1beta1.ComponentDefinition{}
+	sharedKey := client.ObjectKey{Namespace: sharedNamespace, Name: utils.ComponentDefinitionConfigMapName(cd.Name)}
+	reconcile := func() {
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	}
+
+	reconcile()
+	shared := &corev1.ConfigMap{}
+	require.NoError(t, r.Get(ctx, sharedKey, shared))
+	require.Equal(t, cd.Namespace, shared.Labels[oam.LabelSharedSchemaSourceNamespace])
+	require.Contains(t, shared.Data[types.OpenapiV3JSONSchema], `"image"`)
+
+	// updates propagate to the shared copy
+	got.Spec.Schematic.CUE.Template += "\nparameter: port: *80 | int\n"
+	require.NoError(t, r.Update(ctx, got))
+	reconcile()
+	require.NoError(t, r.Get(ctx, sharedKey, shared))
+	require.Contains(t, shared.Data[types.OpenapiV3JSONSchema], `"port"`)
+
+	// the definition of the same name in another namespace can't take over the shared copy
+	other := newFakeComponentDefinition("shared-cd", "team-a")
+	other.Labels = map[string]string{oam.LabelSystemScopedDefinition: "true"}
+	require.NoError(t, r.Create(ctx, other))
+	_, err := reconcileFake(t, r, other.Name, other.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(other), other))
+	require.Contains(t, other.GetCondition(condition.TypeSynced).Message, `published by the definition in namespace "default"`)
+
+	// the shared copy is removed once the definition is no longer system-scoped
+	delete(got.Labels, oam.LabelSystemScopedDefinition)
+	require.NoError(t, r.Update(ctx, got))
+	reconcile()
+	require.True(t, apierrors.IsNotFound(r.Get(ctx, sharedKey, shared)))
+
+	// and when the definition is deleted
+	got.Labels = map[string]string{oam.LabelSystemScopedDefinition: "true"}
+	require.NoError(t, r.Update(ctx, got))
+	reconcile()
+	require.NoError(t, r.Get(ctx, sharedKey, shared))
+	require.NoError(t, r.Delete(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.True(t, apierrors.IsNotFound(r.Get(ctx, sharedKey, shared)))
+}
@@ -0,0 +1,222 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	velaslices "github.com/kubevela/pkg/util/slices"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velaerrors "github.com/oam-dev/kubevela/pkg/utils/errors"
+)
+
+// replicateToSpokes replicates the DefinitionRevision and the schema ConfigMaps of the definition to the spoke
+// clusters, so that the schema can be resolved locally there. The spoke clusters are reached by the client through
+// the cluster gateway. Every cluster is replicated independently, the failure of a cluster is reported by an event
+// and doesn't stop the others. The clusters failed are returned.
+func (r *Reconciler) replicateToSpokes(ctx context.Context, def *v1beta1.ComponentDefinition, defRev *v1beta1.DefinitionRevision) []string {
+	// the stored revision is replicated, which carries the labels and annotations missing on the returned revision,
+	// e.g. the name of the definition the replicas are found by once the definition is deleted
+	stored := &v1beta1.DefinitionRevision{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: def.Namespace, Name: defRev.Name}, stored); err != nil {
+		r.recorder().Event(def, event.Warning("cannot replicate to the spoke clusters", errors.Wrapf(err, "cannot get the DefinitionRevision %s", defRev.Name)))
+		return r.spokeClusters
+	}
+	objs := []client.Object{stored}
+	// the schemas stored by an external store are not replicated
+	if r.schemaStore == nil {
+		for _, name := range []string{def.Name, defRev.Name} {
+			cm := &corev1.ConfigMap{}
+			key := utils.SchemaConfigMapKey(r.schemaStorageNamespace, def.Namespace, name)
+			if err := r.Get(ctx, key, cm); err != nil {
+				r.recorder().Event(def, event.Warning("cannot replicate to the spoke clusters", errors.Wrapf(err, "cannot get the schema ConfigMap %s", key)))
+				return r.spokeClusters
+			}
+			objs = append(objs, cm)
+			fragments, err := listSchemaFragments(ctx, r.Client, cm)
+			if err != nil {
+				r.recorder().Event(def, event.Warning("cannot replicate to the spoke clusters", err))
+				return r.spokeClusters
+			}
+			objs = append(objs, fragments...)
+		}
+	}
+	return r.forEachSpoke(ctx, def, "cannot replicate to the spoke cluster %s", func(clusterCtx context.Context) error {
+		for _, obj := range objs {
+			if err := replicateObject(clusterCtx, r.Client, obj); err != nil {
+				return errors.Wrapf(err, "cannot replicate %T %s", obj, client.ObjectKeyFromObject(obj))
+			}
+		}
+		return nil
+	})
+}
+
+// pruneSpokeReplicas deletes the replicas of the garbage collected DefinitionRevisions and their schema ConfigMaps
+// from the spoke clusters. The replicas of the schema fragments are left as they may be shared by other schemas.
+func (r *Reconciler) pruneSpokeReplicas(ctx context.Context, def *v1beta1.ComponentDefinition, collected []string) []string {
+	if len(collected) == 0 {
+		return nil
+	}
+	return r.forEachSpoke(ctx, def, "cannot prune the replicas in the spoke cluster %s", func(clusterCtx context.Context) error {
+		return r.deleteReplicas(clusterCtx, def, collected)
+	})
+}
+
+// deleteSpokeReplicas deletes the replicas of all the DefinitionRevisions of the deleted definition and the schema
+// ConfigMaps from the spoke clusters. The replicas are found by the labels copied from the DefinitionRevisions, so
+// the replicas of the revisions collected before are deleted as well.
+func (r *Reconciler) deleteSpokeReplicas(ctx context.Context, def *v1beta1.ComponentDefinition) []string {
+	return r.forEachSpoke(ctx, def, "cannot delete the replicas in the spoke cluster %s", func(clusterCtx context.Context) error {
+		defRevList := new(v1beta1.DefinitionRevisionList)
+		if err := r.List(clusterCtx, defRevList, client.InNamespace(def.Namespace),
+			client.MatchingLabels{oam.LabelComponentDefinitionName: def.Name}); err != nil {
+			return errors.Wrapf(err, "cannot list the replicas of DefinitionRevisions of ComponentDefinition %s", def.Name)
+		}
+		names := []string{def.Name}
+		for _, rev := range defRevList.Items {
+			names = append(names, rev.Name)
+		}
+		return r.deleteReplicas(clusterCtx, def, names)
+	})
+}
+
+// deleteReplicas deletes the replicas of the DefinitionRevisions and the schema ConfigMaps of the names in the
+// cluster of the context, the name of the definition itself only has the schema ConfigMap. Replicas already gone
+// are ignored.
+func (r *Reconciler) deleteReplicas(ctx context.Context, def *v1beta1.ComponentDefinition, names []string) error {
+	var errs []error
+	for _, name := range names {
+		var replicas []client.Object
+		if name != def.Name {
+			replicas = append(replicas, &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Namespace: def.Namespace, Name: name}})
+		}
+		if r.schemaStore == nil {
+			key := utils.SchemaConfigMapKey(r.schemaStorageNamespace, def.Namespace, name)
+			replicas = append(replicas, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}})
+		}
+		for _, replica := range replicas {
+			if err := r.Delete(ctx, replica); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, errors.Wrapf(err, "cannot delete the replica of %T %s", replica, client.ObjectKeyFromObject(replica)))
+			}
+		}
+	}
+	return velaerrors.AggregateErrors(errs)
+}
+
+// forEachSpoke runs the fn against every spoke cluster independently, the failure of a cluster is reported by an
+// event of the reason formatted with the cluster and doesn't stop the others. The clusters failed are returned.
+func (r *Reconciler) forEachSpoke(ctx context.Context, def *v1beta1.ComponentDefinition, reason string, fn func(clusterCtx context.Context) error) []string {
+	errs := velaslices.ParMap(r.spokeClusters, func(cluster string) error {
+		return fn(multicluster.ContextWithClusterName(ctx, cluster))
+	})
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, r.spokeClusters[i])
+			r.recorder().Event(def, event.Warning(event.Reason(fmt.Sprintf(reason, r.spokeClusters[i])), err))
+		}
+	}
+	return failed
+}
+
+// replicateObject creates or updates the copy of the object in the cluster of the context. The copy drops the
+// metadata only meaningful in the source cluster, e.g. the owner references. The copy is not updated if its content,
+// labels and annotations are unchanged.
+func replicateObject(ctx context.Context, cli client.Client, obj client.Object) error {
+	replica, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("cannot copy %T", obj)
+	}
+	replica.SetResourceVersion("")
+	replica.SetUID("")
+	replica.SetGeneration(0)
+	replica.SetCreationTimestamp(metav1.Time{})
+	replica.SetOwnerReferences(nil)
+	replica.SetManagedFields(nil)
+	existing, _ := obj.DeepCopyObject().(client.Object)
+	err := cli.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if apierrors.IsNotFound(err) {
+		return cli.Create(ctx, replica)
+	}
+	if err != nil {
+		return err
+	}
+	if replicaUnchanged(replica, existing) {
+		return nil
+	}
+	replica.SetResourceVersion(existing.GetResourceVersion())
+	return cli.Update(ctx, replica)
+}
+
+// replicaUnchanged tells whether the replica carries the same content, labels and annotations as the existing copy,
+// the other metadata is ignored
+func replicaUnchanged(replica, existing client.Object) bool {
+	if !apiequality.Semantic.DeepEqual(replica.GetLabels(), existing.GetLabels()) ||
+		!apiequality.Semantic.DeepEqual(replica.GetAnnotations(), existing.GetAnnotations()) {
+		return false
+	}
+	content := func(obj client.Object) (map[string]interface{}, error) {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range []string{"apiVersion", "kind", "metadata"} {
+			delete(u, field)
+		}
+		return u, nil
+	}
+	replicaContent, err := content(replica)
+	if err != nil {
+		return false
+	}
+	existingContent, err := content(existing)
+	if err != nil {
+		return false
+	}
+	return apiequality.Semantic.DeepEqual(replicaContent, existingContent)
+}
+
+// listSchemaFragments returns the ConfigMaps of the schema fragments the schema ConfigMap refers to, which are
+// replicated along with it so that the references resolve in the spoke clusters
+func listSchemaFragments(ctx context.Context, cli client.Reader, cm *corev1.ConfigMap) ([]client.Object, error) {
+	schema, err := utils.GetOpenAPISchemaFromConfigMap(cm)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read the schema ConfigMap %s", client.ObjectKeyFromObject(cm))
+	}
+	cms, err := utils.ListSchemaFragments(ctx, cli, schema)
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]client.Object, 0, len(cms))
+	for _, fragment := range cms {
+		objs = append(objs, fragment)
+	}
+	return objs, nil
+}
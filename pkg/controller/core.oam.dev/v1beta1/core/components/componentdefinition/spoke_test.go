@@ -0,0 +1,161 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
+)
+
+// unreachableClient rejects all the requests like a cluster that cannot be reached
+type unreachableClient struct {
+	client.Client
+}
+
+var errUnreachable = errors.New("the cluster is unreachable")
+
+func (c *unreachableClient) Get(context.Context, client.ObjectKey, client.Object, ...client.GetOption) error {
+	return errUnreachable
+}
+
+func TestReplicateToSpokes(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("spoke-cd", "default")
+	r := newFakeReconciler(t, cd)
+	spoke := fake.NewClientBuilder().WithScheme(r.Scheme).Build()
+	cli := multicluster.NewFakeClient(r.Client)
+	cli.AddCluster("spoke", spoke)
+	cli.AddCluster("unreachable", &unreachableClient{Client: fake.NewClientBuilder().WithScheme(r.Scheme).Build()})
+	r.Client = cli
+	recorder := &countingRecorder{}
+	r.record = recorder
+	r.spokeClusters = []string{"spoke", "unreachable"}
+	r.schemaBackoff = workqueue.NewItemExponentialFailureRateLimiter(schemaBackoffBaseDelay, schemaBackoffMaxDelay)
+
+	replicated := func(revision string) {
+		defRev := &v1beta1.DefinitionRevision{}
+		require.NoError(t, spoke.Get(ctx, client.ObjectKey{Namespace: "default", Name: revision}, defRev))
+		require.Empty(t, defRev.OwnerReferences)
+		for _, name := range []string{cd.Name, revision} {
+			cm := &corev1.ConfigMap{}
+			require.NoError(t, spoke.Get(ctx, client.ObjectKey{Namespace: "default", Name: utils.ComponentDefinitionConfigMapName(name)}, cm))
+			hubCM := &corev1.ConfigMap{}
+			require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: "default", Name: utils.ComponentDefinitionConfigMapName(name)}, hubCM))
+			require.Equal(t, hubCM.Data, cm.Data)
+			require.Empty(t, cm.OwnerReferences)
+		}
+	}
+
+	// the unreachable cluster doesn't stop the replication to the others
+	result, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NotZero(t, result.RequeueAfter)
+	require.Equal(t, 1, recorder.warnings)
+	replicated("spoke-cd-v1")
+
+	// the replicas are updated by the new revision
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), cd))
+	cd.Spec.Schematic.CUE.Template = "parameter: {\n\timage: string\n\tport: int\n}\noutput: {}\n"
+	require.NoError(t, r.Update(ctx, cd))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, 2, recorder.warnings)
+	replicated("spoke-cd-v2")
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, spoke.Get(ctx, client.ObjectKey{Namespace: "default", Name: utils.ComponentDefinitionConfigMapName(cd.Name)}, cm))
+	require.Contains(t, cm.Data[types.OpenapiV3JSONSchema], "port")
+
+	// nothing is replicated without spoke clusters
+	r.spokeClusters = nil
+	result, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Zero(t, result.RequeueAfter)
+	require.Equal(t, 2, recorder.warnings)
+}
+
+func TestPruneSpokeReplicas(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("prune-cd", "default")
+	r := newFakeReconciler(t, cd)
+	r.defRevLimit = 1
+	spoke := &writeCountingClient{Client: fake.NewClientBuilder().WithScheme(r.Scheme).Build()}
+	cli := multicluster.NewFakeClient(r.Client)
+	cli.AddCluster("spoke", spoke)
+	r.Client = cli
+	r.spokeClusters = []string{"spoke"}
+	replicas := func() (revisions []string, configMaps []string) {
+		revList := &v1beta1.DefinitionRevisionList{}
+		require.NoError(t, spoke.List(ctx, revList))
+		for _, rev := range revList.Items {
+			revisions = append(revisions, rev.Name)
+		}
+		cmList := &corev1.ConfigMapList{}
+		require.NoError(t, spoke.List(ctx, cmList))
+		for _, cm := range cmList.Items {
+			configMaps = append(configMaps, cm.Name)
+		}
+		return revisions, configMaps
+	}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	revisions, configMaps := replicas()
+	require.Equal(t, []string{"prune-cd-v1"}, revisions)
+	require.ElementsMatch(t, []string{utils.ComponentDefinitionConfigMapName("prune-cd"), utils.ComponentDefinitionConfigMapName("prune-cd-v1")}, configMaps)
+
+	// the unchanged replicas are not updated again
+	writes := spoke.writes
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Equal(t, writes, spoke.writes)
+
+	// the replicas of the collected revisions are pruned
+	for _, port := range []string{"port", "targetPort"} {
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), cd))
+		cd.Spec.Schematic.CUE.Template = "parameter: {\n\timage: string\n\t" + port + ": int\n}\noutput: {}\n"
+		require.NoError(t, r.Update(ctx, cd))
+		_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	revisions, configMaps = replicas()
+	require.ElementsMatch(t, []string{"prune-cd-v2", "prune-cd-v3"}, revisions)
+	require.NotContains(t, configMaps, utils.ComponentDefinitionConfigMapName("prune-cd-v1"))
+	require.Contains(t, configMaps, utils.ComponentDefinitionConfigMapName("prune-cd-v3"))
+
+	// all the replicas are deleted along with the definition
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), cd))
+	require.NoError(t, r.Delete(ctx, cd))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(cd), &v1beta1.ComponentDefinition{})))
+	revisions, configMaps = replicas()
+	require.Empty(t, revisions)
+	require.Empty(t, configMaps)
+}
@@ -0,0 +1,511 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestSchemaConfigMapRef(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("schema-ref-cd", "default")
+	r := newFakeReconciler(t, cd)
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.NotNil(t, got.Status.SchemaConfigMapRef)
+	require.Equal(t, got.Status.LatestRevision.Name, got.Status.SchemaConfigMapRef.Revision)
+	require.Equal(t, utils.ComponentDefinitionConfigMapName(got.Status.LatestRevision.Name), got.Status.SchemaConfigMapRef.Name)
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: got.Status.SchemaConfigMapRef.Name}, cm))
+	require.Contains(t, cm.Data[types.OpenapiV3JSONSchema], `"image"`)
+
+	got.Spec.Schematic.CUE.Template += "\nparameter: port: *80 | int\n"
+	require.NoError(t, r.Update(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, "schema-ref-cd-v2", got.Status.SchemaConfigMapRef.Revision)
+	require.Equal(t, utils.ComponentDefinitionConfigMapName("schema-ref-cd-v2"), got.Status.SchemaConfigMapRef.Name)
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: got.Status.SchemaConfigMapRef.Name}, cm))
+	require.Contains(t, cm.Data[types.OpenapiV3JSONSchema], `"port"`)
+}
+
+func TestSchemaReadyCondition(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("schema-ready-cd", "default")
+	cd.Spec.Schematic.CUE.Template = cuePackageTemplate
+	cd.Annotations = map[string]string{oam.AnnotationCUEPackageConfigMaps: "cue-helpers"}
+	r := newFakeReconciler(t, cd)
+	got := &v1beta1.ComponentDefinition{}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	schemaReady := got.GetCondition(coredef.TypeSchemaReady)
+	require.Equal(t, corev1.ConditionFalse, schemaReady.Status)
+	require.Equal(t, condition.ReasonReconcileError, schemaReady.Reason)
+	require.Contains(t, schemaReady.Message, "cue-helpers")
+
+	// the schema becomes ready on the existing revision
+	require.NoError(t, r.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cue-helpers", Namespace: cd.Namespace},
+		Data: map[string]string{
+			utils.CUEPackagePathKey: "example.com/helpers",
+			"port.cue":              "package helpers\n\n#Port: int & >0 & <65536\n",
+		},
+	}))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, "schema-ready-cd-v1", got.Status.LatestRevision.Name)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+
+	// the schema fails on a new revision
+	got.Spec.Schematic.CUE.Template += "\nparameter: port: helpers.#Unknown\n"
+	require.NoError(t, r.Update(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, "schema-ready-cd-v2", got.Status.LatestRevision.Name)
+	require.Equal(t, corev1.ConditionFalse, got.GetCondition(coredef.TypeSchemaReady).Status)
+}
+
+func TestTerraformVariablesValidCondition(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("tf-cd", "default")
+	cd.Spec.Schematic = &common.Schematic{Terraform: &common.Terraform{Configuration: `
+variable "size" {
+  type    = number
+  default = "large"
+}
+`}}
+	r := newFakeReconciler(t, cd)
+	got := &v1beta1.ComponentDefinition{}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	valid := got.GetCondition(coredef.TypeTerraformVariablesValid)
+	require.Equal(t, corev1.ConditionFalse, valid.Status)
+	require.Contains(t, valid.Message, "the default value of variable size")
+	require.Equal(t, corev1.ConditionFalse, got.GetCondition(coredef.TypeSchemaReady).Status)
+
+	got.Spec.Schematic.Terraform.Configuration = `
+variable "size" {
+  type    = number
+  default = 10
+}
+`
+	require.NoError(t, r.Update(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeTerraformVariablesValid).Status)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+}
+
+func TestStatusTemplateValidCondition(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("status-template-cd", "default")
+	cd.Spec.Status = &common.Status{
+		HealthPolicy: "isHealth: context.output.status.readyReplicas ==",
+		CustomStatus: `message: "Ready: \(context.output.status.readyReplicas)"`,
+	}
+	r := newFakeReconciler(t, cd)
+	got := &v1beta1.ComponentDefinition{}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	valid := got.GetCondition(coredef.TypeStatusTemplateValid)
+	require.Equal(t, corev1.ConditionFalse, valid.Status)
+	require.Contains(t, valid.Message, "compile healthPolicy")
+	// the schema is stored regardless of the broken status templates
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+
+	got.Spec.Status.HealthPolicy = "isHealth: context.output.status.readyReplicas == parameter.replicas"
+	require.NoError(t, r.Update(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeStatusTemplateValid).Status)
+}
+
+func TestSchemaGenerationWithinBudgetCondition(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("expensive-cd", "default")
+	cd.Spec.Schematic.CUE.Template = `import "list"
+
+output: {}
+parameter: items: *[ for i in list.Range(0, 100, 1) for j in list.Range(0, 100, 1) {i * j}] | [...int]
+`
+	r := newFakeReconciler(t, cd)
+	r.schemaGenerationTimeout = 10 * time.Millisecond
+	got := &v1beta1.ComponentDefinition{}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	withinBudget := got.GetCondition(coredef.TypeSchemaGenerationWithinBudget)
+	require.Equal(t, corev1.ConditionFalse, withinBudget.Status)
+	require.Contains(t, withinBudget.Message, "time budget")
+	require.Equal(t, corev1.ConditionFalse, got.GetCondition(coredef.TypeSchemaReady).Status)
+
+	r.schemaGenerationTimeout = time.Minute
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaGenerationWithinBudget).Status)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+}
+
+func TestReconcileCancelled(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("cancelled-cd", "default")
+	cd.Spec.Schematic.CUE.Template = `import "list"
+
+output: {}
+parameter: items: *[ for i in list.Range(0, 200, 1) for j in list.Range(0, 200, 1) {i * j}] | [...int]
+`
+	r := newFakeReconciler(t, cd)
+	reconcileCtx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := r.Reconcile(reconcileCtx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cd)})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// the cancellation is not regarded as a broken schema
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionUnknown, got.GetCondition(coredef.TypeSchemaReady).Status)
+}
+
+func TestDeprecatedCondition(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("deprecated-cd", "default")
+	cd.Annotations = map[string]string{
+		oam.AnnotationDefinitionDeprecated:         "true",
+		oam.AnnotationDefinitionDeprecationMessage: "the image is no longer maintained",
+		oam.AnnotationDefinitionReplacement:        "webservice",
+	}
+	r := newFakeReconciler(t, cd)
+	counter := &countingRecorder{}
+	r.record = counter
+	got := &v1beta1.ComponentDefinition{}
+
+	for i := 0; i < 3; i++ {
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, counter.warnings)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	deprecated := got.GetCondition(coredef.TypeDeprecated)
+	require.Equal(t, corev1.ConditionTrue, deprecated.Status)
+	require.Equal(t, coredef.ReasonDeprecated, deprecated.Reason)
+	require.Equal(t, "the definition deprecated-cd is deprecated: the image is no longer maintained, use webservice instead", deprecated.Message)
+
+	delete(got.Annotations, oam.AnnotationDefinitionDeprecated)
+	require.NoError(t, r.Update(ctx, got))
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionFalse, got.GetCondition(coredef.TypeDeprecated).Status)
+	require.Equal(t, 1, counter.warnings)
+
+	notDeprecated := newFakeComponentDefinition("not-deprecated-cd", "default")
+	r = newFakeReconciler(t, notDeprecated)
+	_, err = reconcileFake(t, r, notDeprecated.Name, notDeprecated.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(notDeprecated), got))
+	require.Equal(t, corev1.ConditionUnknown, got.GetCondition(coredef.TypeDeprecated).Status)
+}
+
+func TestRevisionFrozenCondition(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("frozen-cd", "default")
+	r := newFakeReconciler(t, cd)
+	got := &v1beta1.ComponentDefinition{}
+	reconcile := func() {
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	}
+
+	reconcile()
+	require.Equal(t, "frozen-cd-v1", got.Status.LatestRevision.Name)
+	require.Equal(t, corev1.ConditionUnknown, got.GetCondition(coredef.TypeRevisionFrozen).Status)
+
+	// freeze and change the definition
+	got.Annotations = map[string]string{oam.AnnotationDefinitionRevisionFreeze: "true"}
+	got.Spec.Schematic.CUE.Template += "\nparameter: port: *80 | int\n"
+	require.NoError(t, r.Update(ctx, got))
+	reconcile()
+	require.Equal(t, "frozen-cd-v1", got.Status.LatestRevision.Name)
+	frozen := got.GetCondition(coredef.TypeRevisionFrozen)
+	require.Equal(t, corev1.ConditionTrue, frozen.Status)
+	require.Contains(t, frozen.Message, "frozen-cd-v1")
+	require.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: "frozen-cd-v2"}, &v1beta1.DefinitionRevision{})))
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: utils.ComponentDefinitionConfigMapName(cd.Name)}, cm))
+	require.NotContains(t, cm.Data[types.OpenapiV3JSONSchema], `"port"`)
+
+	// unfreeze
+	delete(got.Annotations, oam.AnnotationDefinitionRevisionFreeze)
+	require.NoError(t, r.Update(ctx, got))
+	reconcile()
+	require.Equal(t, "frozen-cd-v2", got.Status.LatestRevision.Name)
+	unfrozen := got.GetCondition(coredef.TypeRevisionFrozen)
+	require.Equal(t, corev1.ConditionFalse, unfrozen.Status)
+	require.Equal(t, coredef.ReasonRevisionUnfrozen, unfrozen.Reason)
+	require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: utils.ComponentDefinitionConfigMapName(cd.Name)}, cm))
+	require.Contains(t, cm.Data[types.OpenapiV3JSONSchema], `"port"`)
+}
+
+type statusUpdateCountingClient struct {
+	client.Client
+	updates int
+}
+
+func (c *statusUpdateCountingClient) Status() client.SubResourceWriter {
+	return &statusUpdateCountingWriter{SubResourceWriter: c.Client.Status(), client: c}
+}
+
+type statusUpdateCountingWriter struct {
+	client.SubResourceWriter
+	client *statusUpdateCountingClient
+}
+
+func (w *statusUpdateCountingWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	w.client.updates++
+	return w.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+func TestUpdateStatusSkipsUnchangedStatus(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("unchanged-status-cd", "default")
+	r := newFakeReconciler(t, cd)
+	cli := &statusUpdateCountingClient{Client: r.Client}
+	r.Client = cli
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	updates := cli.updates
+	require.NotZero(t, updates)
+
+	require.NoError(t, r.UpdateStatus(ctx, got.DeepCopy()))
+	require.Equal(t, updates, cli.updates)
+
+	changed := got.DeepCopy()
+	changed.Status.ConfigMapRef = "changed"
+	require.NoError(t, r.UpdateStatus(ctx, changed))
+	require.Equal(t, updates+1, cli.updates)
+}
+
+func TestTraitCompatibilityStatus(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("trait-compatibility-cd", "default")
+	cd.Annotations = map[string]string{
+		oam.AnnotationCompatibleTraits:   "scaler,gateway",
+		oam.AnnotationIncompatibleTraits: "hpa",
+	}
+	r := newFakeReconciler(t, cd)
+	counter := &countingRecorder{}
+	r.record = counter
+	got := &v1beta1.ComponentDefinition{}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, &v1beta1.TraitCompatibility{CompatibleTraits: []string{"scaler", "gateway"}, IncompatibleTraits: []string{"hpa"}},
+		got.Status.TraitCompatibility)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeTraitCompatibilityValid).Status)
+
+	// a malformed declaration is reported and not surfaced
+	got.Annotations[oam.AnnotationIncompatibleTraits] = "hpa,scaler"
+	require.NoError(t, r.Update(ctx, got))
+	for i := 0; i < 2; i++ {
+		_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Nil(t, got.Status.TraitCompatibility)
+	invalid := got.GetCondition(coredef.TypeTraitCompatibilityValid)
+	require.Equal(t, corev1.ConditionFalse, invalid.Status)
+	require.Contains(t, invalid.Message, "trait scaler")
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+	require.Equal(t, 1, counter.warnings)
+
+	// the condition is recovered once the declaration is removed
+	delete(got.Annotations, oam.AnnotationCompatibleTraits)
+	delete(got.Annotations, oam.AnnotationIncompatibleTraits)
+	require.NoError(t, r.Update(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Nil(t, got.Status.TraitCompatibility)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeTraitCompatibilityValid).Status)
+}
+
+func TestCapabilityMetadataStatus(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("capability-metadata-cd", "default")
+	cd.Annotations = map[string]string{
+		types.AnnoDefinitionCategory: "database",
+		types.AnnoDefinitionIcon:     "https://example.com/icons/mysql.png",
+	}
+	r := newFakeReconciler(t, cd)
+	counter := &countingRecorder{}
+	r.record = counter
+	got := &v1beta1.ComponentDefinition{}
+	schemaAnnotations := func() map[string]string {
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: utils.ComponentDefinitionConfigMapName(cd.Name)}, cm))
+		return cm.Annotations
+	}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeCapabilityMetadataValid).Status)
+	require.Equal(t, "database", schemaAnnotations()[types.AnnoDefinitionCategory])
+	require.Equal(t, "https://example.com/icons/mysql.png", schemaAnnotations()[types.AnnoDefinitionIcon])
+
+	// the invalid metadata is reported and not stored
+	got.Annotations[types.AnnoDefinitionCategory] = "games"
+	require.NoError(t, r.Update(ctx, got))
+	for i := 0; i < 2; i++ {
+		_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	invalid := got.GetCondition(coredef.TypeCapabilityMetadataValid)
+	require.Equal(t, corev1.ConditionFalse, invalid.Status)
+	require.Contains(t, invalid.Message, `unknown category "games"`)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+	require.Equal(t, 1, counter.warnings)
+	require.NotContains(t, schemaAnnotations(), types.AnnoDefinitionCategory)
+	require.NotContains(t, schemaAnnotations(), types.AnnoDefinitionIcon)
+
+	// the condition is recovered once the metadata is fixed
+	got.Annotations[types.AnnoDefinitionCategory] = "storage"
+	require.NoError(t, r.Update(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeCapabilityMetadataValid).Status)
+	require.Equal(t, "storage", schemaAnnotations()[types.AnnoDefinitionCategory])
+}
+
+func TestValidationIssuesReportedAtOnce(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("many-issues", "default")
+	cd.Spec.Schematic.CUE.Template = "parameter: {\n\timage: string\n"
+	cd.Spec.Status = &common.Status{CustomStatus: "message: {"}
+	cd.Annotations = map[string]string{oam.AnnotationCompatibleTraits: "Invalid_Trait"}
+	r := newFakeReconciler(t, cd)
+	r.schemaBackoff = workqueue.NewItemExponentialFailureRateLimiter(schemaBackoffBaseDelay, schemaBackoffMaxDelay)
+	recorder := &countingRecorder{}
+	r.record = recorder
+
+	result, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	// the definition is requeued to be checked again
+	require.Positive(t, result.RequeueAfter)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	synced := got.GetCondition(condition.TypeSynced)
+	require.Equal(t, condition.ReasonReconcileError, synced.Reason)
+	require.Contains(t, synced.Message, "the definition has 3 problems:\n")
+	for _, conditionType := range []condition.ConditionType{coredef.TypeSchemaReady, coredef.TypeStatusTemplateValid, coredef.TypeTraitCompatibilityValid} {
+		require.Contains(t, synced.Message, "\n- "+string(conditionType)+": ")
+		require.Equal(t, corev1.ConditionFalse, got.GetCondition(conditionType).Status, conditionType)
+	}
+	require.Equal(t, 3, recorder.warnings)
+}
+
+func TestParametersUsedCondition(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("unused-parameters-cd", "default")
+	cd.Spec.Schematic.CUE.Template = `
+parameter: {
+	image: string
+	replicas: *1 | int
+}
+output: {
+	apiVersion: "apps/v1"
+	kind: "Deployment"
+	spec: template: spec: containers: [{image: parameter.image}]
+}
+`
+	r := newFakeReconciler(t, cd)
+	got := &v1beta1.ComponentDefinition{}
+
+	// the detection is opt-in
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionUnknown, got.GetCondition(coredef.TypeParametersUsed).Status)
+
+	r.detectUnusedParameters = true
+	recorder := &countingRecorder{}
+	r.record = recorder
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	used := got.GetCondition(coredef.TypeParametersUsed)
+	require.Equal(t, corev1.ConditionFalse, used.Status)
+	require.Contains(t, used.Message, "never used by the template: replicas")
+	require.Equal(t, 1, recorder.warnings)
+	// the unused parameters don't fail the definition
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(condition.TypeSynced).Status)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+
+	got.Spec.Status = &common.Status{HealthPolicy: "isHealth: context.output.status.readyReplicas == parameter.replicas"}
+	require.NoError(t, r.Update(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeParametersUsed).Status)
+	require.Equal(t, 1, recorder.warnings)
+}
@@ -0,0 +1,163 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlEvent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	oamctrl "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velaerrors "github.com/oam-dev/kubevela/pkg/utils/errors"
+)
+
+// pruneStoredSchemas deletes the schema ConfigMaps of the definition in the schema storage namespace, except the
+// ones of the definition itself and of the given DefinitionRevisions. The ConfigMaps there can't be owned by the
+// definition in another namespace, so they have to be cleaned up once the revisions are garbage collected. All the
+// ConfigMaps of the definition are deleted if no revision is given, which is used when the definition is deleted.
+func pruneStoredSchemas(ctx context.Context, cli client.Client, storageNamespace string, def *v1beta1.ComponentDefinition, revisions []v1beta1.DefinitionRevision) error {
+	if storageNamespace == "" {
+		return nil
+	}
+	keep := map[string]bool{}
+	if len(revisions) > 0 {
+		keep[utils.SchemaConfigMapKey(storageNamespace, def.Namespace, def.Name).Name] = true
+		for _, rev := range revisions {
+			keep[utils.SchemaConfigMapKey(storageNamespace, def.Namespace, rev.Name).Name] = true
+		}
+	}
+	cmList := &corev1.ConfigMapList{}
+	if err := cli.List(ctx, cmList, client.InNamespace(storageNamespace), client.MatchingLabels{
+		oam.LabelComponentDefinitionName:     def.Name,
+		oam.LabelSharedSchemaSourceNamespace: def.Namespace,
+	}); err != nil {
+		return errors.Wrapf(err, "cannot list the schema ConfigMaps of ComponentDefinition %s", def.Name)
+	}
+	var errs []error
+	for i := range cmList.Items {
+		cm := cmList.Items[i]
+		if keep[cm.Name] {
+			continue
+		}
+		if err := cli.Delete(ctx, &cm); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, errors.Wrapf(err, "cannot delete ConfigMap %s/%s", storageNamespace, cm.Name))
+		}
+	}
+	return velaerrors.AggregateErrors(errs)
+}
+
+// newSchemaStore creates the external SchemaStore configured by the args, nil is returned for the default ConfigMaps
+func newSchemaStore(args oamctrl.Args) (utils.SchemaStore, error) {
+	switch args.SchemaStorageBackend {
+	case "", utils.SchemaStorageBackendConfigMap:
+		return nil, nil
+	case utils.SchemaStorageBackendS3:
+		return utils.NewS3SchemaStore(utils.S3SchemaStoreConfig{
+			Endpoint:        args.SchemaStorageS3Endpoint,
+			Bucket:          args.SchemaStorageS3Bucket,
+			Region:          args.SchemaStorageS3Region,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		})
+	default:
+		return nil, errors.Errorf("unknown schema storage backend %s", args.SchemaStorageBackend)
+	}
+}
+
+// deleteExternalSchemas deletes the schemas of the definition and its DefinitionRevisions from the external store.
+// The schemas of the DefinitionRevisions garbage collected before are deleted by deleteCollectedExternalSchemas.
+func deleteExternalSchemas(ctx context.Context, cli client.Client, store utils.SchemaStore, def *v1beta1.ComponentDefinition) error {
+	if store == nil {
+		return nil
+	}
+	defRevList := new(v1beta1.DefinitionRevisionList)
+	if err := cli.List(ctx, defRevList, client.InNamespace(def.Namespace),
+		client.MatchingLabels{oam.LabelComponentDefinitionName: def.Name}); err != nil {
+		return errors.Wrapf(err, "cannot list DefinitionRevisions of ComponentDefinition %s", def.Name)
+	}
+	names := []string{def.Name}
+	for _, rev := range defRevList.Items {
+		names = append(names, rev.Name)
+	}
+	return deleteCollectedExternalSchemas(ctx, store, def.Namespace, names)
+}
+
+// deleteCollectedExternalSchemas deletes the schemas of the garbage collected DefinitionRevisions from the external
+// store, which aren't owned by the revisions like the ConfigMaps
+func deleteCollectedExternalSchemas(ctx context.Context, store utils.SchemaStore, namespace string, collected []string) error {
+	if store == nil {
+		return nil
+	}
+	var errs []error
+	for _, name := range collected {
+		if err := store.Delete(ctx, namespace, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return velaerrors.AggregateErrors(errs)
+}
+
+// schemaConfigMapDeletedPredicate only passes the deletion of the ConfigMaps storing the schema of definitions
+func schemaConfigMapDeletedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc:  func(ctrlEvent.CreateEvent) bool { return false },
+		UpdateFunc:  func(ctrlEvent.UpdateEvent) bool { return false },
+		GenericFunc: func(ctrlEvent.GenericEvent) bool { return false },
+		DeleteFunc: func(e ctrlEvent.DeleteEvent) bool {
+			return e.Object.GetLabels()[types.LabelDefinition] == "schema"
+		},
+	}
+}
+
+// findDefinitionForSchemaConfigMap finds the ComponentDefinition whose schema is stored in the ConfigMap, so that the
+// ConfigMap deleted by accident is recreated by the next reconcile. The ConfigMaps of DefinitionRevisions which have
+// been garbage collected are ignored.
+func (r *Reconciler) findDefinitionForSchemaConfigMap(obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	if name, namespace := labels[oam.LabelComponentDefinitionName], labels[oam.LabelSharedSchemaSourceNamespace]; name != "" && namespace != "" {
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: namespace, Name: name}}}
+	}
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil {
+		return nil
+	}
+	switch owner.Kind {
+	case v1beta1.ComponentDefinitionKind:
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: owner.Name}}}
+	case v1beta1.DefinitionRevisionKind:
+		defRev := &v1beta1.DefinitionRevision{}
+		if err := r.Get(context.Background(), client.ObjectKey{Namespace: obj.GetNamespace(), Name: owner.Name}, defRev); err != nil {
+			return nil
+		}
+		if name := defRev.Labels[oam.LabelComponentDefinitionName]; name != "" {
+			return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlEvent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	oamctrl "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestSchemaStorageNamespace(t *testing.T) {
+	ctx := context.Background()
+	const storageNamespace = "vela-system"
+	cd := newFakeComponentDefinition("web", "default")
+	cd.Annotations = map[string]string{oam.AnnotationDefinitionRevisionLimit: "1"}
+	another := newFakeComponentDefinition("web", "team-a")
+	r := newFakeReconciler(t, cd, another)
+	r.schemaStorageNamespace = storageNamespace
+	exists := func(namespace, name string) bool {
+		err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &corev1.ConfigMap{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+
+	for _, def := range []*v1beta1.ComponentDefinition{cd, another} {
+		_, err := reconcileFake(t, r, def.Name, def.Namespace)
+		require.NoError(t, err)
+	}
+	// the definitions of the same name in different namespaces don't collide
+	require.True(t, exists(storageNamespace, "component-schema-default.web"))
+	require.True(t, exists(storageNamespace, "component-schema-default.web-v1"))
+	require.True(t, exists(storageNamespace, "component-schema-team-a.web"))
+	require.True(t, exists(storageNamespace, "component-schema-team-a.web-v1"))
+	require.False(t, exists(cd.Namespace, "component-schema-web"))
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, "component-schema-default.web", got.Status.ConfigMapRef)
+	require.Equal(t, &v1beta1.SchemaConfigMapReference{Name: "component-schema-default.web-v1", Namespace: storageNamespace, Revision: "web-v1"},
+		got.Status.SchemaConfigMapRef)
+	def := utils.NewCapabilityComponentDef(got)
+	def.SchemaStorageNamespace = storageNamespace
+	schema, err := def.GetSchemaFromConfigMap(ctx, r.Client, cd.Namespace, "web-v1")
+	require.NoError(t, err)
+	require.Contains(t, schema.Properties, "image")
+
+	// the schema ConfigMaps of collected revisions are pruned
+	for _, template := range []string{"\nparameter: port: *80 | int\n", "\nparameter: cpu: *\"1\" | string\n"} {
+		got.Spec.Schematic.CUE.Template += template
+		require.NoError(t, r.Update(ctx, got))
+		_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	}
+	require.False(t, exists(storageNamespace, "component-schema-default.web-v1"))
+	require.True(t, exists(storageNamespace, "component-schema-default.web-v2"))
+	require.True(t, exists(storageNamespace, "component-schema-default.web-v3"))
+
+	// all the schema ConfigMaps of the definition are removed with the definition
+	require.NoError(t, r.Delete(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	cmList := &corev1.ConfigMapList{}
+	require.NoError(t, r.List(ctx, cmList, client.InNamespace(storageNamespace)))
+	var names []string
+	for _, cm := range cmList.Items {
+		names = append(names, cm.Name)
+	}
+	require.ElementsMatch(t, []string{"component-schema-team-a.web", "component-schema-team-a.web-v1"}, names)
+}
+
+func TestRecreateDeletedSchemaConfigMap(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("self-heal", "default")
+	r := newFakeReconciler(t, cd)
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+
+	deleted := schemaConfigMapDeletedPredicate()
+	for _, name := range []string{"component-schema-self-heal", "component-schema-self-heal-v1"} {
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: name}, cm))
+		require.NoError(t, r.Delete(ctx, cm))
+		require.True(t, deleted.Delete(ctrlEvent.DeleteEvent{Object: cm}))
+		require.Equal(t, []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(cd)}}, r.findDefinitionForSchemaConfigMap(cm))
+	}
+	require.False(t, deleted.Delete(ctrlEvent.DeleteEvent{Object: &corev1.ConfigMap{}}))
+
+	// the status is unchanged, but the schema ConfigMaps are still recreated
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	for _, name := range []string{"component-schema-self-heal", "component-schema-self-heal-v1"} {
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: name}, &corev1.ConfigMap{}))
+	}
+
+	stored := &corev1.ConfigMap{}
+	stored.Labels = map[string]string{oam.LabelComponentDefinitionName: "web", oam.LabelSharedSchemaSourceNamespace: "team-a"}
+	require.Equal(t, []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: "team-a", Name: "web"}}}, r.findDefinitionForSchemaConfigMap(stored))
+}
+
+func TestForceSchemaRefresh(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("refresh-cd", "default")
+	r := newFakeReconciler(t, cd)
+	getSchemaConfigMap := func() *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: "component-schema-refresh-cd-v1"}, cm))
+		return cm
+	}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	cm := getSchemaConfigMap()
+	require.NotContains(t, cm.Annotations, oam.AnnotationForceSchemaRefresh)
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	old := got.DeepCopy()
+	got.Annotations = map[string]string{oam.AnnotationForceSchemaRefresh: "2023-01-01T00:00:00Z"}
+	require.NoError(t, r.Update(ctx, got))
+	// the refresh is not filtered out although the spec is unchanged
+	require.True(t, definitionChangedPredicate().Update(ctrlEvent.UpdateEvent{ObjectOld: old, ObjectNew: got}))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, "refresh-cd-v1", got.Status.LatestRevision.Name)
+	refreshed := getSchemaConfigMap()
+	require.NotEqual(t, cm.ResourceVersion, refreshed.ResourceVersion)
+	require.Equal(t, "2023-01-01T00:00:00Z", refreshed.Annotations[oam.AnnotationForceSchemaRefresh])
+}
+
+// memorySchemaStore is an in-memory utils.SchemaStore
+type memorySchemaStore struct {
+	mu      sync.Mutex
+	schemas map[string][]byte
+}
+
+func (s *memorySchemaStore) Backend() string { return "memory" }
+
+func (s *memorySchemaStore) Location(namespace, name string) string {
+	return "memory://" + namespace + "/" + name
+}
+
+func (s *memorySchemaStore) Put(_ context.Context, namespace, name string, schema []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schemas[s.Location(namespace, name)] = schema
+	return nil
+}
+
+func (s *memorySchemaStore) Get(_ context.Context, namespace, name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schema, ok := s.schemas[s.Location(namespace, name)]
+	if !ok {
+		return nil, utils.ErrSchemaNotStored
+	}
+	return schema, nil
+}
+
+func (s *memorySchemaStore) Delete(_ context.Context, namespace, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.schemas, s.Location(namespace, name))
+	return nil
+}
+
+func TestExternalSchemaStore(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("external-cd", "default")
+	r := newFakeReconciler(t, cd)
+	store := &memorySchemaStore{schemas: map[string][]byte{}}
+	r.schemaStore = store
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, &v1beta1.SchemaStorageReference{Backend: "memory", Location: "memory://default/external-cd-v1", Revision: "external-cd-v1"},
+		got.Status.SchemaStorageRef)
+	require.Empty(t, got.Status.ConfigMapRef)
+	require.Nil(t, got.Status.SchemaConfigMapRef)
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+	cmList := &corev1.ConfigMapList{}
+	require.NoError(t, r.List(ctx, cmList))
+	require.Empty(t, cmList.Items)
+	require.Len(t, store.schemas, 2)
+
+	// the reads go through the same store
+	def := utils.NewCapabilityComponentDef(got)
+	def.SchemaStore = store
+	schema, err := def.GetSchemaFromConfigMap(ctx, r.Client, cd.Namespace, "external-cd-v1")
+	require.NoError(t, err)
+	require.Contains(t, schema.Properties, "image")
+
+	require.NoError(t, r.Delete(ctx, got))
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.Empty(t, store.schemas)
+}
+
+func TestExternalSchemaStoreRevisionGC(t *testing.T) {
+	ctx := context.Background()
+	cd := newFakeComponentDefinition("external-gc-cd", "default")
+	r := newFakeReconciler(t, cd)
+	r.defRevLimit = 1
+	store := &memorySchemaStore{schemas: map[string][]byte{}}
+	r.schemaStore = store
+
+	for i := 0; i < 4; i++ {
+		got := &v1beta1.ComponentDefinition{}
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+		got.Spec.Schematic.CUE.Template = fakeCDTemplate + fmt.Sprintf("\nparameter: port%d?: int\n", i)
+		require.NoError(t, r.Update(ctx, got))
+		_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+	}
+
+	revs := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, r.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	require.Len(t, revs.Items, 2)
+	// only the schemas of the definition and the revisions retained are kept in the store
+	expected := []string{store.Location(cd.Namespace, cd.Name)}
+	for _, rev := range revs.Items {
+		expected = append(expected, store.Location(cd.Namespace, rev.Name))
+	}
+	var locations []string
+	for location := range store.schemas {
+		locations = append(locations, location)
+	}
+	require.ElementsMatch(t, expected, locations)
+}
+
+func TestNewSchemaStore(t *testing.T) {
+	store, err := newSchemaStore(oamctrl.Args{SchemaStorageBackend: utils.SchemaStorageBackendConfigMap})
+	require.NoError(t, err)
+	require.Nil(t, store)
+
+	store, err = newSchemaStore(oamctrl.Args{SchemaStorageBackend: utils.SchemaStorageBackendS3,
+		SchemaStorageS3Endpoint: "https://s3.example.com", SchemaStorageS3Bucket: "schemas"})
+	require.NoError(t, err)
+	require.Equal(t, utils.SchemaStorageBackendS3, store.Backend())
+
+	_, err = newSchemaStore(oamctrl.Args{SchemaStorageBackend: utils.SchemaStorageBackendS3})
+	require.Error(t, err)
+	_, err = newSchemaStore(oamctrl.Args{SchemaStorageBackend: "etcd"})
+	require.Error(t, err)
+}
+
+func TestTenantLabels(t *testing.T) {
+	ctx := context.Background()
+	const (
+		tenantKey     = "tenant.example.com/name"
+		costCenterKey = "tenant.example.com/cost-center"
+	)
+	existing := newFakeComponentDefinition("tenant-existing", "default")
+	existing.Labels = map[string]string{tenantKey: "team-a", "app": "web"}
+	created := newFakeComponentDefinition("tenant-created", "default")
+	r := newFakeReconciler(t, existing, created)
+	_, err := reconcileFake(t, r, existing.Name, existing.Namespace)
+	require.NoError(t, err)
+
+	// the tenant labels configured later are set on the existing revision as well
+	r.options = parseOptions(oamctrl.Args{
+		DefTenantLabels:    map[string]string{costCenterKey: "platform", tenantKey: "shared"},
+		DefTenantLabelKeys: []string{tenantKey},
+	})
+	r.defRevLimit = defRevisionLimit
+	for cd, tenant := range map[*v1beta1.ComponentDefinition]string{existing: "team-a", created: "shared"} {
+		_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+		require.NoError(t, err)
+		got := &v1beta1.ComponentDefinition{}
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+		require.NotNil(t, got.Status.LatestRevision)
+		require.Equal(t, int64(1), got.Status.LatestRevision.Revision)
+
+		// the label of the definition takes precedence over the configured one
+		defRev := &v1beta1.DefinitionRevision{}
+		require.NoError(t, r.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: got.Status.LatestRevision.Name}, defRev))
+		require.Equal(t, tenant, defRev.Labels[tenantKey])
+		require.Equal(t, "platform", defRev.Labels[costCenterKey])
+		require.Equal(t, cd.Name, defRev.Labels[oam.LabelComponentDefinitionName])
+		for _, name := range []string{cd.Name, defRev.Name} {
+			cm := &corev1.ConfigMap{}
+			require.NoError(t, r.Get(ctx, utils.SchemaConfigMapKey("", cd.Namespace, name), cm))
+			require.Equal(t, tenant, cm.Labels[tenantKey], name)
+			require.Equal(t, "platform", cm.Labels[costCenterKey], name)
+		}
+	}
+}
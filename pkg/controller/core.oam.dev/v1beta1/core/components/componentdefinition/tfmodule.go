@@ -0,0 +1,160 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/memory"
+	velaslices "github.com/kubevela/pkg/util/slices"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// defaultTerraformRegistry is the registry of the Terraform modules whose source has no registry host
+const defaultTerraformRegistry = "registry.terraform.io"
+
+// terraformRegistrySource matches the module sources of a registry, i.e. [<host>/]<namespace>/<name>/<provider>
+var terraformRegistrySource = regexp.MustCompile(`^(?:([a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)+)/)?([a-zA-Z0-9_-]+)/([a-zA-Z0-9_-]+)/([a-zA-Z0-9_-]+)$`)
+
+// TerraformModuleResolver checks whether the remote module referenced by a Terraform definition can be resolved,
+// e.g. the git repository or the registry module exists
+type TerraformModuleResolver interface {
+	Resolve(ctx context.Context, source, version string) error
+}
+
+// remoteModuleResolver resolves the git repositories, the registry modules and the http archives of the module
+// sources without downloading them. The local paths and the other kinds of sources, e.g. s3 buckets and git over ssh
+// which require credentials, are not checked.
+type remoteModuleResolver struct {
+	httpClient *http.Client
+}
+
+// newRemoteModuleResolver returns the resolver of the remote module sources
+func newRemoteModuleResolver() TerraformModuleResolver {
+	return &remoteModuleResolver{httpClient: http.DefaultClient}
+}
+
+// Resolve implements TerraformModuleResolver
+func (m *remoteModuleResolver) Resolve(ctx context.Context, source, _ string) error {
+	switch {
+	case strings.HasPrefix(source, "./"), strings.HasPrefix(source, "../"):
+		return nil
+	case strings.HasPrefix(source, "git::"):
+		return m.resolveGit(ctx, strings.TrimPrefix(source, "git::"))
+	case strings.HasPrefix(source, "github.com/"), strings.HasPrefix(source, "bitbucket.org/"):
+		return m.resolveGit(ctx, "https://"+source)
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		return m.resolveHTTP(ctx, source)
+	}
+	if match := terraformRegistrySource.FindStringSubmatch(source); match != nil {
+		host := match[1]
+		if host == "" {
+			host = defaultTerraformRegistry
+		}
+		return m.resolveHTTP(ctx, fmt.Sprintf("https://%s/v1/modules/%s/%s/%s/versions", host, match[2], match[3], match[4]))
+	}
+	return nil
+}
+
+// resolveGit lists the references of the git repository, the subdirectory and the query of the source are dropped
+func (m *remoteModuleResolver) resolveGit(ctx context.Context, source string) error {
+	url, _, _ := strings.Cut(source, "?")
+	if i := strings.Index(url, "://"); i >= 0 {
+		if j := strings.Index(url[i+3:], "//"); j >= 0 {
+			url = url[:i+3+j]
+		}
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{url}})
+	if _, err := remote.ListContext(ctx, &git.ListOptions{}); err != nil {
+		return errors.Wrapf(err, "cannot list the git repository %s", url)
+	}
+	return nil
+}
+
+// resolveHTTP checks the url responds without errors
+func (m *remoteModuleResolver) resolveHTTP(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s responds %s", url, resp.Status)
+	}
+	return nil
+}
+
+// checkTerraformModules resolves the remote modules called by the inline configuration of a Terraform definition
+// within the timeout, and reports the unreachable modules in the TerraformModulesReachable condition. The remote
+// configurations are not checked as they are fetched to generate the schema.
+func (r *Reconciler) checkTerraformModules(ctx context.Context, def *v1beta1.ComponentDefinition) []condition.Condition {
+	if r.terraformModuleResolver == nil || def.Spec.Schematic == nil || def.Spec.Schematic.Terraform == nil ||
+		def.Spec.Schematic.Terraform.Type == "remote" {
+		return nil
+	}
+	calls, err := common.ParseTerraformModuleCalls(def.Spec.Schematic.Terraform.Configuration)
+	if err != nil {
+		// the invalid configuration is reported by the schema generation
+		return nil
+	}
+	names := make([]string, 0, len(calls))
+	for name := range calls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if r.terraformModuleCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.terraformModuleCheckTimeout)
+		defer cancel()
+	}
+	errs := velaslices.ParMap(names, func(name string) error {
+		call := calls[name]
+		return r.terraformModuleResolver.Resolve(ctx, call.Source, call.Version)
+	})
+	var unreachable []string
+	for i, err := range errs {
+		if err != nil {
+			unreachable = append(unreachable, fmt.Sprintf("module %s (%s): %s", names[i], calls[names[i]].Source, err.Error()))
+		}
+	}
+	if len(unreachable) != 0 {
+		err = errors.Errorf("the Terraform modules are unreachable: %s", strings.Join(unreachable, "; "))
+		r.recorder().Event(def, event.Warning("Terraform modules are unreachable", err))
+		return []condition.Condition{condition.ErrorCondition(coredef.TypeTerraformModulesReachable, err)}
+	}
+	return []condition.Condition{condition.ReadyCondition(coredef.TypeTerraformModulesReachable)}
+}
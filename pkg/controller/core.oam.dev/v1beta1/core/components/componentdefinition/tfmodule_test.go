@@ -0,0 +1,112 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+)
+
+// fakeModuleResolver resolves the module sources in the reachable set
+type fakeModuleResolver struct {
+	reachable map[string]bool
+}
+
+func (m *fakeModuleResolver) Resolve(_ context.Context, source, _ string) error {
+	if !m.reachable[source] {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+func TestCheckTerraformModules(t *testing.T) {
+	ctx := context.Background()
+	configuration := `
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}
+
+module "bucket" {
+  source = "git::https://example.com/modules/bucket.git?ref=v1"
+}
+
+variable "name" {
+  description = "the name of the bucket"
+  type        = string
+}
+`
+	cd := newFakeComponentDefinition("tf-modules", "default")
+	cd.Spec.Schematic = &common.Schematic{Terraform: &common.Terraform{Configuration: configuration}}
+	r := newFakeReconciler(t, cd)
+	resolver := &fakeModuleResolver{reachable: map[string]bool{"terraform-aws-modules/vpc/aws": true}}
+	r.terraformModuleResolver = resolver
+	r.terraformModuleCheckTimeout = time.Second
+	recorder := &countingRecorder{}
+	r.record = recorder
+
+	got := &v1beta1.ComponentDefinition{}
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	cond := got.GetCondition(coredef.TypeTerraformModulesReachable)
+	require.Equal(t, corev1.ConditionFalse, cond.Status)
+	require.Contains(t, cond.Message, "module bucket (git::https://example.com/modules/bucket.git?ref=v1): not found")
+	require.NotContains(t, cond.Message, "module vpc")
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeSchemaReady).Status)
+	require.Equal(t, 1, recorder.warnings)
+
+	resolver.reachable["git::https://example.com/modules/bucket.git?ref=v1"] = true
+	_, err = reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, corev1.ConditionTrue, got.GetCondition(coredef.TypeTerraformModulesReachable).Status)
+
+	// the check is disabled without resolver
+	r.terraformModuleResolver = nil
+	require.Nil(t, r.checkTerraformModules(ctx, got))
+}
+
+func TestRemoteModuleResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/modules/bucket.zip" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	resolver := &remoteModuleResolver{httpClient: server.Client()}
+	ctx := context.Background()
+
+	require.NoError(t, resolver.Resolve(ctx, server.URL+"/modules/bucket.zip", ""))
+	require.ErrorContains(t, resolver.Resolve(ctx, server.URL+"/modules/missing.zip", ""), "404")
+	// the local paths and the sources requiring credentials are not checked
+	for _, source := range []string{"./modules/vpc", "../vpc", "git::ssh://git@example.com/vpc.git", "s3::https://s3.amazonaws.com/bucket/vpc.zip"} {
+		require.NoError(t, resolver.Resolve(ctx, source, ""))
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+)
+
+// tracingServiceName is the service name of the spans exported by the controller
+const tracingServiceName = "kubevela-core"
+
+// newTracerProvider creates the tracer provider exporting the spans to the OTLP gRPC endpoint in batches. The
+// connection is established lazily, so the controller starts even if the collector isn't reachable yet.
+func newTracerProvider(ctx context.Context, endpoint string, insecure bool) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(tracingServiceName))),
+	), nil
+}
+
+// tracer returns the tracer of the reconciler, no span is recorded if no tracer provider is set, e.g. the tracing
+// is disabled or the reconciler is used without a manager
+func (r *Reconciler) tracer() trace.Tracer {
+	if r.tracerProvider == nil {
+		return trace.NewNoopTracerProvider().Tracer(coredef.TracerName)
+	}
+	return r.tracerProvider.Tracer(coredef.TracerName)
+}
+
+// traceReconcile starts the span of the reconcile, the child spans are started from the returned context. The result
+// is recorded like timeReconcile when the span ends, a requeue without error is recorded as requeue.
+func (r *Reconciler) traceReconcile(ctx context.Context, req ctrl.Request, def *v1beta1.ComponentDefinition, res *ctrl.Result, retErr *error) (context.Context, func()) {
+	ctx, span := r.tracer().Start(ctx, "ComponentDefinition.Reconcile", trace.WithAttributes(
+		coredef.SpanAttributeDefinitionName.String(req.Name), coredef.SpanAttributeDefinitionNamespace.String(req.Namespace)))
+	return ctx, func() {
+		if latest := def.Status.LatestRevision; latest != nil {
+			span.SetAttributes(coredef.SpanAttributeDefinitionRevision.String(latest.Name))
+		}
+		switch {
+		case *retErr != nil:
+			coredef.EndSpan(span, *retErr)
+			return
+		case def.GetCondition(condition.TypeSynced).Reason == condition.ReasonReconcileError:
+			span.SetStatus(codes.Error, def.GetCondition(condition.TypeSynced).Message)
+			span.SetAttributes(coredef.SpanAttributeResult.String("error"))
+		case res.Requeue || res.RequeueAfter > 0:
+			span.SetAttributes(coredef.SpanAttributeResult.String("requeue"))
+		default:
+			span.SetAttributes(coredef.SpanAttributeResult.String("success"))
+		}
+		span.End()
+	}
+}
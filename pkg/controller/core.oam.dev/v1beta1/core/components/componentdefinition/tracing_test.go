@@ -0,0 +1,90 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	coredef "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1beta1/core"
+)
+
+func TestReconcileSpans(t *testing.T) {
+	cd := newFakeComponentDefinition("traced-cd", "default")
+	broken := newFakeComponentDefinition("traced-broken-cd", "default")
+	broken.Spec.Schematic.CUE.Template = "parameter: {\n\timage: string\n"
+	r := newFakeReconciler(t, cd, broken)
+	exporter := tracetest.NewInMemoryExporter()
+	r.tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	attributesOf := func(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, kv := range span.Attributes {
+			attrs[kv.Key] = kv.Value
+		}
+		return attrs
+	}
+	spansByName := func() map[string]tracetest.SpanStub {
+		spans := map[string]tracetest.SpanStub{}
+		for _, span := range exporter.GetSpans() {
+			spans[span.Name] = span
+		}
+		return spans
+	}
+
+	_, err := reconcileFake(t, r, cd.Name, cd.Namespace)
+	require.NoError(t, err)
+	spans := spansByName()
+	require.Len(t, spans, 4)
+	root := spans["ComponentDefinition.Reconcile"]
+	require.False(t, root.Parent.IsValid())
+	rootAttrs := attributesOf(root)
+	require.Equal(t, "traced-cd", rootAttrs[coredef.SpanAttributeDefinitionName].AsString())
+	require.Equal(t, "default", rootAttrs[coredef.SpanAttributeDefinitionNamespace].AsString())
+	require.Equal(t, "traced-cd-v1", rootAttrs[coredef.SpanAttributeDefinitionRevision].AsString())
+	require.Equal(t, "success", rootAttrs[coredef.SpanAttributeResult].AsString())
+	for _, name := range []string{"GenerateDefinitionRevision", "StoreOpenAPISchema", "CleanUpDefinitionRevision"} {
+		child, ok := spans[name]
+		require.True(t, ok, name)
+		require.Equal(t, root.SpanContext.TraceID(), child.SpanContext.TraceID(), name)
+		require.Equal(t, root.SpanContext.SpanID(), child.Parent.SpanID(), name)
+		attrs := attributesOf(child)
+		require.Equal(t, "traced-cd", attrs[coredef.SpanAttributeDefinitionName].AsString(), name)
+		require.Equal(t, "success", attrs[coredef.SpanAttributeResult].AsString(), name)
+	}
+	require.Equal(t, "traced-cd-v1", attributesOf(spans["GenerateDefinitionRevision"])[coredef.SpanAttributeDefinitionRevision].AsString())
+	require.True(t, attributesOf(spans["GenerateDefinitionRevision"])[coredef.SpanAttributeNewRevision].AsBool())
+	require.Equal(t, "traced-cd-v1", attributesOf(spans["StoreOpenAPISchema"])[coredef.SpanAttributeDefinitionRevision].AsString())
+
+	// the failure of storing the schema is recorded in the spans
+	exporter.Reset()
+	_, err = reconcileFake(t, r, broken.Name, broken.Namespace)
+	require.NoError(t, err)
+	spans = spansByName()
+	store := spans["StoreOpenAPISchema"]
+	require.Equal(t, codes.Error, store.Status.Code)
+	require.Equal(t, "error", attributesOf(store)[coredef.SpanAttributeResult].AsString())
+	require.NotEmpty(t, store.Events)
+	root = spans["ComponentDefinition.Reconcile"]
+	require.Equal(t, codes.Error, root.Status.Code)
+	require.Equal(t, "error", attributesOf(root)[coredef.SpanAttributeResult].AsString())
+}
@@ -0,0 +1,80 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const (
+	// TypeDeprecated indicates whether the definition is deprecated
+	TypeDeprecated condition.ConditionType = "Deprecated"
+	// ReasonDeprecated is the reason of the Deprecated condition when the definition is deprecated
+	ReasonDeprecated condition.ConditionReason = "Deprecated"
+	// ReasonNotDeprecated is the reason of the Deprecated condition when the deprecation is withdrawn
+	ReasonNotDeprecated condition.ConditionReason = "NotDeprecated"
+)
+
+// IsDeprecated checks whether the definition is marked as deprecated by annotation
+func IsDeprecated(def metav1.Object) bool {
+	deprecated, err := strconv.ParseBool(def.GetAnnotations()[oam.AnnotationDefinitionDeprecated])
+	return err == nil && deprecated
+}
+
+// GetDeprecationMessage returns the message explaining the deprecation of the definition,
+// including its replacement if it's declared
+func GetDeprecationMessage(def metav1.Object) string {
+	annotations := def.GetAnnotations()
+	msg := fmt.Sprintf("the definition %s is deprecated", def.GetName())
+	if reason := annotations[oam.AnnotationDefinitionDeprecationMessage]; reason != "" {
+		msg += ": " + reason
+	}
+	if replacement := annotations[oam.AnnotationDefinitionReplacement]; replacement != "" {
+		msg += fmt.Sprintf(", use %s instead", replacement)
+	}
+	return msg
+}
+
+// DeprecatedCondition returns the Deprecated condition of the definition. If the definition is not deprecated
+// and has never been, no condition is returned.
+func DeprecatedCondition(def metav1.Object, current condition.Condition) []condition.Condition {
+	if IsDeprecated(def) {
+		return []condition.Condition{{
+			Type:               TypeDeprecated,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonDeprecated,
+			Message:            GetDeprecationMessage(def),
+		}}
+	}
+	if current.Status == corev1.ConditionTrue {
+		return []condition.Condition{{
+			Type:               TypeDeprecated,
+			Status:             corev1.ConditionFalse,
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonNotDeprecated,
+		}}
+	}
+	return nil
+}
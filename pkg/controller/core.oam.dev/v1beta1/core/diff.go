@@ -0,0 +1,150 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aryann/difflib"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// DiffDefinitionRevision returns a unified diff of the definition spec embedded in two DefinitionRevisions.
+// The CUE template is normalized by NormalizeCUETemplate and metadata is ignored, so an empty string is returned if
+// the two revisions are semantically identical even though their revision hashes are different.
+func DiffDefinitionRevision(old, new *v1beta1.DefinitionRevision) (string, error) {
+	if old == nil || new == nil {
+		return "", errors.New("cannot diff nil DefinitionRevision")
+	}
+	oldLines, err := normalizedDefinitionSpec(old)
+	if err != nil {
+		return "", err
+	}
+	newLines, err := normalizedDefinitionSpec(new)
+	if err != nil {
+		return "", err
+	}
+	diffs := difflib.Diff(oldLines, newLines)
+	changed := false
+	for _, d := range diffs {
+		if d.Delta != difflib.Common {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", old.Name, new.Name))
+	for _, d := range diffs {
+		sb.WriteString(d.String())
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// DefinitionRevisionPreview is the effect of changing the spec of a definition on its DefinitionRevisions
+type DefinitionRevisionPreview struct {
+	// LatestRevision is the name of the latest revision of the definition, it's empty if there's no revision yet
+	LatestRevision string
+	// NewRevision tells whether a new DefinitionRevision would be generated by the change
+	NewRevision bool
+	// Revision is the name of the DefinitionRevision the changed definition would be stored as
+	Revision string
+	// Diff is the diff of the change from the latest revision returned by DiffDefinitionRevision, which is empty if
+	// the change is semantically identical to the latest revision, e.g. only the whitespaces of the template change
+	Diff string
+}
+
+// PreviewComponentDefinitionRevision previews changing the spec of the ComponentDefinition of the key to the proposed
+// spec. The revision is generated like the controller does, and compared with the latest revision of the definition,
+// but nothing is written.
+func PreviewComponentDefinitionRevision(ctx context.Context, cli client.Client, key client.ObjectKey, spec v1beta1.ComponentDefinitionSpec,
+	options ...DefinitionRevisionOption) (*DefinitionRevisionPreview, error) {
+	cd := &v1beta1.ComponentDefinition{}
+	if err := cli.Get(ctx, key, cd); err != nil {
+		return nil, errors.Wrapf(err, "cannot get the ComponentDefinition %s", key)
+	}
+	cd.Spec = *spec.DeepCopy()
+	defRev, isNewRev, err := GenerateDefinitionRevision(ctx, cli, cd, options...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot generate the DefinitionRevision of ComponentDefinition %s", key)
+	}
+	preview := &DefinitionRevisionPreview{NewRevision: isNewRev, Revision: defRev.Name}
+	if cd.Status.LatestRevision == nil {
+		return preview, nil
+	}
+	preview.LatestRevision = cd.Status.LatestRevision.Name
+	latest := &v1beta1.DefinitionRevision{}
+	if err = cli.Get(ctx, client.ObjectKey{Namespace: key.Namespace, Name: preview.LatestRevision}, latest); err != nil {
+		return nil, errors.Wrapf(err, "cannot get the latest DefinitionRevision %s", preview.LatestRevision)
+	}
+	if preview.Diff, err = DiffDefinitionRevision(latest, defRev); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// normalizedDefinitionSpec returns the lines of the definition spec in YAML format with CUE template normalized
+func normalizedDefinitionSpec(defRev *v1beta1.DefinitionRevision) ([]string, error) {
+	var spec interface{}
+	var schematic *common.Schematic
+	switch defRev.Spec.DefinitionType {
+	case common.ComponentType:
+		s := defRev.Spec.ComponentDefinition.Spec.DeepCopy()
+		schematic, spec = s.Schematic, s
+	case common.TraitType:
+		s := defRev.Spec.TraitDefinition.Spec.DeepCopy()
+		schematic, spec = s.Schematic, s
+	case common.PolicyType:
+		s := defRev.Spec.PolicyDefinition.Spec.DeepCopy()
+		schematic, spec = s.Schematic, s
+	case common.WorkflowStepType:
+		s := defRev.Spec.WorkflowStepDefinition.Spec.DeepCopy()
+		schematic, spec = s.Schematic, s
+	default:
+		return nil, fmt.Errorf("unsupported definition type %s of DefinitionRevision %s", defRev.Spec.DefinitionType, defRev.Name)
+	}
+	if schematic != nil && schematic.CUE != nil {
+		schematic.CUE.Template = diffCUETemplate(schematic.CUE.Template)
+	}
+	out, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal the spec of DefinitionRevision %s", defRev.Name)
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n"), nil
+}
+
+// diffCUETemplate normalizes the CUE template by NormalizeCUETemplate, and expands the tabs indenting the lines so that
+// the template is rendered as a block of lines in YAML rather than a quoted string
+func diffCUETemplate(template string) string {
+	lines := strings.Split(NormalizeCUETemplate(template), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, "\t")
+		lines[i] = strings.Repeat("  ", len(line)-len(trimmed)) + trimmed
+	}
+	return strings.Join(lines, "\n")
+}
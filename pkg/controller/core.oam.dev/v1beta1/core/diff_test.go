@@ -0,0 +1,137 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestDiffDefinitionRevision(t *testing.T) {
+	ctx := context.Background()
+	generate := func(template string) *v1beta1.DefinitionRevision {
+		cd := newTestComponentDefinition("worker", template)
+		defRev, _, err := GenerateDefinitionRevision(ctx, newTestClient(cd), cd)
+		require.NoError(t, err)
+		return defRev
+	}
+
+	old := generate("output: {\n\tapiVersion: \"apps/v1\"\n\tkind: \"Deployment\"\n}\n")
+	whitespace := generate("output: {\n    apiVersion:   \"apps/v1\"\n\n    kind: \"Deployment\"\n}")
+	whitespace.ResourceVersion = "2"
+	require.NotEqual(t, old.Spec.RevisionHash, whitespace.Spec.RevisionHash)
+	diff, err := DiffDefinitionRevision(old, whitespace)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	// the whitespaces inside the string literals are not normalized
+	literal := generate("output: {\n\tapiVersion: \"apps/v1\"\n\tkind: \"Deployment\"\n\tdata: \"a  b\"\n}\n")
+	spaced := generate("output: {\n\tapiVersion: \"apps/v1\"\n\tkind: \"Deployment\"\n\tdata: \"a b\"\n}\n")
+	diff, err = DiffDefinitionRevision(literal, spaced)
+	require.NoError(t, err)
+	require.Contains(t, diff, `-         data:       "a  b"`)
+	require.Contains(t, diff, `+         data:       "a b"`)
+
+	changed := generate("output: {\n\tapiVersion: \"apps/v1\"\n\tkind: \"StatefulSet\"\n}\n")
+	changed.Name = "worker-v2"
+	diff, err = DiffDefinitionRevision(old, changed)
+	require.NoError(t, err)
+	require.Contains(t, diff, "--- worker-v1\n+++ worker-v2\n")
+	require.Contains(t, diff, `-         kind:       "Deployment"`)
+	require.Contains(t, diff, `+         kind:       "StatefulSet"`)
+
+	_, err = DiffDefinitionRevision(nil, changed)
+	require.Error(t, err)
+}
+
+func TestPreviewComponentDefinitionRevision(t *testing.T) {
+	ctx := context.Background()
+	template := "output: {\n\tapiVersion: \"apps/v1\"\n\tkind: \"Deployment\"\n}\n"
+	cd := newTestComponentDefinition("worker", template)
+	cli := newTestClient(cd)
+	key := client.ObjectKeyFromObject(cd)
+
+	// no revision yet
+	preview, err := PreviewComponentDefinitionRevision(ctx, cli, key, cd.Spec)
+	require.NoError(t, err)
+	require.Equal(t, &DefinitionRevisionPreview{NewRevision: true, Revision: "worker-v1"}, preview)
+
+	_, _, err = ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+		cd.Status.LatestRevision = revision
+		return cli.Status().Update(ctx, cd)
+	})
+	require.NoError(t, err)
+	specWithTemplate := func(template string) v1beta1.ComponentDefinitionSpec {
+		return newTestComponentDefinition("worker", template).Spec
+	}
+
+	testCases := map[string]struct {
+		template    string
+		newRevision bool
+		revision    string
+		diff        []string
+	}{
+		"no change": {
+			template: template,
+			revision: "worker-v1",
+		},
+		"whitespace only": {
+			template:    "output: {\n    apiVersion:   \"apps/v1\"\n\n    kind: \"Deployment\"\n}",
+			newRevision: true,
+			revision:    "worker-v2",
+		},
+		"substantive change": {
+			template:    "output: {\n\tapiVersion: \"apps/v1\"\n\tkind: \"StatefulSet\"\n}\n",
+			newRevision: true,
+			revision:    "worker-v2",
+			diff:        []string{"--- worker-v1\n+++ worker-v2\n", `-         kind:       "Deployment"`, `+         kind:       "StatefulSet"`},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			preview, err := PreviewComponentDefinitionRevision(ctx, cli, key, specWithTemplate(tc.template))
+			require.NoError(t, err)
+			require.Equal(t, "worker-v1", preview.LatestRevision)
+			require.Equal(t, tc.newRevision, preview.NewRevision)
+			require.Equal(t, tc.revision, preview.Revision)
+			if len(tc.diff) == 0 {
+				require.Empty(t, preview.Diff)
+			}
+			for _, diff := range tc.diff {
+				require.Contains(t, preview.Diff, diff)
+			}
+		})
+	}
+
+	// nothing is written by the previews
+	revs := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, cli.List(ctx, revs))
+	require.Len(t, revs.Items, 1)
+	live := &v1beta1.ComponentDefinition{}
+	require.NoError(t, cli.Get(ctx, key, live))
+	require.Equal(t, template, live.Spec.Schematic.CUE.Template)
+
+	_, err = PreviewComponentDefinitionRevision(ctx, cli, client.ObjectKey{Namespace: "default", Name: "missing"}, cd.Spec)
+	require.Error(t, err)
+}
@@ -0,0 +1,147 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+var (
+	// ErrRevisionGeneration identifies the DefinitionError of generating the DefinitionRevision of a definition
+	ErrRevisionGeneration = errors.New("cannot generate DefinitionRevision")
+	// ErrRevisionCreation identifies the DefinitionError of creating or updating a DefinitionRevision
+	ErrRevisionCreation = errors.New("cannot create DefinitionRevision")
+	// ErrDefinitionUpdate identifies the DefinitionError of updating the status of a definition
+	ErrDefinitionUpdate = errors.New("cannot update definition")
+	// ErrSchemaStorage identifies the DefinitionError of storing the parameter schema of a definition
+	ErrSchemaStorage = errors.New("cannot store schema")
+)
+
+// definitionErrorFormats keep the messages of the DefinitionErrors the same as the ones formatted by fmt.Errorf
+var definitionErrorFormats = map[error]string{
+	ErrRevisionGeneration: util.ErrGenerateDefinitionRevision,
+	ErrRevisionCreation:   util.ErrCreateDefinitionRevision,
+	ErrDefinitionUpdate:   util.ErrUpdateComponentDefinition,
+	ErrSchemaStorage:      util.ErrStoreCapabilityInConfigMap,
+}
+
+// DefinitionError is the error failing a step of reconciling a definition. errors.Is tells the failed step by the
+// Kind, e.g. ErrSchemaStorage, and errors.As reaches the definition name and the cause.
+type DefinitionError struct {
+	// Kind is the failed step, one of ErrRevisionGeneration, ErrRevisionCreation, ErrDefinitionUpdate and ErrSchemaStorage
+	Kind error
+	// Name is the name of the definition, or the name of the DefinitionRevision for ErrRevisionCreation
+	Name string
+	// Cause is the error causing the failure
+	Cause error
+}
+
+// NewDefinitionError returns the DefinitionError of the kind
+func NewDefinitionError(kind error, name string, cause error) error {
+	return &DefinitionError{Kind: kind, Name: name, Cause: cause}
+}
+
+// Error implements error
+func (e *DefinitionError) Error() string {
+	format, ok := definitionErrorFormats[e.Kind]
+	if !ok {
+		return fmt.Sprintf("%v %s: %v", e.Kind, e.Name, e.Cause)
+	}
+	return fmt.Sprintf(format, e.Name, e.Cause)
+}
+
+// Unwrap returns the cause
+func (e *DefinitionError) Unwrap() error {
+	return e.Cause
+}
+
+// Is matches the kind of the error
+func (e *DefinitionError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// ValidationIssue is a problem of a definition reported by the condition of the type
+type ValidationIssue struct {
+	// Type is the type of the condition reporting the issue, e.g. TypeSchemaReady
+	Type condition.ConditionType
+	// Err is the problem
+	Err error
+}
+
+// Error implements error
+func (i ValidationIssue) Error() string {
+	return fmt.Sprintf("%s: %v", i.Type, i.Err)
+}
+
+// Unwrap returns the problem
+func (i ValidationIssue) Unwrap() error {
+	return i.Err
+}
+
+// ValidationIssues returns the issues reported by the failed conditions, i.e. the ones made by ErrorCondition, in
+// the order of the conditions
+func ValidationIssues(conditions ...condition.Condition) []ValidationIssue {
+	var issues []ValidationIssue
+	for _, cond := range conditions {
+		if cond.Status == corev1.ConditionFalse && cond.Reason == condition.ReasonReconcileError {
+			issues = append(issues, ValidationIssue{Type: cond.Type, Err: errors.New(cond.Message)})
+		}
+	}
+	return issues
+}
+
+// ValidationError aggregates all the issues found by validating a definition, so that they are reported at once
+// rather than one by one. errors.Is and errors.As reach each of the issues.
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+// NewValidationError returns the ValidationError of the issues, nil is returned if there is no issue
+func NewValidationError(issues ...ValidationIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: issues}
+}
+
+// Error implements error. The message of a single issue is kept as it is, and multiple issues are listed line by line.
+func (e *ValidationError) Error() string {
+	if len(e.Issues) == 1 {
+		return e.Issues[0].Err.Error()
+	}
+	lines := []string{fmt.Sprintf("the definition has %d problems:", len(e.Issues))}
+	for _, issue := range e.Issues {
+		lines = append(lines, "- "+issue.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the issues
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		errs = append(errs, issue)
+	}
+	return errs
+}
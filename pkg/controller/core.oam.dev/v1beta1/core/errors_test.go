@@ -0,0 +1,83 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func TestDefinitionError(t *testing.T) {
+	cause := fmt.Errorf("wrapped: %w", context.DeadlineExceeded)
+	testCases := map[string]struct {
+		kind   error
+		format string
+	}{
+		"revision generation": {kind: ErrRevisionGeneration, format: util.ErrGenerateDefinitionRevision},
+		"revision creation":   {kind: ErrRevisionCreation, format: util.ErrCreateDefinitionRevision},
+		"definition update":   {kind: ErrDefinitionUpdate, format: util.ErrUpdateComponentDefinition},
+		"schema storage":      {kind: ErrSchemaStorage, format: util.ErrStoreCapabilityInConfigMap},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := fmt.Errorf("reconcile: %w", NewDefinitionError(tc.kind, "worker", cause))
+			require.Equal(t, "reconcile: "+fmt.Sprintf(tc.format, "worker", cause), err.Error())
+			require.ErrorIs(t, err, tc.kind)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			for _, other := range []error{ErrRevisionGeneration, ErrRevisionCreation, ErrDefinitionUpdate, ErrSchemaStorage} {
+				if other != tc.kind {
+					require.False(t, errors.Is(err, other))
+				}
+			}
+			var defErr *DefinitionError
+			require.ErrorAs(t, err, &defErr)
+			require.Equal(t, "worker", defErr.Name)
+			require.Equal(t, cause, defErr.Cause)
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	require.NoError(t, NewValidationError())
+	require.Empty(t, ValidationIssues(condition.ReadyCondition(TypeSchemaReady), condition.ReconcileSuccess()))
+
+	schemaErr := NewDefinitionError(ErrSchemaStorage, "worker", errors.New("invalid CUE"))
+	single := NewValidationError(ValidationIssue{Type: TypeSchemaReady, Err: schemaErr})
+	// the message of a single issue is kept as it is
+	require.Equal(t, schemaErr.Error(), single.Error())
+
+	issues := append([]ValidationIssue{{Type: TypeSchemaReady, Err: schemaErr}}, ValidationIssues(
+		condition.ErrorCondition(TypeStatusTemplateValid, errors.New("compile customStatus: syntax error")),
+		condition.ReadyCondition(TypePolicyCompliant),
+		condition.ErrorCondition(TypeTraitCompatibilityValid, errors.New("invalid trait name")))...)
+	err := NewValidationError(issues...)
+	require.Equal(t, "the definition has 3 problems:\n"+
+		"- SchemaReady: "+schemaErr.Error()+"\n"+
+		"- StatusTemplateValid: compile customStatus: syntax error\n"+
+		"- TraitCompatibilityValid: invalid trait name", err.Error())
+	require.ErrorIs(t, err, ErrSchemaStorage)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Issues, 3)
+}
@@ -0,0 +1,88 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is a period, e.g. a change freeze, during which the DefinitionRevisions are never garbage
+// collected. The collection is deferred until the window closes.
+type MaintenanceWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// String formats the window as <start>/<end> in RFC3339
+func (w MaintenanceWindow) String() string {
+	return w.Start.Format(time.RFC3339) + "/" + w.End.Format(time.RFC3339)
+}
+
+// ParseMaintenanceWindows parses the maintenance windows formatted as <start>/<end> in RFC3339, e.g.
+// 2024-12-20T00:00:00Z/2025-01-06T00:00:00Z
+func ParseMaintenanceWindows(windows []string) ([]MaintenanceWindow, error) {
+	var parsed []MaintenanceWindow
+	for _, w := range windows {
+		start, end, ok := strings.Cut(w, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid maintenance window %q, it should be <start>/<end> in RFC3339", w)
+		}
+		window := MaintenanceWindow{}
+		var err error
+		if window.Start, err = time.Parse(time.RFC3339, strings.TrimSpace(start)); err != nil {
+			return nil, fmt.Errorf("invalid start of maintenance window %q: %w", w, err)
+		}
+		if window.End, err = time.Parse(time.RFC3339, strings.TrimSpace(end)); err != nil {
+			return nil, fmt.Errorf("invalid end of maintenance window %q: %w", w, err)
+		}
+		if !window.End.After(window.Start) {
+			return nil, fmt.Errorf("invalid maintenance window %q, the end should be after the start", w)
+		}
+		parsed = append(parsed, window)
+	}
+	return parsed, nil
+}
+
+// RevisionGCMaintenanceWindows are the maintenance windows during which the garbage collection of DefinitionRevisions
+// is skipped
+type RevisionGCMaintenanceWindows []MaintenanceWindow
+
+// ApplyToDefinitionRevisionConfig apply revision gc maintenance windows to the config
+func (w RevisionGCMaintenanceWindows) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.gcMaintenanceWindows = append(cfg.gcMaintenanceWindows, w...)
+}
+
+// ActiveUntil returns the time when the maintenance windows open at the time close, the overlapping and adjacent
+// windows are joined. False is returned if no window is open at the time.
+func (w RevisionGCMaintenanceWindows) ActiveUntil(now time.Time) (time.Time, bool) {
+	var until time.Time
+	for extended := true; extended; {
+		extended = false
+		for _, window := range w {
+			at := now
+			if until.After(now) {
+				at = until
+			}
+			if !at.Before(window.Start) && at.Before(window.End) && window.End.After(until) {
+				until, extended = window.End, true
+			}
+		}
+	}
+	return until, !until.IsZero()
+}
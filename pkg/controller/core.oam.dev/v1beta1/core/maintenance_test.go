@@ -0,0 +1,66 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMaintenanceWindows(t *testing.T) {
+	windows, err := ParseMaintenanceWindows([]string{"2024-12-20T00:00:00Z/2025-01-06T00:00:00Z", " 2025-03-01T08:00:00+08:00 / 2025-03-01T20:00:00+08:00 "})
+	require.NoError(t, err)
+	require.Len(t, windows, 2)
+	require.Equal(t, "2024-12-20T00:00:00Z/2025-01-06T00:00:00Z", windows[0].String())
+	require.Equal(t, 12*time.Hour, windows[1].End.Sub(windows[1].Start))
+
+	for _, invalid := range []string{"2024-12-20T00:00:00Z", "2024-12-20/2025-01-06", "2025-01-06T00:00:00Z/2024-12-20T00:00:00Z"} {
+		_, err = ParseMaintenanceWindows([]string{invalid})
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestMaintenanceWindowsActiveUntil(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2025, 1, 1, hour, 0, 0, 0, time.UTC) }
+	windows := RevisionGCMaintenanceWindows{
+		{Start: at(1), End: at(3)},
+		// overlaps with the first window
+		{Start: at(2), End: at(5)},
+		// adjacent to the second window
+		{Start: at(5), End: at(6)},
+		{Start: at(8), End: at(9)},
+	}
+	testCases := map[string]struct {
+		now   time.Time
+		until time.Time
+	}{
+		"before all":        {now: at(0)},
+		"in joined windows": {now: at(1), until: at(6)},
+		"in the last part":  {now: at(5), until: at(6)},
+		"at the end":        {now: at(6)},
+		"in a later window": {now: at(8), until: at(9)},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			until, ok := windows.ActiveUntil(tc.now)
+			require.Equal(t, !tc.until.IsZero(), ok)
+			require.Equal(t, tc.until, until)
+		})
+	}
+}
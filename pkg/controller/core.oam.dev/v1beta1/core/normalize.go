@@ -0,0 +1,73 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/parser"
+	"cuelang.org/go/cue/token"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// NormalizeCUETemplate strips the comments and the blank lines of the CUE template and formats it, so that the
+// templates only different in comments and whitespaces are normalized to the same one. The template which can't be
+// parsed is returned as it is.
+func NormalizeCUETemplate(template string) string {
+	f, err := parser.ParseFile("-", template)
+	if err != nil {
+		return template
+	}
+	ast.Walk(f, func(n ast.Node) bool {
+		if n.Pos().RelPos() == token.NewSection {
+			ast.SetRelPos(n, token.Newline)
+		}
+		return true
+	}, nil)
+	normalized, err := format.Node(f)
+	if err != nil {
+		return template
+	}
+	return string(normalized)
+}
+
+// normalize returns the copy of the DefinitionRevision with the CUE templates normalized if it's enabled, the
+// DefinitionRevision itself is returned otherwise
+func (cfg *definitionRevisionConfig) normalize(defRev *v1beta1.DefinitionRevision) *v1beta1.DefinitionRevision {
+	if !cfg.normalizeCUE {
+		return defRev
+	}
+	normalized := defRev.DeepCopy()
+	for _, schematic := range []*common.Schematic{
+		normalized.Spec.ComponentDefinition.Spec.Schematic,
+		normalized.Spec.TraitDefinition.Spec.Schematic,
+		normalized.Spec.PolicyDefinition.Spec.Schematic,
+		normalized.Spec.WorkflowStepDefinition.Spec.Schematic,
+	} {
+		if schematic != nil && schematic.CUE != nil {
+			schematic.CUE.Template = NormalizeCUETemplate(schematic.CUE.Template)
+		}
+	}
+	return normalized
+}
+
+// equalDefRevision compares the spec of the DefinitionRevisions, with the CUE templates normalized if it's enabled
+func (cfg *definitionRevisionConfig) equalDefRevision(old, new *v1beta1.DefinitionRevision) bool {
+	return DeepEqualDefRevision(cfg.normalize(old), cfg.normalize(new))
+}
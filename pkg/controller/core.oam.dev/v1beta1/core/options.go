@@ -0,0 +1,207 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// DefinitionRevisionOption option for generating and reconciling DefinitionRevision
+type DefinitionRevisionOption interface {
+	ApplyToDefinitionRevisionConfig(*definitionRevisionConfig)
+}
+
+type definitionRevisionConfig struct {
+	namingStrategy          RevisionNamingStrategy
+	hasher                  RevisionHasher
+	ignoredMetadataPrefixes []string
+	gcByUsage               bool
+	signingKey              ed25519.PrivateKey
+	verificationKey         ed25519.PublicKey
+	normalizeCUE            bool
+	labels                  map[string]string
+	gcMaintenanceWindows    RevisionGCMaintenanceWindows
+	gcGracePeriod           RevisionGCGracePeriod
+	generatedBy             string
+	onRevisionsCollected    RevisionsCollectedHook
+}
+
+func newDefinitionRevisionConfig(options ...DefinitionRevisionOption) *definitionRevisionConfig {
+	cfg := &definitionRevisionConfig{namingStrategy: RevisionNamingSequential, hasher: utils.ComputeSpecHash, gcByUsage: true}
+	for _, option := range options {
+		option.ApplyToDefinitionRevisionConfig(cfg)
+	}
+	return cfg
+}
+
+// RevisionNamingStrategy decides how the name of a new DefinitionRevision is generated
+type RevisionNamingStrategy string
+
+const (
+	// RevisionNamingSequential names the DefinitionRevision with the incrementing revision number, e.g. webservice-v2
+	RevisionNamingSequential RevisionNamingStrategy = "sequential"
+	// RevisionNamingHashBased names the DefinitionRevision with the revision hash, e.g. webservice-5f8d7c6b9a2e4d10,
+	// so that identical definitions produce the same revision name across clusters
+	RevisionNamingHashBased RevisionNamingStrategy = "hash-based"
+)
+
+// ApplyToDefinitionRevisionConfig apply revision naming strategy to the config
+func (s RevisionNamingStrategy) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.namingStrategy = s
+}
+
+// RevisionHasher computes the revision hash of the definition spec
+type RevisionHasher func(spec interface{}) (string, error)
+
+// ApplyToDefinitionRevisionConfig apply revision hasher to the config
+func (h RevisionHasher) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.hasher = h
+}
+
+// IgnoredMetadataPrefixes are the key prefixes of the labels and annotations which are stripped from the definition
+// before it's recorded into the DefinitionRevision, e.g. the ones added by GitOps tools, so that the operational
+// metadata never makes difference between revisions
+type IgnoredMetadataPrefixes []string
+
+// ApplyToDefinitionRevisionConfig apply ignored metadata prefixes to the config
+func (p IgnoredMetadataPrefixes) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.ignoredMetadataPrefixes = append(cfg.ignoredMetadataPrefixes, p...)
+}
+
+// RevisionGCByUsage decides whether the garbage collection keeps the DefinitionRevisions referenced by Applications,
+// e.g. `worker@v2`. Otherwise the oldest revisions are collected regardless of their usage.
+type RevisionGCByUsage bool
+
+// ApplyToDefinitionRevisionConfig apply revision gc by usage to the config
+func (u RevisionGCByUsage) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.gcByUsage = bool(u)
+}
+
+// RevisionGCGracePeriod is the minimum age of the DefinitionRevisions by their creation timestamps before they are
+// eligible for the garbage collection, so that the revisions just superseded are kept for a while to roll back to.
+// The revisions over the limit are collected once they age past the grace period.
+type RevisionGCGracePeriod time.Duration
+
+// ApplyToDefinitionRevisionConfig apply revision gc grace period to the config
+func (p RevisionGCGracePeriod) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.gcGracePeriod = p
+}
+
+// Retains tells whether the DefinitionRevision is still in the grace period at the time, and returns the time when
+// it becomes eligible for the garbage collection
+func (p RevisionGCGracePeriod) Retains(rev *v1beta1.DefinitionRevision, now time.Time) (time.Time, bool) {
+	eligible := rev.CreationTimestamp.Add(time.Duration(p))
+	return eligible, p > 0 && now.Before(eligible)
+}
+
+// NextCollection returns the earliest time when a DefinitionRevision over the limit, retained by the grace period
+// at the time, becomes eligible for the garbage collection. False is returned if no revision is over the limit or
+// retained. The latest revision is never collected.
+func (p RevisionGCGracePeriod) NextCollection(revisions []v1beta1.DefinitionRevision, revisionLimit int, latest string, now time.Time) (time.Time, bool) {
+	var next time.Time
+	if p <= 0 || len(revisions) <= revisionLimit+1 {
+		return next, false
+	}
+	for i := range revisions {
+		if revisions[i].Name == latest {
+			continue
+		}
+		if eligible, retained := p.Retains(&revisions[i], now); retained && (next.IsZero() || eligible.Before(next)) {
+			next = eligible
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// NormalizeCUETemplates compares the definitions with the comments and the formatting of their CUE templates
+// normalized, so that the edits without semantic changes, e.g. comment changes, don't create new DefinitionRevisions.
+// The revisions still record the templates as they are.
+type NormalizeCUETemplates bool
+
+// ApplyToDefinitionRevisionConfig apply CUE template normalization to the config
+func (n NormalizeCUETemplates) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.normalizeCUE = bool(n)
+}
+
+// RevisionLabels are the extra labels set on the DefinitionRevisions, e.g. the tenant labels that the label based
+// RBAC and network policies select. The labels of the definition itself take precedence over them.
+type RevisionLabels map[string]string
+
+// ApplyToDefinitionRevisionConfig apply revision labels to the config
+func (l RevisionLabels) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.labels = util.MergeMapOverrideWithDst(cfg.labels, l)
+}
+
+// RevisionGeneratedBy is the build version of the controller stamped on the DefinitionRevisions whose schemas it
+// generates, so that the schema regressions can be correlated with the controller rollouts. It's also recorded in
+// the provenance of the revisions created, which falls back to the build version of the running binary if it's empty.
+type RevisionGeneratedBy string
+
+// ApplyToDefinitionRevisionConfig apply the controller version generating the revisions to the config
+func (g RevisionGeneratedBy) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.generatedBy = string(g)
+}
+
+// RevisionsCollectedHook is called with the names of the DefinitionRevisions of the definition collected by the
+// garbage collection, e.g. to delete the schemas of the collected revisions kept out of the cluster
+type RevisionsCollectedHook func(definition metav1.Object, collected []string)
+
+// ApplyToDefinitionRevisionConfig apply the hook of collected revisions to the config
+func (h RevisionsCollectedHook) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.onRevisionsCollected = h
+}
+
+// annotations returns the annotations set on the DefinitionRevisions by the config, which are updated on the reused
+// revisions as well since their schemas are regenerated by the running controller
+func (cfg *definitionRevisionConfig) annotations() map[string]string {
+	if cfg.generatedBy == "" {
+		return nil
+	}
+	return map[string]string{oam.AnnotationDefinitionGeneratedBy: cfg.generatedBy}
+}
+
+// stripIgnoredMetadata returns the metadata without the keys matching the ignored prefixes
+func (cfg *definitionRevisionConfig) stripIgnoredMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 || len(cfg.ignoredMetadataPrefixes) == 0 {
+		return metadata
+	}
+	stripped := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if !cfg.isIgnoredMetadata(k) {
+			stripped[k] = v
+		}
+	}
+	return stripped
+}
+
+func (cfg *definitionRevisionConfig) isIgnoredMetadata(key string) bool {
+	for _, prefix := range cfg.ignoredMetadataPrefixes {
+		if prefix != "" && strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
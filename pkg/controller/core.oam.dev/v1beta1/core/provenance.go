@@ -0,0 +1,80 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/version"
+)
+
+// provenanceAnnotations are the annotations of the definition identifying who made the change, which are copied onto
+// the DefinitionRevisions. The labels of the definition, e.g. app.kubernetes.io/managed-by, are copied as a whole.
+var provenanceAnnotations = []string{
+	oam.AnnotationApplicationUsername,
+	oam.AnnotationResourceURL,
+}
+
+// setRevisionProvenance records what the DefinitionRevision is created from, when and by which version of the
+// controller, so that every revision carries a trail of the change causing it
+func setRevisionProvenance(def metav1.Object, defRev *v1beta1.DefinitionRevision, controllerVersion string, now time.Time) {
+	annotations := map[string]string{
+		oam.AnnotationRevisionCreatedAt:         now.UTC().Format(time.RFC3339),
+		oam.AnnotationRevisionControllerVersion: revisionControllerVersion(controllerVersion),
+		oam.AnnotationRevisionSourceGeneration:  strconv.FormatInt(def.GetGeneration(), 10),
+	}
+	if manager := lastFieldManager(def); manager != "" {
+		annotations[oam.AnnotationRevisionFieldManager] = manager
+	}
+	for _, key := range provenanceAnnotations {
+		if value, ok := def.GetAnnotations()[key]; ok {
+			annotations[key] = value
+		}
+	}
+	defRev.SetAnnotations(util.MergeMapOverrideWithDst(defRev.GetAnnotations(), annotations))
+}
+
+// revisionControllerVersion returns the controller version recorded in the provenance, which falls back to the build
+// version of the running binary if it's not configured
+func revisionControllerVersion(controllerVersion string) string {
+	if controllerVersion == "" {
+		return version.VelaVersion
+	}
+	return controllerVersion
+}
+
+// lastFieldManager returns the manager which changed the definition most recently, the changes of the status are
+// ignored as they are made by the controller itself
+func lastFieldManager(def metav1.Object) string {
+	var manager string
+	var last *metav1.Time
+	for _, entry := range def.GetManagedFields() {
+		if entry.Subresource != "" || entry.Time == nil {
+			continue
+		}
+		if last == nil || !entry.Time.Before(last) {
+			manager, last = entry.Manager, entry.Time
+		}
+	}
+	return manager
+}
@@ -0,0 +1,889 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+	"github.com/oam-dev/kubevela/version"
+)
+
+func newTestComponentDefinition(name, template string) *v1beta1.ComponentDefinition {
+	return &v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Workload:  common.WorkloadTypeDescriptor{Type: "deployments.apps"},
+			Schematic: &common.Schematic{CUE: &common.CUE{Template: template}},
+		},
+	}
+}
+
+func newTestClient(objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(objs...).Build()
+}
+
+func TestGenerateDefinitionRevisionNamingStrategy(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cli := newTestClient(cd)
+
+	defRev, isNew, err := GenerateDefinitionRevision(ctx, cli, cd)
+	require.NoError(t, err)
+	require.True(t, isNew)
+	require.Equal(t, "worker-v1", defRev.Name)
+
+	defRev, isNew, err = GenerateDefinitionRevision(ctx, cli, cd, RevisionNamingSequential)
+	require.NoError(t, err)
+	require.True(t, isNew)
+	require.Equal(t, "worker-v1", defRev.Name)
+
+	hashRev, isNew, err := GenerateDefinitionRevision(ctx, cli, cd, RevisionNamingHashBased)
+	require.NoError(t, err)
+	require.True(t, isNew)
+	require.Equal(t, "worker-"+hashRev.Spec.RevisionHash, hashRev.Name)
+	require.Equal(t, int64(1), hashRev.Spec.Revision)
+
+	// identical definitions in another cluster produce the same revision name
+	another, _, err := GenerateDefinitionRevision(ctx, newTestClient(cd.DeepCopy()), cd.DeepCopy(), RevisionNamingHashBased)
+	require.NoError(t, err)
+	require.Equal(t, hashRev.Name, another.Name)
+
+	// changing the definition back to an existing revision reuses it
+	require.NoError(t, CreateDefinitionRevision(ctx, cli, cd, hashRev.DeepCopy()))
+	changed := cd.DeepCopy()
+	changed.Status.LatestRevision = &common.Revision{Name: "worker-v2", Revision: 2, RevisionHash: "another-hash"}
+	reused, isNew, err := GenerateDefinitionRevision(ctx, cli, changed, RevisionNamingHashBased)
+	require.NoError(t, err)
+	require.True(t, isNew)
+	require.Equal(t, hashRev.Name, reused.Name)
+	require.Equal(t, int64(3), reused.Spec.Revision)
+	require.Equal(t, "1,3", reused.Annotations[oam.AnnotationRevisionNumbers])
+}
+
+func TestHashNamedRevisionNumbers(t *testing.T) {
+	ctx := util.SetNamespaceInCtx(context.Background(), "default")
+	cd := newTestComponentDefinition("worker", "output: {a: 1}")
+	cli := newTestClient(cd.DeepCopy())
+	apply := func(template string) *v1beta1.DefinitionRevision {
+		cd.Spec.Schematic.CUE.Template = template
+		defRev, isNew, err := GenerateDefinitionRevision(ctx, cli, cd, RevisionNamingHashBased)
+		require.NoError(t, err)
+		require.True(t, isNew)
+		require.NoError(t, CreateDefinitionRevision(ctx, cli, cd, defRev.DeepCopy()))
+		cd.Status.LatestRevision = &common.Revision{Name: defRev.Name, Revision: defRev.Spec.Revision, RevisionHash: defRev.Spec.RevisionHash}
+		return defRev
+	}
+
+	// A -> B -> A -> C
+	a := apply("output: {a: 1}")
+	b := apply("output: {b: 1}")
+	reverted := apply("output: {a: 1}")
+	c := apply("output: {c: 1}")
+	require.Equal(t, a.Name, reverted.Name)
+	require.Equal(t, []int64{1, 2, 3, 4}, []int64{a.Spec.Revision, b.Spec.Revision, reverted.Spec.Revision, c.Spec.Revision})
+	stored := &v1beta1.DefinitionRevision{}
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: reverted.Name}, stored))
+	require.Equal(t, int64(3), stored.Spec.Revision)
+	require.Equal(t, []int64{1, 3}, util.DefinitionRevisionNumbers(stored))
+
+	// the pinned revision numbers resolve to the hash-named revisions
+	for typ, template := range map[string]string{"worker@v1": "output: {a: 1}", "worker@v2": "output: {b: 1}", "worker@v3": "output: {a: 1}", "worker@v4": "output: {c: 1}"} {
+		def := &v1beta1.ComponentDefinition{}
+		require.NoError(t, util.GetCapabilityDefinition(ctx, cli, def, typ), typ)
+		require.Equal(t, template, def.Spec.Schematic.CUE.Template, typ)
+	}
+	require.True(t, apierrors.IsNotFound(util.GetCapabilityDefinition(ctx, cli, &v1beta1.ComponentDefinition{}, "worker@v5")))
+
+	// the revision pinned by its former number is never collected
+	require.NoError(t, cli.Create(ctx, &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: cd.Namespace},
+		Spec:       v1beta1.ApplicationSpec{Components: []common.ApplicationComponent{{Name: "comp", Type: "worker@v1"}}},
+	}))
+	require.NoError(t, CleanUpDefinitionRevision(ctx, cli, cd, 0))
+	revs := new(v1beta1.DefinitionRevisionList)
+	require.NoError(t, cli.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	var names []string
+	for _, rev := range revs.Items {
+		names = append(names, rev.Name)
+	}
+	require.ElementsMatch(t, []string{a.Name, c.Name}, names)
+}
+
+func TestGenerateDefinitionRevisionNormalizeCUE(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "parameter: {\n\timage: string\n}\noutput: {\n\timage: parameter.image\n}\n")
+	for _, normalize := range []bool{true, false} {
+		t.Run(fmt.Sprintf("normalize=%t", normalize), func(t *testing.T) {
+			cli := newTestClient(cd.DeepCopy())
+			defRev, isNew, err := GenerateDefinitionRevision(ctx, cli, cd, NormalizeCUETemplates(normalize))
+			require.NoError(t, err)
+			require.True(t, isNew)
+			require.NoError(t, CreateDefinitionRevision(ctx, cli, cd, defRev.DeepCopy()))
+
+			// the comments and the formatting are the only changes
+			commented := cd.DeepCopy()
+			commented.Status.LatestRevision = &common.Revision{Name: defRev.Name, Revision: defRev.Spec.Revision, RevisionHash: defRev.Spec.RevisionHash}
+			commented.Spec.Schematic.CUE.Template = "// the image to run\nparameter: {\n  // image name\n  image:   string\n}\n\noutput: {\n\timage: parameter.image // passed through\n}\n"
+			next, isNew, err := GenerateDefinitionRevision(ctx, cli, commented, NormalizeCUETemplates(normalize))
+			require.NoError(t, err)
+			require.Equal(t, !normalize, isNew)
+			if normalize {
+				require.Equal(t, defRev.Name, next.Name)
+			} else {
+				require.Equal(t, "worker-v2", next.Name)
+			}
+
+			// the semantic changes always create new revisions
+			changed := commented.DeepCopy()
+			changed.Spec.Schematic.CUE.Template = "parameter: {\n\timage: string\n\tport: int\n}\noutput: {\n\timage: parameter.image\n}\n"
+			_, isNew, err = GenerateDefinitionRevision(ctx, cli, changed, NormalizeCUETemplates(normalize))
+			require.NoError(t, err)
+			require.True(t, isNew)
+		})
+	}
+}
+
+func TestCleanUpDefinitionRevisionPinnedByApplication(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cd.Status.LatestRevision = &common.Revision{Name: "worker-v3", Revision: 3}
+	objs := []client.Object{cd}
+	for i := 1; i <= 3; i++ {
+		objs = append(objs, &v1beta1.DefinitionRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("worker-v%d", i),
+				Namespace: cd.Namespace,
+				Labels:    map[string]string{oam.LabelComponentDefinitionName: cd.Name},
+			},
+			Spec: v1beta1.DefinitionRevisionSpec{Revision: int64(i), DefinitionType: common.ComponentType},
+		})
+	}
+	objs = append(objs, &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: cd.Namespace},
+		Spec:       v1beta1.ApplicationSpec{Components: []common.ApplicationComponent{{Name: "comp", Type: "worker@v2"}}},
+	})
+	cli := newTestClient(objs...)
+
+	require.NoError(t, CleanUpDefinitionRevision(ctx, cli, cd, 1))
+	revs := new(v1beta1.DefinitionRevisionList)
+	require.NoError(t, cli.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	var names []string
+	for _, rev := range revs.Items {
+		names = append(names, rev.Name)
+	}
+	require.ElementsMatch(t, []string{"worker-v2", "worker-v3"}, names)
+}
+
+func TestCleanUpAllDefinitionRevisions(t *testing.T) {
+	ctx := context.Background()
+	var objs []client.Object
+	// the definitions with 1, 3 and 5 revisions, the latest revision is always the last one
+	for name, count := range map[string]int{"single": 1, "few": 3, "many": 5, "annotated": 5} {
+		cd := newTestComponentDefinition(name, "output: {}")
+		cd.Status.LatestRevision = &common.Revision{Name: fmt.Sprintf("%s-v%d", name, count), Revision: int64(count)}
+		if name == "annotated" {
+			cd.Annotations = map[string]string{oam.AnnotationDefinitionRevisionLimit: "3"}
+		}
+		objs = append(objs, cd)
+		for i := 1; i <= count; i++ {
+			objs = append(objs, &v1beta1.DefinitionRevision{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("%s-v%d", name, i),
+					Namespace: cd.Namespace,
+					Labels:    map[string]string{oam.LabelComponentDefinitionName: name},
+				},
+				Spec: v1beta1.DefinitionRevisionSpec{Revision: int64(i), DefinitionType: common.ComponentType},
+			})
+		}
+	}
+	objs = append(objs, &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       v1beta1.ApplicationSpec{Components: []common.ApplicationComponent{{Name: "comp", Type: "many@v1"}}},
+	})
+	cli := newTestClient(objs...)
+
+	report, err := CleanUpAllDefinitionRevisions(ctx, cli, 1)
+	require.NoError(t, err)
+	require.Empty(t, report.Failed)
+	require.Equal(t, map[string][]string{
+		"default/few": {"few-v1"},
+		// the revision referenced by the application is kept
+		"default/many":      {"many-v2", "many-v3", "many-v4"},
+		"default/annotated": {"annotated-v1"},
+	}, report.Collected)
+	require.Equal(t, 5, report.Total())
+
+	revs := new(v1beta1.DefinitionRevisionList)
+	require.NoError(t, cli.List(ctx, revs, client.InNamespace("default"), client.MatchingLabels{oam.LabelComponentDefinitionName: "many"}))
+	var names []string
+	for _, rev := range revs.Items {
+		names = append(names, rev.Name)
+	}
+	require.ElementsMatch(t, []string{"many-v1", "many-v5"}, names)
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: "default", Name: "many"}, got))
+	require.Equal(t, int64(3), got.Status.CollectedRevisions)
+
+	// nothing is left to collect
+	report, err = CleanUpAllDefinitionRevisions(ctx, cli, 1)
+	require.NoError(t, err)
+	require.Zero(t, report.Total())
+
+	// the referenced revision is collected without the usage based retention
+	report, err = CleanUpAllDefinitionRevisions(ctx, cli, 0, RevisionGCByUsage(false))
+	require.NoError(t, err)
+	require.Equal(t, []string{"many-v1"}, report.Collected["default/many"])
+}
+
+func TestRevisionGCMaintenanceWindows(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cd.Status.LatestRevision = &common.Revision{Name: "worker-v3", Revision: 3}
+	objs := []client.Object{cd}
+	for i := 1; i <= 3; i++ {
+		objs = append(objs, &v1beta1.DefinitionRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("worker-v%d", i),
+				Namespace: cd.Namespace,
+				Labels:    map[string]string{oam.LabelComponentDefinitionName: cd.Name},
+			},
+			Spec: v1beta1.DefinitionRevisionSpec{Revision: int64(i), DefinitionType: common.ComponentType, ComponentDefinition: *cd},
+		})
+	}
+	cli := newTestClient(objs...)
+	countRevisions := func() int {
+		revs := new(v1beta1.DefinitionRevisionList)
+		require.NoError(t, cli.List(ctx, revs, client.InNamespace(cd.Namespace)))
+		return len(revs.Items)
+	}
+	now := time.Now()
+	open := RevisionGCMaintenanceWindows{{Start: now.Add(-time.Hour), End: now.Add(time.Hour)}}
+	closed := RevisionGCMaintenanceWindows{{Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}}
+
+	// nothing is collected inside the window
+	require.NoError(t, CleanUpDefinitionRevision(ctx, cli, cd, 1, open))
+	report, err := CleanUpAllDefinitionRevisions(ctx, cli, 1, open)
+	require.NoError(t, err)
+	require.Zero(t, report.Total())
+	recorder := record.NewFakeRecorder(10)
+	_, _, err = ReconcileDefinitionRevision(ctx, cli, event.NewAPIRecorder(recorder), cd, 1, func(revision *common.Revision) error {
+		cd.Status.LatestRevision = revision
+		return cli.Status().Update(ctx, cd)
+	}, open)
+	require.NoError(t, err)
+	// the new revision is still created
+	require.Equal(t, 4, countRevisions())
+	require.Len(t, recorder.Events, 1)
+	require.Contains(t, <-recorder.Events, "the garbage collection is deferred until the maintenance window closes at "+open[0].End.Format(time.RFC3339))
+
+	// the collection runs once the window closes
+	require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(cd), cd))
+	_, _, err = ReconcileDefinitionRevision(ctx, cli, event.NewAPIRecorder(recorder), cd, 1, func(revision *common.Revision) error {
+		cd.Status.LatestRevision = revision
+		return cli.Status().Update(ctx, cd)
+	}, closed)
+	require.NoError(t, err)
+	require.Equal(t, 2, countRevisions())
+	require.Contains(t, <-recorder.Events, "deleted 2 DefinitionRevisions: worker-v1, worker-v2")
+}
+
+func TestRevisionGCGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cd.Status.LatestRevision = &common.Revision{Name: "worker-v3", Revision: 3}
+	objs := []client.Object{cd}
+	for i, age := range []time.Duration{2 * time.Hour, 10 * time.Minute, time.Minute} {
+		objs = append(objs, &v1beta1.DefinitionRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              fmt.Sprintf("worker-v%d", i+1),
+				Namespace:         cd.Namespace,
+				Labels:            map[string]string{oam.LabelComponentDefinitionName: cd.Name},
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Spec: v1beta1.DefinitionRevisionSpec{Revision: int64(i + 1), DefinitionType: common.ComponentType, ComponentDefinition: *cd},
+		})
+	}
+	cli := newTestClient(objs...)
+	listRevisions := func() []v1beta1.DefinitionRevision {
+		revs := new(v1beta1.DefinitionRevisionList)
+		require.NoError(t, cli.List(ctx, revs, client.InNamespace(cd.Namespace)))
+		return revs.Items
+	}
+	revisionNames := func() []string {
+		var names []string
+		for _, rev := range listRevisions() {
+			names = append(names, rev.Name)
+		}
+		return names
+	}
+	gracePeriod := RevisionGCGracePeriod(time.Hour)
+
+	// the recent revision over the limit is retained
+	require.NoError(t, CleanUpDefinitionRevision(ctx, cli, cd, 0, gracePeriod))
+	require.ElementsMatch(t, []string{"worker-v2", "worker-v3"}, revisionNames())
+	next, ok := gracePeriod.NextCollection(listRevisions(), 0, "worker-v3", now)
+	require.True(t, ok)
+	require.WithinDuration(t, now.Add(50*time.Minute), next, time.Second)
+	// nothing is waiting for the grace period within the limit
+	_, ok = gracePeriod.NextCollection(listRevisions(), 1, "worker-v3", now)
+	require.False(t, ok)
+
+	// the revision is collected once it ages past the grace period
+	require.NoError(t, CleanUpDefinitionRevision(ctx, cli, cd, 0, RevisionGCGracePeriod(5*time.Minute)))
+	require.ElementsMatch(t, []string{"worker-v3"}, revisionNames())
+	_, ok = gracePeriod.NextCollection(listRevisions(), 0, "worker-v3", now)
+	require.False(t, ok)
+}
+
+func TestRevisionHashCollision(t *testing.T) {
+	ctx := context.Background()
+	collidingHasher := RevisionHasher(func(interface{}) (string, error) { return "collide", nil })
+	for _, strategy := range []RevisionNamingStrategy{RevisionNamingSequential, RevisionNamingHashBased} {
+		t.Run(string(strategy), func(t *testing.T) {
+			cd := newTestComponentDefinition("worker", "output: {}")
+			cli := newTestClient(cd)
+			reconcile := func() *v1beta1.DefinitionRevision {
+				defRev, result, err := ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+					cd.Status.LatestRevision = revision
+					return cli.Status().Update(ctx, cd)
+				}, strategy, collidingHasher)
+				require.NoError(t, err)
+				require.Nil(t, result)
+				return defRev
+			}
+
+			first := reconcile()
+			require.Empty(t, first.Annotations[oam.AnnotationRevisionHashCollision])
+			require.NotEqual(t, corev1.ConditionTrue, cd.GetCondition(TypeRevisionHashCollision).Status)
+
+			cd.Spec.Schematic.CUE.Template = "output: {kind: \"Deployment\"}"
+			require.NoError(t, cli.Update(ctx, cd))
+			second := reconcile()
+			require.NotEqual(t, first.Name, second.Name)
+			require.Equal(t, first.Spec.RevisionHash, second.Spec.RevisionHash)
+			require.Equal(t, int64(2), second.Spec.Revision)
+
+			revs := new(v1beta1.DefinitionRevisionList)
+			require.NoError(t, cli.List(ctx, revs, client.InNamespace(cd.Namespace)))
+			require.Len(t, revs.Items, 2)
+			stored := &v1beta1.DefinitionRevision{}
+			require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: second.Name}, stored))
+			require.Equal(t, first.Name, stored.Annotations[oam.AnnotationRevisionHashCollision])
+			require.Equal(t, "output: {kind: \"Deployment\"}", stored.Spec.ComponentDefinition.Spec.Schematic.CUE.Template)
+
+			got := &v1beta1.ComponentDefinition{}
+			require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(cd), got))
+			collision := got.GetCondition(TypeRevisionHashCollision)
+			require.Equal(t, corev1.ConditionTrue, collision.Status)
+			require.Contains(t, collision.Message, first.Name)
+		})
+	}
+}
+
+func TestDefinitionRevisionLimitAnnotation(t *testing.T) {
+	ctx := context.Background()
+	newDefinition := func(limit string) (*v1beta1.ComponentDefinition, client.Client) {
+		cd := newTestComponentDefinition("worker", "output: {}")
+		if limit != "" {
+			cd.Annotations = map[string]string{oam.AnnotationDefinitionRevisionLimit: limit}
+		}
+		cd.Status.LatestRevision = &common.Revision{Name: "worker-v5", Revision: 5}
+		objs := []client.Object{cd}
+		for i := 1; i <= 5; i++ {
+			objs = append(objs, &v1beta1.DefinitionRevision{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("worker-v%d", i),
+					Namespace: cd.Namespace,
+					Labels:    map[string]string{oam.LabelComponentDefinitionName: cd.Name},
+				},
+				Spec: v1beta1.DefinitionRevisionSpec{Revision: int64(i), DefinitionType: common.ComponentType},
+			})
+		}
+		return cd, newTestClient(objs...)
+	}
+	countRevisions := func(cli client.Client) int {
+		revs := new(v1beta1.DefinitionRevisionList)
+		require.NoError(t, cli.List(ctx, revs))
+		return len(revs.Items)
+	}
+
+	cd, cli := newDefinition("")
+	require.NoError(t, CleanUpDefinitionRevision(ctx, cli, cd, 1))
+	require.Equal(t, 2, countRevisions(cli))
+
+	cd, cli = newDefinition("3")
+	require.NoError(t, CleanUpDefinitionRevision(ctx, cli, cd, 1))
+	require.Equal(t, 4, countRevisions(cli))
+
+	cd, cli = newDefinition("0")
+	require.NoError(t, CleanUpDefinitionRevision(ctx, cli, cd, 3))
+	require.Equal(t, 1, countRevisions(cli))
+
+	for _, malformed := range []string{"-1", "ten"} {
+		cd, _ = newDefinition(malformed)
+		limit, err := GetDefinitionRevisionLimit(cd, 2)
+		require.Error(t, err)
+		require.Equal(t, 2, limit)
+
+		cd.Status.LatestRevision = nil
+		cd.Spec.Schematic.CUE.Template = "output: {kind: \"Deployment\"}"
+		cli = newTestClient(cd)
+		recorder := record.NewFakeRecorder(10)
+		_, _, err = ReconcileDefinitionRevision(ctx, cli, event.NewAPIRecorder(recorder), cd, 2, func(revision *common.Revision) error {
+			cd.Status.LatestRevision = revision
+			return cli.Status().Update(ctx, cd)
+		})
+		require.NoError(t, err)
+		require.Len(t, recorder.Events, 1)
+		require.Contains(t, <-recorder.Events, oam.AnnotationDefinitionRevisionLimit)
+	}
+}
+
+// deleteFailingClient fails to delete the objects of the given name
+type deleteFailingClient struct {
+	client.Client
+	failOn string
+}
+
+func (c *deleteFailingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if obj.GetName() == c.failOn {
+		return fmt.Errorf("failed to delete %s", obj.GetName())
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func TestCleanUpDefinitionRevisionAggregateErrors(t *testing.T) {
+	ctx := context.Background()
+	newDefinition := func() (*v1beta1.ComponentDefinition, client.Client) {
+		cd := newTestComponentDefinition("worker", "output: {}")
+		cd.Status.LatestRevision = &common.Revision{Name: "worker-v6", Revision: 6}
+		objs := []client.Object{cd}
+		for i := 1; i <= 6; i++ {
+			objs = append(objs, &v1beta1.DefinitionRevision{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("worker-v%d", i),
+					Namespace: cd.Namespace,
+					Labels:    map[string]string{oam.LabelComponentDefinitionName: cd.Name},
+				},
+				Spec: v1beta1.DefinitionRevisionSpec{Revision: int64(i), DefinitionType: common.ComponentType},
+			})
+		}
+		return cd, &deleteFailingClient{Client: newTestClient(objs...), failOn: "worker-v2"}
+	}
+
+	cd, cli := newDefinition()
+	collected, err := cleanUpDefinitionRevision(ctx, cli, cd, 1, newDefinitionRevisionConfig())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot delete DefinitionRevision worker-v2")
+	require.Equal(t, []string{"worker-v1", "worker-v3", "worker-v4"}, collected)
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: "worker-v5"}, &v1beta1.DefinitionRevision{}))
+
+	cd, cli = newDefinition()
+	recorder := record.NewFakeRecorder(10)
+	_, _, err = ReconcileDefinitionRevision(ctx, cli, event.NewAPIRecorder(recorder), cd, 1, func(revision *common.Revision) error {
+		cd.Status.LatestRevision = revision
+		return cli.Status().Update(ctx, cd)
+	})
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 2)
+	require.Contains(t, <-recorder.Events, "deleted 4 DefinitionRevisions: worker-v1, worker-v3, worker-v4, worker-v5")
+	require.Contains(t, <-recorder.Events, "cannot delete DefinitionRevision worker-v2")
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.Equal(t, int64(4), got.Status.CollectedRevisions)
+}
+
+// concurrentCreateClient simulates another reconcile which creates the rival DefinitionRevision right after
+// the DefinitionRevision is found absent, and conflicts the first update
+type concurrentCreateClient struct {
+	client.Client
+	rival     *v1beta1.DefinitionRevision
+	conflicts int
+}
+
+func (c *concurrentCreateClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if c.rival != nil && key.Name == c.rival.Name {
+		if err := c.Client.Create(ctx, c.rival); err != nil {
+			return err
+		}
+		c.rival = nil
+		return apierrors.NewNotFound(v1beta1.SchemeGroupVersion.WithResource("definitionrevisions").GroupResource(), key.Name)
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *concurrentCreateClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if c.conflicts > 0 {
+		c.conflicts--
+		return apierrors.NewConflict(v1beta1.SchemeGroupVersion.WithResource("definitionrevisions").GroupResource(), obj.GetName(), errors.New("modified"))
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestCreateDefinitionRevisionConcurrently(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	defRev, _, err := GenerateDefinitionRevision(ctx, newTestClient(cd), cd)
+	require.NoError(t, err)
+
+	rival := defRev.DeepCopy()
+	rival.Namespace = cd.Namespace
+	rival.Spec.ComponentDefinition.Spec.Schematic.CUE.Template = "output: kind: \"Deployment\""
+	cli := &concurrentCreateClient{Client: newTestClient(cd), rival: rival, conflicts: 1}
+
+	recorder := record.NewFakeRecorder(10)
+	got, _, err := ReconcileDefinitionRevision(ctx, cli, event.NewAPIRecorder(recorder), cd, 10, func(revision *common.Revision) error {
+		cd.Status.LatestRevision = revision
+		return cli.Status().Update(ctx, cd)
+	})
+	require.NoError(t, err)
+	require.Equal(t, defRev.Name, got.Name)
+	require.Len(t, recorder.Events, 0)
+	require.Equal(t, 0, cli.conflicts)
+
+	stored := &v1beta1.DefinitionRevision{}
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: defRev.Name}, stored))
+	require.Equal(t, "output: {}", stored.Spec.ComponentDefinition.Spec.Schematic.CUE.Template)
+	require.Equal(t, cd.Name, stored.Labels[oam.LabelComponentDefinitionName])
+}
+
+func TestIgnoredMetadataPrefixes(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cli := newTestClient(cd)
+	ignored := IgnoredMetadataPrefixes{"argocd.argoproj.io/", "kubectl.kubernetes.io/last-applied-configuration"}
+	reconcile := func() *v1beta1.DefinitionRevision {
+		defRev, _, err := ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+			cd.Status.LatestRevision = revision
+			return cli.Status().Update(ctx, cd)
+		}, ignored)
+		require.NoError(t, err)
+		require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(cd), cd))
+		return defRev
+	}
+	require.Equal(t, "worker-v1", reconcile().Name)
+
+	cd.Annotations = map[string]string{
+		"argocd.argoproj.io/sync-wave":                     "1",
+		"kubectl.kubernetes.io/last-applied-configuration": "{}",
+		"description": "worker",
+	}
+	cd.Labels = map[string]string{"argocd.argoproj.io/instance": "defs"}
+	require.NoError(t, cli.Update(ctx, cd))
+	defRev := reconcile()
+	require.Equal(t, "worker-v1", defRev.Name)
+	require.Equal(t, map[string]string{"description": "worker"}, defRev.Spec.ComponentDefinition.Annotations)
+	require.Empty(t, defRev.Spec.ComponentDefinition.Labels)
+
+	cd.Spec.Schematic.CUE.Template = "output: kind: \"Deployment\""
+	require.NoError(t, cli.Update(ctx, cd))
+	defRev = reconcile()
+	require.Equal(t, "worker-v2", defRev.Name)
+	stored := &v1beta1.DefinitionRevision{}
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: defRev.Name}, stored))
+	require.Equal(t, map[string]string{"description": "worker"}, stored.Spec.ComponentDefinition.Annotations)
+}
+
+func TestSchematicTypeLabel(t *testing.T) {
+	testCases := map[string]struct {
+		schematic *common.Schematic
+		expected  string
+	}{
+		"cue": {
+			schematic: &common.Schematic{CUE: &common.CUE{Template: "output: {}"}},
+			expected:  "cue",
+		},
+		"terraform": {
+			schematic: &common.Schematic{Terraform: &common.Terraform{Configuration: `variable "name" {}`}},
+			expected:  "terraform",
+		},
+		"jsonschema": {
+			schematic: &common.Schematic{JSONSchema: &common.JSONSchema{Schema: `{"type": "object"}`}},
+			expected:  "jsonschema",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cd := newTestComponentDefinition("worker", "")
+			cd.Spec.Schematic = tc.schematic
+			cli := newTestClient(cd)
+			reconcile := func() *v1beta1.DefinitionRevision {
+				defRev, _, err := ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+					cd.Status.LatestRevision = revision
+					return cli.Status().Update(ctx, cd)
+				})
+				require.NoError(t, err)
+				stored := &v1beta1.DefinitionRevision{}
+				require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: defRev.Name}, stored))
+				return stored
+			}
+			stored := reconcile()
+			require.Equal(t, tc.expected, stored.Labels[oam.LabelDefinitionSchematicType])
+
+			// the label is added to the existing revision created without it
+			delete(stored.Labels, oam.LabelDefinitionSchematicType)
+			require.NoError(t, cli.Update(ctx, stored))
+			require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(cd), cd))
+			stored = reconcile()
+			require.Equal(t, "worker-v1", stored.Name)
+			require.Equal(t, tc.expected, stored.Labels[oam.LabelDefinitionSchematicType])
+		})
+	}
+}
+
+func TestFamilyLabel(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cd.Labels = map[string]string{oam.LabelDefinitionFamily: "database"}
+	cli := newTestClient(cd)
+	reconcile := func() *v1beta1.DefinitionRevision {
+		defRev, _, err := ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+			cd.Status.LatestRevision = revision
+			return cli.Status().Update(ctx, cd)
+		})
+		require.NoError(t, err)
+		require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(cd), cd))
+		stored := &v1beta1.DefinitionRevision{}
+		require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: defRev.Name}, stored))
+		return stored
+	}
+	stored := reconcile()
+	require.Equal(t, "database", stored.Labels[oam.LabelDefinitionFamily])
+
+	// changing the family in place doesn't generate a new revision, but the label of the revision follows it
+	cd.Labels[oam.LabelDefinitionFamily] = "gateway"
+	require.NoError(t, cli.Update(ctx, cd))
+	stored = reconcile()
+	require.Equal(t, "worker-v1", stored.Name)
+	require.Equal(t, "gateway", stored.Labels[oam.LabelDefinitionFamily])
+
+	revs := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, cli.List(ctx, revs, client.MatchingLabels{oam.LabelDefinitionFamily: "gateway"}))
+	require.Len(t, revs.Items, 1)
+}
+
+func TestRevisionGeneratedBy(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cli := newTestClient(cd)
+	reconcile := func(options ...DefinitionRevisionOption) *v1beta1.DefinitionRevision {
+		defRev, _, err := ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+			cd.Status.LatestRevision = revision
+			return cli.Status().Update(ctx, cd)
+		}, options...)
+		require.NoError(t, err)
+		require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(cd), cd))
+		stored := &v1beta1.DefinitionRevision{}
+		require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: defRev.Name}, stored))
+		return stored
+	}
+	stored := reconcile(RevisionGeneratedBy("v1.9.0"))
+	require.Equal(t, "v1.9.0", stored.Annotations[oam.AnnotationDefinitionGeneratedBy])
+	require.Equal(t, "v1.9.0", stored.Annotations[oam.AnnotationRevisionControllerVersion])
+
+	// the schema of the latest revision is regenerated by the new controller, while the provenance is kept
+	stored = reconcile(RevisionGeneratedBy("v1.10.0"))
+	require.Equal(t, "worker-v1", stored.Name)
+	require.Equal(t, "v1.10.0", stored.Annotations[oam.AnnotationDefinitionGeneratedBy])
+	require.Equal(t, "v1.9.0", stored.Annotations[oam.AnnotationRevisionControllerVersion])
+
+	stored = reconcile()
+	require.Equal(t, "v1.10.0", stored.Annotations[oam.AnnotationDefinitionGeneratedBy])
+	require.Equal(t, "v1.9.0", stored.Annotations[oam.AnnotationRevisionControllerVersion])
+}
+
+func TestRevisionProvenance(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cd.Generation = 3
+	cd.Labels = map[string]string{"app.kubernetes.io/managed-by": "argocd"}
+	cd.Annotations = map[string]string{
+		oam.AnnotationApplicationUsername: "alice",
+		oam.AnnotationResourceURL:         "https://github.com/example/definitions/blob/main/worker.cue",
+		"unrelated":                       "value",
+	}
+	earlier, later := metav1.NewTime(time.Now().Add(-time.Hour)), metav1.Now()
+	cd.ManagedFields = []metav1.ManagedFieldsEntry{
+		{Manager: "kubectl-client-side-apply", Operation: metav1.ManagedFieldsOperationUpdate, Time: &earlier},
+		{Manager: "argocd-controller", Operation: metav1.ManagedFieldsOperationApply, Time: &later},
+		{Manager: "kubevela", Operation: metav1.ManagedFieldsOperationUpdate, Time: &later, Subresource: "status"},
+	}
+	cli := newTestClient(cd)
+
+	before := time.Now().Add(-time.Second)
+	defRev, _, err := ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+		cd.Status.LatestRevision = revision
+		return cli.Status().Update(ctx, cd)
+	})
+	require.NoError(t, err)
+	stored := &v1beta1.DefinitionRevision{}
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: defRev.Name}, stored))
+
+	require.Equal(t, "argocd", stored.Labels["app.kubernetes.io/managed-by"])
+	require.Equal(t, "alice", stored.Annotations[oam.AnnotationApplicationUsername])
+	require.Equal(t, cd.Annotations[oam.AnnotationResourceURL], stored.Annotations[oam.AnnotationResourceURL])
+	require.NotContains(t, stored.Annotations, "unrelated")
+	require.Equal(t, "3", stored.Annotations[oam.AnnotationRevisionSourceGeneration])
+	require.Equal(t, "argocd-controller", stored.Annotations[oam.AnnotationRevisionFieldManager])
+	require.Equal(t, version.VelaVersion, stored.Annotations[oam.AnnotationRevisionControllerVersion])
+	createdAt, err := time.Parse(time.RFC3339, stored.Annotations[oam.AnnotationRevisionCreatedAt])
+	require.NoError(t, err)
+	require.False(t, createdAt.Before(before.Truncate(time.Second)))
+}
+
+func TestRevisionGCByUsage(t *testing.T) {
+	testCases := map[string]struct {
+		gcByUsage bool
+		expected  []string
+	}{
+		"keep the pinned revision": {gcByUsage: true, expected: []string{"worker-v2", "worker-v3"}},
+		"ignore the usage":         {gcByUsage: false, expected: []string{"worker-v3"}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cd := newTestComponentDefinition("worker", "output: {}")
+			cd.Status.LatestRevision = &common.Revision{Name: "worker-v3", Revision: 3}
+			objs := []client.Object{cd, &v1beta1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: cd.Namespace},
+				Spec:       v1beta1.ApplicationSpec{Components: []common.ApplicationComponent{{Name: "comp", Type: "worker@v2"}}},
+			}}
+			for i := 1; i <= 3; i++ {
+				objs = append(objs, &v1beta1.DefinitionRevision{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      fmt.Sprintf("worker-v%d", i),
+						Namespace: cd.Namespace,
+						Labels:    map[string]string{oam.LabelComponentDefinitionName: cd.Name},
+					},
+					Spec: v1beta1.DefinitionRevisionSpec{Revision: int64(i), DefinitionType: common.ComponentType},
+				})
+			}
+			cli := newTestClient(objs...)
+
+			_, err := cleanUpDefinitionRevision(ctx, cli, cd, 0, newDefinitionRevisionConfig(RevisionGCByUsage(tc.gcByUsage)))
+			require.NoError(t, err)
+			revs := new(v1beta1.DefinitionRevisionList)
+			require.NoError(t, cli.List(ctx, revs, client.InNamespace(cd.Namespace)))
+			var names []string
+			for _, rev := range revs.Items {
+				names = append(names, rev.Name)
+			}
+			require.ElementsMatch(t, tc.expected, names)
+		})
+	}
+}
+
+func TestReconstructMissingLatestRevision(t *testing.T) {
+	ctx := context.Background()
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cli := newTestClient(cd)
+	updateLatestRevision := func(revision *common.Revision) error {
+		cd.Status.LatestRevision = revision
+		return cli.Status().Update(ctx, cd)
+	}
+	defRev, _, err := ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, updateLatestRevision)
+	require.NoError(t, err)
+	require.Equal(t, "worker-v1", defRev.Name)
+	require.NoError(t, cli.Delete(ctx, &v1beta1.DefinitionRevision{
+		ObjectMeta: metav1.ObjectMeta{Namespace: cd.Namespace, Name: defRev.Name}}))
+
+	recorder := record.NewFakeRecorder(10)
+	defRev, _, err = ReconcileDefinitionRevision(ctx, cli, event.NewAPIRecorder(recorder), cd, 10, updateLatestRevision)
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	require.Contains(t, <-recorder.Events, "the latest DefinitionRevision worker-v1 is missing")
+	stored := &v1beta1.DefinitionRevision{}
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: "worker-v1"}, stored))
+	require.Equal(t, int64(1), stored.Spec.Revision)
+	require.Equal(t, "true", stored.Annotations[oam.AnnotationRevisionReconstructed])
+	require.Equal(t, &common.Revision{Name: "worker-v1", Revision: 1, RevisionHash: defRev.Spec.RevisionHash}, cd.Status.LatestRevision)
+
+	revs := new(v1beta1.DefinitionRevisionList)
+	require.NoError(t, cli.List(ctx, revs, client.InNamespace(cd.Namespace)))
+	require.Len(t, revs.Items, 1)
+}
+
+func TestRecreateDefinitionWithSameName(t *testing.T) {
+	testCases := map[string]struct {
+		namingStrategy RevisionNamingStrategy
+	}{
+		"sequential": {namingStrategy: RevisionNamingSequential},
+		"hash-based": {namingStrategy: RevisionNamingHashBased},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cli := newTestClient()
+			reconcile := func(cd *v1beta1.ComponentDefinition) *v1beta1.DefinitionRevision {
+				defRev, _, err := ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+					cd.Status.LatestRevision = revision
+					return cli.Status().Update(ctx, cd)
+				}, tc.namingStrategy)
+				require.NoError(t, err)
+				return defRev
+			}
+
+			former := newTestComponentDefinition("worker", "output: {}")
+			former.UID = "former-uid"
+			require.NoError(t, cli.Create(ctx, former))
+			var formerRevisions []string
+			for _, template := range []string{"output: {}", "output: {kind: \"Job\"}", "output: {kind: \"Deployment\"}"} {
+				former.Spec.Schematic.CUE.Template = template
+				require.NoError(t, cli.Update(ctx, former))
+				formerRevisions = append(formerRevisions, reconcile(former).Name)
+			}
+			require.NoError(t, cli.Delete(ctx, former))
+
+			// the definition is recreated before the revisions of the former one are collected
+			cd := newTestComponentDefinition("worker", "output: {}")
+			cd.UID = "new-uid"
+			require.NoError(t, cli.Create(ctx, cd))
+			defRev := reconcile(cd)
+			require.Equal(t, formerRevisions[0], defRev.Name)
+			require.Equal(t, int64(1), cd.Status.LatestRevision.Revision)
+
+			revs := new(v1beta1.DefinitionRevisionList)
+			require.NoError(t, cli.List(ctx, revs, client.InNamespace(cd.Namespace)))
+			require.Len(t, revs.Items, 1)
+			stored := revs.Items[0]
+			require.Equal(t, defRev.Name, stored.Name)
+			require.Equal(t, "new-uid", stored.Labels[oam.LabelDefinitionUID])
+			require.Equal(t, types.UID("new-uid"), stored.Spec.ComponentDefinition.UID)
+		})
+	}
+}
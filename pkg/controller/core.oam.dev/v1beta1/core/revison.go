@@ -19,16 +19,23 @@ package core
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -39,35 +46,169 @@ import (
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	velaerrors "github.com/oam-dev/kubevela/pkg/utils/errors"
+)
+
+const (
+	// TypeRevisionHashCollision indicates whether the latest DefinitionRevision has the same revision hash
+	// with an existing DefinitionRevision of different spec
+	TypeRevisionHashCollision condition.ConditionType = "RevisionHashCollision"
+	// ReasonRevisionHashCollision is the reason of the RevisionHashCollision condition when hash collides
+	ReasonRevisionHashCollision condition.ConditionReason = "HashCollision"
+	// ReasonNoRevisionHashCollision is the reason of the RevisionHashCollision condition when hash doesn't collide
+	ReasonNoRevisionHashCollision condition.ConditionReason = "NoHashCollision"
+	// TypeSchemaReady indicates whether the OpenAPI schema of the definition parameters is stored and available
+	TypeSchemaReady = "SchemaReady"
+	// TypeTerraformVariablesValid indicates whether the parameters of a Terraform definition match its variables
+	TypeTerraformVariablesValid = "TerraformVariablesValid"
+	// TypeSchemaGenerationWithinBudget indicates whether the schema of a CUE definition is generated within the time budget
+	TypeSchemaGenerationWithinBudget = "SchemaGenerationWithinBudget"
+	// TypeRevisionFrozen indicates whether the generation of new DefinitionRevisions is frozen
+	TypeRevisionFrozen condition.ConditionType = "RevisionFrozen"
+	// ReasonRevisionFrozen is the reason of the RevisionFrozen condition when the revision is frozen
+	ReasonRevisionFrozen condition.ConditionReason = "Frozen"
+	// ReasonRevisionUnfrozen is the reason of the RevisionFrozen condition when the freeze is lifted
+	ReasonRevisionUnfrozen condition.ConditionReason = "Unfrozen"
+	// TypeSchemaSizeWithinThreshold indicates whether the size of the schema of the definition is below the warning threshold
+	TypeSchemaSizeWithinThreshold = "SchemaSizeWithinThreshold"
+	// TypePolicyCompliant indicates whether the rendered output of the definition complies with the definition policies
+	TypePolicyCompliant = "PolicyCompliant"
+	// TypeStatusTemplateValid indicates whether the health policy and custom status templates of the definition compile
+	TypeStatusTemplateValid = "StatusTemplateValid"
+	// TypeTerraformModulesReachable indicates whether the remote modules called by a Terraform definition are reachable
+	TypeTerraformModulesReachable = "TerraformModulesReachable"
+	// TypeAliasAccepted indicates whether the alias of the definition is unique in its namespace
+	TypeAliasAccepted = "AliasAccepted"
+	// TypeCircuitOpen indicates whether the reconcile of the definition is backed off after consecutive failures
+	TypeCircuitOpen condition.ConditionType = "CircuitOpen"
+	// ReasonCircuitOpen is the reason of the CircuitOpen condition when the consecutive failures reach the threshold
+	ReasonCircuitOpen condition.ConditionReason = "ConsecutiveFailures"
+	// ReasonCircuitClosed is the reason of the CircuitOpen condition when the definition is reconciled normally again
+	ReasonCircuitClosed condition.ConditionReason = "Closed"
+	// TypeRolledBack indicates whether the last rollback requested by oam.AnnotationDefinitionRollbackTo is done
+	TypeRolledBack condition.ConditionType = "RolledBack"
+	// ReasonRolledBack is the reason of the RolledBack condition when the spec is rolled back to the revision
+	ReasonRolledBack condition.ConditionReason = "RolledBack"
+	// ReasonInvalidRollbackTarget is the reason of the RolledBack condition when the requested revision is not a
+	// revision of the definition
+	ReasonInvalidRollbackTarget condition.ConditionReason = "InvalidTarget"
+	// ReasonRollbackWithdrawn is the reason of the RolledBack condition when the rollback to an invalid target is
+	// withdrawn by removing the annotation
+	ReasonRollbackWithdrawn condition.ConditionReason = "Withdrawn"
 )
 
 // GenerateDefinitionRevision will generate a definition revision the generated revision
 // will be compare with the last revision to see if there's any difference.
-func GenerateDefinitionRevision(ctx context.Context, cli client.Client, def runtime.Object) (*v1beta1.DefinitionRevision, bool, error) {
+func GenerateDefinitionRevision(ctx context.Context, cli client.Client, def runtime.Object, options ...DefinitionRevisionOption) (*v1beta1.DefinitionRevision, bool, error) {
+	cfg := newDefinitionRevisionConfig(options...)
 	isNamedRev, defRevNamespacedName, err := isNamedRevision(def)
 	if err != nil {
 		return nil, false, err
 	}
 	if isNamedRev {
-		return generateNamedDefinitionRevision(ctx, cli, def, defRevNamespacedName)
+		return generateNamedDefinitionRevision(ctx, cli, def, defRevNamespacedName, cfg)
 	}
 
-	defRev, lastRevision, err := GatherRevisionInfo(def)
+	defRev, lastRevision, err := gatherRevisionInfo(def, cfg)
 	if err != nil {
 		return defRev, false, err
 	}
-	isNewRev, err := compareWithLastDefRevisionSpec(ctx, cli, defRev, lastRevision)
+	isNewRev, err := compareWithLastDefRevisionSpec(ctx, cli, defRev, lastRevision, cfg)
 	if err != nil {
 		return defRev, isNewRev, err
 	}
-	if isNewRev {
+	if isNewRev && !isRevisionReconstructed(defRev) {
 		defRevName, revNum := getDefNextRevision(defRev, lastRevision)
 		defRev.Name = defRevName
 		defRev.Spec.Revision = revNum
+		if cfg.namingStrategy == RevisionNamingHashBased {
+			if err = nameDefRevisionByHash(ctx, cli, defRev, cfg); err != nil {
+				return defRev, isNewRev, err
+			}
+		}
 	}
 	return defRev, isNewRev, nil
 }
 
+// nameDefRevisionByHash names the DefinitionRevision with the revision hash. If the definition is changed back to
+// the content of an existing revision, the existing revision is reused with the next revision number, which is
+// recorded along with its former numbers so that the Applications pinning any of them keep resolving to it. If an
+// existing revision has the same hash but a different spec, the hash collides and the name is disambiguated with a
+// numeric suffix.
+func nameDefRevisionByHash(ctx context.Context, cli client.Client, defRev *v1beta1.DefinitionRevision, cfg *definitionRevisionConfig) error {
+	baseName := strings.Join([]string{getDefName(defRev), defRev.Spec.RevisionHash}, "-")
+	defRev.Name = baseName
+	for i := 1; ; i++ {
+		existing := &v1beta1.DefinitionRevision{}
+		err := cli.Get(ctx, client.ObjectKey{Namespace: getDefNamespace(defRev), Name: defRev.Name}, existing)
+		if apierrors.IsNotFound(err) || (err == nil && isStaleRevision(existing, getDefMeta(defRev).GetUID())) {
+			// the revision of the former definition is replaced on creation
+			setRevisionNumbers(defRev, defRev.Spec.Revision)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if cfg.equalDefRevision(existing, defRev) {
+			setRevisionNumbers(defRev, append(util.DefinitionRevisionNumbers(existing), defRev.Spec.Revision)...)
+			return nil
+		}
+		markRevisionHashCollision(defRev, existing.Name)
+		defRev.Name = fmt.Sprintf("%s-%d", baseName, i)
+	}
+}
+
+// setRevisionNumbers records the revision numbers assigned to the hash-named DefinitionRevision
+func setRevisionNumbers(defRev *v1beta1.DefinitionRevision, numbers ...int64) {
+	values := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		values = append(values, strconv.FormatInt(number, 10))
+	}
+	defRev.SetAnnotations(util.MergeMapOverrideWithDst(defRev.GetAnnotations(), map[string]string{oam.AnnotationRevisionNumbers: strings.Join(values, ",")}))
+}
+
+// isPinnedRevision tells whether the DefinitionRevision is pinned by Applications by its name or by any revision
+// number assigned to it, the latter is how the hash-named revisions are pinned
+func isPinnedRevision(defRev *v1beta1.DefinitionRevision, defName string, pinned map[string]bool) bool {
+	if pinned[defRev.Name] {
+		return true
+	}
+	for _, number := range util.DefinitionRevisionNumbers(defRev) {
+		if pinned[ConstructDefinitionRevisionName(defName, strconv.FormatInt(number, 10))] {
+			return true
+		}
+	}
+	return false
+}
+
+// isStaleRevision tells whether the DefinitionRevision is generated from a former definition of the same name, which is
+// deleted and recreated before its revisions are collected. The revisions created before the UID of the definition is
+// recorded are considered to belong to the current definition.
+func isStaleRevision(defRev *v1beta1.DefinitionRevision, uid types.UID) bool {
+	owner := defRev.GetLabels()[oam.LabelDefinitionUID]
+	return owner != "" && uid != "" && owner != string(uid)
+}
+
+// markRevisionHashCollision annotates the DefinitionRevision with the existing revision it collides with
+func markRevisionHashCollision(defRev *v1beta1.DefinitionRevision, existing string) {
+	klog.InfoS("revision hash collides with an existing definitionRevision of different spec",
+		"revisionHash", defRev.Spec.RevisionHash, "existing", existing)
+	defRev.SetAnnotations(util.MergeMapOverrideWithDst(defRev.GetAnnotations(), map[string]string{oam.AnnotationRevisionHashCollision: existing}))
+}
+
+// markRevisionReconstructed names the DefinitionRevision after the latest revision which is found missing, so that
+// the missing revision is recreated rather than superseded by a new revision of the same spec
+func markRevisionReconstructed(defRev *v1beta1.DefinitionRevision, lastRevision *common.Revision) {
+	klog.InfoS("the latest definitionRevision is missing and will be reconstructed", "definitionRevision", lastRevision.Name)
+	defRev.Name = lastRevision.Name
+	defRev.Spec.Revision = lastRevision.Revision
+	defRev.SetAnnotations(util.MergeMapOverrideWithDst(defRev.GetAnnotations(), map[string]string{oam.AnnotationRevisionReconstructed: "true"}))
+}
+
+func isRevisionReconstructed(defRev *v1beta1.DefinitionRevision) bool {
+	return defRev.GetAnnotations()[oam.AnnotationRevisionReconstructed] == "true"
+}
+
 func isNamedRevision(def runtime.Object) (bool, types.NamespacedName, error) {
 	defMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(def)
 	if err != nil {
@@ -86,7 +227,7 @@ func isNamedRevision(def runtime.Object) (bool, types.NamespacedName, error) {
 	return true, types.NamespacedName{Name: defRevName, Namespace: defNs}, nil
 }
 
-func generateNamedDefinitionRevision(ctx context.Context, cli client.Client, def runtime.Object, defRevNamespacedName types.NamespacedName) (*v1beta1.DefinitionRevision, bool, error) {
+func generateNamedDefinitionRevision(ctx context.Context, cli client.Client, def runtime.Object, defRevNamespacedName types.NamespacedName, cfg *definitionRevisionConfig) (*v1beta1.DefinitionRevision, bool, error) {
 	oldDefRev := new(v1beta1.DefinitionRevision)
 
 	// definitionRevision is immutable, if the requested definitionRevision already exists, return directly.
@@ -96,7 +237,7 @@ func generateNamedDefinitionRevision(ctx context.Context, cli client.Client, def
 	}
 
 	if apierrors.IsNotFound(err) {
-		newDefRev, lastRevision, err := GatherRevisionInfo(def)
+		newDefRev, lastRevision, err := gatherRevisionInfo(def, cfg)
 		if err != nil {
 			return newDefRev, false, err
 		}
@@ -110,6 +251,10 @@ func generateNamedDefinitionRevision(ctx context.Context, cli client.Client, def
 
 // GatherRevisionInfo gather revision information from definition
 func GatherRevisionInfo(def runtime.Object) (*v1beta1.DefinitionRevision, *common.Revision, error) {
+	return gatherRevisionInfo(def, newDefinitionRevisionConfig())
+}
+
+func gatherRevisionInfo(def runtime.Object, cfg *definitionRevisionConfig) (*v1beta1.DefinitionRevision, *common.Revision, error) {
 	defRev := &v1beta1.DefinitionRevision{}
 	var LastRevision *common.Revision
 	switch definition := def.(type) {
@@ -137,7 +282,11 @@ func GatherRevisionInfo(def runtime.Object) (*v1beta1.DefinitionRevision, *commo
 		return nil, nil, fmt.Errorf("unsupported type %v", definition)
 	}
 
-	defHash, err := computeDefinitionRevisionHash(defRev)
+	if defMeta := getDefMeta(defRev); defMeta != nil {
+		defMeta.SetLabels(cfg.stripIgnoredMetadata(defMeta.GetLabels()))
+		defMeta.SetAnnotations(cfg.stripIgnoredMetadata(defMeta.GetAnnotations()))
+	}
+	defHash, err := computeDefinitionRevisionHash(cfg.normalize(defRev), cfg.hasher)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -145,27 +294,27 @@ func GatherRevisionInfo(def runtime.Object) (*v1beta1.DefinitionRevision, *commo
 	return defRev, LastRevision, nil
 }
 
-func computeDefinitionRevisionHash(defRev *v1beta1.DefinitionRevision) (string, error) {
+func computeDefinitionRevisionHash(defRev *v1beta1.DefinitionRevision, hasher RevisionHasher) (string, error) {
 	var defHash string
 	var err error
 	switch defRev.Spec.DefinitionType {
 	case common.ComponentType:
-		defHash, err = utils.ComputeSpecHash(&defRev.Spec.ComponentDefinition.Spec)
+		defHash, err = hasher(&defRev.Spec.ComponentDefinition.Spec)
 		if err != nil {
 			return defHash, err
 		}
 	case common.TraitType:
-		defHash, err = utils.ComputeSpecHash(&defRev.Spec.TraitDefinition.Spec)
+		defHash, err = hasher(&defRev.Spec.TraitDefinition.Spec)
 		if err != nil {
 			return defHash, err
 		}
 	case common.PolicyType:
-		defHash, err = utils.ComputeSpecHash(&defRev.Spec.PolicyDefinition.Spec)
+		defHash, err = hasher(&defRev.Spec.PolicyDefinition.Spec)
 		if err != nil {
 			return defHash, err
 		}
 	case common.WorkflowStepType:
-		defHash, err = utils.ComputeSpecHash(&defRev.Spec.WorkflowStepDefinition.Spec)
+		defHash, err = hasher(&defRev.Spec.WorkflowStepDefinition.Spec)
 		if err != nil {
 			return defHash, err
 		}
@@ -174,7 +323,7 @@ func computeDefinitionRevisionHash(defRev *v1beta1.DefinitionRevision) (string,
 }
 
 func compareWithLastDefRevisionSpec(ctx context.Context, cli client.Client,
-	newDefRev *v1beta1.DefinitionRevision, lastRevision *common.Revision) (bool, error) {
+	newDefRev *v1beta1.DefinitionRevision, lastRevision *common.Revision, cfg *definitionRevisionConfig) (bool, error) {
 	if lastRevision == nil {
 		return true, nil
 	}
@@ -185,23 +334,17 @@ func compareWithLastDefRevisionSpec(ctx context.Context, cli client.Client,
 	// check if the DefinitionRevision is deep equal in Spec level
 	// get the last revision from K8s and double check
 	defRev := &v1beta1.DefinitionRevision{}
-	var namespace string
-	switch newDefRev.Spec.DefinitionType {
-	case common.ComponentType:
-		namespace = newDefRev.Spec.ComponentDefinition.Namespace
-	case common.TraitType:
-		namespace = newDefRev.Spec.TraitDefinition.Namespace
-	case common.PolicyType:
-		namespace = newDefRev.Spec.PolicyDefinition.Namespace
-	case common.WorkflowStepType:
-		namespace = newDefRev.Spec.WorkflowStepDefinition.Namespace
-	}
 	if err := cli.Get(ctx, client.ObjectKey{Name: lastRevision.Name,
-		Namespace: namespace}, defRev); err != nil {
+		Namespace: getDefNamespace(newDefRev)}, defRev); err != nil {
+		if apierrors.IsNotFound(err) {
+			// the same hash means the spec is not changed since the missing revision is created
+			markRevisionReconstructed(newDefRev, lastRevision)
+			return true, nil
+		}
 		return false, errors.Wrapf(err, "get the definitionRevision %s", lastRevision.Name)
 	}
 
-	if DeepEqualDefRevision(defRev, newDefRev) {
+	if cfg.equalDefRevision(defRev, newDefRev) {
 		// No difference on spec, will not create a new revision
 		// align the name and resourceVersion
 		newDefRev.Name = defRev.Name
@@ -210,6 +353,7 @@ func compareWithLastDefRevisionSpec(ctx context.Context, cli client.Client,
 		return false, nil
 	}
 	// if reach here, it's same hash but different spec
+	markRevisionHashCollision(newDefRev, defRev.Name)
 	return true, nil
 }
 
@@ -235,19 +379,31 @@ func getDefNextRevision(defRev *v1beta1.DefinitionRevision, lastRevision *common
 	if lastRevision != nil {
 		nextRevision = lastRevision.Revision + 1
 	}
-	var name string
+	defRevName := strings.Join([]string{getDefName(defRev), fmt.Sprintf("v%d", nextRevision)}, "-")
+	return defRevName, nextRevision
+}
+
+// getDefMeta returns the metadata of the definition embedded in the DefinitionRevision
+func getDefMeta(defRev *v1beta1.DefinitionRevision) metav1.Object {
 	switch defRev.Spec.DefinitionType {
 	case common.ComponentType:
-		name = defRev.Spec.ComponentDefinition.Name
+		return &defRev.Spec.ComponentDefinition
 	case common.TraitType:
-		name = defRev.Spec.TraitDefinition.Name
+		return &defRev.Spec.TraitDefinition
 	case common.PolicyType:
-		name = defRev.Spec.PolicyDefinition.Name
+		return &defRev.Spec.PolicyDefinition
 	case common.WorkflowStepType:
-		name = defRev.Spec.WorkflowStepDefinition.Name
+		return &defRev.Spec.WorkflowStepDefinition
 	}
-	defRevName := strings.Join([]string{name, fmt.Sprintf("v%d", nextRevision)}, "-")
-	return defRevName, nextRevision
+	return &metav1.ObjectMeta{}
+}
+
+func getDefName(defRev *v1beta1.DefinitionRevision) string {
+	return getDefMeta(defRev).GetName()
+}
+
+func getDefNamespace(defRev *v1beta1.DefinitionRevision) string {
+	return getDefMeta(defRev).GetNamespace()
 }
 
 // ConstructDefinitionRevisionName construct the name of DefinitionRevision.
@@ -255,10 +411,98 @@ func ConstructDefinitionRevisionName(definitionName, revision string) string {
 	return strings.Join([]string{definitionName, fmt.Sprintf("v%s", revision)}, "-")
 }
 
-// CleanUpDefinitionRevision check all definitionRevisions, remove them if the number of them exceed the limit
-func CleanUpDefinitionRevision(ctx context.Context, cli client.Client, def runtime.Object, revisionLimit int) error {
+// GetDefinitionRevisionLimit returns the revision limit of the definition overridden by the annotation
+// oam.AnnotationDefinitionRevisionLimit. The default limit is returned along with an error if the annotation is invalid.
+func GetDefinitionRevisionLimit(def runtime.Object, defaultLimit int) (int, error) {
+	accessor, err := apimeta.Accessor(def)
+	if err != nil {
+		return defaultLimit, err
+	}
+	value, ok := accessor.GetAnnotations()[oam.AnnotationDefinitionRevisionLimit]
+	if !ok {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return defaultLimit, fmt.Errorf("invalid value %q of annotation %s, it must be a non-negative integer", value, oam.AnnotationDefinitionRevisionLimit)
+	}
+	return limit, nil
+}
+
+// CleanUpDefinitionRevision check all definitionRevisions, remove them if the number of them exceed the limit.
+// The limit can be overridden by the annotation oam.AnnotationDefinitionRevisionLimit of the definition. Nothing is
+// removed in the RevisionGCMaintenanceWindows of the options.
+func CleanUpDefinitionRevision(ctx context.Context, cli client.Client, def runtime.Object, revisionLimit int, options ...DefinitionRevisionOption) error {
+	_, err := cleanUpDefinitionRevision(ctx, cli, def, revisionLimit, newDefinitionRevisionConfig(options...))
+	return err
+}
+
+// DefinitionRevisionCleanUpReport reports the DefinitionRevisions collected by CleanUpAllDefinitionRevisions
+type DefinitionRevisionCleanUpReport struct {
+	// Collected are the names of the collected DefinitionRevisions by the <namespace>/<name> of ComponentDefinitions
+	Collected map[string][]string
+	// Failed are the errors of cleaning up by the <namespace>/<name> of ComponentDefinitions
+	Failed map[string]error
+}
+
+// Total returns the number of the collected DefinitionRevisions
+func (r *DefinitionRevisionCleanUpReport) Total() int {
+	var total int
+	for _, collected := range r.Collected {
+		total += len(collected)
+	}
+	return total
+}
+
+// CleanUpAllDefinitionRevisions applies CleanUpDefinitionRevision to all the ComponentDefinitions at once, e.g. to
+// reclaim the excess revisions right after the revision limit is lowered rather than waiting for the reconciles. The
+// limit can still be overridden by the annotation of each definition, and the revisions referenced by Applications
+// are kept unless RevisionGCByUsage is disabled by the options. The definitions being deleted are skipped. A failed
+// definition doesn't stop the others, the failures are reported and aggregated into the error.
+func CleanUpAllDefinitionRevisions(ctx context.Context, cli client.Client, revisionLimit int, options ...DefinitionRevisionOption) (*DefinitionRevisionCleanUpReport, error) {
+	cfg := newDefinitionRevisionConfig(options...)
+	defList := new(v1beta1.ComponentDefinitionList)
+	if err := cli.List(ctx, defList); err != nil {
+		return nil, errors.Wrap(err, "cannot list ComponentDefinitions")
+	}
+	report := &DefinitionRevisionCleanUpReport{Collected: map[string][]string{}, Failed: map[string]error{}}
+	var errs []error
+	for i := range defList.Items {
+		def := &defList.Items[i]
+		if !def.DeletionTimestamp.IsZero() {
+			continue
+		}
+		key := client.ObjectKeyFromObject(def).String()
+		collected, err := cleanUpDefinitionRevision(ctx, cli, def, revisionLimit, cfg)
+		if len(collected) > 0 {
+			report.Collected[key] = collected
+			if err := recordCollectedRevisions(ctx, cli, def, len(collected)); err != nil {
+				klog.InfoS("Failed to record the number of collected DefinitionRevisions", "err", err, "componentDefinition", key)
+			}
+			if cfg.onRevisionsCollected != nil {
+				cfg.onRevisionsCollected(def, collected)
+			}
+		}
+		if err != nil {
+			report.Failed[key] = err
+			errs = append(errs, errors.Wrapf(err, "cannot clean up DefinitionRevisions of ComponentDefinition %s", key))
+		}
+	}
+	return report, velaerrors.AggregateErrors(errs)
+}
+
+// cleanUpDefinitionRevision returns the names of the DefinitionRevisions deleted. The deletion goes on if some
+// revisions fail to be deleted, and the errors are aggregated. Nothing is deleted in the maintenance windows.
+func cleanUpDefinitionRevision(ctx context.Context, cli client.Client, def runtime.Object, revisionLimit int, cfg *definitionRevisionConfig) ([]string, error) {
+	if until, ok := cfg.gcMaintenanceWindows.ActiveUntil(time.Now()); ok {
+		klog.InfoS("Skip the garbage collection of DefinitionRevisions in the maintenance window", "until", until)
+		return nil, nil
+	}
+	revisionLimit, _ = GetDefinitionRevisionLimit(def, revisionLimit)
 	var listOpts []client.ListOption
 	var usingRevision *common.Revision
+	var defType common.DefinitionType
+	var defNamespace string
 
 	switch definition := def.(type) {
 	case *v1beta1.ComponentDefinition:
@@ -267,41 +511,71 @@ func CleanUpDefinitionRevision(ctx context.Context, cli client.Client, def runti
 			client.MatchingLabels{oam.LabelComponentDefinitionName: definition.Name},
 		}
 		usingRevision = definition.Status.LatestRevision
+		defType, defNamespace = common.ComponentType, definition.Namespace
 	case *v1beta1.TraitDefinition:
 		listOpts = []client.ListOption{
 			client.InNamespace(definition.Namespace),
 			client.MatchingLabels{oam.LabelTraitDefinitionName: definition.Name},
 		}
 		usingRevision = definition.Status.LatestRevision
+		defType, defNamespace = common.TraitType, definition.Namespace
 	case *v1beta1.PolicyDefinition:
 		listOpts = []client.ListOption{
 			client.InNamespace(definition.Namespace),
 			client.MatchingLabels{oam.LabelPolicyDefinitionName: definition.Name},
 		}
 		usingRevision = definition.Status.LatestRevision
+		defType, defNamespace = common.PolicyType, definition.Namespace
 	case *v1beta1.WorkflowStepDefinition:
 		listOpts = []client.ListOption{
 			client.InNamespace(definition.Namespace),
 			client.MatchingLabels{oam.LabelWorkflowStepDefinitionName: definition.Name}}
 		usingRevision = definition.Status.LatestRevision
+		defType, defNamespace = common.WorkflowStepType, definition.Namespace
 	}
 
 	if usingRevision == nil {
-		return nil
+		return nil, nil
 	}
 
 	defRevList := new(v1beta1.DefinitionRevisionList)
 	if err := cli.List(ctx, defRevList, listOpts...); err != nil {
-		return err
+		return nil, err
+	}
+	var collected []string
+	var errs []error
+	// the revisions of the former definition of the same name are always collected, and don't count to the limit
+	var revisions []v1beta1.DefinitionRevision
+	uid := def.(metav1.Object).GetUID()
+	for _, rev := range defRevList.Items {
+		if !isStaleRevision(&rev, uid) {
+			revisions = append(revisions, rev)
+			continue
+		}
+		klog.InfoS("cleanup the definitionRevision of the former definition", "definitionRevision", klog.KObj(&rev))
+		if err := cli.Delete(ctx, rev.DeepCopy()); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, errors.Wrapf(err, "cannot delete DefinitionRevision %s", rev.Name))
+			continue
+		}
+		collected = append(collected, rev.Name)
 	}
-	needKill := len(defRevList.Items) - revisionLimit - 1
+	needKill := len(revisions) - revisionLimit - 1
 	if needKill <= 0 {
-		return nil
+		return collected, velaerrors.AggregateErrors(errs)
 	}
 	klog.InfoS("cleanup old definitionRevision", "needKillNum", needKill)
 
-	sortedRevision := defRevList.Items
+	pinnedRevisions := map[string]bool{}
+	if cfg.gcByUsage {
+		var err error
+		if pinnedRevisions, err = listPinnedDefinitionRevisions(ctx, cli, defNamespace, defType); err != nil {
+			return nil, err
+		}
+	}
+
+	sortedRevision := revisions
 	sort.Sort(historiesByRevision(sortedRevision))
+	now := time.Now()
 
 	for _, rev := range sortedRevision {
 		if needKill <= 0 {
@@ -310,12 +584,76 @@ func CleanUpDefinitionRevision(ctx context.Context, cli client.Client, def runti
 		if rev.Name == usingRevision.Name {
 			continue
 		}
-		if err := cli.Delete(ctx, rev.DeepCopy()); err != nil && !apierrors.IsNotFound(err) {
-			return err
+		if isPinnedRevision(&rev, def.(metav1.Object).GetName(), pinnedRevisions) {
+			klog.InfoS("skip cleaning up the definitionRevision referenced by applications", "definitionRevision", klog.KObj(&rev))
+			continue
+		}
+		if eligible, retained := cfg.gcGracePeriod.Retains(&rev, now); retained {
+			klog.InfoS("skip cleaning up the definitionRevision in the grace period", "definitionRevision", klog.KObj(&rev), "eligibleAt", eligible)
+			continue
 		}
 		needKill--
+		if err := cli.Delete(ctx, rev.DeepCopy()); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, errors.Wrapf(err, "cannot delete DefinitionRevision %s", rev.Name))
+			continue
+		}
+		collected = append(collected, rev.Name)
 	}
-	return nil
+	return collected, velaerrors.AggregateErrors(errs)
+}
+
+// listPinnedDefinitionRevisions returns the names of DefinitionRevisions which are explicitly referenced by
+// Applications, e.g. `worker@v2` refers to the DefinitionRevision worker-v2, or to the hash-named revision assigned
+// the revision number 2, see isPinnedRevision. Definitions in the system
+// definition namespace can be referenced by Applications in all namespaces.
+func listPinnedDefinitionRevisions(ctx context.Context, cli client.Client, namespace string, defType common.DefinitionType) (map[string]bool, error) {
+	var listOpts []client.ListOption
+	if namespace != oam.SystemDefinitionNamespace {
+		listOpts = append(listOpts, client.InNamespace(namespace))
+	}
+	appList := new(v1beta1.ApplicationList)
+	if err := cli.List(ctx, appList, listOpts...); err != nil {
+		return nil, errors.Wrap(err, "failed to list applications referencing the definitionRevisions")
+	}
+
+	pinned := make(map[string]bool)
+	addPinned := func(typ string) {
+		if !strings.Contains(typ, "@") {
+			return
+		}
+		if revName, err := util.ConvertDefinitionRevName(typ); err == nil {
+			pinned[revName] = true
+		}
+	}
+	for _, app := range appList.Items {
+		switch defType {
+		case common.ComponentType:
+			for _, comp := range app.Spec.Components {
+				addPinned(comp.Type)
+			}
+		case common.TraitType:
+			for _, comp := range app.Spec.Components {
+				for _, trait := range comp.Traits {
+					addPinned(trait.Type)
+				}
+			}
+		case common.PolicyType:
+			for _, policy := range app.Spec.Policies {
+				addPinned(policy.Type)
+			}
+		case common.WorkflowStepType:
+			if app.Spec.Workflow == nil {
+				continue
+			}
+			for _, step := range app.Spec.Workflow.Steps {
+				addPinned(step.Type)
+				for _, sub := range step.SubSteps {
+					addPinned(sub.Type)
+				}
+			}
+		}
+	}
+	return pinned, nil
 }
 
 type historiesByRevision []v1beta1.DefinitionRevision
@@ -333,25 +671,60 @@ func ReconcileDefinitionRevision(ctx context.Context,
 	definition util.ConditionedObject,
 	revisionLimit int,
 	updateLatestRevision func(*common.Revision) error,
+	options ...DefinitionRevisionOption,
 ) (*v1beta1.DefinitionRevision, *ctrl.Result, error) {
+	if record == nil {
+		record = event.NewNopRecorder()
+	}
 
 	// generate DefinitionRevision from componentDefinition
-	defRev, isNewRevision, err := GenerateDefinitionRevision(ctx, cli, definition)
+	spanCtx, span := StartSpan(ctx, "GenerateDefinitionRevision",
+		SpanAttributeDefinitionName.String(definition.GetName()), SpanAttributeDefinitionNamespace.String(definition.GetNamespace()))
+	defRev, isNewRevision, err := GenerateDefinitionRevision(spanCtx, cli, definition, options...)
+	if defRev != nil {
+		span.SetAttributes(SpanAttributeDefinitionRevision.String(defRev.Name), SpanAttributeNewRevision.Bool(isNewRevision))
+	}
+	EndSpan(span, err)
 	if err != nil {
 		klog.ErrorS(err, "Could not generate DefinitionRevision", "componentDefinition", klog.KObj(definition))
 		record.Event(definition, event.Warning("Could not generate DefinitionRevision", err))
 		return nil, &ctrl.Result{}, util.PatchCondition(ctx, cli, definition,
-			condition.ReconcileError(fmt.Errorf(util.ErrGenerateDefinitionRevision, definition.GetName(), err)))
+			condition.ReconcileError(NewDefinitionError(ErrRevisionGeneration, definition.GetName(), err)))
+	}
+
+	frozenRev, err := getFrozenDefinitionRevision(ctx, cli, definition)
+	if err != nil {
+		klog.ErrorS(err, "Could not get the frozen DefinitionRevision", "definition", klog.KObj(definition))
+		record.Event(definition, event.Warning("cannot get the frozen DefinitionRevision", err))
+		return nil, &ctrl.Result{}, util.PatchCondition(ctx, cli, definition, condition.ReconcileError(err))
+	}
+	if err = setRevisionFrozenCondition(ctx, cli, definition, frozenRev); err != nil {
+		return nil, &ctrl.Result{}, err
+	}
+	if isNewRevision && frozenRev != nil {
+		klog.InfoS("Skip creating DefinitionRevision as the revision is frozen", "definition", klog.KObj(definition),
+			"definitionRevision", defRev.Name, "frozenRevision", frozenRev.Name)
+		defRev, isNewRevision = frozenRev, false
 	}
 
+	cfg := newDefinitionRevisionConfig(options...)
 	if isNewRevision {
-		if err := CreateDefinitionRevision(ctx, cli, definition, defRev.DeepCopy()); err != nil {
+		if len(cfg.signingKey) != 0 {
+			SignDefinitionRevision(defRev, cfg.signingKey)
+		}
+		defRev.SetAnnotations(util.MergeMapOverrideWithDst(defRev.GetAnnotations(), cfg.annotations()))
+		if err := createDefinitionRevision(ctx, cli, definition, defRev.DeepCopy(), cfg); err != nil {
 			klog.ErrorS(err, "Could not create DefinitionRevision")
 			record.Event(definition, event.Warning("cannot create DefinitionRevision", err))
 			return nil, &ctrl.Result{}, util.PatchCondition(ctx, cli, definition,
-				condition.ReconcileError(fmt.Errorf(util.ErrCreateDefinitionRevision, defRev.Name, err)))
+				condition.ReconcileError(NewDefinitionError(ErrRevisionCreation, defRev.Name, err)))
 		}
 		klog.InfoS("Successfully created definitionRevision", "definitionRevision", klog.KObj(defRev))
+		if isRevisionReconstructed(defRev) {
+			record.Event(definition, event.Warning("DefinitionRevision reconstructed",
+				fmt.Errorf("the latest DefinitionRevision %s is missing and reconstructed from the current spec", defRev.Name)))
+		}
+		setRevisionHashCollisionCondition(record, definition, defRev)
 
 		if err := updateLatestRevision(&common.Revision{
 			Name:         defRev.Name,
@@ -361,28 +734,160 @@ func ReconcileDefinitionRevision(ctx context.Context,
 			klog.ErrorS(err, "Could not update Definition Status")
 			record.Event(definition, event.Warning("cannot update the definition status", err))
 			return nil, &ctrl.Result{}, util.PatchCondition(ctx, cli, definition,
-				condition.ReconcileError(fmt.Errorf(util.ErrUpdateComponentDefinition, definition.GetName(), err)))
+				condition.ReconcileError(NewDefinitionError(ErrDefinitionUpdate, definition.GetName(), err)))
 		}
 		klog.InfoS("Successfully updated the status.latestRevision of the definition", "Definition", klog.KRef(definition.GetNamespace(), definition.GetName()),
 			"Name", defRev.Name, "Revision", defRev.Spec.Revision, "RevisionHash", defRev.Spec.RevisionHash)
+	} else if err := labelDefinitionRevision(ctx, cli, definition, defRev, cfg.labels, cfg.annotations()); err != nil {
+		klog.InfoS("Failed to label the DefinitionRevision", "err", err, "definitionRevision", defRev.Name)
+		record.Event(definition, event.Warning("cannot label DefinitionRevision", err))
+	}
+
+	if len(cfg.verificationKey) != 0 {
+		if err := setRevisionSignatureCondition(ctx, cli, record, definition, defRev, cfg); err != nil {
+			klog.InfoS("Failed to set the signature condition of the definition", "err", err, "definitionRevision", defRev.Name)
+		}
 	}
 
-	if err = CleanUpDefinitionRevision(ctx, cli, definition, revisionLimit); err != nil {
+	if _, err = GetDefinitionRevisionLimit(definition, revisionLimit); err != nil {
+		klog.InfoS("Fall back to the default revision limit", "err", err, "revisionLimit", revisionLimit)
+		record.Event(definition, event.Warning("invalid DefinitionRevision limit", err))
+	}
+	if until, ok := cfg.gcMaintenanceWindows.ActiveUntil(time.Now()); ok {
+		record.Event(definition, event.Normal("DefinitionRevision garbage collection skipped",
+			fmt.Sprintf("the garbage collection is deferred until the maintenance window closes at %s", until.Format(time.RFC3339))))
+		return defRev, nil, nil
+	}
+	spanCtx, span = StartSpan(ctx, "CleanUpDefinitionRevision",
+		SpanAttributeDefinitionName.String(definition.GetName()), SpanAttributeDefinitionNamespace.String(definition.GetNamespace()))
+	collected, err := cleanUpDefinitionRevision(spanCtx, cli, definition, revisionLimit, cfg)
+	span.SetAttributes(SpanAttributeCollectedRevisions.Int(len(collected)))
+	EndSpan(span, err)
+	if len(collected) > 0 {
+		record.Event(definition, event.Normal("DefinitionRevisions garbage collected",
+			fmt.Sprintf("deleted %d DefinitionRevisions: %s", len(collected), strings.Join(collected, ", "))))
+		if err := recordCollectedRevisions(ctx, cli, definition, len(collected)); err != nil {
+			klog.InfoS("Failed to record the number of collected DefinitionRevisions", "err", err)
+		}
+		if cfg.onRevisionsCollected != nil {
+			cfg.onRevisionsCollected(definition, collected)
+		}
+	}
+	if err != nil {
 		klog.InfoS("Failed to collect garbage", "err", err)
 		record.Event(definition, event.Warning("failed to garbage collect DefinitionRevision of type ComponentDefinition", err))
 	}
 	return defRev, nil, nil
 }
 
+// recordCollectedRevisions adds the number of collected DefinitionRevisions to the status of ComponentDefinition
+func recordCollectedRevisions(ctx context.Context, cli client.Client, definition util.ConditionedObject, count int) error {
+	componentDefinition, ok := definition.(*v1beta1.ComponentDefinition)
+	if !ok {
+		return nil
+	}
+	patch := client.MergeFrom(componentDefinition.DeepCopy())
+	componentDefinition.Status.CollectedRevisions += int64(count)
+	return cli.Status().Patch(ctx, componentDefinition, patch)
+}
+
+// IsRevisionFrozen checks whether the generation of new DefinitionRevisions is frozen for the definition
+func IsRevisionFrozen(def metav1.Object) bool {
+	frozen, err := strconv.ParseBool(def.GetAnnotations()[oam.AnnotationDefinitionRevisionFreeze])
+	return err == nil && frozen
+}
+
+// getFrozenDefinitionRevision returns the latest DefinitionRevision of the definition if the revision is frozen.
+// Nil is returned if the revision is not frozen or there is no revision to freeze yet.
+func getFrozenDefinitionRevision(ctx context.Context, cli client.Client, definition util.ConditionedObject) (*v1beta1.DefinitionRevision, error) {
+	if !IsRevisionFrozen(definition) {
+		return nil, nil
+	}
+	_, latest, err := GatherRevisionInfo(definition)
+	if err != nil || latest == nil {
+		return nil, err
+	}
+	defRev := &v1beta1.DefinitionRevision{}
+	if err = cli.Get(ctx, client.ObjectKey{Namespace: definition.GetNamespace(), Name: latest.Name}, defRev); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "cannot get the latest DefinitionRevision %s", latest.Name)
+	}
+	return defRev, nil
+}
+
+// setRevisionFrozenCondition patches the RevisionFrozen condition of the definition if it's changed
+func setRevisionFrozenCondition(ctx context.Context, cli client.Client, definition util.ConditionedObject, frozenRev *v1beta1.DefinitionRevision) error {
+	var cond condition.Condition
+	switch {
+	case frozenRev != nil:
+		cond = condition.Condition{
+			Type:               TypeRevisionFrozen,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonRevisionFrozen,
+			Message:            fmt.Sprintf("new DefinitionRevisions are not created, the definition is frozen at %s", frozenRev.Name),
+		}
+	case definition.GetCondition(TypeRevisionFrozen).Status == corev1.ConditionTrue:
+		cond = condition.Condition{
+			Type:               TypeRevisionFrozen,
+			Status:             corev1.ConditionFalse,
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonRevisionUnfrozen,
+		}
+	default:
+		return nil
+	}
+	if !util.IsConditionChanged([]condition.Condition{cond}, definition) {
+		return nil
+	}
+	return util.PatchCondition(ctx, cli, definition, cond)
+}
+
+// setRevisionHashCollisionCondition sets the RevisionHashCollision condition of the definition according to the new
+// DefinitionRevision, the condition will be persisted along with the latest revision in the status
+func setRevisionHashCollisionCondition(record event.Recorder, definition util.ConditionedObject, defRev *v1beta1.DefinitionRevision) {
+	existing, collided := defRev.GetAnnotations()[oam.AnnotationRevisionHashCollision]
+	if !collided {
+		if definition.GetCondition(TypeRevisionHashCollision).Status == corev1.ConditionTrue {
+			definition.SetConditions(condition.Condition{
+				Type:               TypeRevisionHashCollision,
+				Status:             corev1.ConditionFalse,
+				LastTransitionTime: metav1.Now(),
+				Reason:             ReasonNoRevisionHashCollision,
+			})
+		}
+		return
+	}
+	msg := fmt.Sprintf("the revision hash %s of definitionRevision %s collides with definitionRevision %s of different spec",
+		defRev.Spec.RevisionHash, defRev.Name, existing)
+	record.Event(definition, event.Warning("DefinitionRevision hash collision", errors.New(msg)))
+	definition.SetConditions(condition.Condition{
+		Type:               TypeRevisionHashCollision,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRevisionHashCollision,
+		Message:            msg,
+	})
+}
+
 // CreateDefinitionRevision create the revision of the definition
 func CreateDefinitionRevision(ctx context.Context, cli client.Client, def util.ConditionedObject, defRev *v1beta1.DefinitionRevision) error {
+	return createDefinitionRevision(ctx, cli, def, defRev, newDefinitionRevisionConfig())
+}
+
+// createDefinitionRevision creates the DefinitionRevision with the extra labels of the config besides the labels of
+// the definition
+func createDefinitionRevision(ctx context.Context, cli client.Client, def util.ConditionedObject, defRev *v1beta1.DefinitionRevision, cfg *definitionRevisionConfig) error {
 	namespace := def.GetNamespace()
-	defRev.SetLabels(def.GetLabels())
+	defRev.SetLabels(util.MergeMapOverrideWithDst(cfg.labels, def.GetLabels()))
 
 	var labelKey string
-	switch def.(type) {
+	switch d := def.(type) {
 	case *v1beta1.ComponentDefinition:
 		labelKey = oam.LabelComponentDefinitionName
+		defRev.SetLabels(util.MergeMapOverrideWithDst(defRev.Labels, map[string]string{oam.LabelDefinitionSchematicType: SchematicType(d)}))
 	case *v1beta1.TraitDefinition:
 		labelKey = oam.LabelTraitDefinitionName
 	case *v1beta1.PolicyDefinition:
@@ -396,15 +901,94 @@ func CreateDefinitionRevision(ctx context.Context, cli client.Client, def util.C
 		defRev.SetLabels(defRev.Labels)
 	}
 
+	if uid := def.GetUID(); uid != "" {
+		defRev.SetLabels(util.MergeMapOverrideWithDst(defRev.Labels, map[string]string{oam.LabelDefinitionUID: string(uid)}))
+	}
+
 	defRev.SetNamespace(namespace)
+	setRevisionProvenance(def, defRev, cfg.generatedBy, time.Now())
 
+	return createOrUpdateDefinitionRevision(ctx, cli, defRev)
+}
+
+// SchematicType returns the schematic type of the ComponentDefinition derived from its workload type. The
+// definitions which are neither Terraform nor JSON Schema ones are rendered by CUE.
+func SchematicType(def *v1beta1.ComponentDefinition) string {
+	switch utils.NewCapabilityComponentDef(def).WorkloadType {
+	case util.TerraformDef:
+		return "terraform"
+	case util.JSONSchemaDef:
+		return "jsonschema"
+	case util.HELMDef:
+		return "helm"
+	case util.KubeDef:
+		return "kube"
+	default:
+		return "cue"
+	}
+}
+
+// labelDefinitionRevision sets the extra labels and annotations, the schematic type label and the family label of
+// ComponentDefinition on the existing DefinitionRevision, which may be created before the labels are introduced or
+// before the schematic or the family is changed in place
+func labelDefinitionRevision(ctx context.Context, cli client.Client, definition util.ConditionedObject, defRev *v1beta1.DefinitionRevision, labels, annotations map[string]string) error {
+	labels = util.MergeMapOverrideWithDst(labels, nil)
+	if componentDefinition, ok := definition.(*v1beta1.ComponentDefinition); ok {
+		labels = util.MergeMapOverrideWithDst(labels, map[string]string{oam.LabelDefinitionSchematicType: SchematicType(componentDefinition)})
+		if family, ok := componentDefinition.GetLabels()[oam.LabelDefinitionFamily]; ok {
+			labels[oam.LabelDefinitionFamily] = family
+		}
+	}
+	if len(labels) == 0 && len(annotations) == 0 {
+		return nil
+	}
 	rev := &v1beta1.DefinitionRevision{}
-	err := cli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: defRev.Name}, rev)
-	if apierrors.IsNotFound(err) {
-		err = cli.Create(ctx, defRev)
-		if apierrors.IsAlreadyExists(err) {
+	if err := cli.Get(ctx, client.ObjectKey{Namespace: definition.GetNamespace(), Name: defRev.Name}, rev); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	mergedLabels := util.MergeMapOverrideWithDst(rev.Labels, labels)
+	mergedAnnotations := util.MergeMapOverrideWithDst(rev.Annotations, annotations)
+	if reflect.DeepEqual(mergedLabels, rev.Labels) && reflect.DeepEqual(mergedAnnotations, rev.Annotations) {
+		return nil
+	}
+	patch := client.MergeFrom(rev.DeepCopy())
+	rev.SetLabels(mergedLabels)
+	rev.SetAnnotations(mergedAnnotations)
+	return cli.Patch(ctx, rev, patch)
+}
+
+// createOrUpdateDefinitionRevision creates the DefinitionRevision or updates the existing one of the same name.
+// Concurrent reconciles may create or update the same revision, so an AlreadyExists error of creation switches
+// to update and conflicts of update are retried.
+func createOrUpdateDefinitionRevision(ctx context.Context, cli client.Client, defRev *v1beta1.DefinitionRevision) error {
+	key := client.ObjectKeyFromObject(defRev)
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		rev := &v1beta1.DefinitionRevision{}
+		err := cli.Get(ctx, key, rev)
+		if apierrors.IsNotFound(err) {
+			if err = cli.Create(ctx, defRev); !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+			err = cli.Get(ctx, key, rev)
+		}
+		if err != nil {
+			return err
+		}
+		if isStaleRevision(rev, types.UID(defRev.Labels[oam.LabelDefinitionUID])) {
+			// the revision is left by the former definition of the same name, never inherit it
+			klog.InfoS("replace the definitionRevision of the former definition", "definitionRevision", klog.KObj(rev))
+			if err = cli.Delete(ctx, rev); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			return cli.Create(ctx, defRev)
+		}
+		if apiequality.Semantic.DeepEqual(rev.Spec, defRev.Spec) &&
+			apiequality.Semantic.DeepEqual(rev.Labels, util.MergeMapOverrideWithDst(rev.Labels, defRev.Labels)) {
 			return nil
 		}
-	}
-	return err
+		rev.Spec = defRev.Spec
+		rev.SetLabels(util.MergeMapOverrideWithDst(rev.Labels, defRev.Labels))
+		rev.SetAnnotations(util.MergeMapOverrideWithDst(rev.Annotations, defRev.Annotations))
+		return cli.Update(ctx, rev)
+	})
 }
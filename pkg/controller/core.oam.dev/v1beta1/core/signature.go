@@ -0,0 +1,177 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/condition"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const (
+	// TypeRevisionSignatureValid indicates whether the latest DefinitionRevision of the definition carries a valid
+	// signature, it's only set when the verification key is configured
+	TypeRevisionSignatureValid condition.ConditionType = "RevisionSignatureValid"
+	// ReasonRevisionSignatureVerified means the signature of the latest DefinitionRevision is verified
+	ReasonRevisionSignatureVerified condition.ConditionReason = "RevisionSignatureVerified"
+	// ReasonRevisionUnsigned means the latest DefinitionRevision is not signed
+	ReasonRevisionUnsigned condition.ConditionReason = "RevisionUnsigned"
+	// ReasonRevisionSignatureInvalid means the signature of the latest DefinitionRevision doesn't match its content
+	ReasonRevisionSignatureInvalid condition.ConditionReason = "RevisionSignatureInvalid"
+)
+
+var (
+	// ErrRevisionUnsigned is returned by VerifyDefinitionRevision if the DefinitionRevision is not signed
+	ErrRevisionUnsigned = errors.New("the DefinitionRevision is not signed")
+	// ErrRevisionSignatureInvalid is returned by VerifyDefinitionRevision if the signature doesn't match the revision
+	ErrRevisionSignatureInvalid = errors.New("the signature of the DefinitionRevision is invalid")
+)
+
+// RevisionSigningKey is the Ed25519 private key signing the new DefinitionRevisions. The revisions are not signed if
+// the key is empty.
+type RevisionSigningKey ed25519.PrivateKey
+
+// ApplyToDefinitionRevisionConfig apply revision signing key to the config
+func (k RevisionSigningKey) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.signingKey = ed25519.PrivateKey(k)
+}
+
+// RevisionVerificationKey is the Ed25519 public key verifying the latest DefinitionRevision of the definition, the
+// result is recorded in the RevisionSignatureValid condition. The verification is disabled if the key is empty.
+type RevisionVerificationKey ed25519.PublicKey
+
+// ApplyToDefinitionRevisionConfig apply revision verification key to the config
+func (k RevisionVerificationKey) ApplyToDefinitionRevisionConfig(cfg *definitionRevisionConfig) {
+	cfg.verificationKey = ed25519.PublicKey(k)
+}
+
+// SignDefinitionRevision signs the revision hash of the DefinitionRevision with the private key, and records the
+// detached signature in the spec of the revision
+func SignDefinitionRevision(defRev *v1beta1.DefinitionRevision, key ed25519.PrivateKey) {
+	defRev.Spec.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(defRev.Spec.RevisionHash)))
+}
+
+// VerifyDefinitionRevision verifies the signature of the DefinitionRevision with the public key. The revision hash is
+// recomputed from the recorded definition as well, so that tampering with the definition is detected even if the hash
+// and the signature are left untouched. The options must hash the revision as it was generated, e.g. with the same
+// RevisionHasher and NormalizeCUETemplates.
+func VerifyDefinitionRevision(defRev *v1beta1.DefinitionRevision, key ed25519.PublicKey, options ...DefinitionRevisionOption) error {
+	if defRev.Spec.Signature == "" {
+		return ErrRevisionUnsigned
+	}
+	signature, err := base64.StdEncoding.DecodeString(defRev.Spec.Signature)
+	if err != nil || !ed25519.Verify(key, []byte(defRev.Spec.RevisionHash), signature) {
+		return ErrRevisionSignatureInvalid
+	}
+	cfg := newDefinitionRevisionConfig(options...)
+	hash, err := computeDefinitionRevisionHash(cfg.normalize(defRev), cfg.hasher)
+	if err != nil {
+		return errors.Wrapf(err, "cannot compute the revision hash of DefinitionRevision %s", defRev.Name)
+	}
+	if hash != defRev.Spec.RevisionHash {
+		return errors.Wrapf(ErrRevisionSignatureInvalid, "the revision hash %s doesn't match the recorded definition", defRev.Spec.RevisionHash)
+	}
+	return nil
+}
+
+// LoadRevisionSigningKey loads the PEM encoded PKCS #8 Ed25519 private key from the file
+func LoadRevisionSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEMFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse the private key in %s", path)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key in %s is %T rather than an Ed25519 key", path, key)
+	}
+	return privateKey, nil
+}
+
+// LoadRevisionVerificationKey loads the PEM encoded PKIX Ed25519 public key from the file
+func LoadRevisionVerificationKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEMFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse the public key in %s", path)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the public key in %s is %T rather than an Ed25519 key", path, key)
+	}
+	return publicKey, nil
+}
+
+func readPEMFile(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path) // #nosec G304 the path is configured by the operator
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read the key file %s", path)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block is found in %s", path)
+	}
+	return block, nil
+}
+
+// setRevisionSignatureCondition verifies the stored DefinitionRevision and patches the RevisionSignatureValid
+// condition of the definition if it's changed
+func setRevisionSignatureCondition(ctx context.Context, cli client.Client, record event.Recorder, definition util.ConditionedObject,
+	defRev *v1beta1.DefinitionRevision, cfg *definitionRevisionConfig) error {
+	rev := &v1beta1.DefinitionRevision{}
+	if err := cli.Get(ctx, client.ObjectKey{Namespace: definition.GetNamespace(), Name: defRev.Name}, rev); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	cond := condition.Condition{
+		Type:               TypeRevisionSignatureValid,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRevisionSignatureVerified,
+	}
+	if err := VerifyDefinitionRevision(rev, cfg.verificationKey, cfg.hasher, NormalizeCUETemplates(cfg.normalizeCUE)); err != nil {
+		cond.Status, cond.Reason = corev1.ConditionFalse, ReasonRevisionSignatureInvalid
+		if errors.Is(err, ErrRevisionUnsigned) {
+			cond.Reason = ReasonRevisionUnsigned
+		}
+		cond.Message = fmt.Sprintf("DefinitionRevision %s: %s", rev.Name, err.Error())
+		record.Event(definition, event.Warning("cannot verify the DefinitionRevision", errors.Wrap(err, rev.Name)))
+	}
+	if !util.IsConditionChanged([]condition.Condition{cond}, definition) {
+		return nil
+	}
+	return util.PatchCondition(ctx, cli, definition, cond)
+}
@@ -0,0 +1,159 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestSignDefinitionRevision(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	defRev, _, err := GatherRevisionInfo(newTestComponentDefinition("worker", "output: {}"))
+	require.NoError(t, err)
+
+	require.ErrorIs(t, VerifyDefinitionRevision(defRev, publicKey), ErrRevisionUnsigned)
+	SignDefinitionRevision(defRev, privateKey)
+	require.NotEmpty(t, defRev.Spec.Signature)
+	require.NoError(t, VerifyDefinitionRevision(defRev, publicKey))
+	require.ErrorIs(t, VerifyDefinitionRevision(defRev, otherPublicKey), ErrRevisionSignatureInvalid)
+
+	testCases := map[string]func(defRev *v1beta1.DefinitionRevision){
+		"tampered definition": func(defRev *v1beta1.DefinitionRevision) {
+			defRev.Spec.ComponentDefinition.Spec.Schematic.CUE.Template = "output: {kind: \"Job\"}"
+		},
+		"tampered hash": func(defRev *v1beta1.DefinitionRevision) {
+			defRev.Spec.RevisionHash = "0123456789abcdef"
+		},
+		"malformed signature": func(defRev *v1beta1.DefinitionRevision) {
+			defRev.Spec.Signature = "not-base64"
+		},
+	}
+	for name, tamper := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tampered := defRev.DeepCopy()
+			tamper(tampered)
+			require.ErrorIs(t, VerifyDefinitionRevision(tampered, publicKey), ErrRevisionSignatureInvalid)
+		})
+	}
+}
+
+func TestReconcileSignedDefinitionRevision(t *testing.T) {
+	ctx := context.Background()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	cd := newTestComponentDefinition("worker", "output: {}")
+	cli := newTestClient(cd)
+	reconcile := func(options ...DefinitionRevisionOption) {
+		_, _, err := ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+			cd.Status.LatestRevision = revision
+			return cli.Status().Update(ctx, cd)
+		}, options...)
+		require.NoError(t, err)
+	}
+
+	// the revision created without the signing key is flagged once the verification is enabled
+	reconcile()
+	reconcile(RevisionVerificationKey(publicKey))
+	cond := cd.GetCondition(TypeRevisionSignatureValid)
+	require.Equal(t, corev1.ConditionFalse, cond.Status)
+	require.Equal(t, ReasonRevisionUnsigned, cond.Reason)
+
+	cd.Spec.Schematic.CUE.Template = "output: {kind: \"Deployment\"}"
+	require.NoError(t, cli.Update(ctx, cd))
+	reconcile(RevisionSigningKey(privateKey), RevisionVerificationKey(publicKey))
+	stored := &v1beta1.DefinitionRevision{}
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: "worker-v2"}, stored))
+	require.NoError(t, VerifyDefinitionRevision(stored, publicKey))
+	require.Equal(t, corev1.ConditionTrue, cd.GetCondition(TypeRevisionSignatureValid).Status)
+
+	// the revision signed by another key is detected on the next reconcile
+	_, otherPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	SignDefinitionRevision(stored, otherPrivateKey)
+	require.NoError(t, cli.Update(ctx, stored))
+	reconcile(RevisionSigningKey(privateKey), RevisionVerificationKey(publicKey))
+	cond = cd.GetCondition(TypeRevisionSignatureValid)
+	require.Equal(t, corev1.ConditionFalse, cond.Status)
+	require.Equal(t, ReasonRevisionSignatureInvalid, cond.Reason)
+}
+
+func TestLoadRevisionKeys(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+		return path
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	loadedPrivateKey, err := LoadRevisionSigningKey(writePEM("signing.pem", "PRIVATE KEY", privateDER))
+	require.NoError(t, err)
+	require.True(t, privateKey.Equal(loadedPrivateKey))
+	loadedPublicKey, err := LoadRevisionVerificationKey(writePEM("verification.pem", "PUBLIC KEY", publicDER))
+	require.NoError(t, err)
+	require.True(t, publicKey.Equal(loadedPublicKey))
+
+	_, err = LoadRevisionSigningKey(writePEM("public.pem", "PUBLIC KEY", publicDER))
+	require.Error(t, err)
+	_, err = LoadRevisionVerificationKey(filepath.Join(dir, "not-exist.pem"))
+	require.Error(t, err)
+}
+
+func TestVerifyNormalizedDefinitionRevision(t *testing.T) {
+	ctx := context.Background()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	cd := newTestComponentDefinition("worker", "// the output\noutput: {\n  kind:   \"Deployment\" // the kind\n}\n")
+	cli := newTestClient(cd)
+	options := []DefinitionRevisionOption{NormalizeCUETemplates(true), RevisionSigningKey(privateKey), RevisionVerificationKey(publicKey)}
+	_, _, err = ReconcileDefinitionRevision(ctx, cli, event.NewNopRecorder(), cd, 10, func(revision *common.Revision) error {
+		cd.Status.LatestRevision = revision
+		return cli.Status().Update(ctx, cd)
+	}, options...)
+	require.NoError(t, err)
+	require.Equal(t, corev1.ConditionTrue, cd.GetCondition(TypeRevisionSignatureValid).Status)
+
+	// the revision records the template as it is, and the hash is computed over the normalized one
+	stored := &v1beta1.DefinitionRevision{}
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: cd.Namespace, Name: "worker-v1"}, stored))
+	require.Equal(t, cd.Spec.Schematic.CUE.Template, stored.Spec.ComponentDefinition.Spec.Schematic.CUE.Template)
+	require.NoError(t, VerifyDefinitionRevision(stored, publicKey, NormalizeCUETemplates(true)))
+	require.ErrorIs(t, VerifyDefinitionRevision(stored, publicKey), ErrRevisionSignatureInvalid)
+}
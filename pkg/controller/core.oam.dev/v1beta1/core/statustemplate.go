@@ -0,0 +1,71 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+
+	"cuelang.org/go/cue/cuecontext"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+// StatusTemplateRuntimeContext declares the fields only available when the status of a workload or a trait is
+// evaluated, so that the templates referring to them can be compiled at registration
+const StatusTemplateRuntimeContext = "\ncontext: _\nparameter: _\n"
+
+// StatusTemplateError is the error compiling a status template of a definition
+type StatusTemplateError struct {
+	// Field is the field of the template, either healthPolicy or customStatus
+	Field string
+	// Err is the compile error of CUE
+	Err error
+}
+
+// Error implements error
+func (e *StatusTemplateError) Error() string {
+	return fmt.Sprintf("compile %s: %s", e.Field, e.Err.Error())
+}
+
+// Unwrap returns the compile error
+func (e *StatusTemplateError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateStatusTemplates compiles the health policy and the custom status of a ComponentDefinition or a
+// TraitDefinition, so that broken templates are found before they are evaluated by Applications. The first broken
+// template is reported by a StatusTemplateError.
+func ValidateStatusTemplates(status *common.Status) error {
+	if status == nil {
+		return nil
+	}
+	if status.HealthPolicy != "" {
+		if err := compileStatusTemplate(status.HealthPolicy); err != nil {
+			return &StatusTemplateError{Field: "healthPolicy", Err: err}
+		}
+	}
+	if status.CustomStatus != "" {
+		if err := compileStatusTemplate(status.CustomStatus); err != nil {
+			return &StatusTemplateError{Field: "customStatus", Err: err}
+		}
+	}
+	return nil
+}
+
+func compileStatusTemplate(template string) error {
+	return cuecontext.New().CompileString(template + StatusTemplateRuntimeContext).Err()
+}
@@ -0,0 +1,66 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestValidateStatusTemplates(t *testing.T) {
+	testCases := map[string]struct {
+		status   *common.Status
+		errField string
+	}{
+		"no status": {},
+		"valid": {
+			status: &common.Status{
+				HealthPolicy: `isHealth: context.output.status.readyReplicas == context.output.status.replicas`,
+				CustomStatus: `message: "\(context.output.status.readyReplicas) replicas are ready"`,
+			},
+		},
+		"invalid health policy": {
+			status:   &common.Status{HealthPolicy: `isHealth: (`, CustomStatus: `message: (`},
+			errField: "healthPolicy",
+		},
+		"invalid custom status": {
+			status:   &common.Status{CustomStatus: `message: "ready" +`},
+			errField: "customStatus",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			component := &v1beta1.ComponentDefinition{Spec: v1beta1.ComponentDefinitionSpec{Status: tc.status}}
+			trait := &v1beta1.TraitDefinition{Spec: v1beta1.TraitDefinitionSpec{Status: tc.status}}
+			for _, status := range []*common.Status{component.Spec.Status, trait.Spec.Status} {
+				err := ValidateStatusTemplates(status)
+				if tc.errField == "" {
+					require.NoError(t, err)
+					continue
+				}
+				var templateErr *StatusTemplateError
+				require.ErrorAs(t, err, &templateErr)
+				require.Equal(t, tc.errField, templateErr.Field)
+				require.ErrorContains(t, err, "compile "+tc.errField)
+			}
+		})
+	}
+}
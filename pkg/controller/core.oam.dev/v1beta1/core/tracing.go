@@ -0,0 +1,61 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the OpenTelemetry tracer creating the spans of the definition reconciles
+const TracerName = "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+
+const (
+	// SpanAttributeDefinitionName is the span attribute of the name of the reconciled definition
+	SpanAttributeDefinitionName = attribute.Key("definition.name")
+	// SpanAttributeDefinitionNamespace is the span attribute of the namespace of the reconciled definition
+	SpanAttributeDefinitionNamespace = attribute.Key("definition.namespace")
+	// SpanAttributeDefinitionRevision is the span attribute of the DefinitionRevision handled in the span
+	SpanAttributeDefinitionRevision = attribute.Key("definition.revision")
+	// SpanAttributeNewRevision is the span attribute of whether the DefinitionRevision is newly generated
+	SpanAttributeNewRevision = attribute.Key("definition.revision.new")
+	// SpanAttributeCollectedRevisions is the span attribute of the number of the garbage collected DefinitionRevisions
+	SpanAttributeCollectedRevisions = attribute.Key("definition.revision.collected")
+	// SpanAttributeResult is the span attribute of the result of the span, either success, requeue or error
+	SpanAttributeResult = attribute.Key("result")
+)
+
+// StartSpan starts a child span of the span in the context. The tracer provider of the parent span is used, so no
+// span is recorded unless the caller is traced.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records the result of the span and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(SpanAttributeResult.String("error"))
+	} else {
+		span.SetAttributes(SpanAttributeResult.String("success"))
+	}
+	span.End()
+}
@@ -0,0 +1,74 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velaerrors "github.com/oam-dev/kubevela/pkg/utils/errors"
+)
+
+// TypeTraitCompatibilityValid indicates whether the trait compatibility declared by the definition is well-formed
+const TypeTraitCompatibilityValid = "TraitCompatibilityValid"
+
+// ParseTraitCompatibility parses the trait compatibility declared by the annotations oam.AnnotationCompatibleTraits
+// and oam.AnnotationIncompatibleTraits of the definition. It returns nil if neither annotation is set. Every trait
+// must be a valid definition name, and must not be declared twice or in both lists.
+func ParseTraitCompatibility(def metav1.Object) (*v1beta1.TraitCompatibility, error) {
+	annotations := def.GetAnnotations()
+	compatible, compatibleSet := annotations[oam.AnnotationCompatibleTraits]
+	incompatible, incompatibleSet := annotations[oam.AnnotationIncompatibleTraits]
+	if !compatibleSet && !incompatibleSet {
+		return nil, nil
+	}
+	declared := map[string]string{}
+	var errs velaerrors.ErrorList
+	parse := func(key, value string) []string {
+		var traits []string
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if msgs := validation.IsDNS1123Subdomain(name); len(msgs) != 0 {
+				errs = append(errs, fmt.Errorf("invalid trait name %q in %s: %s", name, key, strings.Join(msgs, ",")))
+				continue
+			}
+			if previous, ok := declared[name]; ok {
+				errs = append(errs, fmt.Errorf("trait %s in %s is already declared in %s", name, key, previous))
+				continue
+			}
+			declared[name] = key
+			traits = append(traits, name)
+		}
+		return traits
+	}
+	compatibility := &v1beta1.TraitCompatibility{
+		CompatibleTraits:   parse(oam.AnnotationCompatibleTraits, compatible),
+		IncompatibleTraits: parse(oam.AnnotationIncompatibleTraits, incompatible),
+	}
+	if errs.HasError() {
+		return nil, errs
+	}
+	return compatibility, nil
+}
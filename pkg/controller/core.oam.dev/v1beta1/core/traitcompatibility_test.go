@@ -0,0 +1,81 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestParseTraitCompatibility(t *testing.T) {
+	testCases := map[string]struct {
+		annotations map[string]string
+		expected    *v1beta1.TraitCompatibility
+		errContains string
+	}{
+		"not declared": {
+			annotations: map[string]string{oam.AnnotationDefinitionDeprecated: "true"},
+		},
+		"declared": {
+			annotations: map[string]string{
+				oam.AnnotationCompatibleTraits:   "scaler, gateway,,",
+				oam.AnnotationIncompatibleTraits: "hpa",
+			},
+			expected: &v1beta1.TraitCompatibility{
+				CompatibleTraits:   []string{"scaler", "gateway"},
+				IncompatibleTraits: []string{"hpa"},
+			},
+		},
+		"empty": {
+			annotations: map[string]string{oam.AnnotationCompatibleTraits: ""},
+			expected:    &v1beta1.TraitCompatibility{},
+		},
+		"invalid name": {
+			annotations: map[string]string{oam.AnnotationCompatibleTraits: "scaler,Bad_Trait"},
+			errContains: `invalid trait name "Bad_Trait"`,
+		},
+		"duplicated": {
+			annotations: map[string]string{oam.AnnotationIncompatibleTraits: "hpa,hpa"},
+			errContains: "trait hpa in definition.oam.dev/incompatible-traits is already declared in definition.oam.dev/incompatible-traits",
+		},
+		"conflicting": {
+			annotations: map[string]string{
+				oam.AnnotationCompatibleTraits:   "scaler,hpa",
+				oam.AnnotationIncompatibleTraits: "hpa",
+			},
+			errContains: "trait hpa in definition.oam.dev/incompatible-traits is already declared in definition.oam.dev/compatible-traits",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			def := &v1beta1.ComponentDefinition{ObjectMeta: metav1.ObjectMeta{Name: "worker", Annotations: tc.annotations}}
+			compatibility, err := ParseTraitCompatibility(def)
+			if tc.errContains != "" {
+				require.ErrorContains(t, err, tc.errContains)
+				require.Nil(t, compatibility)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, compatibility)
+		})
+	}
+}
@@ -101,10 +101,18 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			condition.ReconcileError(fmt.Errorf(util.ErrStoreCapabilityInConfigMap, traitDefinition.Name, err)))
 	}
 
-	if traitDefinition.Status.ConfigMapRef != cmName {
+	statusTemplateCondition := condition.ReadyCondition(coredef.TypeStatusTemplateValid)
+	if err := coredef.ValidateStatusTemplates(traitDefinition.Spec.Status); err != nil {
+		klog.InfoS("Invalid status templates of traitDefinition", "traitDefinition", klog.KRef(req.Namespace, req.Name), "err", err)
+		r.record.Event(&traitDefinition, event.Warning("invalid status templates", err))
+		statusTemplateCondition = condition.ErrorCondition(coredef.TypeStatusTemplateValid, err)
+	}
+
+	if traitDefinition.Status.ConfigMapRef != cmName ||
+		util.IsConditionChanged([]condition.Condition{statusTemplateCondition}, &traitDefinition) {
 		traitDefinition.Status.ConfigMapRef = cmName
 		// Override the conditions, which maybe include the error info.
-		traitDefinition.Status.Conditions = []condition.Condition{condition.ReconcileSuccess()}
+		traitDefinition.Status.Conditions = []condition.Condition{condition.ReconcileSuccess(), statusTemplateCondition}
 		if err := r.UpdateStatus(ctx, &traitDefinition); err != nil {
 			klog.ErrorS(err, "Could not update TraitDefinition Status", "traitDefinition", klog.KRef(req.Namespace, req.Name))
 			r.record.Event(&traitDefinition, event.Warning("Could not update TraitDefinition Status", err))
@@ -0,0 +1,32 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+)
+
+// TypeParametersUsed indicates whether all the parameters declared by the CUE template of the definition are used
+const TypeParametersUsed = "ParametersUsed"
+
+// FindUnusedParameters returns the sorted names of the top-level parameters declared by the CUE template of the
+// ComponentDefinition which are referenced by neither the template nor the status templates, see
+// utils.FindUnusedParameters.
+func FindUnusedParameters(def *v1beta1.ComponentDefinition) ([]string, error) {
+	return utils.FindUnusedParameters(def)
+}
@@ -0,0 +1,118 @@
+/*
+ Copyright 2021. The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+func TestFindUnusedParameters(t *testing.T) {
+	testCases := map[string]struct {
+		template string
+		status   *common.Status
+		unused   []string
+	}{
+		"all used": {
+			template: `
+parameter: {
+	image: string
+	port?: int
+	"cpu-limit": *"1" | string
+}
+output: {
+	spec: containers: [{
+		image: parameter.image
+		if parameter.port != _|_ {
+			ports: [{containerPort: parameter.port}]
+		}
+		resources: limits: cpu: parameter["cpu-limit"]
+	}]
+}
+`,
+		},
+		"unused": {
+			template: `
+parameter: {
+	image: string
+	replicas: *1 | int
+	debug?: bool
+	env?: [...{name: string}]
+}
+output: {
+	metadata: name: "\(parameter.image)-app"
+	spec: containers: [for e in parameter.env {name: e.name}]
+}
+`,
+			unused: []string{"debug", "replicas"},
+		},
+		"used by the status templates": {
+			template: `
+parameter: {
+	image: string
+	minReady: *1 | int
+}
+output: spec: image: parameter.image
+`,
+			status: &common.Status{HealthPolicy: `isHealth: context.output.status.readyReplicas >= parameter.minReady`},
+		},
+		"referenced as a whole": {
+			template: `
+parameter: {
+	image: string
+	labels: [string]: string
+}
+output: spec: parameter
+`,
+		},
+		"referenced by dynamic index": {
+			template: `
+parameter: {
+	image: string
+	tag: string
+}
+_keys: ["image", "tag"]
+output: spec: {for k in _keys {(k): parameter[k]}}
+`,
+		},
+		"parameter is not a struct literal": {
+			template: `
+#Params: {image: string}
+parameter: #Params
+output: {}
+`,
+		},
+		"no parameter": {
+			template: "output: {}\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cd := newTestComponentDefinition("worker", tc.template)
+			cd.Spec.Status = tc.status
+			unused, err := FindUnusedParameters(cd)
+			require.NoError(t, err)
+			require.Equal(t, tc.unused, unused)
+		})
+	}
+
+	_, err := FindUnusedParameters(newTestComponentDefinition("worker", "parameter: {"))
+	require.Error(t, err)
+}
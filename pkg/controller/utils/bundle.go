@@ -0,0 +1,179 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// DefinitionBundle is the portable JSON bundle of a ComponentDefinition, its DefinitionRevisions and the schema
+// ConfigMaps of them, which is used to back up the definition or migrate it to another cluster along with its history.
+// The cluster specific metadata, e.g. the UIDs and the resource versions, are not carried.
+type DefinitionBundle struct {
+	ComponentDefinition v1beta1.ComponentDefinition `json:"componentDefinition"`
+	// Revisions are sorted by the revision numbers
+	Revisions []v1beta1.DefinitionRevision `json:"revisions,omitempty"`
+	// SchemaConfigMaps are the schema ConfigMaps of the definition and the revisions, and the schema fragments they
+	// refer to
+	SchemaConfigMaps []v1.ConfigMap `json:"schemaConfigMaps,omitempty"`
+}
+
+// ExportDefinitionBundle exports the ComponentDefinition with all its DefinitionRevisions and the schema ConfigMaps
+// stored in its namespace into a DefinitionBundle. The schema ConfigMaps missing, e.g. pruned along with the
+// collected revisions, are skipped.
+func ExportDefinitionBundle(ctx context.Context, k8sClient client.Client, namespace, name string) (*DefinitionBundle, error) {
+	bundle := &DefinitionBundle{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &bundle.ComponentDefinition); err != nil {
+		return nil, errors.Wrapf(err, "failed to get ComponentDefinition %s/%s", namespace, name)
+	}
+	revisions := &v1beta1.DefinitionRevisionList{}
+	if err := k8sClient.List(ctx, revisions, client.InNamespace(namespace),
+		client.MatchingLabels{oam.LabelComponentDefinitionName: name}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list the DefinitionRevisions of ComponentDefinition %s/%s", namespace, name)
+	}
+	bundle.Revisions = revisions.Items
+	sort.Slice(bundle.Revisions, func(i, j int) bool {
+		return bundle.Revisions[i].Spec.Revision < bundle.Revisions[j].Spec.Revision
+	})
+
+	names := []string{name}
+	for _, rev := range bundle.Revisions {
+		names = append(names, rev.Name)
+	}
+	fragments := map[client.ObjectKey]bool{}
+	for _, n := range names {
+		cm := &v1.ConfigMap{}
+		if err := k8sClient.Get(ctx, SchemaConfigMapKey("", namespace, n), cm); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to get the schema ConfigMap of %s", n)
+		}
+		bundle.SchemaConfigMaps = append(bundle.SchemaConfigMaps, *cm)
+		jsonSchema, err := GetOpenAPISchemaFromConfigMap(cm)
+		if err != nil {
+			return nil, err
+		}
+		referred, err := ListSchemaFragments(ctx, k8sClient, jsonSchema)
+		if err != nil {
+			return nil, err
+		}
+		for _, fragment := range referred {
+			if key := client.ObjectKeyFromObject(fragment); !fragments[key] {
+				fragments[key] = true
+				bundle.SchemaConfigMaps = append(bundle.SchemaConfigMaps, *fragment)
+			}
+		}
+	}
+
+	clearClusterMetadata(&bundle.ComponentDefinition)
+	for i := range bundle.Revisions {
+		clearClusterMetadata(&bundle.Revisions[i])
+	}
+	for i := range bundle.SchemaConfigMaps {
+		clearClusterMetadata(&bundle.SchemaConfigMaps[i])
+	}
+	return bundle, nil
+}
+
+// ImportDefinitionBundle recreates the ComponentDefinition of the DefinitionBundle with its DefinitionRevisions and
+// schema ConfigMaps in their original namespace. The revisions keep their names, numbers and hashes, and the status of
+// the definition is restored, so the history continues from the latest revision. The owner references and the
+// definition UID label are pointed to the recreated objects. The definition must not exist, while the existing
+// revisions and schema ConfigMaps of the same names are overwritten.
+func ImportDefinitionBundle(ctx context.Context, k8sClient client.Client, bundle *DefinitionBundle) error {
+	cd := bundle.ComponentDefinition.DeepCopy()
+	status := cd.Status.DeepCopy()
+	if err := k8sClient.Create(ctx, cd); err != nil {
+		return errors.Wrapf(err, "failed to create ComponentDefinition %s/%s", cd.Namespace, cd.Name)
+	}
+	cd.Status = *status
+	if err := k8sClient.Status().Update(ctx, cd); err != nil {
+		return errors.Wrapf(err, "failed to restore the status of ComponentDefinition %s/%s", cd.Namespace, cd.Name)
+	}
+	owners := map[string]k8stypes.UID{v1beta1.ComponentDefinitionKind + "/" + cd.Name: cd.UID}
+
+	for i := range bundle.Revisions {
+		rev := bundle.Revisions[i].DeepCopy()
+		rev.OwnerReferences = repointOwnerReferences(rev.OwnerReferences, owners)
+		if _, ok := rev.Labels[oam.LabelDefinitionUID]; ok {
+			rev.Labels = util.MergeMapOverrideWithDst(rev.Labels, map[string]string{oam.LabelDefinitionUID: string(cd.UID)})
+		}
+		if err := createOrOverwrite(ctx, k8sClient, rev, &v1beta1.DefinitionRevision{}); err != nil {
+			return errors.Wrapf(err, "failed to import DefinitionRevision %s", rev.Name)
+		}
+		owners[v1beta1.DefinitionRevisionKind+"/"+rev.Name] = rev.UID
+	}
+	for i := range bundle.SchemaConfigMaps {
+		cm := bundle.SchemaConfigMaps[i].DeepCopy()
+		cm.OwnerReferences = repointOwnerReferences(cm.OwnerReferences, owners)
+		if err := createOrOverwrite(ctx, k8sClient, cm, &v1.ConfigMap{}); err != nil {
+			return errors.Wrapf(err, "failed to import ConfigMap %s/%s", cm.Namespace, cm.Name)
+		}
+	}
+	return nil
+}
+
+// clearClusterMetadata removes the metadata only meaningful in the cluster the object is read from
+func clearClusterMetadata(obj metav1.Object) {
+	obj.SetUID("")
+	obj.SetResourceVersion("")
+	obj.SetGeneration(0)
+	obj.SetCreationTimestamp(metav1.Time{})
+	obj.SetManagedFields(nil)
+	obj.SetSelfLink("")
+}
+
+// repointOwnerReferences points the owner references to the UIDs of the imported owners, the references to the owners
+// not imported are dropped
+func repointOwnerReferences(refs []metav1.OwnerReference, owners map[string]k8stypes.UID) []metav1.OwnerReference {
+	var repointed []metav1.OwnerReference
+	for _, ref := range refs {
+		if uid, ok := owners[ref.Kind+"/"+ref.Name]; ok {
+			ref.UID = uid
+			repointed = append(repointed, ref)
+		}
+	}
+	return repointed
+}
+
+// createOrOverwrite creates the object, or overwrites the existing one of the same name with it
+func createOrOverwrite(ctx context.Context, k8sClient client.Client, obj, existing client.Object) error {
+	err := k8sClient.Create(ctx, obj)
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	if err = k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	obj.SetUID(existing.GetUID())
+	return k8sClient.Update(ctx, obj)
+}
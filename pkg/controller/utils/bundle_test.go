@@ -0,0 +1,133 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// uidClient assigns the UIDs to the created objects like the API server
+type uidClient struct {
+	client.Client
+}
+
+func (c uidClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if obj.GetUID() == "" {
+		obj.SetUID(uuid.NewUUID())
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestDefinitionBundleRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	const resources = "resources: {\n\t\trequests: {\n\t\t\tcpu: *\"100m\" | string\n\t\t\tmemory: *\"128Mi\" | string\n\t\t}\n\t}\n"
+	cd := newSchemaCacheTestDefinition("parameter: {\n\timage: string\n\t" + resources + "}\noutput: {}\n")
+	cd.TypeMeta = metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.ComponentDefinitionKind}
+	source := uidClient{fake.NewClientBuilder().WithScheme(velacommon.Scheme).Build()}
+	require.NoError(t, source.Create(ctx, cd))
+	owner := metav1.OwnerReference{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.ComponentDefinitionKind, Name: cd.Name, UID: cd.UID}
+	for i, hash := range []string{"hash-v1", "hash-v2"} {
+		rev := &v1beta1.DefinitionRevision{
+			TypeMeta: metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.DefinitionRevisionKind},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            cd.Name + "-v" + []string{"1", "2"}[i],
+				Namespace:       cd.Namespace,
+				Labels:          map[string]string{oam.LabelComponentDefinitionName: cd.Name, oam.LabelDefinitionUID: string(cd.UID)},
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+			Spec: v1beta1.DefinitionRevisionSpec{Revision: int64(i + 1), RevisionHash: hash, DefinitionType: common.ComponentType, ComponentDefinition: *cd},
+		}
+		require.NoError(t, source.Create(ctx, rev))
+		def := NewCapabilityComponentDef(cd)
+		def.SchemaFragmentMinSize = 50
+		_, err := def.StoreOpenAPISchema(ctx, source, cd.Namespace, cd.Name, rev.Name)
+		require.NoError(t, err)
+	}
+	cd.Status.LatestRevision = &common.Revision{Name: "worker-v2", Revision: 2, RevisionHash: "hash-v2"}
+	require.NoError(t, source.Status().Update(ctx, cd))
+
+	bundle, err := ExportDefinitionBundle(ctx, source, cd.Namespace, cd.Name)
+	require.NoError(t, err)
+	require.Len(t, bundle.Revisions, 2)
+	// the schemas of the definition and the revisions, and the nested fragments shared by them
+	require.Len(t, bundle.SchemaConfigMaps, 6)
+	assert.Empty(t, bundle.ComponentDefinition.UID)
+	assert.Empty(t, bundle.ComponentDefinition.ResourceVersion)
+	data, err := json.Marshal(bundle)
+	require.NoError(t, err)
+
+	imported := &DefinitionBundle{}
+	require.NoError(t, json.Unmarshal(data, imported))
+	target := uidClient{fake.NewClientBuilder().WithScheme(velacommon.Scheme).Build()}
+	require.NoError(t, ImportDefinitionBundle(ctx, target, imported))
+
+	got := &v1beta1.ComponentDefinition{}
+	require.NoError(t, target.Get(ctx, client.ObjectKeyFromObject(cd), got))
+	require.NotEmpty(t, got.UID)
+	require.NotEqual(t, cd.UID, got.UID)
+	assert.Equal(t, cd.Spec, got.Spec)
+	assert.Equal(t, cd.Status.LatestRevision, got.Status.LatestRevision)
+	revisions := &v1beta1.DefinitionRevisionList{}
+	require.NoError(t, target.List(ctx, revisions, client.InNamespace(cd.Namespace)))
+	require.Len(t, revisions.Items, 2)
+	revUIDs := map[string]k8stypes.UID{}
+	for _, rev := range revisions.Items {
+		revUIDs[rev.Name] = rev.UID
+		assert.Equal(t, map[string]int64{"worker-v1": 1, "worker-v2": 2}[rev.Name], rev.Spec.Revision)
+		assert.Equal(t, map[string]string{"worker-v1": "hash-v1", "worker-v2": "hash-v2"}[rev.Name], rev.Spec.RevisionHash)
+		assert.Equal(t, string(got.UID), rev.Labels[oam.LabelDefinitionUID])
+		require.Len(t, rev.OwnerReferences, 1)
+		assert.Equal(t, got.UID, rev.OwnerReferences[0].UID)
+	}
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, target.Get(ctx, SchemaConfigMapKey("", cd.Namespace, "worker-v1"), cm))
+	require.Len(t, cm.OwnerReferences, 1)
+	assert.Equal(t, revUIDs["worker-v1"], cm.OwnerReferences[0].UID)
+	require.NoError(t, target.Get(ctx, SchemaConfigMapKey("", cd.Namespace, "worker"), cm))
+	require.Len(t, cm.OwnerReferences, 1)
+	assert.Equal(t, got.UID, cm.OwnerReferences[0].UID)
+
+	// the imported schemas resolve to the same schemas with the fragments
+	for _, name := range []string{"worker", "worker-v1", "worker-v2"} {
+		expected, err := (&ConfigMapSchemaStore{Client: source}).Get(ctx, cd.Namespace, name)
+		require.NoError(t, err)
+		actual, err := (&ConfigMapSchemaStore{Client: target}).Get(ctx, cd.Namespace, name)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(actual))
+		assert.Contains(t, string(actual), "requests")
+	}
+
+	// the definition is never overwritten
+	require.Error(t, ImportDefinitionBundle(ctx, target, imported))
+}
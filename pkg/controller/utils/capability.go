@@ -18,16 +18,28 @@
 package utils
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"cuelang.org/go/cue/build"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-git/go-git/v5"
 	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	cueutil "github.com/kubevela/pkg/cue/util"
+	velaslices "github.com/kubevela/pkg/util/slices"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,14 +47,17 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/pkg/schema"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
+	velaerrors "github.com/oam-dev/kubevela/pkg/utils/errors"
 	"github.com/oam-dev/kubevela/pkg/utils/terraform"
 )
 
@@ -73,8 +88,14 @@ const (
 const (
 	// GitCredsKnownHosts is a key in git credentials secret
 	GitCredsKnownHosts string = "known_hosts"
+	// CUEPackagePathKey is the key in the ConfigMap of a CUE package to store its import path
+	CUEPackagePathKey string = "path"
 )
 
+// schemaCompressionThreshold is the size of OpenAPI v3 JSON schema above which it will be stored compressed,
+// it leaves room for the other fields under the 1MiB size limit of ConfigMap
+const schemaCompressionThreshold = 512 * 1024
+
 // ErrNoSectionParameterInCue means there is not parameter section in Cue template of a workload
 type ErrNoSectionParameterInCue struct {
 	capName string
@@ -98,7 +119,37 @@ type CapabilityComponentDefinition struct {
 	WorkloadType    util.WorkloadType `json:"workloadType"`
 	WorkloadDefName string            `json:"workloadDefName"`
 
-	Terraform *commontypes.Terraform `json:"terraform"`
+	Terraform  *commontypes.Terraform  `json:"terraform"`
+	JSONSchema *commontypes.JSONSchema `json:"jsonSchema"`
+
+	// SchemaStorageNamespace centralizes the schema ConfigMaps into the namespace if it's set,
+	// see SchemaConfigMapKey for how the ConfigMaps are named there
+	SchemaStorageNamespace string `json:"-"`
+	// SchemaGenerationTimeout is the time budget of evaluating the CUE template to generate the schema, the
+	// generation is unlimited if it's not positive
+	SchemaGenerationTimeout time.Duration `json:"-"`
+	// SchemaCompilationSemaphore bounds the concurrent evaluations of CUE templates if it's set, it's shared by the
+	// definitions so that the CPU of compilations is capped independently of the concurrent reconciles. An evaluation
+	// exceeding the time budget keeps its slot until it finishes, but it's not started again while it's running, so
+	// an expensive template holds one slot at most.
+	SchemaCompilationSemaphore *semaphore.Weighted `json:"-"`
+	// SchemaSize is the size in bytes of the schema generated by the last StoreOpenAPISchema
+	SchemaSize int `json:"-"`
+	// SchemaStore stores the schema outside the ConfigMaps if it's set
+	SchemaStore SchemaStore `json:"-"`
+	// SchemaCache reuses the schema generated from the same CUE schematic content if it's set
+	SchemaCache *SchemaCache `json:"-"`
+	// SchemaContentHash is the hash of the schematic content the last schema is generated from, it's only computed
+	// with SchemaCache
+	SchemaContentHash string `json:"-"`
+	// SchemaChecksum records the checksum of the stored schema on the schema ConfigMaps and the DefinitionRevision,
+	// see VerifySchemaChecksum
+	SchemaChecksum bool `json:"-"`
+	// SchemaAnnotations are recorded on the schema ConfigMaps along with the schema, e.g. the category and the icon
+	// of the definition, so that they are read in one place
+	SchemaAnnotations map[string]string `json:"-"`
+	// ExcludeInternalParameters removes the parameters of the internal access level from the schema
+	ExcludeInternalParameters bool `json:"-"`
 	CapabilityBaseDefinition
 }
 
@@ -115,6 +166,10 @@ func NewCapabilityComponentDef(componentDefinition *v1beta1.ComponentDefinition)
 			def.WorkloadType = util.TerraformDef
 			def.Terraform = componentDefinition.Spec.Schematic.Terraform
 		}
+		if componentDefinition.Spec.Schematic.JSONSchema != nil {
+			def.WorkloadType = util.JSONSchemaDef
+			def.JSONSchema = componentDefinition.Spec.Schematic.JSONSchema
+		}
 	}
 	def.ComponentDefinition = *componentDefinition.DeepCopy()
 	return def
@@ -122,11 +177,127 @@ func NewCapabilityComponentDef(componentDefinition *v1beta1.ComponentDefinition)
 
 // GetOpenAPISchema gets OpenAPI v3 schema by WorkloadDefinition name
 func (def *CapabilityComponentDefinition) GetOpenAPISchema(ctx context.Context, name string) ([]byte, error) {
+	return def.generateOpenAPISchema(ctx, name)
+}
+
+func (def *CapabilityComponentDefinition) generateOpenAPISchema(ctx context.Context, name string, imports ...*build.Instance) ([]byte, error) {
 	capability, err := appfile.ConvertTemplateJSON2Object(name, def.ComponentDefinition.Spec.Extension, def.ComponentDefinition.Spec.Schematic)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert ComponentDefinition to Capability Object")
 	}
-	return getOpenAPISchema(ctx, capability)
+	return getOpenAPISchema(ctx, capability, imports...)
+}
+
+// ErrSchemaGenerationTimeout means the CUE template is too expensive to generate the schema within the time budget
+var ErrSchemaGenerationTimeout = errors.New("the evaluation of CUE template exceeds the time budget")
+
+// generateOpenAPISchemaWithinBudget generates the schema from the CUE template and gives up once the generation
+// exceeds SchemaGenerationTimeout or the context is done. The CUE evaluation can't be interrupted, so the abandoned
+// evaluation goes on in background until it finishes, but the reconcile is not blocked by it. The evaluations are
+// tracked by the hash of the schematic content, and a generation of the content being evaluated waits for the running
+// evaluation instead of starting another one, so the repeated reconciles of an expensive template don't pile up the
+// evaluations. The evaluation waits for SchemaCompilationSemaphore if it's set, which is held until the evaluation
+// finishes even if it's abandoned, and the budget starts once the evaluation starts or is joined.
+func (def *CapabilityComponentDefinition) generateOpenAPISchemaWithinBudget(ctx context.Context, hash, name string, imports ...*build.Instance) ([]byte, error) {
+	evaluation, err := schemaEvaluations.start(ctx, hash, def.SchemaCompilationSemaphore, func(ctx context.Context) ([]byte, error) {
+		return def.generateOpenAPISchema(ctx, name, imports...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var budget <-chan time.Time
+	if def.SchemaGenerationTimeout > 0 {
+		timer := time.NewTimer(def.SchemaGenerationTimeout)
+		defer timer.Stop()
+		budget = timer.C
+	}
+	select {
+	case <-evaluation.done:
+		return evaluation.schema, evaluation.err
+	case <-budget:
+		return nil, errors.Wrapf(ErrSchemaGenerationTimeout, "timeout %s", def.SchemaGenerationTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// schemaEvaluations are the evaluations of CUE templates running in the controller
+var schemaEvaluations = &schemaEvaluationTracker{running: map[string]*schemaEvaluation{}}
+
+// schemaEvaluationTracker tracks the running evaluations of CUE templates by the hash of the schematic content
+type schemaEvaluationTracker struct {
+	mu      sync.Mutex
+	running map[string]*schemaEvaluation
+}
+
+// schemaEvaluation is an evaluation of CUE template, the result is set once done is closed
+type schemaEvaluation struct {
+	done   chan struct{}
+	schema []byte
+	err    error
+}
+
+// start returns the running evaluation of the hash, or starts a new one in background once the semaphore is acquired
+// if it's set. The evaluation is shared by the callers, so it's not cancelled with the context of the caller.
+func (t *schemaEvaluationTracker) start(ctx context.Context, hash string, sem *semaphore.Weighted,
+	evaluate func(ctx context.Context) ([]byte, error)) (*schemaEvaluation, error) {
+	if evaluation := t.get(hash); evaluation != nil {
+		return evaluation, nil
+	}
+	if sem != nil {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+	release := func() {
+		if sem != nil {
+			sem.Release(1)
+		}
+	}
+	t.mu.Lock()
+	if evaluation, ok := t.running[hash]; ok {
+		t.mu.Unlock()
+		release()
+		return evaluation, nil
+	}
+	evaluation := &schemaEvaluation{done: make(chan struct{})}
+	t.running[hash] = evaluation
+	t.mu.Unlock()
+	go func() {
+		defer release()
+		evaluation.schema, evaluation.err = evaluate(context.WithoutCancel(ctx))
+		t.mu.Lock()
+		delete(t.running, hash)
+		t.mu.Unlock()
+		close(evaluation.done)
+	}()
+	return evaluation, nil
+}
+
+// get returns the running evaluation of the hash if any
+func (t *schemaEvaluationTracker) get(hash string) *schemaEvaluation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running[hash]
+}
+
+// GetOpenAPISchemaFromJSONSchema validates the JSON Schema declared by the schematic and returns it as the
+// parameter schema. The schema is stored as it is, so it must be a well-formed schema of an object.
+func GetOpenAPISchemaFromJSONSchema(jsonSchema *commontypes.JSONSchema) ([]byte, error) {
+	if jsonSchema == nil || strings.TrimSpace(jsonSchema.Schema) == "" {
+		return nil, errors.New("no schema is set in JSON Schema specification")
+	}
+	s := openapi3.NewSchema()
+	if err := s.UnmarshalJSON([]byte(jsonSchema.Schema)); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the JSON Schema")
+	}
+	if err := s.Validate(context.Background()); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON Schema")
+	}
+	if s.Type != openapi3.TypeObject {
+		return nil, fmt.Errorf("the type of the JSON Schema of parameters must be %s, but got %q", openapi3.TypeObject, s.Type)
+	}
+	return []byte(jsonSchema.Schema), nil
 }
 
 // GetOpenAPISchemaFromTerraformComponentDefinition gets OpenAPI v3 schema by WorkloadDefinition name
@@ -203,6 +374,150 @@ func GetOpenAPISchemaFromTerraformComponentDefinition(configuration string) ([]b
 	return generateJSONSchemaWithRequiredProperty(schemas, required)
 }
 
+// GenerateExample generates a minimal component of the ComponentDefinition in YAML format, the required parameters
+// are filled with placeholder values. It can be used to show users how to use the ComponentDefinition.
+func (def *CapabilityComponentDefinition) GenerateExample(ctx context.Context) ([]byte, error) {
+	var jsonSchema []byte
+	var err error
+	switch def.WorkloadType {
+	case util.TerraformDef:
+		if def.Terraform == nil || def.Terraform.Type == "remote" {
+			return nil, fmt.Errorf("cannot generate example for the Terraform ComponentDefinition %s without inline configuration", def.Name)
+		}
+		jsonSchema, err = GetOpenAPISchemaFromTerraformComponentDefinition(def.Terraform.Configuration)
+	case util.JSONSchemaDef:
+		jsonSchema, err = GetOpenAPISchemaFromJSONSchema(def.JSONSchema)
+	default:
+		jsonSchema, err = def.GetOpenAPISchema(ctx, def.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OpenAPI v3 JSON schema for capability %s: %w", def.Name, err)
+	}
+	return GenerateExampleFromSchema(def.Name, jsonSchema)
+}
+
+// GenerateExampleFromSchema generates a minimal component of the given type in YAML format from the OpenAPI v3 JSON
+// schema of its parameters, e.g. the schema stored in ConfigMap. Only the required parameters are filled: the default
+// value or the first enum value is used if present, otherwise a placeholder value of the parameter type is used.
+func GenerateExampleFromSchema(componentType string, jsonSchema []byte) ([]byte, error) {
+	s := openapi3.NewSchema()
+	if err := s.UnmarshalJSON(jsonSchema); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the OpenAPI v3 JSON schema of parameters")
+	}
+	component := struct {
+		Name       string      `json:"name"`
+		Type       string      `json:"type"`
+		Properties interface{} `json:"properties,omitempty"`
+	}{
+		Name:       "my-" + componentType,
+		Type:       componentType,
+		Properties: exampleValue("", s),
+	}
+	return yaml.Marshal(component)
+}
+
+func exampleValue(name string, s *openapi3.Schema) interface{} {
+	if s.Default != nil {
+		return s.Default
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	switch s.Type {
+	case openapi3.TypeString:
+		return fmt.Sprintf("<%s>", name)
+	case openapi3.TypeInteger, openapi3.TypeNumber:
+		if s.Min != nil {
+			return *s.Min
+		}
+		return 0
+	case openapi3.TypeBoolean:
+		return false
+	case openapi3.TypeArray:
+		items := []interface{}{}
+		if s.MinItems > 0 && s.Items != nil && s.Items.Value != nil {
+			items = append(items, exampleValue(name, s.Items.Value))
+		}
+		return items
+	default:
+		properties := map[string]interface{}{}
+		for _, required := range s.Required {
+			if property, ok := s.Properties[required]; ok && property.Value != nil {
+				properties[required] = exampleValue(required, property.Value)
+			}
+		}
+		return properties
+	}
+}
+
+// TerraformVariableError means the parameters generated from a Terraform configuration don't match its variables
+type TerraformVariableError struct {
+	Mismatches []string
+}
+
+func (e *TerraformVariableError) Error() string {
+	return fmt.Sprintf("the parameters don't match the variables of the Terraform configuration: %s", strings.Join(e.Mismatches, "; "))
+}
+
+// ValidateTerraformVariables validates that the OpenAPI v3 JSON schema of parameters matches the variables declared in
+// the Terraform configuration, including the existence, the requirement and the default value of each variable, and
+// that the variables referenced by the resources, the modules and the outputs are declared.
+// A *TerraformVariableError is returned if there is any mismatch.
+func ValidateTerraformVariables(configuration string, jsonSchema []byte) error {
+	variables, _, err := common.ParseTerraformVariables(configuration)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the variables of the Terraform configuration")
+	}
+	references, err := common.ParseTerraformVariableReferences(configuration)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the variable references of the Terraform configuration")
+	}
+	s := openapi3.NewSchema()
+	if err = s.UnmarshalJSON(jsonSchema); err != nil {
+		return errors.Wrap(err, "failed to parse the OpenAPI v3 JSON schema of parameters")
+	}
+	required := make(map[string]bool)
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	otherProperties := parseOtherProperties4TerraformDefinition()
+
+	var mismatches []string
+	for name, v := range variables {
+		property, ok := s.Properties[name]
+		if !ok || property.Value == nil {
+			mismatches = append(mismatches, fmt.Sprintf("variable %s is not a parameter", name))
+			continue
+		}
+		if v.Required != required[name] {
+			mismatches = append(mismatches, fmt.Sprintf("variable %s is required: %t, but parameter %s is required: %t", name, v.Required, name, required[name]))
+		}
+		if v.Default != nil {
+			if err = property.Value.VisitJSON(v.Default); err != nil {
+				mismatches = append(mismatches, fmt.Sprintf("the default value of variable %s doesn't match its type: %v", name, err))
+			}
+		}
+	}
+	for name := range s.Properties {
+		if _, ok := variables[name]; ok {
+			continue
+		}
+		if _, ok := otherProperties[name]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("parameter %s is not a declared variable", name))
+		}
+	}
+	for name := range references {
+		if _, ok := variables[name]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("var.%s is referenced but not a declared variable", name))
+		}
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	sort.Strings(mismatches)
+	return &TerraformVariableError{Mismatches: mismatches}
+}
+
 // GetTerraformConfigurationFromRemote gets Terraform Configuration(HCL)
 func GetTerraformConfigurationFromRemote(name, remoteURL, remotePath string, sshPublicKey *gitssh.PublicKeys) (string, error) {
 	userHome, err := os.UserHomeDir()
@@ -356,14 +671,105 @@ func GetGitSSHPublicKey(ctx context.Context, k8sClient client.Client, gitCredent
 	return publicKey, nil
 }
 
-// StoreOpenAPISchema stores OpenAPI v3 schema in ConfigMap from WorkloadDefinition
+// StoreOpenAPISchema stores OpenAPI v3 schema in ConfigMap from WorkloadDefinition. The name of the ConfigMap of the
+// definition is returned, which is empty if the schema is stored by SchemaStore instead.
 func (def *CapabilityComponentDefinition) StoreOpenAPISchema(ctx context.Context, k8sClient client.Client, namespace, name, revName string) (string, error) {
+	jsonSchema, err := def.getComponentOpenAPISchema(ctx, k8sClient, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	def.SchemaSize = len(jsonSchema)
+	if def.SchemaStore != nil {
+		errs := velaslices.ParMap([]string{def.ComponentDefinition.Name, revName}, func(name string) error {
+			return def.SchemaStore.Put(ctx, namespace, name, jsonSchema)
+		})
+		return "", velaerrors.AggregateErrors(errs)
+	}
+	componentDefinition := def.ComponentDefinition
+	// Create a configmap to store parameter for each definitionRevision
+	defRev := new(v1beta1.DefinitionRevision)
+	if err = k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: revName}, defRev); err != nil {
+		return "", err
+	}
+	// record the handled value of the forced refresh, the schema is regenerated on every reconcile
+	annotations := util.MergeMapOverrideWithDst(map[string]string{}, def.SchemaAnnotations)
+	if refresh, ok := componentDefinition.Annotations[oam.AnnotationForceSchemaRefresh]; ok {
+		annotations[oam.AnnotationForceSchemaRefresh] = refresh
+	}
+	if def.SchemaContentHash != "" {
+		annotations[oam.AnnotationSchemaContentHash] = def.SchemaContentHash
+	}
+	var checksum string
+	if def.SchemaChecksum {
+		if checksum, err = SchemaChecksum(jsonSchema); err != nil {
+			return "", fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, componentDefinition.Name, err)
+		}
+		annotations[oam.AnnotationSchemaChecksum] = checksum
+	}
+	targets := []schemaConfigMapTarget{{
+		definitionName: componentDefinition.Name,
+		labels:         componentDefinition.Labels,
+		annotations:    annotations,
+		owner: metav1.OwnerReference{
+			APIVersion: componentDefinition.APIVersion,
+			Kind:       componentDefinition.Kind,
+			Name:       componentDefinition.Name,
+			UID:        componentDefinition.GetUID(),
+		},
+	}, {
+		definitionName: revName,
+		labels:         defRev.Spec.ComponentDefinition.Labels,
+		annotations:    annotations,
+		owner: metav1.OwnerReference{
+			APIVersion: defRev.APIVersion,
+			Kind:       defRev.Kind,
+			Name:       defRev.Name,
+			UID:        defRev.GetUID(),
+		},
+	}}
+	storageNamespace := namespace
+	if def.SchemaStorageNamespace != "" {
+		storageNamespace = def.SchemaStorageNamespace
+		for i := range targets {
+			target := &targets[i]
+			target.configMapName = SchemaConfigMapKey(def.SchemaStorageNamespace, namespace, target.definitionName).Name
+			target.labels = util.MergeMapOverrideWithDst(target.labels, map[string]string{
+				oam.LabelComponentDefinitionName:     componentDefinition.Name,
+				oam.LabelSharedSchemaSourceNamespace: namespace,
+			})
+			if storageNamespace != namespace {
+				// owner references across namespaces are not allowed, such ConfigMaps are cleaned up by the controller
+				target.owner = metav1.OwnerReference{}
+			}
+		}
+	}
+	cmName, err := def.storeSchemaConfigMaps(ctx, k8sClient, storageNamespace, typeComponentDefinition, jsonSchema, targets)
+	if err != nil || checksum == "" || defRev.GetAnnotations()[oam.AnnotationSchemaChecksum] == checksum {
+		return cmName, err
+	}
+	patch := client.MergeFrom(defRev.DeepCopy())
+	metav1.SetMetaDataAnnotation(&defRev.ObjectMeta, oam.AnnotationSchemaChecksum, checksum)
+	if err = k8sClient.Patch(ctx, defRev, patch); err != nil {
+		return cmName, fmt.Errorf("cannot record the schema checksum on DefinitionRevision %s: %w", defRev.Name, err)
+	}
+	return cmName, nil
+}
+
+// GenerateOpenAPISchema generates the OpenAPI v3 JSON schema of the parameters like StoreOpenAPISchema, but never
+// stores it, e.g. to validate the definition without side effects
+func (def *CapabilityComponentDefinition) GenerateOpenAPISchema(ctx context.Context, k8sClient client.Client, namespace, name string) ([]byte, error) {
+	return def.getComponentOpenAPISchema(ctx, k8sClient, namespace, name)
+}
+
+// getComponentOpenAPISchema generates the OpenAPI v3 JSON schema of the parameters according to the schematic, removes
+// the internal parameters if ExcludeInternalParameters is set, and applies the registered SchemaPostProcessors to it
+func (def *CapabilityComponentDefinition) getComponentOpenAPISchema(ctx context.Context, k8sClient client.Client, namespace, name string) ([]byte, error) {
 	var jsonSchema []byte
 	var err error
 	switch def.WorkloadType {
 	case util.TerraformDef:
 		if def.Terraform == nil {
-			return "", fmt.Errorf("no Configuration is set in Terraform specification: %s", def.Name)
+			return nil, fmt.Errorf("no Configuration is set in Terraform specification: %s", def.Name)
 		}
 		configuration := def.Terraform.Configuration
 		if def.Terraform.Type == "remote" {
@@ -373,53 +779,92 @@ func (def *CapabilityComponentDefinition) StoreOpenAPISchema(ctx context.Context
 				gitCredentialsSecretReference := def.Terraform.GitCredentialsSecretReference
 				publicKey, err = GetGitSSHPublicKey(ctx, k8sClient, gitCredentialsSecretReference)
 				if err != nil {
-					return "", fmt.Errorf("issue with gitCredentialsSecretReference %s/%s: %w", gitCredentialsSecretReference.Namespace, gitCredentialsSecretReference.Name, err)
+					return nil, fmt.Errorf("issue with gitCredentialsSecretReference %s/%s: %w", gitCredentialsSecretReference.Namespace, gitCredentialsSecretReference.Name, err)
 				}
 			}
 			configuration, err = GetTerraformConfigurationFromRemote(def.Name, def.Terraform.Configuration, def.Terraform.Path, publicKey)
 			if err != nil {
-				return "", fmt.Errorf("cannot get Terraform configuration %s from remote: %w", def.Name, err)
+				return nil, fmt.Errorf("cannot get Terraform configuration %s from remote: %w", def.Name, err)
 			}
 		}
 		jsonSchema, err = GetOpenAPISchemaFromTerraformComponentDefinition(configuration)
+		if err == nil {
+			err = ValidateTerraformVariables(configuration, jsonSchema)
+		}
+	case util.JSONSchemaDef:
+		jsonSchema, err = GetOpenAPISchemaFromJSONSchema(def.JSONSchema)
 	default:
-		jsonSchema, err = def.GetOpenAPISchema(ctx, name)
+		var packages []cuePackage
+		if packages, err = readCUEPackages(ctx, k8sClient, namespace, def.ComponentDefinition.GetAnnotations()); err != nil {
+			return nil, err
+		}
+		var processed bool
+		if jsonSchema, processed, err = def.generateCUEOpenAPISchema(ctx, k8sClient, namespace, name, packages); err == nil && processed {
+			return jsonSchema, nil
+		}
 	}
-	if err != nil {
-		return "", fmt.Errorf("failed to generate OpenAPI v3 JSON schema for capability %s: %w", def.Name, err)
+	if err == nil && def.ExcludeInternalParameters {
+		jsonSchema, err = removeInternalParameters(jsonSchema)
+	}
+	if err == nil {
+		jsonSchema, err = postProcessSchema(&def.ComponentDefinition, jsonSchema)
 	}
-	componentDefinition := def.ComponentDefinition
-	ownerReference := []metav1.OwnerReference{{
-		APIVersion:         componentDefinition.APIVersion,
-		Kind:               componentDefinition.Kind,
-		Name:               componentDefinition.Name,
-		UID:                componentDefinition.GetUID(),
-		Controller:         pointer.Bool(true),
-		BlockOwnerDeletion: pointer.Bool(true),
-	}}
-	cmName, err := def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, componentDefinition.Name, typeComponentDefinition, componentDefinition.Labels, nil, jsonSchema, ownerReference)
 	if err != nil {
-		return cmName, err
+		return nil, fmt.Errorf("failed to generate OpenAPI v3 JSON schema for capability %s: %w", def.Name, err)
 	}
+	return jsonSchema, nil
+}
 
-	// Create a configmap to store parameter for each definitionRevision
-	defRev := new(v1beta1.DefinitionRevision)
-	if err = k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: revName}, defRev); err != nil {
-		return "", err
+// removeInternalParameters removes the parameters of the internal access level from the JSON schema
+func removeInternalParameters(jsonSchema []byte) ([]byte, error) {
+	s := openapi3.NewSchema()
+	if err := s.UnmarshalJSON(jsonSchema); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI v3 JSON schema: %w", err)
 	}
-	ownerReference = []metav1.OwnerReference{{
-		APIVersion:         defRev.APIVersion,
-		Kind:               defRev.Kind,
-		Name:               defRev.Name,
-		UID:                defRev.GetUID(),
-		Controller:         pointer.Bool(true),
-		BlockOwnerDeletion: pointer.Bool(true),
-	}}
-	_, err = def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, revName, typeComponentDefinition, defRev.Spec.ComponentDefinition.Labels, nil, jsonSchema, ownerReference)
-	if err != nil {
-		return cmName, err
+	schema.RemoveInternalParameters(s)
+	return s.MarshalJSON()
+}
+
+// schemaConfigMapTarget describes a ConfigMap storing the OpenAPI schema, owned by a definition or a DefinitionRevision
+type schemaConfigMapTarget struct {
+	definitionName string
+	// configMapName overrides the name of the ConfigMap derived from the definitionName if it's set
+	configMapName    string
+	labels           map[string]string
+	annotations      map[string]string
+	appliedWorkloads []string
+	owner            metav1.OwnerReference
+}
+
+// storeSchemaConfigMaps writes the schema ConfigMaps of the targets concurrently. The name of the ConfigMap of the
+// first target is returned, and errors are reported in the order of targets, so the result doesn't depend on scheduling.
+func (def *CapabilityBaseDefinition) storeSchemaConfigMaps(ctx context.Context, k8sClient client.Client, namespace, definitionType string,
+	jsonSchema []byte, targets []schemaConfigMapTarget, opts ...velaslices.ParOption) (string, error) {
+	type result struct {
+		cmName string
+		err    error
 	}
-	return cmName, nil
+	results := velaslices.ParMap(targets, func(target schemaConfigMapTarget) result {
+		var ownerReferences []metav1.OwnerReference
+		if owner := target.owner; owner.Name != "" {
+			owner.Controller = pointer.Bool(true)
+			owner.BlockOwnerDeletion = pointer.Bool(true)
+			ownerReferences = []metav1.OwnerReference{owner}
+		}
+		cmName := target.configMapName
+		if cmName == "" {
+			cmName = capabilityConfigMapName(definitionType, target.definitionName)
+		}
+		err := def.createOrUpdateConfigMap(ctx, k8sClient, namespace, cmName, target.definitionName,
+			target.labels, target.annotations, target.appliedWorkloads, jsonSchema, ownerReferences)
+		return result{cmName: cmName, err: err}
+	}, opts...)
+	for _, res := range results {
+		if res.err != nil {
+			return results[0].cmName, res.err
+		}
+	}
+	return results[0].cmName, nil
 }
 
 // CapabilityTraitDefinition is the Capability struct for TraitDefinition
@@ -630,29 +1075,68 @@ func (def *CapabilityPolicyDefinition) StoreOpenAPISchema(ctx context.Context, k
 
 // CapabilityBaseDefinition is the base struct for CapabilityWorkloadDefinition and CapabilityTraitDefinition
 type CapabilityBaseDefinition struct {
+	// DisableSchemaCompression stores the large schema uncompressed, which may exceed the size limit of ConfigMap
+	DisableSchemaCompression bool `json:"-"`
+	// SchemaFragmentMinSize stores the nested parameter schemas no smaller than the size in bytes as the fragments
+	// shared by definitions, which are referred by $ref from the schema. It's disabled if not positive.
+	SchemaFragmentMinSize int `json:"-"`
+	// SchemaLabels are the extra labels of the schema ConfigMaps, e.g. the tenant labels that the label based RBAC and
+	// network policies select. The labels of the definition take precedence over them.
+	SchemaLabels map[string]string `json:"-"`
+	// OpenAPIV3Document also stores the complete OpenAPI v3 document of the parameters along with the schema, for the
+	// tools which only consume OpenAPI v3 documents
+	OpenAPIV3Document bool `json:"-"`
 }
 
 // CreateOrUpdateConfigMap creates ConfigMap to store OpenAPI v3 schema or or updates data in ConfigMap
 func (def *CapabilityBaseDefinition) CreateOrUpdateConfigMap(ctx context.Context, k8sClient client.Client, namespace,
 	definitionName, definitionType string, labels map[string]string, appliedWorkloads []string, jsonSchema []byte, ownerReferences []metav1.OwnerReference) (string, error) {
-	cmName := fmt.Sprintf("%s-%s%s", definitionType, types.CapabilityConfigMapNamePrefix, definitionName)
+	cmName := capabilityConfigMapName(definitionType, definitionName)
+	return cmName, def.createOrUpdateConfigMap(ctx, k8sClient, namespace, cmName, definitionName, labels, nil, appliedWorkloads, jsonSchema, ownerReferences)
+}
+
+func (def *CapabilityBaseDefinition) createOrUpdateConfigMap(ctx context.Context, k8sClient client.Client, namespace, cmName,
+	definitionName string, labels, annotations map[string]string, appliedWorkloads []string, jsonSchema []byte, ownerReferences []metav1.OwnerReference) error {
 	var cm v1.ConfigMap
-	var data = map[string]string{
-		types.OpenapiV3JSONSchema: string(jsonSchema),
+	var err error
+	extras := map[string][]byte{}
+	if def.OpenAPIV3Document {
+		if extras[types.OpenapiV3Document], err = GenerateOpenAPIV3Document(definitionName, jsonSchema); err != nil {
+			return fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, definitionName, err)
+		}
+	}
+	if defaults, err := GenerateParameterDefaults(jsonSchema); err != nil {
+		return fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, definitionName, err)
+	} else if defaults != nil {
+		extras[types.ParameterDefaults] = defaults
+	}
+	if def.SchemaFragmentMinSize > 0 {
+		var fragments map[string][]byte
+		if jsonSchema, fragments, err = ExtractSchemaFragments(jsonSchema, namespace, def.SchemaFragmentMinSize); err != nil {
+			return fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, definitionName, err)
+		}
+		if err = publishSchemaFragments(ctx, k8sClient, namespace, fragments); err != nil {
+			return fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, definitionName, err)
+		}
+	}
+	data, err := encodeOpenAPISchema(jsonSchema, extras, !def.DisableSchemaCompression)
+	if err != nil {
+		return fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, definitionName, err)
 	}
+	labels = util.MergeMapOverrideWithDst(def.SchemaLabels, labels)
 	if labels == nil {
 		labels = make(map[string]string)
 	}
 	labels[types.LabelDefinition] = "schema"
 	labels[types.LabelDefinitionName] = definitionName
-	annotations := make(map[string]string)
+	annotations = util.MergeMapOverrideWithDst(map[string]string{}, annotations)
 	if appliedWorkloads != nil {
 		annotations[types.AnnoDefinitionAppliedWorkloads] = strings.Join(appliedWorkloads, ",")
 	}
 
 	// No need to check the existence of namespace, if it doesn't exist, API server will return the error message
 	// before it's to be reconciled by ComponentDefinition/TraitDefinition controller.
-	err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: cmName}, &cm)
+	err = k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: cmName}, &cm)
 	if err != nil && apierrors.IsNotFound(err) {
 		cm = v1.ConfigMap{
 			TypeMeta: metav1.TypeMeta{
@@ -670,25 +1154,257 @@ func (def *CapabilityBaseDefinition) CreateOrUpdateConfigMap(ctx context.Context
 		}
 		err = k8sClient.Create(ctx, &cm)
 		if err != nil {
-			return cmName, fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, definitionName, err)
+			return fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, definitionName, err)
 		}
 		klog.InfoS("Successfully stored Capability Schema in ConfigMap", "configMap", klog.KRef(namespace, cmName))
-		return cmName, nil
+		return nil
 	}
 
 	cm.Data = data
 	cm.Labels = labels
 	cm.Annotations = annotations
 	if err = k8sClient.Update(ctx, &cm); err != nil {
-		return cmName, fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, definitionName, err)
+		return fmt.Errorf(util.ErrUpdateCapabilityInConfigMap, definitionName, err)
 	}
 	klog.InfoS("Successfully update Capability Schema in ConfigMap", "configMap", klog.KRef(namespace, cmName))
-	return cmName, nil
+	return nil
+}
+
+// encodeOpenAPISchema builds the ConfigMap data of the OpenAPI v3 JSON schema and the extra data derived from it, e.g.
+// the OpenAPI v3 document, they will be gzip compressed and base64 encoded if compress is set and they exceed
+// schemaCompressionThreshold, so that they can fit into the size limit of ConfigMap
+func encodeOpenAPISchema(jsonSchema []byte, extras map[string][]byte, compress bool) (map[string]string, error) {
+	values := map[string][]byte{types.OpenapiV3JSONSchema: jsonSchema}
+	size := len(jsonSchema)
+	for key, value := range extras {
+		values[key] = value
+		size += len(value)
+	}
+	data := make(map[string]string, len(values)+1)
+	if !compress || size <= schemaCompressionThreshold {
+		for key, value := range values {
+			data[key] = string(value)
+		}
+		return data, nil
+	}
+	for key, value := range values {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(value); err != nil {
+			return nil, errors.Wrapf(err, "failed to compress %s", key)
+		}
+		if err := w.Close(); err != nil {
+			return nil, errors.Wrapf(err, "failed to compress %s", key)
+		}
+		data[key] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	data[types.OpenapiV3JSONSchemaEncoding] = types.SchemaEncodingGzipBase64
+	return data, nil
+}
+
+// GetOpenAPISchemaFromConfigMap returns the OpenAPI v3 JSON schema stored in the ConfigMap, it will be decompressed
+// if the schema is stored compressed
+func GetOpenAPISchemaFromConfigMap(cm *v1.ConfigMap) ([]byte, error) {
+	return decodeSchemaData(cm, types.OpenapiV3JSONSchema)
+}
+
+// GetOpenAPIV3DocumentFromConfigMap returns the OpenAPI v3 document stored in the ConfigMap, it's nil if the document
+// is not stored, see CapabilityBaseDefinition.OpenAPIV3Document
+func GetOpenAPIV3DocumentFromConfigMap(cm *v1.ConfigMap) ([]byte, error) {
+	if _, ok := cm.Data[types.OpenapiV3Document]; !ok {
+		return nil, nil
+	}
+	return decodeSchemaData(cm, types.OpenapiV3Document)
+}
+
+func decodeSchemaData(cm *v1.ConfigMap, key string) ([]byte, error) {
+	data := cm.Data[key]
+	switch encoding := cm.Data[types.OpenapiV3JSONSchemaEncoding]; encoding {
+	case "":
+		return []byte(data), nil
+	case types.SchemaEncodingGzipBase64:
+		compressed, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s in ConfigMap %s", key, cm.Name)
+		}
+		r, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decompress %s in ConfigMap %s", key, cm.Name)
+		}
+		defer func() {
+			_ = r.Close()
+		}()
+		decompressed, err := io.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decompress %s in ConfigMap %s", key, cm.Name)
+		}
+		return decompressed, nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %s of %s in ConfigMap %s", encoding, key, cm.Name)
+	}
+}
+
+// GetParameterDefaultsFromConfigMap returns the default values of the parameters stored in the ConfigMap. They're
+// derived from the stored schema if the ConfigMap is written before the defaults are stored.
+func GetParameterDefaultsFromConfigMap(cm *v1.ConfigMap) (map[string]interface{}, error) {
+	var data []byte
+	var err error
+	if _, ok := cm.Data[types.ParameterDefaults]; ok {
+		data, err = decodeSchemaData(cm, types.ParameterDefaults)
+	} else {
+		var jsonSchema []byte
+		if jsonSchema, err = GetOpenAPISchemaFromConfigMap(cm); err == nil {
+			data, err = GenerateParameterDefaults(jsonSchema)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defaults := map[string]interface{}{}
+	if data == nil {
+		return defaults, nil
+	}
+	if err = json.Unmarshal(data, &defaults); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s in ConfigMap %s", types.ParameterDefaults, cm.Name)
+	}
+	return defaults, nil
+}
+
+// GetParameterDefaults returns the default values of the parameters applied by the schema stored by
+// StoreOpenAPISchema, the parameters without defaults are omitted
+func (def *CapabilityComponentDefinition) GetParameterDefaults(ctx context.Context, k8sClient client.Client, namespace, name string) (map[string]interface{}, error) {
+	schema, err := def.GetSchemaFromConfigMap(ctx, k8sClient, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return ExtractParameterDefaults(schema), nil
+}
+
+// GenerateParameterDefaults returns the JSON object of the default values of the parameters applied by the OpenAPI v3
+// JSON schema, it's nil if no parameter has a default value
+func GenerateParameterDefaults(jsonSchema []byte) ([]byte, error) {
+	schema := &openapi3.Schema{}
+	if err := schema.UnmarshalJSON(jsonSchema); err != nil {
+		return nil, errors.Wrap(err, "failed to parse OpenAPI v3 JSON schema")
+	}
+	defaults := ExtractParameterDefaults(schema)
+	if len(defaults) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(defaults)
+}
+
+// ExtractParameterDefaults returns the default values of the properties of the schema. The defaults of the nested
+// properties are collected into nested objects unless the object property has a default value of its own, and the
+// properties without defaults are omitted.
+func ExtractParameterDefaults(schema *openapi3.Schema) map[string]interface{} {
+	defaults := map[string]interface{}{}
+	for name, property := range schema.Properties {
+		if property == nil || property.Value == nil {
+			continue
+		}
+		if property.Value.Default != nil {
+			defaults[name] = property.Value.Default
+			continue
+		}
+		if nested := ExtractParameterDefaults(property.Value); len(nested) != 0 {
+			defaults[name] = nested
+		}
+	}
+	return defaults
+}
+
+// FindUndocumentedParameters returns the sorted names of the top-level parameters of the OpenAPI v3 JSON schema
+// which have no description
+func FindUndocumentedParameters(jsonSchema []byte) ([]string, error) {
+	schema := openapi3.NewSchema()
+	if err := schema.UnmarshalJSON(jsonSchema); err != nil {
+		return nil, err
+	}
+	var names []string
+	for name, property := range schema.Properties {
+		if property == nil || property.Value == nil || strings.TrimSpace(property.Value.Description) == "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GenerateOpenAPIV3Document wraps the OpenAPI v3 JSON schema of the parameters into a complete OpenAPI v3 document,
+// where the schema is the parameter component like the document generated from the CUE template
+func GenerateOpenAPIV3Document(name string, jsonSchema []byte) ([]byte, error) {
+	schema := &openapi3.Schema{}
+	if err := schema.UnmarshalJSON(jsonSchema); err != nil {
+		return nil, errors.Wrap(err, "failed to parse OpenAPI v3 JSON schema")
+	}
+	document := &openapi3.T{
+		OpenAPI: "3.0.0",
+		Info:    &openapi3.Info{Title: name, Version: "no version"},
+		Paths:   openapi3.Paths{},
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{"parameter": openapi3.NewSchemaRef("", schema)},
+		},
+	}
+	return document.MarshalJSON()
+}
+
+var (
+	// ErrSchemaNotStored means the schema ConfigMap of the definition has not been created by the controller yet
+	ErrSchemaNotStored = errors.New("the OpenAPI v3 JSON schema is not stored yet")
+	// ErrSchemaCorrupted means the schema stored in the ConfigMap cannot be decoded or parsed
+	ErrSchemaCorrupted = errors.New("the stored OpenAPI v3 JSON schema is corrupted")
+)
+
+// GetSchemaFromConfigMap gets the OpenAPI v3 schema of the parameters stored by StoreOpenAPISchema. The name is either
+// the name of the ComponentDefinition or of one of its DefinitionRevisions. ErrSchemaNotStored is returned if the
+// schema is not stored yet, and ErrSchemaCorrupted is returned if the stored schema cannot be parsed.
+func (def *CapabilityComponentDefinition) GetSchemaFromConfigMap(ctx context.Context, k8sClient client.Client, namespace, name string) (*openapi3.Schema, error) {
+	store := def.GetSchemaStore(k8sClient)
+	data, err := store.Get(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	s := openapi3.NewSchema()
+	if err = s.UnmarshalJSON(data); err != nil {
+		return nil, errors.Wrapf(ErrSchemaCorrupted, "failed to parse the schema in %s: %s", store.Location(namespace, name), err.Error())
+	}
+	return s, nil
+}
+
+// GetSchemaStore returns the SchemaStore of the definition, which defaults to the ConfigMaps
+func (def *CapabilityComponentDefinition) GetSchemaStore(k8sClient client.Client) SchemaStore {
+	if def.SchemaStore != nil {
+		return def.SchemaStore
+	}
+	return &ConfigMapSchemaStore{Client: k8sClient, StorageNamespace: def.SchemaStorageNamespace}
+}
+
+// ComponentDefinitionConfigMapName returns the name of the ConfigMap which stores the OpenAPI v3 schema
+// of a ComponentDefinition or of one of its DefinitionRevisions
+func ComponentDefinitionConfigMapName(name string) string {
+	return capabilityConfigMapName(typeComponentDefinition, name)
+}
+
+// SchemaConfigMapKey returns the key of the ConfigMap which stores the OpenAPI v3 schema of the ComponentDefinition
+// or DefinitionRevision in the namespace. If storageNamespace is set, the ConfigMaps of all namespaces are centralized
+// in it, and the namespace is encoded into the ConfigMap name, e.g. component-schema-<namespace>.<name>, so that
+// definitions of the same name in different namespaces never collide. Namespaces never contain dots, so the encoded
+// name is unambiguous.
+func SchemaConfigMapKey(storageNamespace, namespace, name string) client.ObjectKey {
+	if storageNamespace == "" {
+		return client.ObjectKey{Namespace: namespace, Name: ComponentDefinitionConfigMapName(name)}
+	}
+	return client.ObjectKey{Namespace: storageNamespace, Name: ComponentDefinitionConfigMapName(namespace + "." + name)}
+}
+
+func capabilityConfigMapName(definitionType, definitionName string) string {
+	return fmt.Sprintf("%s-%s%s", definitionType, types.CapabilityConfigMapNamePrefix, definitionName)
 }
 
 // getOpenAPISchema is the main function for GetDefinition API
-func getOpenAPISchema(ctx context.Context, capability types.Capability) ([]byte, error) {
-	s, err := schema.ParsePropertiesToSchema(ctx, capability.CueTemplate)
+func getOpenAPISchema(ctx context.Context, capability types.Capability, imports ...*build.Instance) ([]byte, error) {
+	s, err := schema.ParsePropertiesToSchemaWithImports(ctx, capability.CueTemplate, imports)
 	if err != nil {
 		return nil, err
 	}
@@ -699,3 +1415,57 @@ func getOpenAPISchema(ctx context.Context, capability types.Capability) ([]byte,
 	}
 	return parameter, nil
 }
+
+// LoadCUEPackages loads the CUE packages stored in the ConfigMaps listed by the annotation
+// oam.AnnotationCUEPackageConfigMaps of a definition. The import path of the package is specified
+// by the CUEPackagePathKey of the ConfigMap and the other keys ending with .cue are the files of the package.
+func LoadCUEPackages(ctx context.Context, k8sClient client.Client, namespace string, annotations map[string]string) ([]*build.Instance, error) {
+	packages, err := readCUEPackages(ctx, k8sClient, namespace, annotations)
+	if err != nil {
+		return nil, err
+	}
+	return buildCUEPackages(namespace, packages)
+}
+
+// readCUEPackages reads the content of the CUE packages listed by the annotation oam.AnnotationCUEPackageConfigMaps
+func readCUEPackages(ctx context.Context, k8sClient client.Client, namespace string, annotations map[string]string) ([]cuePackage, error) {
+	var packages []cuePackage
+	for _, name := range strings.Split(annotations[oam.AnnotationCUEPackageConfigMaps], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		cm := &v1.ConfigMap{}
+		if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, cm); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("the ConfigMap %s/%s of the CUE package is not found", namespace, name)
+			}
+			return nil, errors.Wrapf(err, "failed to get the ConfigMap %s/%s of the CUE package", namespace, name)
+		}
+		path := cm.Data[CUEPackagePathKey]
+		if path == "" {
+			return nil, fmt.Errorf("the import path of the CUE package is not set by the key %q of ConfigMap %s/%s", CUEPackagePathKey, namespace, name)
+		}
+		templates := make(map[string]string)
+		for key, template := range cm.Data {
+			if strings.HasSuffix(key, ".cue") {
+				templates[key] = template
+			}
+		}
+		packages = append(packages, cuePackage{Path: path, Templates: templates, configMap: name})
+	}
+	return packages, nil
+}
+
+// buildCUEPackages builds the CUE packages read by readCUEPackages to be imported by the CUE templates
+func buildCUEPackages(namespace string, packages []cuePackage) ([]*build.Instance, error) {
+	var imports []*build.Instance
+	for _, pkg := range packages {
+		bi, err := cueutil.BuildImport(pkg.Path, pkg.Templates)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to build the CUE package in ConfigMap %s/%s", namespace, pkg.configMap)
+		}
+		imports = append(imports, bi)
+	}
+	return imports, nil
+}
@@ -21,19 +21,26 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/go-cmp/cmp"
+	velaslices "github.com/kubevela/pkg/util/slices"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
 
 	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"golang.org/x/crypto/ssh/testdata"
@@ -535,3 +542,628 @@ func TestGetGitSSHPublicKey(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreLargeOpenAPISchema(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := fake.NewClientBuilder().Build()
+	def := &CapabilityBaseDefinition{}
+
+	var properties []string
+	for i := 0; i < 20000; i++ {
+		properties = append(properties, fmt.Sprintf(`"param%d":{"type":"string","description":"the parameter %d of the component"}`, i, i))
+	}
+	largeSchema := []byte(fmt.Sprintf(`{"properties":{%s},"type":"object"}`, strings.Join(properties, ",")))
+	const configMapSizeLimit = 1024 * 1024
+	assert.Greater(t, len(largeSchema), configMapSizeLimit)
+
+	cmName, err := def.CreateOrUpdateConfigMap(ctx, k8sClient, "default", "large", typeComponentDefinition, nil, nil, largeSchema, nil)
+	assert.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: cmName}, cm))
+	assert.Equal(t, types.SchemaEncodingGzipBase64, cm.Data[types.OpenapiV3JSONSchemaEncoding])
+	size := 0
+	for k, v := range cm.Data {
+		size += len(k) + len(v)
+	}
+	assert.Less(t, size, configMapSizeLimit)
+	schema, err := GetOpenAPISchemaFromConfigMap(cm)
+	assert.NoError(t, err)
+	assert.Equal(t, largeSchema, schema)
+
+	// a small schema is stored as plain text and the encoding marker is removed
+	smallSchema := []byte(`{"properties":{"image":{"type":"string"}},"type":"object"}`)
+	_, err = def.CreateOrUpdateConfigMap(ctx, k8sClient, "default", "large", typeComponentDefinition, nil, nil, smallSchema, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: cmName}, cm))
+	assert.Equal(t, string(smallSchema), cm.Data[types.OpenapiV3JSONSchema])
+	assert.NotContains(t, cm.Data, types.OpenapiV3JSONSchemaEncoding)
+	schema, err = GetOpenAPISchemaFromConfigMap(cm)
+	assert.NoError(t, err)
+	assert.Equal(t, smallSchema, schema)
+
+	cm.Data[types.OpenapiV3JSONSchemaEncoding] = "unknown"
+	_, err = GetOpenAPISchemaFromConfigMap(cm)
+	assert.Error(t, err)
+}
+
+func TestValidateTerraformVariables(t *testing.T) {
+	configuration := `
+variable "bucket" {
+  description = "OSS bucket name"
+  type        = string
+}
+
+variable "acl" {
+  type    = string
+  default = "private"
+}
+`
+	jsonSchema, err := GetOpenAPISchemaFromTerraformComponentDefinition(configuration)
+	assert.NoError(t, err)
+	assert.NoError(t, ValidateTerraformVariables(configuration, jsonSchema))
+
+	cases := map[string]struct {
+		configuration string
+		jsonSchema    string
+		mismatches    []string
+	}{
+		"default value mismatches type": {
+			configuration: `
+variable "size" {
+  type    = number
+  default = "large"
+}`,
+			mismatches: []string{"the default value of variable size doesn't match its type"},
+		},
+		"variable missing in parameters": {
+			configuration: configuration,
+			jsonSchema:    `{"type":"object","required":["bucket"],"properties":{"bucket":{"type":"string"}}}`,
+			mismatches:    []string{"variable acl is not a parameter"},
+		},
+		"undeclared parameter and requirement mismatch": {
+			configuration: configuration,
+			jsonSchema:    `{"type":"object","properties":{"bucket":{"type":"string"},"acl":{"type":"string"},"zone":{"type":"string"}}}`,
+			mismatches: []string{
+				"parameter zone is not a declared variable",
+				"variable bucket is required: true, but parameter bucket is required: false",
+			},
+		},
+		"misspelled variable reference": {
+			configuration: configuration + `
+resource "alicloud_oss_bucket" "bucket" {
+  bucket = var.bucket
+  acl    = var.acll
+}
+
+output "BUCKET_NAME" {
+  value = var.bucket
+}`,
+			mismatches: []string{"var.acll is referenced but not a declared variable"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := []byte(tc.jsonSchema)
+			if tc.jsonSchema == "" {
+				s, err = GetOpenAPISchemaFromTerraformComponentDefinition(tc.configuration)
+				assert.NoError(t, err)
+			}
+			err := ValidateTerraformVariables(tc.configuration, s)
+			var tfErr *TerraformVariableError
+			assert.ErrorAs(t, err, &tfErr)
+			assert.Len(t, tfErr.Mismatches, len(tc.mismatches))
+			for _, m := range tc.mismatches {
+				assert.Contains(t, err.Error(), m)
+			}
+		})
+	}
+}
+
+func TestGenerateExample(t *testing.T) {
+	cueDef := NewCapabilityComponentDef(&v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "webservice"},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Schematic: &common.Schematic{CUE: &common.CUE{Template: `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+}
+parameter: {
+	image:     string
+	port:      *80 | int
+	imagePull: "Always" | "Never" | "IfNotPresent"
+	replicas:  int & >=1
+	cmd?: [...string]
+	resources: {
+		cpu:     string
+		memory?: string
+	}
+}
+`}},
+		},
+	})
+	example, err := cueDef.GenerateExample(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, `name: my-webservice
+properties:
+  image: <image>
+  imagePull: Always
+  port: 80
+  replicas: 1
+  resources:
+    cpu: <cpu>
+type: webservice
+`, string(example))
+
+	tfDef := NewCapabilityComponentDef(&v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss"},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Schematic: &common.Schematic{Terraform: &common.Terraform{Configuration: `
+variable "bucket" {
+  type = string
+}
+variable "acl" {
+  type    = string
+  default = "private"
+}
+`}},
+		},
+	})
+	example, err = tfDef.GenerateExample(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, string(example), "bucket: <bucket>")
+	assert.NotContains(t, string(example), "acl")
+
+	tfDef.Terraform.Type = "remote"
+	_, err = tfDef.GenerateExample(context.Background())
+	assert.Error(t, err)
+}
+
+// latencyClient simulates the latency of the API server on writes, and rejects the objects whose name has the prefix
+type latencyClient struct {
+	client.Client
+	latency      time.Duration
+	rejectPrefix string
+}
+
+func (c *latencyClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	time.Sleep(c.latency)
+	if c.rejectPrefix != "" && strings.HasPrefix(obj.GetName(), c.rejectPrefix) {
+		return fmt.Errorf("%s is rejected", obj.GetName())
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func newSchemaConfigMapTargets(n int) []schemaConfigMapTarget {
+	var targets []schemaConfigMapTarget
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("part-%d", i)
+		targets = append(targets, schemaConfigMapTarget{
+			definitionName: name,
+			owner:          metav1.OwnerReference{APIVersion: "core.oam.dev/v1beta1", Kind: "ComponentDefinition", Name: name},
+		})
+	}
+	return targets
+}
+
+func TestStoreSchemaConfigMaps(t *testing.T) {
+	ctx := context.Background()
+	def := &CapabilityBaseDefinition{}
+	jsonSchema := []byte(`{"properties":{"image":{"type":"string"}},"type":"object"}`)
+	targets := newSchemaConfigMapTargets(8)
+	k8sClient := fake.NewClientBuilder().Build()
+
+	cmName, err := def.storeSchemaConfigMaps(ctx, k8sClient, "default", typeComponentDefinition, jsonSchema, targets)
+	assert.NoError(t, err)
+	assert.Equal(t, "component-schema-part-0", cmName)
+	for _, target := range targets {
+		cm := &corev1.ConfigMap{}
+		assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "component-schema-" + target.definitionName}, cm))
+		assert.Equal(t, string(jsonSchema), cm.Data[types.OpenapiV3JSONSchema])
+		assert.Equal(t, target.definitionName, cm.OwnerReferences[0].Name)
+		assert.True(t, *cm.OwnerReferences[0].Controller)
+	}
+
+	// errors are reported in the order of targets
+	targets[3].definitionName = "rejected-3"
+	targets[6].definitionName = "rejected-6"
+	for i := 0; i < 10; i++ {
+		k8sClient := &latencyClient{Client: fake.NewClientBuilder().Build(), rejectPrefix: "component-schema-rejected"}
+		_, err = def.storeSchemaConfigMaps(ctx, k8sClient, "default", typeComponentDefinition, jsonSchema, targets)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "rejected-3")
+	}
+}
+
+func BenchmarkStoreSchemaConfigMaps(b *testing.B) {
+	ctx := context.Background()
+	def := &CapabilityBaseDefinition{}
+	jsonSchema := []byte(`{"properties":{"image":{"type":"string"}},"type":"object"}`)
+	targets := newSchemaConfigMapTargets(16)
+	for _, bc := range []struct {
+		name        string
+		parallelism int
+	}{{"serial", 1}, {"parallel", velaslices.DefaultParallelism}} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				k8sClient := &latencyClient{Client: fake.NewClientBuilder().Build(), latency: time.Millisecond}
+				if _, err := def.storeSchemaConfigMaps(ctx, k8sClient, "default", typeComponentDefinition, jsonSchema, targets,
+					velaslices.Parallelism(bc.parallelism)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetSchemaFromConfigMap(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := fake.NewClientBuilder().Build()
+	def := &CapabilityComponentDefinition{}
+
+	_, err := def.GetSchemaFromConfigMap(ctx, k8sClient, "default", "web")
+	assert.ErrorIs(t, err, ErrSchemaNotStored)
+
+	var properties []string
+	for i := 0; i < 20000; i++ {
+		properties = append(properties, fmt.Sprintf(`"param%d":{"type":"string"}`, i))
+	}
+	largeSchema := []byte(fmt.Sprintf(`{"properties":{%s},"required":["param0"],"type":"object"}`, strings.Join(properties, ",")))
+	_, err = def.CreateOrUpdateConfigMap(ctx, k8sClient, "default", "web", typeComponentDefinition, nil, nil, largeSchema, nil)
+	assert.NoError(t, err)
+	s, err := def.GetSchemaFromConfigMap(ctx, k8sClient, "default", "web")
+	assert.NoError(t, err)
+	assert.Len(t, s.Properties, 20000)
+	assert.Equal(t, []string{"param0"}, s.Required)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "component-schema-web"}, cm))
+	cm.Data[types.OpenapiV3JSONSchema] = "not-base64"
+	assert.NoError(t, k8sClient.Update(ctx, cm))
+	_, err = def.GetSchemaFromConfigMap(ctx, k8sClient, "default", "web")
+	assert.ErrorIs(t, err, ErrSchemaCorrupted)
+
+	delete(cm.Data, types.OpenapiV3JSONSchemaEncoding)
+	cm.Data[types.OpenapiV3JSONSchema] = "{"
+	assert.NoError(t, k8sClient.Update(ctx, cm))
+	_, err = def.GetSchemaFromConfigMap(ctx, k8sClient, "default", "web")
+	assert.ErrorIs(t, err, ErrSchemaCorrupted)
+}
+
+func TestStoreJSONSchemaSchematic(t *testing.T) {
+	ctx := context.Background()
+	schema := `{"type":"object","required":["image"],"properties":{"image":{"type":"string"},"port":{"type":"integer","default":80}}}`
+	cd := &v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "json-web", Namespace: "default"},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Schematic: &common.Schematic{JSONSchema: &common.JSONSchema{Schema: schema}},
+		},
+	}
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "json-web-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+
+	def := NewCapabilityComponentDef(cd)
+	assert.Equal(t, util.JSONSchemaDef, def.WorkloadType)
+	_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+	assert.NoError(t, err)
+	for _, name := range []string{cd.Name, defRev.Name} {
+		s, err := def.GetSchemaFromConfigMap(ctx, k8sClient, "default", name)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"image"}, s.Required)
+		assert.Equal(t, float64(80), s.Properties["port"].Value.Default)
+	}
+	example, err := def.GenerateExample(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, string(example), "image: <image>")
+
+	for _, malformed := range []string{"", "{", `{"type":"string"}`, `{"type":"object","properties":{"port":{"type":"int"}}}`} {
+		_, err = GetOpenAPISchemaFromJSONSchema(&common.JSONSchema{Schema: malformed})
+		assert.Error(t, err, malformed)
+	}
+}
+
+func TestStoreDeprecatedParameters(t *testing.T) {
+	ctx := context.Background()
+	cd := &v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Workload: common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+			Schematic: &common.Schematic{CUE: &common.CUE{Template: `
+parameter: {
+	// +usage=Which image would you like to use for your service
+	// +deprecated=use images instead
+	image?: string
+	// +usage=The images of the containers
+	images: [...string]
+	// +deprecated
+	cmd?: [...string]
+}
+output: {}
+`}},
+		},
+	}
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+
+	def := NewCapabilityComponentDef(cd)
+	_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+	assert.NoError(t, err)
+	for _, name := range []string{cd.Name, defRev.Name} {
+		s, err := def.GetSchemaFromConfigMap(ctx, k8sClient, "default", name)
+		assert.NoError(t, err)
+		image := s.Properties["image"].Value
+		assert.True(t, image.Deprecated)
+		assert.Equal(t, "Which image would you like to use for your service", image.Description)
+		assert.Equal(t, "use images instead", image.Extensions[appfile.DeprecationMessageExtension])
+		cmd := s.Properties["cmd"].Value
+		assert.True(t, cmd.Deprecated)
+		assert.NotContains(t, cmd.Extensions, appfile.DeprecationMessageExtension)
+		assert.Empty(t, cmd.Description)
+		assert.False(t, s.Properties["images"].Value.Deprecated)
+	}
+}
+
+func TestStoreParameterAccessLevels(t *testing.T) {
+	ctx := context.Background()
+	cd := &v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Workload: common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+			Schematic: &common.Schematic{CUE: &common.CUE{Template: `
+parameter: {
+	// +usage=Which image would you like to use for your service
+	// +access=basic
+	image: string
+	// +access=advanced
+	cmd?: [...string]
+	// +access=internal
+	revision: string
+	ports?: [...{
+		port: int
+		// +access=internal
+		hostPort?: int
+	}]
+}
+output: {}
+`}},
+		},
+	}
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+
+	def := NewCapabilityComponentDef(cd)
+	_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+	assert.NoError(t, err)
+	s, err := def.GetSchemaFromConfigMap(ctx, k8sClient, "default", cd.Name)
+	assert.NoError(t, err)
+	image := s.Properties["image"].Value
+	assert.Equal(t, "Which image would you like to use for your service", image.Description)
+	assert.Equal(t, appfile.AccessLevelBasic, image.Extensions[appfile.AccessLevelExtension])
+	assert.Equal(t, appfile.AccessLevelAdvanced, s.Properties["cmd"].Value.Extensions[appfile.AccessLevelExtension])
+	assert.Equal(t, appfile.AccessLevelInternal, s.Properties["revision"].Value.Extensions[appfile.AccessLevelExtension])
+	assert.ElementsMatch(t, []string{"image", "revision"}, s.Required)
+
+	// the internal parameters are excluded along with their required marks
+	def.ExcludeInternalParameters = true
+	_, err = def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+	assert.NoError(t, err)
+	s, err = def.GetSchemaFromConfigMap(ctx, k8sClient, "default", cd.Name)
+	assert.NoError(t, err)
+	assert.NotContains(t, s.Properties, "revision")
+	assert.Equal(t, []string{"image"}, s.Required)
+	assert.Contains(t, s.Properties, "cmd")
+	ports := s.Properties["ports"].Value.Items.Value
+	assert.Contains(t, ports.Properties, "port")
+	assert.NotContains(t, ports.Properties, "hostPort")
+
+	// the ConfigMap backed cache doesn't reuse the schema stored with the other exclusion after a restart
+	for _, exclude := range []bool{false, true} {
+		def := NewCapabilityComponentDef(cd)
+		def.SchemaCache = NewSchemaCache(true)
+		def.ExcludeInternalParameters = exclude
+		_, err = def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+		assert.NoError(t, err)
+		s, err = def.GetSchemaFromConfigMap(ctx, k8sClient, "default", cd.Name)
+		assert.NoError(t, err)
+		if exclude {
+			assert.NotContains(t, s.Properties, "revision")
+		} else {
+			assert.Contains(t, s.Properties, "revision")
+		}
+	}
+}
+
+func TestStoreOpenAPIV3Document(t *testing.T) {
+	ctx := context.Background()
+	cd := &v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Workload: common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+			Schematic: &common.Schematic{CUE: &common.CUE{Template: `
+parameter: {
+	image: string
+	imagePullPolicy?: "Always" | "Never" | "IfNotPresent"
+	resources: {
+		cpu:     *"100m" | string
+		memory?: string
+		limits?: {
+			cpu: string
+		}
+	}
+}
+output: {}
+`}},
+		},
+	}
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+
+	def := NewCapabilityComponentDef(cd)
+	cmName, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+	assert.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: cmName}, cm))
+	assert.NotContains(t, cm.Data, types.OpenapiV3Document)
+	document, err := GetOpenAPIV3DocumentFromConfigMap(cm)
+	assert.NoError(t, err)
+	assert.Nil(t, document)
+
+	def.OpenAPIV3Document = true
+	_, err = def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+	assert.NoError(t, err)
+	for _, name := range []string{cd.Name, defRev.Name} {
+		assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: ComponentDefinitionConfigMapName(name)}, cm))
+		jsonSchema, err := GetOpenAPISchemaFromConfigMap(cm)
+		assert.NoError(t, err)
+		document, err := GetOpenAPIV3DocumentFromConfigMap(cm)
+		assert.NoError(t, err)
+		doc, err := openapi3.NewLoader().LoadFromData(document)
+		assert.NoError(t, err)
+		assert.NoError(t, doc.Validate(ctx))
+		assert.Equal(t, name, doc.Info.Title)
+
+		// the schema in the document is the same as the one stored for backward compatibility
+		parameter := doc.Components.Schemas["parameter"].Value
+		schema := &openapi3.Schema{}
+		assert.NoError(t, schema.UnmarshalJSON(jsonSchema))
+		assert.Equal(t, schema, parameter)
+		assert.ElementsMatch(t, []string{"image", "resources"}, parameter.Required)
+		assert.ElementsMatch(t, []interface{}{"Always", "Never", "IfNotPresent"}, parameter.Properties["imagePullPolicy"].Value.Enum)
+		resources := parameter.Properties["resources"].Value
+		assert.Equal(t, "object", resources.Type)
+		assert.ElementsMatch(t, []string{"cpu"}, resources.Required)
+		assert.Equal(t, "100m", resources.Properties["cpu"].Value.Default)
+		assert.ElementsMatch(t, []string{"cpu"}, resources.Properties["limits"].Value.Required)
+	}
+
+	// the document is compressed along with the large schema
+	var properties []string
+	for i := 0; i < 20000; i++ {
+		properties = append(properties, fmt.Sprintf(`"param%d":{"type":"string","description":"the parameter %d of the component"}`, i, i))
+	}
+	largeSchema := []byte(fmt.Sprintf(`{"properties":{%s},"type":"object"}`, strings.Join(properties, ",")))
+	cmName, err = def.CreateOrUpdateConfigMap(ctx, k8sClient, "default", "large", typeComponentDefinition, nil, nil, largeSchema, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: cmName}, cm))
+	assert.Equal(t, types.SchemaEncodingGzipBase64, cm.Data[types.OpenapiV3JSONSchemaEncoding])
+	document, err = GetOpenAPIV3DocumentFromConfigMap(cm)
+	assert.NoError(t, err)
+	doc, err := openapi3.NewLoader().LoadFromData(document)
+	assert.NoError(t, err)
+	assert.Len(t, doc.Components.Schemas["parameter"].Value.Properties, 20000)
+}
+
+func TestStoreParameterDefaults(t *testing.T) {
+	ctx := context.Background()
+	cd := &v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Workload: common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+			Schematic: &common.Schematic{CUE: &common.CUE{Template: `
+parameter: {
+	image: string
+	replicas:        *1 | int
+	imagePullPolicy: *"IfNotPresent" | "Always" | "Never"
+	privileged:      *false | bool
+	port?:           *80 | int
+	cmd:             *["sleep", "1000"] | [...string]
+	labels:          *{app: "worker"} | {...}
+	resources: {
+		cpu:     *"100m" | string
+		memory?: string
+	}
+	volumes?: [...{
+		name:  string
+		path: *"/data" | string
+	}]
+}
+output: {}
+`}},
+		},
+	}
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+
+	def := NewCapabilityComponentDef(cd)
+	cmName, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+	assert.NoError(t, err)
+	expected := map[string]interface{}{
+		"replicas":        float64(1),
+		"imagePullPolicy": "IfNotPresent",
+		"privileged":      false,
+		"port":            float64(80),
+		"cmd":             []interface{}{"sleep", "1000"},
+		"labels":          map[string]interface{}{"app": "worker"},
+		"resources":       map[string]interface{}{"cpu": "100m"},
+	}
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: cmName}, cm))
+	assert.Contains(t, cm.Data, types.ParameterDefaults)
+	defaults, err := GetParameterDefaultsFromConfigMap(cm)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, defaults)
+	defaults, err = def.GetParameterDefaults(ctx, k8sClient, "default", defRev.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, defaults)
+
+	// the defaults are derived from the schema stored without them
+	delete(cm.Data, types.ParameterDefaults)
+	defaults, err = GetParameterDefaultsFromConfigMap(cm)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, defaults)
+
+	// nothing is stored if no parameter has a default value
+	_, err = def.CreateOrUpdateConfigMap(ctx, k8sClient, "default", "no-defaults", typeComponentDefinition, nil, nil,
+		[]byte(`{"properties":{"image":{"type":"string"}},"required":["image"],"type":"object"}`), nil)
+	assert.NoError(t, err)
+	assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: ComponentDefinitionConfigMapName("no-defaults")}, cm))
+	assert.NotContains(t, cm.Data, types.ParameterDefaults)
+	defaults, err = GetParameterDefaultsFromConfigMap(cm)
+	assert.NoError(t, err)
+	assert.Empty(t, defaults)
+}
+
+func TestSchemaConfigMapKey(t *testing.T) {
+	assert.Equal(t, client.ObjectKey{Namespace: "default", Name: "component-schema-web"}, SchemaConfigMapKey("", "default", "web"))
+	assert.Equal(t, client.ObjectKey{Namespace: "vela-system", Name: "component-schema-default.web"}, SchemaConfigMapKey("vela-system", "default", "web"))
+	assert.Equal(t, client.ObjectKey{Namespace: "vela-system", Name: "component-schema-vela-system.web"}, SchemaConfigMapKey("vela-system", "vela-system", "web"))
+
+	// namespaces never contain dots, so the definitions can't collide even if their names contain dots
+	names := map[string]bool{}
+	for _, def := range []struct{ namespace, name string }{
+		{"default", "web"}, {"team-a", "web"}, {"vela-system", "web"}, {"default", "web-v1"},
+		{"vela-system", "default.web"}, {"default", "a.web"}, {"default-a", "web"}, {"default", "a-web"},
+	} {
+		key := SchemaConfigMapKey("vela-system", def.namespace, def.name)
+		assert.False(t, names[key.Name], "%s collides", key.Name)
+		names[key.Name] = true
+	}
+}
+
+func TestValidateComponentDefinitionsInDir(t *testing.T) {
+	results, err := ValidateComponentDefinitionsInDir(context.Background(), fake.NewClientBuilder().Build(), "testdata/import")
+	assert.Error(t, err)
+
+	var files []string
+	failed := map[string]error{}
+	for _, result := range results {
+		file := filepath.Base(result.File)
+		files = append(files, file)
+		if result.Err != nil {
+			failed[file] = result.Err
+			assert.Contains(t, err.Error(), result.Err.Error())
+			continue
+		}
+		assert.Contains(t, string(result.Schema), `"image"`)
+	}
+	assert.Equal(t, []string{"broken-template.yaml", "no-template.cue", "trait.yaml", "webservice.yaml", "worker.cue"}, files)
+	assert.Len(t, failed, 3)
+	assert.Contains(t, failed["broken-template.yaml"].Error(), "failed to generate OpenAPI v3 JSON schema")
+	assert.Contains(t, failed["no-template.cue"].Error(), "no template found")
+	assert.Contains(t, failed["trait.yaml"].Error(), "expect ComponentDefinition")
+	assert.Equal(t, "worker", results[4].ComponentDefinition.Name)
+	assert.Contains(t, string(results[4].Schema), `"replicas"`)
+
+	_, err = ValidateComponentDefinitionsInDir(context.Background(), fake.NewClientBuilder().Build(), "testdata/not-exist")
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// ConvertWorkloadToComponent converts the legacy WorkloadDefinition into a ComponentDefinition of the same name.
+// The workload is referred by the resource name in spec.workload.type, which is resolved into its GVK by the
+// mutating webhook when the ComponentDefinition is applied. The schematic, the status templates and the other
+// fields of the spec are copied as they are. The information that can't be carried over is recorded as warnings in
+// the definition.oam.dev/conversion-warnings annotation of the ComponentDefinition.
+func ConvertWorkloadToComponent(wd *v1beta1.WorkloadDefinition) (*v1beta1.ComponentDefinition, error) {
+	if wd == nil {
+		return nil, fmt.Errorf("workloadDefinition is nil")
+	}
+	wd = wd.DeepCopy()
+	ref := wd.Spec.Reference
+	if ref.Name == "" || ref.Name == util.Dummy {
+		return nil, fmt.Errorf("workloadDefinition %s doesn't refer to any workload resource", wd.Name)
+	}
+	var warnings []string
+	if ref.Version != "" {
+		warnings = append(warnings, fmt.Sprintf("the version %s of definitionRef %s is not preserved, the preferred version of the resource is used", ref.Version, ref.Name))
+	}
+	if wd.Spec.Schematic == nil {
+		warnings = append(warnings, "no schematic is declared, the component can't render the workload")
+	}
+
+	spec := wd.Spec
+	cd := &v1beta1.ComponentDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1beta1.SchemeGroupVersion.String(),
+			Kind:       v1beta1.ComponentDefinitionKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        wd.Name,
+			Namespace:   wd.Namespace,
+			Labels:      wd.Labels,
+			Annotations: wd.Annotations,
+		},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Workload:           common.WorkloadTypeDescriptor{Type: ref.Name},
+			ChildResourceKinds: spec.ChildResourceKinds,
+			RevisionLabel:      spec.RevisionLabel,
+			PodSpecPath:        spec.PodSpecPath,
+			Status:             spec.Status,
+			Schematic:          spec.Schematic,
+			Extension:          spec.Extension,
+		},
+	}
+	if len(warnings) > 0 {
+		cd.Annotations = util.MergeMapOverrideWithDst(cd.Annotations, map[string]string{
+			oam.AnnotationDefinitionConversionWarnings: strings.Join(warnings, "; "),
+		})
+	}
+	return cd, nil
+}
@@ -0,0 +1,129 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	pkgdef "github.com/oam-dev/kubevela/pkg/definition"
+	velaerrors "github.com/oam-dev/kubevela/pkg/utils/errors"
+)
+
+// ComponentDefinitionFileResult is the validation result of the ComponentDefinition read from a file
+type ComponentDefinitionFileResult struct {
+	// File is the path of the file
+	File string
+	// ComponentDefinition read from the file, it's nil if the file cannot be parsed
+	ComponentDefinition *v1beta1.ComponentDefinition
+	// Schema is the OpenAPI v3 JSON schema generated from the parameters of the ComponentDefinition
+	Schema []byte
+	// Err is the error of reading or validating the ComponentDefinition
+	Err error
+}
+
+// ValidateComponentDefinitionsInDir reads the ComponentDefinitions from the YAML and CUE files in the directory and
+// validates them as the controller does, i.e. compiles the schematic and generates the schema of the parameters, so
+// that the definitions can be checked before applying. The CUE files are in the format of `vela def`. One result is
+// reported for each file in lexical order, and the validation goes on when a file fails. The errors of all the files
+// are aggregated into the returned error. The k8sClient is used to resolve the resources referred by definitions,
+// e.g. CUE packages.
+func ValidateComponentDefinitionsInDir(ctx context.Context, k8sClient client.Client, dir string) ([]ComponentDefinitionFileResult, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read directory %s", dir)
+	}
+	var files []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json", ".cue":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+
+	results := make([]ComponentDefinitionFileResult, 0, len(files))
+	var errs []error
+	for _, file := range files {
+		result := ComponentDefinitionFileResult{File: file}
+		result.ComponentDefinition, result.Err = readComponentDefinitionFile(file)
+		if result.Err == nil {
+			result.Schema, result.Err = validateComponentDefinition(ctx, k8sClient, result.ComponentDefinition)
+		}
+		if result.Err != nil {
+			errs = append(errs, errors.Wrapf(result.Err, "invalid ComponentDefinition in %s", file))
+		}
+		results = append(results, result)
+	}
+	return results, velaerrors.AggregateErrors(errs)
+}
+
+// readComponentDefinitionFile reads the ComponentDefinition from the YAML or CUE file
+func readComponentDefinitionFile(file string) (*v1beta1.ComponentDefinition, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, err
+	}
+	cd := &v1beta1.ComponentDefinition{}
+	if strings.ToLower(filepath.Ext(file)) == ".cue" {
+		def := pkgdef.Definition{}
+		if err = def.FromCUEString(string(data), nil); err != nil {
+			return nil, errors.Wrap(err, "failed to parse CUE")
+		}
+		if def.GetKind() != v1beta1.ComponentDefinitionKind {
+			return nil, fmt.Errorf("expect ComponentDefinition but got %s", def.GetKind())
+		}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(def.Object, cd); err != nil {
+			return nil, errors.Wrap(err, "failed to convert to ComponentDefinition")
+		}
+		return cd, nil
+	}
+	if err = yaml.Unmarshal(data, cd); err != nil {
+		return nil, errors.Wrap(err, "failed to parse YAML")
+	}
+	if cd.Kind != v1beta1.ComponentDefinitionKind {
+		return nil, fmt.Errorf("expect ComponentDefinition but got %q", cd.Kind)
+	}
+	return cd, nil
+}
+
+// validateComponentDefinition compiles the schematic of the ComponentDefinition and generates the schema of parameters
+func validateComponentDefinition(ctx context.Context, k8sClient client.Client, cd *v1beta1.ComponentDefinition) ([]byte, error) {
+	if cd.Name == "" {
+		return nil, errors.New("the name of ComponentDefinition is not set")
+	}
+	namespace := cd.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	def := NewCapabilityComponentDef(cd)
+	return def.getComponentOpenAPISchema(ctx, k8sClient, namespace, cd.Name)
+}
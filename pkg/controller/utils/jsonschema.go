@@ -0,0 +1,134 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// JSONSchemaDraft07 is the meta schema URI of the JSON Schema draft-07 documents
+const JSONSchemaDraft07 = "http://json-schema.org/draft-07/schema#"
+
+// schemaKeywordsDraft07 are the keywords of OpenAPI v3 schema objects carried over to the JSON Schema draft-07 as they
+// are, the other keywords, i.e. nullable, discriminator, xml, externalDocs, deprecated and the extensions, are either
+// translated or dropped
+var schemaKeywordsDraft07 = map[string]bool{
+	"title": true, "description": true, "type": true, "format": true, "default": true, "enum": true,
+	"multipleOf": true, "maximum": true, "minimum": true, "maxLength": true, "minLength": true, "pattern": true,
+	"maxItems": true, "minItems": true, "uniqueItems": true, "maxProperties": true, "minProperties": true,
+	"required": true, "readOnly": true, "writeOnly": true, "$ref": true,
+}
+
+// GetJSONSchemaDraft07 returns the parameters of the ComponentDefinition as a standalone JSON Schema draft-07
+// document, which is translated from the schema stored by StoreOpenAPISchema. The document can be used by IDEs to
+// complete and validate the properties of the component in the Application manifests.
+func (def *CapabilityComponentDefinition) GetJSONSchemaDraft07(ctx context.Context, k8sClient client.Client, namespace, name string) ([]byte, error) {
+	jsonSchema, err := def.GetSchemaStore(k8sClient).Get(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return GenerateJSONSchemaDraft07(name, jsonSchema)
+}
+
+// GenerateJSONSchemaDraft07 translates the OpenAPI v3 JSON schema of the parameters into a standalone JSON Schema
+// draft-07 document titled by the name. The nullable types are turned into the type unions with null, the boolean
+// exclusive bounds into the numeric ones and the example into examples. The properties without title are titled by
+// their names, so that IDEs have something to show along with the descriptions.
+func GenerateJSONSchemaDraft07(name string, jsonSchema []byte) ([]byte, error) {
+	schema := map[string]interface{}{}
+	if err := json.Unmarshal(jsonSchema, &schema); err != nil {
+		return nil, errors.Wrap(err, "failed to parse OpenAPI v3 JSON schema")
+	}
+	document := convertSchemaToDraft07(schema)
+	document["$schema"] = JSONSchemaDraft07
+	if _, ok := document["title"]; !ok {
+		document["title"] = name
+	}
+	if _, ok := document["type"]; !ok {
+		document["type"] = "object"
+	}
+	return json.Marshal(document)
+}
+
+func convertSchemaToDraft07(schema map[string]interface{}) map[string]interface{} {
+	converted := map[string]interface{}{}
+	for key, value := range schema {
+		if schemaKeywordsDraft07[key] {
+			converted[key] = value
+		}
+	}
+	if example, ok := schema["example"]; ok {
+		converted["examples"] = []interface{}{example}
+	}
+	if nullable, _ := schema["nullable"].(bool); nullable {
+		if t, ok := converted["type"].(string); ok {
+			converted["type"] = []interface{}{t, "null"}
+		}
+	}
+	for _, bound := range []string{"Minimum", "Maximum"} {
+		keyword := strings.ToLower(bound)
+		exclusive, _ := schema["exclusive"+bound].(bool)
+		if value, ok := converted[keyword]; ok && exclusive {
+			delete(converted, keyword)
+			converted["exclusive"+bound] = value
+		}
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		convertedProperties := map[string]interface{}{}
+		for name, property := range properties {
+			p, ok := property.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			p = convertSchemaToDraft07(p)
+			if _, ok := p["title"]; !ok {
+				p["title"] = name
+			}
+			convertedProperties[name] = p
+		}
+		converted["properties"] = convertedProperties
+	}
+	for _, keyword := range []string{"items", "additionalProperties", "not"} {
+		switch sub := schema[keyword].(type) {
+		case map[string]interface{}:
+			converted[keyword] = convertSchemaToDraft07(sub)
+		case bool:
+			converted[keyword] = sub
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		subs, ok := schema[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		convertedSubs := make([]interface{}, 0, len(subs))
+		for _, sub := range subs {
+			if s, ok := sub.(map[string]interface{}); ok {
+				convertedSubs = append(convertedSubs, convertSchemaToDraft07(s))
+			}
+		}
+		converted[keyword] = convertedSubs
+	}
+	return converted
+}
@@ -0,0 +1,133 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func compileDraft07(t *testing.T, document []byte) *gojsonschema.Schema {
+	loader := gojsonschema.NewSchemaLoader()
+	loader.Draft = gojsonschema.Draft7
+	loader.Validate = true
+	schema, err := loader.Compile(gojsonschema.NewBytesLoader(document))
+	require.NoError(t, err)
+	return schema
+}
+
+func TestGetJSONSchemaDraft07(t *testing.T) {
+	ctx := context.Background()
+	cd := newSchemaCacheTestDefinition(`
+parameter: {
+	// +usage=The image of the container
+	image: string
+	// +usage=The number of replicas
+	replicas: *1 | int
+	// +usage=The environment variables
+	env?: [...{
+		name:   string
+		value?: string
+	}]
+	resources?: {
+		cpu: *"100m" | string
+	}
+}
+output: {}
+`)
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd,
+		&v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}).Build()
+	def := NewCapabilityComponentDef(cd)
+	_, err := def.GetJSONSchemaDraft07(ctx, k8sClient, "default", "worker")
+	require.ErrorIs(t, err, ErrSchemaNotStored)
+	_, err = def.StoreOpenAPISchema(ctx, k8sClient, "default", "worker", "worker-v1")
+	require.NoError(t, err)
+	document, err := def.GetJSONSchemaDraft07(ctx, k8sClient, "default", "worker")
+	require.NoError(t, err)
+
+	parsed := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(document, &parsed))
+	assert.Equal(t, JSONSchemaDraft07, parsed["$schema"])
+	assert.Equal(t, "worker", parsed["title"])
+	assert.ElementsMatch(t, []interface{}{"image", "replicas"}, parsed["required"])
+	properties := parsed["properties"].(map[string]interface{})
+	image := properties["image"].(map[string]interface{})
+	assert.Equal(t, "image", image["title"])
+	assert.Equal(t, "The image of the container", image["description"])
+	env := properties["env"].(map[string]interface{})
+	assert.Equal(t, "array", env["type"])
+	item := env["items"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"name"}, item["required"])
+
+	schema := compileDraft07(t, document)
+	testCases := map[string]struct {
+		parameters string
+		valid      bool
+	}{
+		"complete":         {parameters: `{"image": "nginx", "replicas": 2, "env": [{"name": "A", "value": "a"}], "resources": {"cpu": "1"}}`, valid: true},
+		"optional omitted": {parameters: `{"image": "nginx", "replicas": 1}`, valid: true},
+		"required missing": {parameters: `{"replicas": 1}`},
+		"nested required":  {parameters: `{"image": "nginx", "replicas": 1, "env": [{"value": "a"}]}`},
+		"wrong type":       {parameters: `{"image": "nginx", "replicas": "1"}`},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, err := schema.Validate(gojsonschema.NewStringLoader(tc.parameters))
+			require.NoError(t, err)
+			assert.Equal(t, tc.valid, result.Valid(), result.Errors())
+		})
+	}
+}
+
+func TestGenerateJSONSchemaDraft07(t *testing.T) {
+	document, err := GenerateJSONSchemaDraft07("worker", []byte(`{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "nullable": true, "example": "nginx", "x-kubernetes-preserve-unknown-fields": true},
+		"ratio": {"type": "number", "minimum": 0, "exclusiveMinimum": true, "maximum": 1},
+		"labels": {"type": "object", "additionalProperties": {"type": "string", "deprecated": true}},
+		"port": {"title": "Port", "oneOf": [{"type": "integer"}, {"type": "string"}]}
+	}
+}`))
+	require.NoError(t, err)
+	compileDraft07(t, document)
+	assert.JSONEq(t, `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"title": "worker",
+	"type": "object",
+	"properties": {
+		"name": {"title": "name", "type": ["string", "null"], "examples": ["nginx"]},
+		"ratio": {"title": "ratio", "type": "number", "exclusiveMinimum": 0, "maximum": 1},
+		"labels": {"title": "labels", "type": "object", "additionalProperties": {"type": "string"}},
+		"port": {"title": "Port", "oneOf": [{"type": "integer"}, {"type": "string"}]}
+	}
+}`, string(document))
+
+	_, err = GenerateJSONSchemaDraft07("worker", []byte("not json"))
+	require.Error(t, err)
+}
@@ -0,0 +1,259 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// LintSeverity is the severity of a LintFinding
+type LintSeverity string
+
+const (
+	// LintSeverityError marks the findings which make the definition fail to apply or to work
+	LintSeverityError LintSeverity = "error"
+	// LintSeverityWarning marks the findings which don't fail the definition but should be fixed
+	LintSeverityWarning LintSeverity = "warning"
+	// LintSeverityInfo marks the findings which are informative only, e.g. the checks skipped
+	LintSeverityInfo LintSeverity = "info"
+)
+
+const (
+	// LintRuleConflictingSchematic reports more than one schematic set in the definition
+	LintRuleConflictingSchematic = "conflicting-schematic"
+	// LintRuleInvalidSchema reports the failure of generating the parameter schema
+	LintRuleInvalidSchema = "invalid-schema"
+	// LintRuleMissingDescription reports the top-level parameters without description
+	LintRuleMissingDescription = "missing-description"
+	// LintRuleUnusedParameter reports the top-level parameters never referenced by the CUE templates
+	LintRuleUnusedParameter = "unused-parameter"
+	// LintRuleDeprecatedParameter reports the parameters marked by the +deprecated tag
+	LintRuleDeprecatedParameter = "deprecated-parameter"
+	// LintRuleOversizedSchema reports the parameter schema larger than LintOptions.SchemaSizeThreshold
+	LintRuleOversizedSchema = "oversized-schema"
+	// LintRuleSkipped reports the checks skipped as they need the resources unavailable to the lint
+	LintRuleSkipped = "skipped"
+)
+
+// DefaultLintSchemaSizeThreshold is the default LintOptions.SchemaSizeThreshold, which is the same as the default
+// schema size warning threshold of the controller
+const DefaultLintSchemaSizeThreshold = 512 * 1024
+
+// LintFinding is an issue of the ComponentDefinition found by LintComponentDefinition
+type LintFinding struct {
+	// Rule is the check reporting the finding, see the LintRule constants
+	Rule     string       `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	// Parameter is the path of the parameter the finding is about, it's empty if the finding is about the definition
+	Parameter string `json:"parameter,omitempty"`
+	Message   string `json:"message"`
+}
+
+// String formats the finding in one line
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%s [%s] %s", f.Severity, f.Rule, f.Message)
+}
+
+// LintOptions configures LintComponentDefinition
+type LintOptions struct {
+	// SchemaSizeThreshold is the size in bytes of the parameter schema above which it's reported as oversized, the
+	// check is disabled if it's not positive
+	SchemaSizeThreshold int
+}
+
+// LintComponentDefinition checks the ComponentDefinition for the common issues before it's applied, with the same
+// logic as the controller and the validating webhook: conflicting schematics, invalid parameter schema, parameters
+// missing descriptions, deprecated parameters, oversized schema and unused parameters. The findings are reported in
+// that order, and the parameters of each check are sorted.
+//
+// The k8sClient is only used to resolve the resources referred by the definition, e.g. CUE packages, it can be nil
+// for the static checks, the checks needing the resources are skipped then. The schema of remote Terraform
+// configurations is never generated.
+func LintComponentDefinition(ctx context.Context, k8sClient client.Client, cd *v1beta1.ComponentDefinition, opts LintOptions) []LintFinding {
+	var findings []LintFinding
+	if fields := SchematicFields(cd.Spec.Schematic); len(fields) > 1 {
+		return append(findings, LintFinding{
+			Rule:     LintRuleConflictingSchematic,
+			Severity: LintSeverityError,
+			Message:  fmt.Sprintf("only one schematic can be set, but got conflicting fields: %s", strings.Join(fields, ", ")),
+		})
+	}
+
+	unused, err := FindUnusedParameters(cd)
+	if err != nil {
+		return append(findings, LintFinding{
+			Rule:     LintRuleInvalidSchema,
+			Severity: LintSeverityError,
+			Message:  fmt.Sprintf("failed to parse the CUE template: %v", err),
+		})
+	}
+
+	if skipped := lintSkippedReason(k8sClient, cd); skipped != "" {
+		findings = append(findings, LintFinding{Rule: LintRuleSkipped, Severity: LintSeverityInfo, Message: skipped})
+	} else {
+		jsonSchema, err := validateComponentDefinition(ctx, k8sClient, cd)
+		if err != nil {
+			return append(findings, LintFinding{Rule: LintRuleInvalidSchema, Severity: LintSeverityError, Message: err.Error()})
+		}
+		schemaFindings, err := lintSchema(jsonSchema, opts)
+		if err != nil {
+			return append(findings, LintFinding{Rule: LintRuleInvalidSchema, Severity: LintSeverityError, Message: err.Error()})
+		}
+		findings = append(findings, schemaFindings...)
+	}
+
+	for _, name := range unused {
+		findings = append(findings, LintFinding{
+			Rule:      LintRuleUnusedParameter,
+			Severity:  LintSeverityWarning,
+			Parameter: name,
+			Message:   fmt.Sprintf("the parameter %s is declared but never referenced by the templates", name),
+		})
+	}
+	return findings
+}
+
+// LintComponentDefinitionFile reads the ComponentDefinition from the YAML or CUE file like
+// ValidateComponentDefinitionsInDir, and lints it by LintComponentDefinition
+func LintComponentDefinitionFile(ctx context.Context, k8sClient client.Client, file string, opts LintOptions) ([]LintFinding, error) {
+	cd, err := readComponentDefinitionFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ComponentDefinition in %s: %w", file, err)
+	}
+	return LintComponentDefinition(ctx, k8sClient, cd, opts), nil
+}
+
+// SchematicFields returns the fields of the schematic which are set, the schematic is conflicting if more than one
+// field is returned
+func SchematicFields(schematic *commontypes.Schematic) []string {
+	if schematic == nil {
+		return nil
+	}
+	var fields []string
+	if schematic.CUE != nil {
+		fields = append(fields, "spec.schematic.cue")
+	}
+	if schematic.Terraform != nil {
+		fields = append(fields, "spec.schematic.terraform")
+	}
+	if schematic.JSONSchema != nil {
+		fields = append(fields, "spec.schematic.jsonSchema")
+	}
+	return fields
+}
+
+// lintSkippedReason returns why the checks of the parameter schema are skipped, it's empty if they are not
+func lintSkippedReason(k8sClient client.Client, cd *v1beta1.ComponentDefinition) string {
+	schematic := cd.Spec.Schematic
+	if schematic != nil && schematic.Terraform != nil && schematic.Terraform.Type == "remote" {
+		return "the parameter schema of the remote Terraform configuration is not checked"
+	}
+	if k8sClient == nil && strings.TrimSpace(cd.GetAnnotations()[oam.AnnotationCUEPackageConfigMaps]) != "" {
+		return fmt.Sprintf("the parameter schema is not checked without cluster, as the CUE packages of annotation %s are needed",
+			oam.AnnotationCUEPackageConfigMaps)
+	}
+	return ""
+}
+
+// lintSchema checks the generated parameter schema for the parameters missing descriptions, the deprecated parameters
+// and the size of the schema
+func lintSchema(jsonSchema []byte, opts LintOptions) ([]LintFinding, error) {
+	var findings []LintFinding
+	undocumented, err := FindUndocumentedParameters(jsonSchema)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range undocumented {
+		findings = append(findings, LintFinding{
+			Rule:      LintRuleMissingDescription,
+			Severity:  LintSeverityWarning,
+			Parameter: name,
+			Message:   fmt.Sprintf("the parameter %s has no description", name),
+		})
+	}
+
+	schema := openapi3.NewSchema()
+	if err = schema.UnmarshalJSON(jsonSchema); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI v3 JSON schema: %w", err)
+	}
+	var deprecated []LintFinding
+	findDeprecatedParameters("", schema, func(path string, param *openapi3.Schema) {
+		msg := fmt.Sprintf("the parameter %s is deprecated", path)
+		if reason, ok := param.Extensions[appfile.DeprecationMessageExtension].(string); ok && reason != "" {
+			msg += ": " + reason
+		}
+		deprecated = append(deprecated, LintFinding{
+			Rule:      LintRuleDeprecatedParameter,
+			Severity:  LintSeverityInfo,
+			Parameter: path,
+			Message:   msg,
+		})
+	})
+	findings = append(findings, deprecated...)
+
+	if opts.SchemaSizeThreshold > 0 && len(jsonSchema) > opts.SchemaSizeThreshold {
+		findings = append(findings, LintFinding{
+			Rule:     LintRuleOversizedSchema,
+			Severity: LintSeverityWarning,
+			Message: fmt.Sprintf("the size of the parameter schema %d bytes exceeds the warning threshold %d bytes",
+				len(jsonSchema), opts.SchemaSizeThreshold),
+		})
+	}
+	return findings, nil
+}
+
+// findDeprecatedParameters calls fn with the path of every deprecated parameter nested in the schema, in the order of
+// the sorted paths
+func findDeprecatedParameters(path string, schema *openapi3.Schema, fn func(path string, param *openapi3.Schema)) {
+	if schema == nil {
+		return
+	}
+	if schema.Items != nil {
+		findDeprecatedParameters(path+"[]", schema.Items.Value, fn)
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop := schema.Properties[name]
+		if prop == nil || prop.Value == nil {
+			continue
+		}
+		child := name
+		if path != "" {
+			child = path + "." + name
+		}
+		if prop.Value.Deprecated {
+			fn(child, prop.Value)
+		}
+		findDeprecatedParameters(child, prop.Value, fn)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestLintComponentDefinition(t *testing.T) {
+	newDef := func(schematic *common.Schematic) *v1beta1.ComponentDefinition {
+		return &v1beta1.ComponentDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.ComponentDefinitionKind},
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+			Spec: v1beta1.ComponentDefinitionSpec{
+				Workload:  common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+				Schematic: schematic,
+			},
+		}
+	}
+	cue := func(template string) *common.Schematic {
+		return &common.Schematic{CUE: &common.CUE{Template: template}}
+	}
+	testCases := map[string]struct {
+		cd       *v1beta1.ComponentDefinition
+		opts     LintOptions
+		findings []LintFinding
+	}{
+		"clean": {
+			cd: newDef(cue("parameter: {\n\t// +usage=The image\n\timage: string\n}\noutput: image: parameter.image\n")),
+		},
+		"conflicting schematic": {
+			cd: newDef(&common.Schematic{
+				CUE:       &common.CUE{Template: "output: {}"},
+				Terraform: &common.Terraform{Configuration: `variable "a" {}`},
+			}),
+			findings: []LintFinding{{
+				Rule:     LintRuleConflictingSchematic,
+				Severity: LintSeverityError,
+				Message:  "only one schematic can be set, but got conflicting fields: spec.schematic.cue, spec.schematic.terraform",
+			}},
+		},
+		"invalid schema": {
+			cd: newDef(cue("parameter: {\n\timage: string\n\timage: 1\n}\noutput: image: parameter.image\n")),
+			findings: []LintFinding{{
+				Rule:     LintRuleInvalidSchema,
+				Severity: LintSeverityError,
+			}},
+		},
+		"missing description": {
+			cd: newDef(cue("parameter: {\n\t// +usage=The image\n\timage: string\n\tcmd?: [...string]\n}\noutput: {image: parameter.image, cmd: parameter.cmd}\n")),
+			findings: []LintFinding{{
+				Rule:      LintRuleMissingDescription,
+				Severity:  LintSeverityWarning,
+				Parameter: "cmd",
+				Message:   "the parameter cmd has no description",
+			}},
+		},
+		"deprecated parameter": {
+			cd: newDef(cue("parameter: {\n\t// +usage=The image\n\t// +deprecated=use images instead\n\timage?: string\n\t// +usage=The images\n\timages: [...string]\n}\noutput: {image: parameter.image, images: parameter.images}\n")),
+			findings: []LintFinding{{
+				Rule:      LintRuleDeprecatedParameter,
+				Severity:  LintSeverityInfo,
+				Parameter: "image",
+				Message:   "the parameter image is deprecated: use images instead",
+			}},
+		},
+		"oversized schema": {
+			cd:   newDef(cue("parameter: {\n\t// +usage=The image\n\timage: string\n}\noutput: image: parameter.image\n")),
+			opts: LintOptions{SchemaSizeThreshold: 10},
+			findings: []LintFinding{{
+				Rule:     LintRuleOversizedSchema,
+				Severity: LintSeverityWarning,
+			}},
+		},
+		"unused parameter": {
+			cd: newDef(cue("parameter: {\n\t// +usage=The image\n\timage: string\n\t// +usage=The port\n\tport: int\n}\noutput: image: parameter.image\n")),
+			findings: []LintFinding{{
+				Rule:      LintRuleUnusedParameter,
+				Severity:  LintSeverityWarning,
+				Parameter: "port",
+				Message:   "the parameter port is declared but never referenced by the templates",
+			}},
+		},
+		"skipped without cluster": {
+			cd: func() *v1beta1.ComponentDefinition {
+				cd := newDef(cue("import \"example.com/lib\"\nparameter: {\n\timage: string\n}\noutput: image: lib.image\n"))
+				cd.Annotations = map[string]string{oam.AnnotationCUEPackageConfigMaps: "lib"}
+				return cd
+			}(),
+			findings: []LintFinding{{
+				Rule:     LintRuleSkipped,
+				Severity: LintSeverityInfo,
+				Message:  "the parameter schema is not checked without cluster, as the CUE packages of annotation definition.oam.dev/cue-package-configmaps are needed",
+			}, {
+				Rule:      LintRuleUnusedParameter,
+				Severity:  LintSeverityWarning,
+				Parameter: "image",
+				Message:   "the parameter image is declared but never referenced by the templates",
+			}},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			findings := LintComponentDefinition(context.Background(), nil, tc.cd, tc.opts)
+			require.Len(t, findings, len(tc.findings), "%v", findings)
+			for i, expected := range tc.findings {
+				if expected.Message == "" {
+					// the message of the finding depends on the CUE evaluation or the schema generated
+					require.NotEmpty(t, findings[i].Message)
+					expected.Message = findings[i].Message
+				}
+				require.Equal(t, expected, findings[i])
+			}
+		})
+	}
+}
+
+func TestLintComponentDefinitionFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "worker.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(strings.TrimSpace(`
+apiVersion: core.oam.dev/v1beta1
+kind: ComponentDefinition
+metadata:
+  name: worker
+spec:
+  workload:
+    definition:
+      apiVersion: apps/v1
+      kind: Deployment
+  schematic:
+    cue:
+      template: |
+        parameter: {
+          image: string
+        }
+        output: image: parameter.image
+`)), 0600))
+	findings, err := LintComponentDefinitionFile(context.Background(), nil, file, LintOptions{SchemaSizeThreshold: DefaultLintSchemaSizeThreshold})
+	require.NoError(t, err)
+	require.Equal(t, []LintFinding{{
+		Rule:      LintRuleMissingDescription,
+		Severity:  LintSeverityWarning,
+		Parameter: "image",
+		Message:   "the parameter image has no description",
+	}}, findings)
+	require.Equal(t, "warning [missing-description] the parameter image has no description", findings[0].String())
+
+	_, err = LintComponentDefinitionFile(context.Background(), nil, filepath.Join(t.TempDir(), "missing.yaml"), LintOptions{})
+	require.Error(t, err)
+}
@@ -0,0 +1,151 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// ParameterError is a violation of the schema by the value of a parameter
+type ParameterError struct {
+	// Field is the path to the parameter, e.g. ports[0].port, it's empty if the parameters as a whole are invalid
+	Field   string
+	Message string
+}
+
+func (e ParameterError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ParameterErrors are all the violations found by ValidateComponentParameters, sorted by the field
+type ParameterErrors []ParameterError
+
+func (e ParameterErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("invalid parameters: %s", strings.Join(msgs, "; "))
+}
+
+// ValidateComponentParameters validates the parameters of a component against the OpenAPI v3 JSON schema stored by
+// StoreOpenAPISchema for the ComponentDefinition of the type. The definition is looked up like util.GetDefinition,
+// i.e. in the namespace set by util.SetNamespaceInCtx and then in the system namespace. ParameterErrors is returned
+// if the parameters violate the schema.
+func ValidateComponentParameters(ctx context.Context, cli client.Client, componentType string, params map[string]interface{}) error {
+	cd := &v1beta1.ComponentDefinition{}
+	if err := util.GetDefinition(ctx, cli, cd, componentType); err != nil {
+		return errors.Wrapf(err, "failed to get ComponentDefinition %s", componentType)
+	}
+	store := &ConfigMapSchemaStore{Client: cli}
+	if ref := cd.Status.SchemaConfigMapRef; ref != nil && ref.Namespace != "" && ref.Namespace != cd.Namespace {
+		store.StorageNamespace = ref.Namespace
+	}
+	data, err := store.Get(ctx, cd.Namespace, cd.Name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the schema of ComponentDefinition %s", componentType)
+	}
+	s := openapi3.NewSchema()
+	if err = s.UnmarshalJSON(data); err != nil {
+		return errors.Wrapf(err, "failed to parse the schema of ComponentDefinition %s", componentType)
+	}
+
+	// round trip the parameters through JSON so that the values are of the types the validation expects,
+	// e.g. float64 for all numbers, and the defaults can be set into the copy
+	var value interface{} = map[string]interface{}{}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal the parameters")
+		}
+		if err = json.Unmarshal(raw, &value); err != nil {
+			return errors.Wrap(err, "failed to unmarshal the parameters")
+		}
+	}
+	// the parameters with a default are required in the schema, but they can be omitted since the default is
+	// filled in when the component is rendered
+	if err = s.VisitJSON(value, openapi3.VisitAsRequest(), openapi3.MultiErrors(), openapi3.DefaultsSet(func() {})); err != nil {
+		return toParameterErrors(err)
+	}
+	return nil
+}
+
+func toParameterErrors(err error) ParameterErrors {
+	var errs ParameterErrors
+	var collect func(err error)
+	collect = func(err error) {
+		var multi openapi3.MultiError
+		if errors.As(err, &multi) {
+			for _, e := range multi {
+				collect(e)
+			}
+			return
+		}
+		var schemaErr *openapi3.SchemaError
+		if errors.As(err, &schemaErr) {
+			errs = append(errs, ParameterError{Field: parameterPath(schemaErr.JSONPointer()), Message: schemaErr.Reason})
+			return
+		}
+		errs = append(errs, ParameterError{Message: err.Error()})
+	}
+	collect(err)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// parameterPath formats the JSON pointer to a value like the parameters are referred in CUE, e.g. ports[0].port
+func parameterPath(pointer []string) string {
+	var sb strings.Builder
+	for _, p := range pointer {
+		if isIndex(p) {
+			sb.WriteString("[" + p + "]")
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString(".")
+		}
+		sb.WriteString(p)
+	}
+	return sb.String()
+}
+
+func isIndex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
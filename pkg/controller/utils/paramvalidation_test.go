@@ -0,0 +1,87 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestValidateComponentParameters(t *testing.T) {
+	ctx := util.SetNamespaceInCtx(context.Background(), "default")
+	cd := newSchemaCacheTestDefinition(`parameter: {
+	image: string
+	replicas: *1 | int
+	ports?: [...{
+		port: int
+	}]
+}
+output: {}
+`)
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+	def := NewCapabilityComponentDef(cd)
+	_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		params map[string]interface{}
+		errs   ParameterErrors
+	}{
+		"valid": {
+			params: map[string]interface{}{"image": "nginx", "replicas": 3, "ports": []interface{}{map[string]interface{}{"port": 80}}},
+		},
+		"missing required field": {
+			params: map[string]interface{}{"replicas": 3},
+			errs:   ParameterErrors{{Field: "image", Message: `property "image" is missing`}},
+		},
+		"type mismatch": {
+			params: map[string]interface{}{"image": 1, "ports": []interface{}{map[string]interface{}{"port": "80"}}},
+			errs: ParameterErrors{
+				{Field: "image", Message: "value must be a string"},
+				{Field: "ports[0].port", Message: "value must be an integer"},
+			},
+		},
+		"no parameters": {
+			errs: ParameterErrors{{Field: "image", Message: `property "image" is missing`}},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateComponentParameters(ctx, k8sClient, "worker", tc.params)
+			if tc.errs == nil {
+				require.NoError(t, err)
+				return
+			}
+			var errs ParameterErrors
+			require.ErrorAs(t, err, &errs)
+			require.Equal(t, tc.errs, errs)
+		})
+	}
+
+	err = ValidateComponentParameters(ctx, k8sClient, "not-exist", map[string]interface{}{})
+	require.Error(t, err)
+}
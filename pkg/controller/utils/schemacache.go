@@ -0,0 +1,177 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/version"
+)
+
+// SchemaCache caches the parameter schemas generated from the CUE templates of the ComponentDefinitions, keyed by
+// the hash of the schematic content the schema is generated from. A reconcile triggered by the metadata of the
+// definition reuses the cached schema instead of compiling the CUE template again. Only the schema of the latest
+// content of each definition is kept, so a changed content invalidates the cached schema.
+type SchemaCache struct {
+	// ConfigMapBacked falls back to the schema ConfigMap of the definition on a miss in memory, e.g. after the
+	// controller restarts, if the ConfigMap is stored from the same content. The stored schema is already processed,
+	// so it's reused as is but not kept in memory. It doesn't apply to SchemaStore.
+	ConfigMapBacked bool
+
+	mu      sync.Mutex
+	entries map[client.ObjectKey]schemaCacheEntry
+}
+
+type schemaCacheEntry struct {
+	hash   string
+	schema []byte
+}
+
+// NewSchemaCache creates an empty SchemaCache
+func NewSchemaCache(configMapBacked bool) *SchemaCache {
+	return &SchemaCache{ConfigMapBacked: configMapBacked, entries: map[client.ObjectKey]schemaCacheEntry{}}
+}
+
+// Get returns the cached schema of the definition if it's generated from the content of the hash
+func (c *SchemaCache) Get(key client.ObjectKey, hash string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.hash != hash {
+		return nil, false
+	}
+	return entry.schema, true
+}
+
+// Put caches the schema of the definition generated from the content of the hash, replacing the schema of any
+// previous content
+func (c *SchemaCache) Put(key client.ObjectKey, hash string, schema []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = schemaCacheEntry{hash: hash, schema: schema}
+}
+
+// Delete drops the cached schema of the definition
+func (c *SchemaCache) Delete(key client.ObjectKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// getFromConfigMap reads the schema stored in the ConfigMap if it's annotated with the hash
+func (c *SchemaCache) getFromConfigMap(ctx context.Context, k8sClient client.Client, cmKey client.ObjectKey, hash string) ([]byte, bool) {
+	if !c.ConfigMapBacked {
+		return nil, false
+	}
+	cm := &v1.ConfigMap{}
+	if err := k8sClient.Get(ctx, cmKey, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.InfoS("Could not read the schema ConfigMap for the schema cache", "configMap", klog.KRef(cmKey.Namespace, cmKey.Name), "err", err)
+		}
+		return nil, false
+	}
+	if cm.Annotations[oam.AnnotationSchemaContentHash] != hash {
+		return nil, false
+	}
+	schema, err := GetOpenAPISchemaFromConfigMap(cm)
+	if err != nil || len(schema) == 0 {
+		return nil, false
+	}
+	if schema, err = ResolveSchemaFragments(ctx, k8sClient, schema); err != nil {
+		return nil, false
+	}
+	return schema, true
+}
+
+// cuePackage is the content of a CUE package stored in a ConfigMap, see LoadCUEPackages
+type cuePackage struct {
+	Path      string
+	Templates map[string]string
+	configMap string
+}
+
+// schematicContent is everything the schema generated from the CUE template depends on. ExcludeInternalParameters
+// and the post-processors don't change the generated schema, but they change the schema stored in the ConfigMap which
+// is reused on a restart, as well as the controller version which the generation and the processing may change with.
+type schematicContent struct {
+	Name                      string
+	Definition                interface{}
+	Packages                  []cuePackage
+	ForceRefresh              string
+	ExcludeInternalParameters bool
+	ControllerVersion         string
+	PostProcessors            []string
+}
+
+// schematicContentHash hashes the schematic content of the definition, which the schema is generated from
+func (def *CapabilityComponentDefinition) schematicContentHash(name string, packages []cuePackage) (string, error) {
+	spec := def.ComponentDefinition.Spec
+	return ComputeSpecHash(schematicContent{
+		Name:                      name,
+		Definition:                []interface{}{spec.Extension, spec.Schematic},
+		Packages:                  packages,
+		ForceRefresh:              def.ComponentDefinition.Annotations[oam.AnnotationForceSchemaRefresh],
+		ExcludeInternalParameters: def.ExcludeInternalParameters,
+		ControllerVersion:         version.VelaVersion,
+		PostProcessors:            schemaPostProcessorChain(),
+	})
+}
+
+// generateCUEOpenAPISchema generates the schema from the CUE template importing the packages. The schema is reused from the SchemaCache if
+// it's set and the schematic content is unchanged, and the hash of the content is recorded in SchemaContentHash.
+// The memory only caches the generated schema, while the schema read from the ConfigMap is stored after the internal
+// parameters are removed and the post-processors are applied, which is reported by processed so it's not processed again.
+func (def *CapabilityComponentDefinition) generateCUEOpenAPISchema(ctx context.Context, k8sClient client.Client, namespace, name string,
+	packages []cuePackage) (schema []byte, processed bool, err error) {
+	cache := def.SchemaCache
+	key := client.ObjectKey{Namespace: namespace, Name: def.ComponentDefinition.Name}
+	hash, err := def.schematicContentHash(name, packages)
+	if err != nil {
+		return nil, false, err
+	}
+	if cache != nil {
+		def.SchemaContentHash = hash
+		if schema, ok := cache.Get(key, hash); ok {
+			return schema, false, nil
+		}
+		if def.SchemaStore == nil {
+			cmKey := SchemaConfigMapKey(def.SchemaStorageNamespace, namespace, def.ComponentDefinition.Name)
+			if schema, ok := cache.getFromConfigMap(ctx, k8sClient, cmKey, hash); ok {
+				return schema, true, nil
+			}
+		}
+	}
+	imports, err := buildCUEPackages(namespace, packages)
+	if err != nil {
+		return nil, false, err
+	}
+	if schema, err = def.generateOpenAPISchemaWithinBudget(ctx, hash, name, imports...); err != nil {
+		return nil, false, err
+	}
+	if cache != nil {
+		cache.Put(key, hash, schema)
+	}
+	return schema, false, nil
+}
@@ -0,0 +1,230 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+	"github.com/oam-dev/kubevela/version"
+)
+
+func newSchemaCacheTestDefinition(template string) *v1beta1.ComponentDefinition {
+	return &v1beta1.ComponentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+		Spec: v1beta1.ComponentDefinitionSpec{
+			Workload:  common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+			Schematic: &common.Schematic{CUE: &common.CUE{Template: template}},
+		},
+	}
+}
+
+func TestSchemaCache(t *testing.T) {
+	ctx := context.Background()
+	cd := newSchemaCacheTestDefinition("parameter: {\n\timage: string\n}\noutput: {}\n")
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+	key := client.ObjectKeyFromObject(cd)
+	cmKey := client.ObjectKey{Namespace: "default", Name: ComponentDefinitionConfigMapName(cd.Name)}
+	storedSchema := func() string {
+		cm := &corev1.ConfigMap{}
+		assert.NoError(t, k8sClient.Get(ctx, cmKey, cm))
+		schema, err := GetOpenAPISchemaFromConfigMap(cm)
+		assert.NoError(t, err)
+		return string(schema)
+	}
+	store := func(cd *v1beta1.ComponentDefinition, cache *SchemaCache) CapabilityComponentDefinition {
+		def := NewCapabilityComponentDef(cd)
+		def.SchemaCache = cache
+		_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+		assert.NoError(t, err)
+		return def
+	}
+
+	cache := NewSchemaCache(false)
+	def := store(cd, cache)
+	assert.NotEmpty(t, def.SchemaContentHash)
+	generated := storedSchema()
+	cached, ok := cache.Get(key, def.SchemaContentHash)
+	assert.True(t, ok)
+	assert.Equal(t, generated, string(cached))
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, k8sClient.Get(ctx, cmKey, cm))
+	assert.Equal(t, def.SchemaContentHash, cm.Annotations[oam.AnnotationSchemaContentHash])
+
+	// the metadata doesn't change the hash, so the cached schema is stored without compiling the template
+	sentinel := `{"properties":{"cached":{"type":"string"}},"type":"object"}`
+	cache.Put(key, def.SchemaContentHash, []byte(sentinel))
+	labeled := cd.DeepCopy()
+	labeled.Labels = map[string]string{"team": "platform"}
+	assert.Equal(t, def.SchemaContentHash, store(labeled, cache).SchemaContentHash)
+	assert.Equal(t, sentinel, storedSchema())
+
+	// forcing the refresh changes the hash
+	refreshed := cd.DeepCopy()
+	refreshed.Annotations = map[string]string{oam.AnnotationForceSchemaRefresh: "1"}
+	assert.NotEqual(t, def.SchemaContentHash, store(refreshed, cache).SchemaContentHash)
+	assert.Equal(t, generated, storedSchema())
+
+	// the changed template invalidates the cached schema
+	changed := newSchemaCacheTestDefinition("parameter: {\n\timage: string\n\tport: int\n}\noutput: {}\n")
+	changedDef := store(changed, cache)
+	assert.NotEqual(t, def.SchemaContentHash, changedDef.SchemaContentHash)
+	assert.Contains(t, storedSchema(), "port")
+	_, ok = cache.Get(key, def.SchemaContentHash)
+	assert.False(t, ok)
+
+	// the ConfigMap backed cache reuses the stored schema of the same content after the memory is lost
+	cmCache := NewSchemaCache(true)
+	assert.NoError(t, k8sClient.Get(ctx, cmKey, cm))
+	cm.Data = map[string]string{"openapi-v3-json-schema": sentinel}
+	assert.NoError(t, k8sClient.Update(ctx, cm))
+	store(changed, cmCache)
+	assert.Equal(t, sentinel, storedSchema())
+	// the stored schema is already processed, so it's not kept in memory
+	_, ok = cmCache.Get(key, changedDef.SchemaContentHash)
+	assert.False(t, ok)
+
+	// the memory cache doesn't read the ConfigMaps
+	store(changed, NewSchemaCache(false))
+	assert.Contains(t, storedSchema(), "port")
+
+	cache.Delete(key)
+	_, ok = cache.Get(key, changedDef.SchemaContentHash)
+	assert.False(t, ok)
+}
+
+func TestSchemaCacheRestartPostProcessing(t *testing.T) {
+	ctx := context.Background()
+	cd := newSchemaCacheTestDefinition("parameter: {\n\timage: string\n}\noutput: {}\n")
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+	var processed int
+	RegisterSchemaPostProcessor("counting", func(_ *v1beta1.ComponentDefinition, schema *openapi3.Schema) (*openapi3.Schema, error) {
+		processed++
+		schema.Description += "processed;"
+		return schema, nil
+	})
+	defer UnregisterSchemaPostProcessor("counting")
+	store := func(cache *SchemaCache) []byte {
+		def := NewCapabilityComponentDef(cd)
+		def.SchemaCache = cache
+		_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+		require.NoError(t, err)
+		schema, err := def.GetSchemaStore(k8sClient).Get(ctx, "default", cd.Name)
+		require.NoError(t, err)
+		return schema
+	}
+
+	stored := store(NewSchemaCache(true))
+	assert.Equal(t, 1, processed)
+	assert.Contains(t, string(stored), `"description":"processed;"`)
+
+	// the controller restarts with the ConfigMap backed cache, the stored schema isn't processed again
+	for i := 0; i < 2; i++ {
+		assert.JSONEq(t, string(stored), string(store(NewSchemaCache(true))))
+	}
+	assert.Equal(t, 1, processed)
+
+	// the schema generated again is processed once
+	cache := NewSchemaCache(false)
+	assert.JSONEq(t, string(stored), string(store(cache)))
+	assert.JSONEq(t, string(stored), string(store(cache)))
+	assert.Equal(t, 3, processed)
+}
+
+func TestSchemaCacheControllerUpgrade(t *testing.T) {
+	ctx := context.Background()
+	cd := newSchemaCacheTestDefinition("parameter: {\n\timage: string\n}\noutput: {}\n")
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+	cache := NewSchemaCache(true)
+	store := func() string {
+		def := NewCapabilityComponentDef(cd)
+		def.SchemaCache = cache
+		_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+		require.NoError(t, err)
+		return def.SchemaContentHash
+	}
+	defer func(v string) { version.VelaVersion = v }(version.VelaVersion)
+
+	version.VelaVersion = "v1.9.0"
+	hash := store()
+	_, ok := cache.Get(client.ObjectKeyFromObject(cd), hash)
+	require.True(t, ok)
+
+	// the schema cached by another controller version is generated again
+	version.VelaVersion = "v1.10.0"
+	upgraded := store()
+	require.NotEqual(t, hash, upgraded)
+	_, ok = cache.Get(client.ObjectKeyFromObject(cd), hash)
+	require.False(t, ok)
+
+	// so is the schema processed by another chain of post-processors
+	RegisterSchemaPostProcessor("upgrade", func(_ *v1beta1.ComponentDefinition, schema *openapi3.Schema) (*openapi3.Schema, error) {
+		return schema, nil
+	})
+	defer UnregisterSchemaPostProcessor("upgrade")
+	require.NotEqual(t, upgraded, store())
+}
+
+func BenchmarkSchemaCache(b *testing.B) {
+	ctx := context.Background()
+	cd := newSchemaCacheTestDefinition(`
+parameter: {
+	image:    string
+	replicas: *1 | int
+	env?: [...{
+		name:   string
+		value?: string
+	}]
+	resources: {
+		cpu:     *"100m" | string
+		memory?: string
+	}
+}
+output: {}
+`)
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd).Build()
+	for _, bc := range []struct {
+		name  string
+		cache *SchemaCache
+	}{{"uncached", nil}, {"cached", NewSchemaCache(false)}} {
+		b.Run(bc.name, func(b *testing.B) {
+			def := NewCapabilityComponentDef(cd)
+			def.SchemaCache = bc.cache
+			for i := 0; i < b.N; i++ {
+				if _, err := def.GenerateOpenAPISchema(ctx, k8sClient, "default", cd.Name); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
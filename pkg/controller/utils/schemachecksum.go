@@ -0,0 +1,78 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// ErrSchemaChecksumMismatch is returned by VerifySchemaChecksum if the schema stored in the ConfigMap doesn't match
+// its checksum, i.e. the ConfigMap is tampered or corrupted
+var ErrSchemaChecksumMismatch = errors.New("schema checksum mismatch")
+
+// SchemaChecksum is the sha256 of the canonical JSON of the schema, so that the formatting doesn't change it
+func SchemaChecksum(jsonSchema []byte) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(jsonSchema, &v); err != nil {
+		return "", errors.Wrap(err, "cannot parse the schema")
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// StoredSchemaChecksum computes the checksum of the schema stored in the ConfigMap with the fragments resolved
+func StoredSchemaChecksum(ctx context.Context, cli client.Client, cm *v1.ConfigMap) (string, error) {
+	jsonSchema, err := GetOpenAPISchemaFromConfigMap(cm)
+	if err != nil {
+		return "", err
+	}
+	if jsonSchema, err = ResolveSchemaFragments(ctx, cli, jsonSchema); err != nil {
+		return "", err
+	}
+	return SchemaChecksum(jsonSchema)
+}
+
+// VerifySchemaChecksum verifies the schema stored in the ConfigMap against the checksum recorded in its annotation,
+// ErrSchemaChecksumMismatch is returned if they differ. The ConfigMaps without the checksum are not verified.
+func VerifySchemaChecksum(ctx context.Context, cli client.Client, cm *v1.ConfigMap) error {
+	expected, ok := cm.GetAnnotations()[oam.AnnotationSchemaChecksum]
+	if !ok {
+		return nil
+	}
+	checksum, err := StoredSchemaChecksum(ctx, cli, cm)
+	if err != nil {
+		return errors.Wrapf(ErrSchemaChecksumMismatch, "cannot read the schema of ConfigMap %s: %v", cm.Name, err)
+	}
+	if checksum != expected {
+		return errors.Wrapf(ErrSchemaChecksumMismatch, "the schema of ConfigMap %s has checksum %s, expected %s", cm.Name, checksum, expected)
+	}
+	return nil
+}
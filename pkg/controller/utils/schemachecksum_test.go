@@ -0,0 +1,83 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestSchemaChecksum(t *testing.T) {
+	checksum, err := SchemaChecksum([]byte(`{"type": "object", "properties": {"image": {"type": "string"}}}`))
+	require.NoError(t, err)
+	require.Len(t, checksum, 64)
+	// the formatting doesn't change the checksum
+	formatted, err := SchemaChecksum([]byte("{\n  \"properties\": {\"image\": {\"type\": \"string\"}},\n  \"type\": \"object\"\n}"))
+	require.NoError(t, err)
+	require.Equal(t, checksum, formatted)
+	changed, err := SchemaChecksum([]byte(`{"type": "object", "properties": {"image": {"type": "integer"}}}`))
+	require.NoError(t, err)
+	require.NotEqual(t, checksum, changed)
+	_, err = SchemaChecksum([]byte("not json"))
+	require.Error(t, err)
+}
+
+func TestStoreSchemaChecksum(t *testing.T) {
+	ctx := context.Background()
+	cd := newSchemaCacheTestDefinition("parameter: {\n\timage: string\n}\noutput: {}\n")
+	defRev := &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd, defRev).Build()
+	def := NewCapabilityComponentDef(cd)
+	def.SchemaChecksum = true
+	_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, defRev.Name)
+	require.NoError(t, err)
+
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(defRev), defRev))
+	checksum := defRev.Annotations[oam.AnnotationSchemaChecksum]
+	require.NotEmpty(t, checksum)
+	for _, name := range []string{cd.Name, defRev.Name} {
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: ComponentDefinitionConfigMapName(name)}, cm))
+		assert.Equal(t, checksum, cm.Annotations[oam.AnnotationSchemaChecksum])
+		assert.NoError(t, VerifySchemaChecksum(ctx, k8sClient, cm))
+	}
+
+	// the tampered schema is detected
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: ComponentDefinitionConfigMapName(cd.Name)}, cm))
+	cm.Data[types.OpenapiV3JSONSchema] = `{"type": "object", "properties": {"image": {"type": "integer"}}}`
+	require.ErrorIs(t, VerifySchemaChecksum(ctx, k8sClient, cm), ErrSchemaChecksumMismatch)
+	cm.Data[types.OpenapiV3JSONSchema] = "corrupted"
+	require.ErrorIs(t, VerifySchemaChecksum(ctx, k8sClient, cm), ErrSchemaChecksumMismatch)
+
+	// the ConfigMaps without the checksum are not verified
+	delete(cm.Annotations, oam.AnnotationSchemaChecksum)
+	require.NoError(t, VerifySchemaChecksum(ctx, k8sClient, cm))
+}
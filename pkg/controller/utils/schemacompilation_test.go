@@ -0,0 +1,173 @@
+//go:build linux
+
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+const schemaCompilationTestTemplate = `
+parameter: {
+	image:    string
+	replicas: *1 | int
+	env?: [...{
+		name:   string
+		value?: string
+	}]
+	resources: {
+		cpu:     *"100m" | string
+		memory?: string
+	}
+}
+output: {}
+`
+
+func TestSchemaCompilationSemaphore(t *testing.T) {
+	cd := newSchemaCacheTestDefinition(schemaCompilationTestTemplate)
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd).Build()
+	def := NewCapabilityComponentDef(cd)
+	def.SchemaCompilationSemaphore = semaphore.NewWeighted(1)
+
+	// the compilation waits for the semaphore held by another one, until the context is done
+	require.NoError(t, def.SchemaCompilationSemaphore.Acquire(context.Background(), 1))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := def.GenerateOpenAPISchema(ctx, k8sClient, "default", cd.Name)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the semaphore is released once the compilation finishes
+	def.SchemaCompilationSemaphore.Release(1)
+	for i := 0; i < 2; i++ {
+		schema, err := def.GenerateOpenAPISchema(context.Background(), k8sClient, "default", cd.Name)
+		require.NoError(t, err)
+		require.Contains(t, string(schema), "replicas")
+	}
+	require.True(t, def.SchemaCompilationSemaphore.TryAcquire(1))
+}
+
+const slowSchemaCompilationTestTemplate = `import "list"
+
+output: {}
+parameter: items: *[ for i in list.Range(0, 120, 1) for j in list.Range(0, 120, 1) {i * j}] | [...int]
+`
+
+func TestSchemaEvaluationsOfSlowTemplate(t *testing.T) {
+	cd := newSchemaCacheTestDefinition(slowSchemaCompilationTestTemplate)
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd).Build()
+	def := NewCapabilityComponentDef(cd)
+	def.SchemaGenerationTimeout = time.Millisecond
+	hash, err := def.schematicContentHash(cd.Name, nil)
+	require.NoError(t, err)
+
+	// the repeated generations exceeding the budget join the evaluation abandoned by the first one
+	_, err = def.GenerateOpenAPISchema(context.Background(), k8sClient, "default", cd.Name)
+	require.ErrorIs(t, err, ErrSchemaGenerationTimeout)
+	evaluation := schemaEvaluations.get(hash)
+	require.NotNil(t, evaluation)
+	for i := 0; i < 5; i++ {
+		_, err = def.GenerateOpenAPISchema(context.Background(), k8sClient, "default", cd.Name)
+		require.ErrorIs(t, err, ErrSchemaGenerationTimeout)
+		require.Same(t, evaluation, schemaEvaluations.get(hash))
+	}
+
+	// the result of the finished evaluation is not kept
+	<-evaluation.done
+	require.NoError(t, evaluation.err)
+	require.Nil(t, schemaEvaluations.get(hash))
+}
+
+func TestSchemaCompilationSemaphoreOfSlowTemplate(t *testing.T) {
+	cd := newSchemaCacheTestDefinition(slowSchemaCompilationTestTemplate)
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd).Build()
+	def := NewCapabilityComponentDef(cd)
+	def.SchemaGenerationTimeout = time.Millisecond
+	def.SchemaCompilationSemaphore = semaphore.NewWeighted(3)
+	hash, err := def.schematicContentHash(cd.Name, nil)
+	require.NoError(t, err)
+
+	// the abandoned evaluations of the same template hold one slot only
+	for i := 0; i < 5; i++ {
+		_, err = def.GenerateOpenAPISchema(context.Background(), k8sClient, "default", cd.Name)
+		require.ErrorIs(t, err, ErrSchemaGenerationTimeout)
+	}
+	evaluation := schemaEvaluations.get(hash)
+	require.NotNil(t, evaluation)
+	require.True(t, def.SchemaCompilationSemaphore.TryAcquire(2))
+	require.False(t, def.SchemaCompilationSemaphore.TryAcquire(1))
+	def.SchemaCompilationSemaphore.Release(2)
+
+	// the slot is released once the evaluation finishes
+	<-evaluation.done
+	require.Eventually(t, func() bool { return def.SchemaCompilationSemaphore.TryAcquire(3) }, time.Second, 10*time.Millisecond)
+}
+
+// BenchmarkSchemaCompilationConcurrency generates the schemas of a burst of new definitions at once, and reports the
+// CPU cores used on average, which is bounded by the concurrency of the semaphore
+func BenchmarkSchemaCompilationConcurrency(b *testing.B) {
+	const burst = 32
+	ctx := context.Background()
+	cd := newSchemaCacheTestDefinition(schemaCompilationTestTemplate)
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd).Build()
+	for _, concurrency := range []int{0, 1, 2} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			var sem *semaphore.Weighted
+			if concurrency > 0 {
+				sem = semaphore.NewWeighted(int64(concurrency))
+			}
+			start, cpuStart := time.Now(), cpuTime(b)
+			for i := 0; i < b.N; i++ {
+				var wg sync.WaitGroup
+				for j := 0; j < burst; j++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						def := NewCapabilityComponentDef(cd)
+						def.SchemaCompilationSemaphore = sem
+						if _, err := def.GenerateOpenAPISchema(ctx, k8sClient, "default", cd.Name); err != nil {
+							b.Error(err)
+						}
+					}()
+				}
+				wg.Wait()
+			}
+			b.ReportMetric((cpuTime(b)-cpuStart).Seconds()/time.Since(start).Seconds(), "cpu-cores")
+		})
+	}
+}
+
+// cpuTime returns the user and system CPU time used by the process
+func cpuTime(b *testing.B) time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		b.Fatal(err)
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
@@ -0,0 +1,249 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// schemaFragmentConfigMapPrefix is the name prefix of the ConfigMaps storing the schema fragments
+const schemaFragmentConfigMapPrefix = "schema-fragment-"
+
+// schemaRefKey is the key of the JSON schema referring to another schema
+const schemaRefKey = "$ref"
+
+// SchemaFragmentRef returns the $ref of the schema fragment stored in the ConfigMap of the namespace. The namespace is
+// part of the reference, so the reference stays valid wherever the schema is copied to.
+func SchemaFragmentRef(namespace, cmName string) string {
+	return namespace + "/" + cmName
+}
+
+// parseSchemaFragmentRef returns the key of the ConfigMap of the fragment if the $ref refers to a schema fragment
+func parseSchemaFragmentRef(ref string) (client.ObjectKey, bool) {
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found || namespace == "" || !strings.HasPrefix(name, schemaFragmentConfigMapPrefix) {
+		return client.ObjectKey{}, false
+	}
+	return client.ObjectKey{Namespace: namespace, Name: name}, true
+}
+
+// ExtractSchemaFragments replaces the nested schemas of the properties, items and additional properties, which are
+// no smaller than minSize bytes, with the $ref of the schema fragments stored in the namespace. The fragments are
+// named by the hash of their content, so the same structure repeated by definitions is stored once. The schema is
+// returned as it is if no fragment is extracted. The fragments are returned by the names of their ConfigMaps.
+func ExtractSchemaFragments(jsonSchema []byte, namespace string, minSize int) ([]byte, map[string][]byte, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(jsonSchema, &schema); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse the schema")
+	}
+	fragments := map[string][]byte{}
+	var extract func(node interface{}) (interface{}, error)
+	extract = func(node interface{}) (interface{}, error) {
+		s, ok := node.(map[string]interface{})
+		if !ok {
+			return node, nil
+		}
+		if err := walkSubSchemas(s, extract); err != nil {
+			return nil, err
+		}
+		if _, isRef := s[schemaRefKey]; isRef {
+			return s, nil
+		}
+		fragment, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(fragment) < minSize {
+			return s, nil
+		}
+		sum := sha256.Sum256(fragment)
+		cmName := schemaFragmentConfigMapPrefix + hex.EncodeToString(sum[:])[:16]
+		fragments[cmName] = fragment
+		return map[string]interface{}{schemaRefKey: SchemaFragmentRef(namespace, cmName)}, nil
+	}
+	if err := walkSubSchemas(schema, extract); err != nil {
+		return nil, nil, err
+	}
+	if len(fragments) == 0 {
+		return jsonSchema, nil, nil
+	}
+	out, err := json.Marshal(schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, fragments, nil
+}
+
+// walkSubSchemas replaces the sub schemas of the properties, items and additional properties by the visit
+func walkSubSchemas(schema map[string]interface{}, visit func(interface{}) (interface{}, error)) error {
+	var err error
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, property := range properties {
+			if properties[name], err = visit(property); err != nil {
+				return err
+			}
+		}
+	}
+	switch items := schema["items"].(type) {
+	case map[string]interface{}:
+		if schema["items"], err = visit(items); err != nil {
+			return err
+		}
+	case []interface{}:
+		for i := range items {
+			if items[i], err = visit(items[i]); err != nil {
+				return err
+			}
+		}
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		if schema["additionalProperties"], err = visit(additional); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishSchemaFragments creates the ConfigMaps of the schema fragments in the namespace. The fragments are immutable
+// as they are named by their content, so the existing ones are left as they are.
+func publishSchemaFragments(ctx context.Context, k8sClient client.Client, namespace string, fragments map[string][]byte) error {
+	for cmName, fragment := range fragments {
+		cm := &v1.ConfigMap{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: cmName}, cm)
+		if err == nil {
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get the schema fragment %s/%s", namespace, cmName)
+		}
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cmName,
+				Namespace: namespace,
+				Labels:    map[string]string{oam.LabelSchemaFragment: "true"},
+			},
+			Data: map[string]string{types.OpenapiV3JSONSchema: string(fragment)},
+		}
+		if err = k8sClient.Create(ctx, cm); err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to publish the schema fragment %s/%s", namespace, cmName)
+		}
+	}
+	return nil
+}
+
+// ResolveSchemaFragments replaces the $ref of the schema fragments with the fragments, so that the schema stored with
+// fragments is resolved to the complete schema. The schema is returned as it is if it refers to no fragment.
+func ResolveSchemaFragments(ctx context.Context, k8sClient client.Reader, jsonSchema []byte) ([]byte, error) {
+	if !strings.Contains(string(jsonSchema), schemaFragmentConfigMapPrefix) {
+		return jsonSchema, nil
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(jsonSchema, &schema); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the schema")
+	}
+	resolved := map[client.ObjectKey]interface{}{}
+	var resolve func(node interface{}) (interface{}, error)
+	resolve = func(node interface{}) (interface{}, error) {
+		s, ok := node.(map[string]interface{})
+		if !ok {
+			return node, nil
+		}
+		ref, _ := s[schemaRefKey].(string)
+		key, isFragment := parseSchemaFragmentRef(ref)
+		if !isFragment {
+			return s, walkSubSchemas(s, resolve)
+		}
+		if fragment, ok := resolved[key]; ok {
+			return fragment, nil
+		}
+		cm := &v1.ConfigMap{}
+		if err := k8sClient.Get(ctx, key, cm); err != nil {
+			return nil, errors.Wrapf(err, "failed to get the schema fragment %s", key)
+		}
+		var fragment map[string]interface{}
+		if err := json.Unmarshal([]byte(cm.Data[types.OpenapiV3JSONSchema]), &fragment); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the schema fragment %s", key)
+		}
+		if err := walkSubSchemas(fragment, resolve); err != nil {
+			return nil, err
+		}
+		resolved[key] = fragment
+		return fragment, nil
+	}
+	if err := walkSubSchemas(schema, resolve); err != nil {
+		return nil, err
+	}
+	return json.Marshal(schema)
+}
+
+// ListSchemaFragments returns the ConfigMaps of the schema fragments the schema refers to, including the fragments
+// referred by the fragments
+func ListSchemaFragments(ctx context.Context, k8sClient client.Reader, jsonSchema []byte) ([]*v1.ConfigMap, error) {
+	if !strings.Contains(string(jsonSchema), schemaFragmentConfigMapPrefix) {
+		return nil, nil
+	}
+	var cms []*v1.ConfigMap
+	visited := map[client.ObjectKey]bool{}
+	var collect func(node interface{}) (interface{}, error)
+	collect = func(node interface{}) (interface{}, error) {
+		s, ok := node.(map[string]interface{})
+		if !ok {
+			return node, nil
+		}
+		ref, _ := s[schemaRefKey].(string)
+		key, isFragment := parseSchemaFragmentRef(ref)
+		if !isFragment {
+			return s, walkSubSchemas(s, collect)
+		}
+		if visited[key] {
+			return s, nil
+		}
+		visited[key] = true
+		cm := &v1.ConfigMap{}
+		if err := k8sClient.Get(ctx, key, cm); err != nil {
+			return nil, errors.Wrapf(err, "failed to get the schema fragment %s", key)
+		}
+		cms = append(cms, cm)
+		var fragment map[string]interface{}
+		if err := json.Unmarshal([]byte(cm.Data[types.OpenapiV3JSONSchema]), &fragment); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the schema fragment %s", key)
+		}
+		return s, walkSubSchemas(fragment, collect)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(jsonSchema, &schema); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the schema")
+	}
+	if err := walkSubSchemas(schema, collect); err != nil {
+		return nil, err
+	}
+	return cms, nil
+}
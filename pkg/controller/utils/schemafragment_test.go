@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestSchemaFragments(t *testing.T) {
+	ctx := context.Background()
+	const resources = "resources: {\n\t\trequests: {\n\t\t\tcpu: *\"100m\" | string\n\t\t\tmemory: *\"128Mi\" | string\n\t\t}\n\t\tlimits: {\n\t\t\tcpu: *\"1\" | string\n\t\t\tmemory: *\"1Gi\" | string\n\t\t}\n\t}\n"
+	worker := newSchemaCacheTestDefinition("parameter: {\n\timage: string\n\t" + resources + "}\noutput: {}\n")
+	webservice := newSchemaCacheTestDefinition("parameter: {\n\tport: int\n\t" + resources + "}\noutput: {}\n")
+	webservice.Name = "webservice"
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(worker, webservice,
+		&v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}},
+		&v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "webservice-v1", Namespace: "default"}}).Build()
+	store := &ConfigMapSchemaStore{Client: k8sClient}
+	rawSchema := func(name string) string {
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, k8sClient.Get(ctx, SchemaConfigMapKey("", "default", name), cm))
+		schema, err := GetOpenAPISchemaFromConfigMap(cm)
+		require.NoError(t, err)
+		return string(schema)
+	}
+
+	// the schemas stored without fragments are the complete schemas to compare with
+	expected := map[string]string{}
+	for _, cd := range []*v1beta1.ComponentDefinition{worker, webservice} {
+		def := NewCapabilityComponentDef(cd)
+		_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, cd.Name+"-v1")
+		require.NoError(t, err)
+		expected[cd.Name] = rawSchema(cd.Name)
+		assert.NotContains(t, expected[cd.Name], "$ref")
+
+		def.SchemaFragmentMinSize = 100
+		_, err = def.StoreOpenAPISchema(ctx, k8sClient, "default", cd.Name, cd.Name+"-v1")
+		require.NoError(t, err)
+	}
+
+	// the resources repeated by both definitions are published once
+	fragments := &corev1.ConfigMapList{}
+	require.NoError(t, k8sClient.List(ctx, fragments, client.InNamespace("default"), client.MatchingLabels{oam.LabelSchemaFragment: "true"}))
+	names := map[string]bool{}
+	for _, cm := range fragments.Items {
+		names[cm.Name] = true
+	}
+	assert.NotEmpty(t, names)
+	for name := range names {
+		assert.True(t, strings.HasPrefix(name, "schema-fragment-"), name)
+	}
+	var resourcesRef string
+	for _, cm := range fragments.Items {
+		if strings.Contains(cm.Data["openapi-v3-json-schema"], `"requests"`) {
+			resourcesRef = SchemaFragmentRef("default", cm.Name)
+		}
+	}
+	require.NotEmpty(t, resourcesRef)
+
+	for _, cd := range []string{worker.Name, webservice.Name} {
+		// the main schema links to the fragment
+		assert.Contains(t, rawSchema(cd), `"resources":{"$ref":"`+resourcesRef+`"}`)
+		// the schema read through the store is resolved to the complete schema
+		resolved, err := store.Get(ctx, "default", cd)
+		require.NoError(t, err)
+		assert.JSONEq(t, expected[cd], string(resolved))
+		listed, err := ListSchemaFragments(ctx, k8sClient, []byte(rawSchema(cd)))
+		require.NoError(t, err)
+		assert.NotEmpty(t, listed)
+		for _, cm := range listed {
+			assert.True(t, names[cm.Name], cm.Name)
+		}
+	}
+
+	// the missing fragment fails the resolution rather than returning a partial schema
+	require.NoError(t, k8sClient.DeleteAllOf(ctx, &corev1.ConfigMap{}, client.InNamespace("default"), client.MatchingLabels{oam.LabelSchemaFragment: "true"}))
+	_, err := store.Get(ctx, "default", worker.Name)
+	assert.ErrorIs(t, err, ErrSchemaCorrupted)
+}
+
+func TestExtractSchemaFragments(t *testing.T) {
+	schema := []byte(`{"type":"object","properties":{"name":{"type":"string"}}}`)
+	out, fragments, err := ExtractSchemaFragments(schema, "default", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, string(schema), string(out))
+	assert.Empty(t, fragments)
+
+	out, fragments, err = ExtractSchemaFragments(schema, "default", 1)
+	require.NoError(t, err)
+	assert.Len(t, fragments, 1)
+	for name, fragment := range fragments {
+		assert.JSONEq(t, `{"type":"string"}`, string(fragment))
+		assert.JSONEq(t, `{"type":"object","properties":{"name":{"$ref":"default/`+name+`"}}}`, string(out))
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// SchemaPostProcessor enriches the OpenAPI v3 schema of the parameters generated from the ComponentDefinition, e.g.
+// adds vendor extensions, UI hints or validation keywords, and returns the schema to store. It must be deterministic,
+// otherwise the stored schema changes on every reconcile.
+type SchemaPostProcessor func(cd *v1beta1.ComponentDefinition, schema *openapi3.Schema) (*openapi3.Schema, error)
+
+var (
+	schemaPostProcessorsMu sync.RWMutex
+	schemaPostProcessors   = map[string]SchemaPostProcessor{}
+)
+
+// RegisterSchemaPostProcessor registers the SchemaPostProcessor of the name, which replaces the one registered with
+// the same name. The post-processors are applied in the order of their names regardless of the order of
+// registration, so that the stored schemas are stable.
+func RegisterSchemaPostProcessor(name string, processor SchemaPostProcessor) {
+	schemaPostProcessorsMu.Lock()
+	defer schemaPostProcessorsMu.Unlock()
+	schemaPostProcessors[name] = processor
+}
+
+// UnregisterSchemaPostProcessor removes the SchemaPostProcessor of the name
+func UnregisterSchemaPostProcessor(name string) {
+	schemaPostProcessorsMu.Lock()
+	defer schemaPostProcessorsMu.Unlock()
+	delete(schemaPostProcessors, name)
+}
+
+// postProcessSchema applies the registered post-processors to the generated schema in the order of their names. The
+// schema is returned as it is if no post-processor is registered.
+func postProcessSchema(cd *v1beta1.ComponentDefinition, jsonSchema []byte) ([]byte, error) {
+	schemaPostProcessorsMu.RLock()
+	names := sortedSchemaPostProcessorNames()
+	processors := make([]SchemaPostProcessor, 0, len(names))
+	for _, name := range names {
+		processors = append(processors, schemaPostProcessors[name])
+	}
+	schemaPostProcessorsMu.RUnlock()
+	if len(processors) == 0 {
+		return jsonSchema, nil
+	}
+
+	schema := openapi3.NewSchema()
+	if err := schema.UnmarshalJSON(jsonSchema); err != nil {
+		return nil, errors.Wrap(err, "failed to parse OpenAPI v3 JSON schema")
+	}
+	for i, process := range processors {
+		processed, err := process(cd, schema)
+		if err != nil {
+			return nil, errors.Wrapf(err, "schema post-processor %s failed", names[i])
+		}
+		if processed != nil {
+			schema = processed
+		}
+	}
+	return schema.MarshalJSON()
+}
+
+// schemaPostProcessorChain returns the names of the registered post-processors in the order they are applied, which
+// identifies the processing of the stored schemas
+func schemaPostProcessorChain() []string {
+	schemaPostProcessorsMu.RLock()
+	defer schemaPostProcessorsMu.RUnlock()
+	return sortedSchemaPostProcessorNames()
+}
+
+// sortedSchemaPostProcessorNames must be called with schemaPostProcessorsMu held
+func sortedSchemaPostProcessorNames() []string {
+	names := make([]string, 0, len(schemaPostProcessors))
+	for name := range schemaPostProcessors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,82 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestSchemaPostProcessors(t *testing.T) {
+	ctx := context.Background()
+	cd := newSchemaCacheTestDefinition("parameter: {\n\timage: string\n\tcmd?: [...string]\n}\noutput: {}\n")
+	k8sClient := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithObjects(cd,
+		&v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: "worker-v1", Namespace: "default"}}).Build()
+	var order []string
+	// the UI hint injected into the image parameter
+	RegisterSchemaPostProcessor("ui-hints", func(cd *v1beta1.ComponentDefinition, schema *openapi3.Schema) (*openapi3.Schema, error) {
+		order = append(order, "ui-hints")
+		if image, ok := schema.Properties["image"]; ok && image.Value != nil {
+			image.Value.Extensions = map[string]interface{}{"x-ui-widget": "ImageInput", "x-ui-definition": cd.Name}
+		}
+		return schema, nil
+	})
+	defer UnregisterSchemaPostProcessor("ui-hints")
+	RegisterSchemaPostProcessor("a-noop", func(_ *v1beta1.ComponentDefinition, _ *openapi3.Schema) (*openapi3.Schema, error) {
+		order = append(order, "a-noop")
+		return nil, nil
+	})
+	defer UnregisterSchemaPostProcessor("a-noop")
+
+	def := NewCapabilityComponentDef(cd)
+	_, err := def.StoreOpenAPISchema(ctx, k8sClient, "default", "worker", "worker-v1")
+	require.NoError(t, err)
+	// the post-processors are applied in the order of names
+	assert.Equal(t, []string{"a-noop", "ui-hints"}, order)
+	for _, name := range []string{"worker", "worker-v1"} {
+		schema, err := def.GetSchemaFromConfigMap(ctx, k8sClient, "default", name)
+		require.NoError(t, err)
+		assert.Equal(t, "ImageInput", schema.Properties["image"].Value.Extensions["x-ui-widget"])
+		assert.Equal(t, "worker", schema.Properties["image"].Value.Extensions["x-ui-definition"])
+		assert.Equal(t, "string", schema.Properties["image"].Value.Type)
+	}
+
+	// the generated schema is stable, so the stored schema doesn't drift
+	stored, err := def.GetSchemaStore(k8sClient).Get(ctx, "default", "worker")
+	require.NoError(t, err)
+	generated, err := def.GenerateOpenAPISchema(ctx, k8sClient, "default", "worker")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(stored), string(generated))
+
+	RegisterSchemaPostProcessor("failing", func(_ *v1beta1.ComponentDefinition, _ *openapi3.Schema) (*openapi3.Schema, error) {
+		return nil, errors.New("boom")
+	})
+	defer UnregisterSchemaPostProcessor("failing")
+	_, err = def.GenerateOpenAPISchema(ctx, k8sClient, "default", "worker")
+	require.ErrorContains(t, err, "schema post-processor failing failed: boom")
+}
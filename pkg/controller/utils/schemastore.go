@@ -0,0 +1,114 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/types"
+)
+
+const (
+	// SchemaStorageBackendConfigMap stores the schemas in ConfigMaps, which is the default backend
+	SchemaStorageBackendConfigMap = "configmap"
+	// SchemaStorageBackendS3 stores the schemas in an S3 compatible object store
+	SchemaStorageBackendS3 = "s3"
+)
+
+// SchemaStore is the storage backend of the OpenAPI v3 JSON schemas of the component definitions. The schemas are
+// addressed by the namespace of the definition and the name of the definition or of one of its DefinitionRevisions.
+type SchemaStore interface {
+	// Backend returns the name of the backend, which is recorded in the status of the definition
+	Backend() string
+	// Location returns where the schema is stored in the backend
+	Location(namespace, name string) string
+	// Put creates or overwrites the schema
+	Put(ctx context.Context, namespace, name string, schema []byte) error
+	// Get returns the schema, ErrSchemaNotStored is returned if the schema doesn't exist
+	Get(ctx context.Context, namespace, name string) ([]byte, error)
+	// Delete deletes the schema, the schema that doesn't exist is ignored
+	Delete(ctx context.Context, namespace, name string) error
+}
+
+// ConfigMapSchemaStore is the SchemaStore keeping the schemas in the ConfigMaps named by SchemaConfigMapKey. The
+// ConfigMaps written by StoreOpenAPISchema are additionally owned by the definitions, Put of the store only writes
+// the schema.
+type ConfigMapSchemaStore struct {
+	Client client.Client
+	// StorageNamespace centralizes the ConfigMaps into the namespace if it's set
+	StorageNamespace string
+}
+
+var _ SchemaStore = &ConfigMapSchemaStore{}
+
+// Backend implements SchemaStore
+func (s *ConfigMapSchemaStore) Backend() string {
+	return SchemaStorageBackendConfigMap
+}
+
+// Location implements SchemaStore, it's the <namespace>/<name> of the ConfigMap
+func (s *ConfigMapSchemaStore) Location(namespace, name string) string {
+	return SchemaConfigMapKey(s.StorageNamespace, namespace, name).String()
+}
+
+// Put implements SchemaStore
+func (s *ConfigMapSchemaStore) Put(ctx context.Context, namespace, name string, schema []byte) error {
+	key := SchemaConfigMapKey(s.StorageNamespace, namespace, name)
+	var base CapabilityBaseDefinition
+	return base.createOrUpdateConfigMap(ctx, s.Client, key.Namespace, key.Name, name, nil, nil, nil, schema, nil)
+}
+
+// Get implements SchemaStore
+func (s *ConfigMapSchemaStore) Get(ctx context.Context, namespace, name string) ([]byte, error) {
+	key := SchemaConfigMapKey(s.StorageNamespace, namespace, name)
+	cm := &v1.ConfigMap{}
+	if err := s.Client.Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(ErrSchemaNotStored, "ConfigMap %s", key)
+		}
+		return nil, errors.Wrapf(err, "failed to get ConfigMap %s", key)
+	}
+	if _, ok := cm.Data[types.OpenapiV3JSONSchema]; !ok {
+		return nil, errors.Wrapf(ErrSchemaNotStored, "ConfigMap %s", key)
+	}
+	data, err := GetOpenAPISchemaFromConfigMap(cm)
+	if err != nil {
+		return nil, errors.Wrap(ErrSchemaCorrupted, err.Error())
+	}
+	if data, err = ResolveSchemaFragments(ctx, s.Client, data); err != nil {
+		return nil, errors.Wrap(ErrSchemaCorrupted, err.Error())
+	}
+	return data, nil
+}
+
+// Delete implements SchemaStore
+func (s *ConfigMapSchemaStore) Delete(ctx context.Context, namespace, name string) error {
+	key := SchemaConfigMapKey(s.StorageNamespace, namespace, name)
+	cm := &v1.ConfigMap{}
+	cm.SetNamespace(key.Namespace)
+	cm.SetName(key.Name)
+	if err := s.Client.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete ConfigMap %s", key)
+	}
+	return nil
+}
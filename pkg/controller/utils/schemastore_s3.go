@@ -0,0 +1,208 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// S3SchemaStoreConfig configures the S3 compatible object store of the schemas
+type S3SchemaStoreConfig struct {
+	// Endpoint is the URL of the object store, e.g. https://s3.us-east-1.amazonaws.com
+	Endpoint string
+	// Bucket stores the schemas, they are keyed as <namespace>/<name>.json
+	Bucket string
+	// Region is used to sign the requests, it defaults to us-east-1
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3SchemaStore is the SchemaStore keeping the schemas in an S3 compatible object store. The requests are addressed
+// in path style and signed with AWS Signature Version 4.
+type S3SchemaStore struct {
+	config   S3SchemaStoreConfig
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+var _ SchemaStore = &S3SchemaStore{}
+
+// NewS3SchemaStore creates the SchemaStore backed by the bucket of the S3 compatible object store
+func NewS3SchemaStore(config S3SchemaStoreConfig) (*S3SchemaStore, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, errors.New("both the endpoint and the bucket of the S3 schema store must be set")
+	}
+	endpoint, err := url.ParseRequestURI(config.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid endpoint of the S3 schema store")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	return &S3SchemaStore{config: config, endpoint: endpoint, client: &http.Client{Timeout: 30 * time.Second}, now: time.Now}, nil
+}
+
+// Backend implements SchemaStore
+func (s *S3SchemaStore) Backend() string {
+	return SchemaStorageBackendS3
+}
+
+// Location implements SchemaStore, it's the s3://<bucket>/<key> of the object
+func (s *S3SchemaStore) Location(namespace, name string) string {
+	return fmt.Sprintf("s3://%s/%s", s.config.Bucket, s3SchemaObjectKey(namespace, name))
+}
+
+// Put implements SchemaStore
+func (s *S3SchemaStore) Put(ctx context.Context, namespace, name string, schema []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s3SchemaObjectKey(namespace, name), schema)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode/100 != 2 {
+		return s3ResponseError(resp, "put", s.Location(namespace, name))
+	}
+	return nil
+}
+
+// Get implements SchemaStore
+func (s *S3SchemaStore) Get(ctx context.Context, namespace, name string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s3SchemaObjectKey(namespace, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errors.Wrapf(ErrSchemaNotStored, "object %s", s.Location(namespace, name))
+	case resp.StatusCode/100 != 2:
+		return nil, s3ResponseError(resp, "get", s.Location(namespace, name))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read object %s", s.Location(namespace, name))
+	}
+	return data, nil
+}
+
+// Delete implements SchemaStore
+func (s *S3SchemaStore) Delete(ctx context.Context, namespace, name string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s3SchemaObjectKey(namespace, name), nil)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return s3ResponseError(resp, "delete", s.Location(namespace, name))
+	}
+	return nil
+}
+
+func s3SchemaObjectKey(namespace, name string) string {
+	return path.Join(namespace, name+".json")
+}
+
+func s3ResponseError(resp *http.Response, operation, location string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("failed to %s object %s: %s %s", operation, location, resp.Status, strings.TrimSpace(string(body)))
+}
+
+func closeBody(resp *http.Response) {
+	_ = resp.Body.Close()
+}
+
+// do sends the request of the object signed with AWS Signature Version 4
+func (s *S3SchemaStore) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	objectPath := strings.TrimSuffix(s.endpoint.Path, "/") + "/" + s.config.Bucket + "/" + key
+	var escaped []string
+	for _, segment := range strings.Split(objectPath, "/") {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+	canonicalURI := strings.Join(escaped, "/")
+	u := *s.endpoint
+	u.Path, u.RawPath = objectPath, canonicalURI
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to %s object %s/%s", strings.ToLower(method), s.config.Bucket, key)
+	}
+	return resp, nil
+}
+
+// sign signs the request with AWS Signature Version 4, the host, the payload hash and the date are signed
+func (s *S3SchemaStore) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, s.config.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+	signingKey := []byte("AWS4" + s.config.SecretAccessKey)
+	for _, v := range []string{date, s.config.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, v)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
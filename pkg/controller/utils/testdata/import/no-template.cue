@@ -0,0 +1,3 @@
+"no-template": {
+	type: "component"
+}
@@ -0,0 +1,20 @@
+worker: {
+	type: "component"
+	description: "Describes long-running workers"
+	attributes: workload: definition: {
+		apiVersion: "apps/v1"
+		kind:       "Deployment"
+	}
+}
+
+template: {
+	output: {
+		apiVersion: "apps/v1"
+		kind:       "Deployment"
+		spec: template: spec: containers: [{image: parameter.image}]
+	}
+	parameter: {
+		image:    string
+		replicas: *1 | int
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ Copyright 2021 The KubeVela Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package utils
+
+import (
+	"sort"
+	"strconv"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/parser"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+const parameterFieldName = "parameter"
+
+// FindUnusedParameters returns the sorted names of the top-level parameters declared by the CUE template of the
+// ComponentDefinition which are referenced by neither the template nor the status templates. The parameters are
+// treated as used if the parameter struct is referenced as a whole, e.g. passed through or iterated, or by a dynamic
+// index, as the references can't be told apart then. Nothing is reported for the definitions without CUE template or
+// whose parameter is not declared by struct literals.
+func FindUnusedParameters(def *v1beta1.ComponentDefinition) ([]string, error) {
+	if def.Spec.Schematic == nil || def.Spec.Schematic.CUE == nil {
+		return nil, nil
+	}
+	file, err := parser.ParseFile("-", def.Spec.Schematic.CUE.Template)
+	if err != nil {
+		return nil, err
+	}
+	declared := map[string]bool{}
+	var others []ast.Node
+	for _, decl := range file.Decls {
+		field, ok := decl.(*ast.Field)
+		if !ok {
+			others = append(others, decl)
+			continue
+		}
+		if name, _, _ := ast.LabelName(field.Label); name != parameterFieldName {
+			others = append(others, decl)
+			continue
+		}
+		st, ok := field.Value.(*ast.StructLit)
+		if !ok {
+			return nil, nil
+		}
+		for _, elt := range st.Elts {
+			if f, ok := elt.(*ast.Field); ok {
+				if name, _, err := ast.LabelName(f.Label); err == nil {
+					declared[name] = true
+				}
+			}
+		}
+	}
+	if len(declared) == 0 {
+		return nil, nil
+	}
+	if status := def.Spec.Status; status != nil {
+		for _, template := range []string{status.HealthPolicy, status.CustomStatus} {
+			if template == "" {
+				continue
+			}
+			// the broken status templates are reported by ValidateStatusTemplates
+			if f, err := parser.ParseFile("-", template); err == nil {
+				others = append(others, f)
+			}
+		}
+	}
+
+	used, all := map[string]bool{}, false
+	for _, node := range others {
+		ast.Walk(node, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.SelectorExpr:
+				if isParameterIdent(x.X) {
+					if name, _, err := ast.LabelName(x.Sel); err == nil {
+						used[name] = true
+						return false
+					}
+				}
+			case *ast.IndexExpr:
+				if isParameterIdent(x.X) {
+					if lit, ok := x.Index.(*ast.BasicLit); ok {
+						if name, err := strconv.Unquote(lit.Value); err == nil {
+							used[name] = true
+							return false
+						}
+					}
+				}
+			case *ast.Ident:
+				if x.Name == parameterFieldName {
+					all = true
+				}
+			}
+			return !all
+		}, nil)
+		if all {
+			return nil, nil
+		}
+	}
+	var unused []string
+	for name := range declared {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	return unused, nil
+}
+
+func isParameterIdent(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == parameterFieldName
+}
@@ -17,11 +17,15 @@ limitations under the License.
 package utils
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/mitchellh/hashstructure/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
@@ -67,3 +71,55 @@ func ComputeSpecHash(spec interface{}) (string, error) {
 	specHashLabel := strconv.FormatUint(specHash, 16)
 	return specHashLabel, nil
 }
+
+// ResolveComponentDefinitionAlias resolves the name or the alias of a ComponentDefinition in the namespace to the
+// canonical name of the definition. The alias is only resolved after it's accepted by the controller.
+func ResolveComponentDefinitionAlias(ctx context.Context, cli client.Reader, namespace, name string) (string, error) {
+	def := &v1beta1.ComponentDefinition{}
+	err := cli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, def)
+	if err == nil {
+		return name, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return "", err
+	}
+	defList := &v1beta1.ComponentDefinitionList{}
+	if err := cli.List(ctx, defList, client.InNamespace(namespace)); err != nil {
+		return "", err
+	}
+	for _, item := range defList.Items {
+		if item.Status.Alias == name {
+			return item.Name, nil
+		}
+	}
+	return "", err
+}
+
+// ComponentDefinitionWorkloadGVKIndex is the field index of the ComponentDefinitions by the GroupVersionKind of the
+// workload referenced through spec.workload.definition
+const ComponentDefinitionWorkloadGVKIndex = "spec.workload.definition.gvk"
+
+// IndexComponentDefinitionByWorkloadGVK extracts the value of ComponentDefinitionWorkloadGVKIndex from the object
+func IndexComponentDefinitionByWorkloadGVK(obj client.Object) []string {
+	def, ok := obj.(*v1beta1.ComponentDefinition)
+	if !ok || def.Spec.Workload.Definition.Kind == "" {
+		return nil
+	}
+	gv, err := schema.ParseGroupVersion(def.Spec.Workload.Definition.APIVersion)
+	if err != nil {
+		return nil
+	}
+	return []string{gv.WithKind(def.Spec.Workload.Definition.Kind).String()}
+}
+
+// ListComponentDefinitionsByWorkloadGVK lists the ComponentDefinitions whose workload is of the given GroupVersionKind,
+// e.g. to find out the definitions which break once the CRD is removed. The reader must be indexed by
+// ComponentDefinitionWorkloadGVKIndex, as the cache of the manager running the ComponentDefinition controller is.
+func ListComponentDefinitionsByWorkloadGVK(ctx context.Context, cli client.Reader, gvk schema.GroupVersionKind, opts ...client.ListOption) ([]v1beta1.ComponentDefinition, error) {
+	defList := &v1beta1.ComponentDefinitionList{}
+	opts = append(opts, client.MatchingFields{ComponentDefinitionWorkloadGVKIndex: gvk.String()})
+	if err := cli.List(ctx, defList, opts...); err != nil {
+		return nil, err
+	}
+	return defList.Items, nil
+}
@@ -17,13 +17,21 @@ limitations under the License.
 package utils
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 func TestConstructExtract(t *testing.T) {
@@ -72,3 +80,117 @@ func TestGetAppRevison(t *testing.T) {
 	assert.Equal(t, revisionName, "myapp-v2")
 	assert.Equal(t, latestRevision, int64(2))
 }
+
+func TestConvertWorkloadToComponent(t *testing.T) {
+	wd := &v1beta1.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "clonesets.apps.kruise.io",
+			Namespace:   "vela-system",
+			Labels:      map[string]string{"team": "infra"},
+			Annotations: map[string]string{"definition.oam.dev/description": "CloneSet workload"},
+		},
+		Spec: v1beta1.WorkloadDefinitionSpec{
+			Reference:          common.DefinitionReference{Name: "clonesets.apps.kruise.io"},
+			ChildResourceKinds: []common.ChildResourceKind{{APIVersion: "v1", Kind: "Pod"}},
+			RevisionLabel:      "controller-revision-hash",
+			PodSpecPath:        "spec.template.spec",
+			Status:             &common.Status{HealthPolicy: "isHealth: true", CustomStatus: `message: "ok"`},
+			Schematic:          &common.Schematic{CUE: &common.CUE{Template: "output: {}\nparameter: {}"}},
+			Extension:          &runtime.RawExtension{Raw: []byte(`{"alias":"cloneset"}`)},
+		},
+	}
+	cd, err := ConvertWorkloadToComponent(wd)
+	assert.NoError(t, err)
+	assert.Equal(t, v1beta1.ComponentDefinitionKind, cd.Kind)
+	assert.Equal(t, wd.ObjectMeta, cd.ObjectMeta)
+	assert.NotContains(t, cd.Annotations, oam.AnnotationDefinitionConversionWarnings)
+
+	// the ComponentDefinition refers to the same workload and keeps the rest of the spec
+	capability := NewCapabilityComponentDef(cd)
+	assert.Equal(t, util.ReferWorkload, capability.WorkloadType)
+	assert.Equal(t, wd.Spec.Reference, common.DefinitionReference{Name: cd.Spec.Workload.Type})
+	assert.Equal(t, wd.Spec.ChildResourceKinds, cd.Spec.ChildResourceKinds)
+	assert.Equal(t, wd.Spec.RevisionLabel, cd.Spec.RevisionLabel)
+	assert.Equal(t, wd.Spec.PodSpecPath, cd.Spec.PodSpecPath)
+	assert.Equal(t, wd.Spec.Status, cd.Spec.Status)
+	assert.Equal(t, wd.Spec.Schematic, cd.Spec.Schematic)
+	assert.Equal(t, wd.Spec.Extension, cd.Spec.Extension)
+
+	// the conversion doesn't share the memory with the WorkloadDefinition
+	cd.Spec.Schematic.CUE.Template = "output: kind: \"CloneSet\""
+	assert.Equal(t, "output: {}\nparameter: {}", wd.Spec.Schematic.CUE.Template)
+
+	wd.Spec.Reference.Version = "v1alpha1"
+	wd.Spec.Schematic = nil
+	cd, err = ConvertWorkloadToComponent(wd)
+	assert.NoError(t, err)
+	assert.Contains(t, cd.Annotations[oam.AnnotationDefinitionConversionWarnings], "the version v1alpha1 of definitionRef")
+	assert.Contains(t, cd.Annotations[oam.AnnotationDefinitionConversionWarnings], "no schematic is declared")
+	assert.NotContains(t, wd.Annotations, oam.AnnotationDefinitionConversionWarnings)
+
+	wd.Spec.Reference = common.DefinitionReference{}
+	_, err = ConvertWorkloadToComponent(wd)
+	assert.Error(t, err)
+}
+
+func TestListComponentDefinitionsByWorkloadGVK(t *testing.T) {
+	ctx := context.Background()
+	newDef := func(name, namespace, apiVersion, kind string) client.Object {
+		return &v1beta1.ComponentDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1beta1.ComponentDefinitionSpec{Workload: common.WorkloadTypeDescriptor{
+				Definition: common.WorkloadGVK{APIVersion: apiVersion, Kind: kind},
+			}},
+		}
+	}
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1beta1.AddToScheme(scheme))
+	cli := fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&v1beta1.ComponentDefinition{}, ComponentDefinitionWorkloadGVKIndex, IndexComponentDefinitionByWorkloadGVK).
+		WithObjects(
+			newDef("webservice", "vela-system", "apps/v1", "Deployment"),
+			newDef("worker", "vela-system", "apps/v1", "Deployment"),
+			newDef("legacy-worker", "vela-system", "apps/v1beta1", "Deployment"),
+			newDef("cron-task", "vela-system", "batch/v1", "CronJob"),
+			newDef("raw", "default", "apps/v1", "Deployment"),
+			newDef("autodetect", "vela-system", "", ""),
+		).Build()
+
+	testCases := map[string]struct {
+		gvk      schema.GroupVersionKind
+		opts     []client.ListOption
+		expected []string
+	}{
+		"shared gvk": {
+			gvk:      schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			expected: []string{"webservice", "worker", "raw"},
+		},
+		"shared gvk in namespace": {
+			gvk:      schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			opts:     []client.ListOption{client.InNamespace("vela-system")},
+			expected: []string{"webservice", "worker"},
+		},
+		"different version": {
+			gvk:      schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"},
+			expected: []string{"legacy-worker"},
+		},
+		"different group": {
+			gvk:      schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"},
+			expected: []string{"cron-task"},
+		},
+		"no definition": {
+			gvk: schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Foo"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			defs, err := ListComponentDefinitionsByWorkloadGVK(ctx, cli, tc.gvk, tc.opts...)
+			assert.NoError(t, err)
+			var names []string
+			for _, def := range defs {
+				names = append(names, def.Name)
+			}
+			assert.ElementsMatch(t, tc.expected, names)
+		})
+	}
+}
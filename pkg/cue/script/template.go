@@ -352,7 +352,7 @@ func (c CUE) ParsePropertiesToSchemaWithCueX(ctx context.Context, templateFieldP
 
 // FIXME: double code with pkg/schema/schema.go to avoid import cycle
 
-// FixOpenAPISchema fixes tainted `description` filed, missing of title `field`.
+// FixOpenAPISchema fixes tainted `description` filed, missing of title `field`, and marks the deprecated parameters.
 func FixOpenAPISchema(name string, schema *openapi3.Schema) {
 	t := schema.Type
 	switch t {
@@ -370,7 +370,16 @@ func FixOpenAPISchema(name string, schema *openapi3.Schema) {
 		schema.Title = name
 	}
 
-	description := schema.Description
+	description, deprecated, message := appfile.ExtractDeprecatedTag(schema.Description)
+	if deprecated {
+		schema.Deprecated = true
+		if message != "" {
+			if schema.Extensions == nil {
+				schema.Extensions = map[string]interface{}{}
+			}
+			schema.Extensions[appfile.DeprecationMessageExtension] = message
+		}
+	}
 	if strings.Contains(description, appfile.UsageTag) {
 		description = strings.Split(description, appfile.UsageTag)[1]
 	}
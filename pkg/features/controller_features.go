@@ -111,6 +111,16 @@ const (
 
 	// DisableWorkflowContextConfigMapCache disable the workflow context's configmap informer cache
 	DisableWorkflowContextConfigMapCache = "DisableWorkflowContextConfigMapCache"
+
+	// DefinitionSchemaCompression compresses the parameter schema of ComponentDefinitions stored in ConfigMaps when it
+	// exceeds 512KiB. If disabled, the schema is always stored as plain JSON, which can be read by older clients but
+	// may exceed the size limit of ConfigMap.
+	DefinitionSchemaCompression featuregate.Feature = "DefinitionSchemaCompression"
+
+	// DefinitionRevisionGCByUsage keeps the DefinitionRevisions referenced by Applications, e.g. `worker@v2`, from
+	// the garbage collection of DefinitionRevisions. If disabled, the oldest revisions beyond the limit are collected
+	// regardless of their usage, which saves listing the Applications.
+	DefinitionRevisionGCByUsage featuregate.Feature = "DefinitionRevisionGCByUsage"
 )
 
 var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
@@ -135,6 +145,8 @@ var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	InformerCacheFilterUnnecessaryFields:          {Default: true, PreRelease: featuregate.Alpha},
 	SharedDefinitionStorageForApplicationRevision: {Default: true, PreRelease: featuregate.Alpha},
 	DisableWorkflowContextConfigMapCache:          {Default: true, PreRelease: featuregate.Alpha},
+	DefinitionSchemaCompression:                   {Default: true, PreRelease: featuregate.Beta},
+	DefinitionRevisionGCByUsage:                   {Default: true, PreRelease: featuregate.Beta},
 }
 
 func init() {
@@ -0,0 +1,51 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	velametrics "github.com/kubevela/pkg/monitor/metrics"
+)
+
+var (
+	// ComponentDefinitionReconcileTimeHistogram report the reconciling time cost of componentDefinition controller
+	ComponentDefinitionReconcileTimeHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "componentdefinition_reconcile_time_seconds",
+		Help:        "componentDefinition reconcile duration distributions.",
+		Buckets:     velametrics.FineGrainedBuckets,
+		ConstLabels: prometheus.Labels{},
+	}, []string{"result"})
+
+	// ComponentDefinitionRevisionCounter report the number of definitionRevisions created or reused by componentDefinition controller
+	ComponentDefinitionRevisionCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "componentdefinition_revision_operation_num",
+		Help: "componentDefinition revision operation times, the operation is create or reuse.",
+	}, []string{"operation"})
+
+	// ComponentDefinitionRevisionGauge report the current number of definitionRevisions of each componentDefinition
+	ComponentDefinitionRevisionGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "componentdefinition_revision_number",
+		Help: "componentDefinition revision number after garbage collection.",
+	}, []string{"namespace", "name"})
+
+	// ComponentDefinitionSchemaSizeGauge report the size in bytes of the OpenAPI schema of each componentDefinition
+	ComponentDefinitionSchemaSizeGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "componentdefinition_schema_size_bytes",
+		Help: "componentDefinition parameter schema size in bytes.",
+	}, []string{"namespace", "name"})
+)
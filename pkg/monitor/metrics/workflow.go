@@ -51,6 +51,10 @@ var collectorGroup = []prometheus.Collector{
 	ClusterPodAllocatableGauge,
 	ClusterMemoryUsageGauge,
 	ClusterCPUUsageGauge,
+	ComponentDefinitionReconcileTimeHistogram,
+	ComponentDefinitionRevisionCounter,
+	ComponentDefinitionRevisionGauge,
+	ComponentDefinitionSchemaSizeGauge,
 }
 
 func init() {
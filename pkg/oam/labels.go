@@ -53,6 +53,23 @@ const (
 	LabelTraitDefinitionName = "trait.oam.dev/name"
 	// LabelManageWorkloadTrait indicates if the trait will manage the lifecycle of the workload
 	LabelManageWorkloadTrait = "trait.oam.dev/manage-workload"
+	// LabelSystemScopedDefinition marks the component definition as system-scoped if it's set to "true",
+	// whose schema ConfigMap is published to the shared schema namespace
+	LabelSystemScopedDefinition = "definition.oam.dev/system-scoped"
+	// LabelSharedSchemaSourceNamespace records the namespace of the definition whose schema ConfigMap is published to
+	// the shared schema namespace or stored in the schema storage namespace
+	LabelSharedSchemaSourceNamespace = "definition.oam.dev/source-namespace"
+	// LabelSchemaFragment marks the ConfigMap storing a schema fragment shared by the schemas of definitions
+	LabelSchemaFragment = "definition.oam.dev/schema-fragment"
+	// LabelDefinitionFamily groups the ComponentDefinitions of the same component family, e.g. database or gateway.
+	// It's copied to the DefinitionRevisions so that the revisions can be filtered by family
+	LabelDefinitionFamily = "definition.oam.dev/family"
+	// LabelDefinitionSchematicType records the schematic type of the ComponentDefinition a DefinitionRevision is
+	// generated from, e.g. cue, terraform or jsonschema
+	LabelDefinitionSchematicType = "definition.oam.dev/schematic-type"
+	// LabelDefinitionUID records the UID of the definition a DefinitionRevision is generated from, which tells apart
+	// the revisions of a former definition deleted and recreated with the same name
+	LabelDefinitionUID = "definition.oam.dev/uid"
 	// LabelPolicyDefinitionName records the name of PolicyDefinition
 	LabelPolicyDefinitionName = "policydefinition.oam.dev/name"
 	// LabelWorkflowStepDefinitionName records the name of WorkflowStepDefinition
@@ -147,6 +164,86 @@ const (
 	// AnnotationDefinitionRevisionName is used to specify the name of DefinitionRevision in component/trait definition
 	AnnotationDefinitionRevisionName = "definitionrevision.oam.dev/name"
 
+	// AnnotationSkipWorkloadConversion indicates that no WorkloadDefinition should be generated for the
+	// componentDefinition, even if the workloadDefinition it refers to does not exist
+	AnnotationSkipWorkloadConversion = "componentdefinition.oam.dev/skip-workload-conversion"
+
+	// AnnotationDefinitionRevisionLimit overrides the number of DefinitionRevisions retained for the definition
+	AnnotationDefinitionRevisionLimit = "definitionrevision.oam.dev/limit"
+
+	// AnnotationRevisionHashCollision records the name of the existing DefinitionRevision which has the same revision hash
+	// but a different spec with the annotated DefinitionRevision
+	AnnotationRevisionHashCollision = "definitionrevision.oam.dev/hash-collision"
+
+	// AnnotationRevisionNumbers records the revision numbers assigned to the hash-named DefinitionRevision, separated by
+	// `,`. The revision is assigned a new number every time the definition is changed back to its content, and the
+	// Applications pinning any of the numbers, e.g. `worker@v1`, are resolved to it.
+	AnnotationRevisionNumbers = "definitionrevision.oam.dev/revision-numbers"
+
+	// AnnotationDefinitionRevisionFreeze stops generating new DefinitionRevisions for the definition if it's set to "true",
+	// the latest DefinitionRevision keeps being used until the annotation is removed
+	AnnotationDefinitionRevisionFreeze = "definitionrevision.oam.dev/freeze"
+
+	// AnnotationRevisionReconstructed marks the DefinitionRevision recreated from the definition after the latest
+	// DefinitionRevision recorded in the status of the definition is found missing
+	AnnotationRevisionReconstructed = "definitionrevision.oam.dev/reconstructed"
+
+	// AnnotationRevisionCreatedAt records when the DefinitionRevision is created by the controller
+	AnnotationRevisionCreatedAt = "definitionrevision.oam.dev/created-at"
+
+	// AnnotationRevisionControllerVersion records the version of the controller creating the DefinitionRevision
+	AnnotationRevisionControllerVersion = "definitionrevision.oam.dev/controller-version"
+
+	// AnnotationDefinitionGeneratedBy records the build version of the controller which generates the schema of the
+	// DefinitionRevision, it's updated when the schema of the latest revision is regenerated by another version
+	AnnotationDefinitionGeneratedBy = "definition.oam.dev/generated-by"
+
+	// AnnotationRevisionSourceGeneration records the generation of the definition which the DefinitionRevision is created from
+	AnnotationRevisionSourceGeneration = "definitionrevision.oam.dev/source-generation"
+
+	// AnnotationRevisionFieldManager records the field manager which last changed the definition before the
+	// DefinitionRevision is created, e.g. kubectl or a GitOps tool
+	AnnotationRevisionFieldManager = "definitionrevision.oam.dev/field-manager"
+
+	// AnnotationCUEPackageConfigMaps is a comma separated list of ConfigMaps in the namespace of the definition,
+	// each of which stores a CUE package that can be imported by the CUE template of the definition
+	AnnotationCUEPackageConfigMaps = "definition.oam.dev/cue-package-configmaps"
+
+	// AnnotationForceSchemaRefresh forces the OpenAPI schema of the definition to be regenerated once its value is
+	// changed, the handled value is recorded on the schema ConfigMaps
+	AnnotationForceSchemaRefresh = "definition.oam.dev/force-schema-refresh"
+
+	// AnnotationSchemaContentHash records on the schema ConfigMaps the hash of the schematic content the schema is
+	// generated from, so that the schema is reused while the content is unchanged
+	AnnotationSchemaContentHash = "definition.oam.dev/schema-content-hash"
+
+	// AnnotationSchemaChecksum records on the schema ConfigMaps and the DefinitionRevisions the checksum of the stored
+	// schema, so that the readers can verify its integrity
+	AnnotationSchemaChecksum = "definition.oam.dev/schema-checksum"
+
+	// AnnotationDefinitionConversionWarnings records the information lost when the ComponentDefinition is converted
+	// from a WorkloadDefinition
+	AnnotationDefinitionConversionWarnings = "definition.oam.dev/conversion-warnings"
+
+	// AnnotationDefinitionDeprecated marks the definition as deprecated if it's set to "true"
+	AnnotationDefinitionDeprecated = "definition.oam.dev/deprecated"
+
+	// AnnotationDefinitionDeprecationMessage explains why the deprecated definition should not be used any more
+	AnnotationDefinitionDeprecationMessage = "definition.oam.dev/deprecation-message"
+
+	// AnnotationDefinitionReplacement is the name of the definition replacing the deprecated one
+	AnnotationDefinitionReplacement = "definition.oam.dev/replacement"
+
+	// AnnotationCompatibleTraits is a comma separated list of the traits known to work with the definition
+	AnnotationCompatibleTraits = "definition.oam.dev/compatible-traits"
+
+	// AnnotationIncompatibleTraits is a comma separated list of the traits which must not be attached to the definition
+	AnnotationIncompatibleTraits = "definition.oam.dev/incompatible-traits"
+
+	// AnnotationDefinitionRollbackTo is the name of a DefinitionRevision of the definition to roll the spec of the
+	// definition back to, it's cleared once the rollback is done
+	AnnotationDefinitionRollbackTo = "definition.oam.dev/rollback-to"
+
 	// AnnotationLastAppliedConfiguration is kubectl annotations for 3-way merge
 	AnnotationLastAppliedConfiguration = "kubectl.kubernetes.io/last-applied-configuration"
 
@@ -212,4 +309,7 @@ const (
 	// FinalizerOrphanResource indicates that the gc process should orphan managed
 	// resources instead of deleting them
 	FinalizerOrphanResource = "app.oam.dev/orphan-resource"
+	// FinalizerComponentDefinition is the componentDefinition finalizer for cleaning up
+	// its DefinitionRevisions and schema ConfigMaps
+	FinalizerComponentDefinition = "componentdefinition.core.oam.dev/finalizer"
 )
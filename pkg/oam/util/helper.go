@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"hash"
 	"hash/fnv"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -109,6 +110,9 @@ const (
 	// TerraformDef describes a workload refer to Terraform
 	TerraformDef WorkloadType = "TerraformDef"
 
+	// JSONSchemaDef describes a workload whose parameters are declared by JSON Schema
+	JSONSchemaDef WorkloadType = "JSONSchemaDef"
+
 	// ReferWorkload describe an existing workload
 	ReferWorkload WorkloadType = "ReferWorkload"
 )
@@ -214,7 +218,7 @@ func GetDefinitionFromNamespace(ctx context.Context, cli client.Reader, definiti
 // GetCapabilityDefinition can get different versions of ComponentDefinition/TraitDefinition
 func GetCapabilityDefinition(ctx context.Context, cli client.Reader, definition client.Object,
 	definitionName string) error {
-	isLatestRevision, defRev, err := fetchDefinitionRev(ctx, cli, definitionName)
+	isLatestRevision, defRev, err := fetchDefinitionRev(ctx, cli, definitionName, definitionNameLabel(definition))
 	if err != nil {
 		return err
 	}
@@ -235,7 +239,7 @@ func GetCapabilityDefinition(ctx context.Context, cli client.Reader, definition
 	return nil
 }
 
-func fetchDefinitionRev(ctx context.Context, cli client.Reader, definitionName string) (bool, *v1beta1.DefinitionRevision, error) {
+func fetchDefinitionRev(ctx context.Context, cli client.Reader, definitionName, nameLabel string) (bool, *v1beta1.DefinitionRevision, error) {
 	// if the component's type doesn't contain '@' means user want to use the latest Definition.
 	if !strings.Contains(definitionName, "@") {
 		return true, nil, nil
@@ -247,11 +251,86 @@ func fetchDefinitionRev(ctx context.Context, cli client.Reader, definitionName s
 	}
 	defRev := new(v1beta1.DefinitionRevision)
 	if err := GetDefinition(ctx, cli, defRev, defRevName); err != nil {
-		return false, nil, err
+		if !apierrors.IsNotFound(err) || nameLabel == "" {
+			return false, nil, err
+		}
+		// the hash-named revisions are found by the revision numbers assigned to them
+		found, findErr := findDefinitionRevisionByNumber(ctx, cli, definitionName, nameLabel)
+		if findErr != nil {
+			return false, nil, findErr
+		}
+		if found == nil {
+			return false, nil, err
+		}
+		defRev = found
 	}
 	return false, defRev, nil
 }
 
+// definitionNameLabel returns the label of DefinitionRevisions recording the name of the definition of the type
+func definitionNameLabel(definition client.Object) string {
+	switch definition.(type) {
+	case *v1beta1.ComponentDefinition:
+		return oam.LabelComponentDefinitionName
+	case *v1beta1.TraitDefinition:
+		return oam.LabelTraitDefinitionName
+	case *v1beta1.PolicyDefinition:
+		return oam.LabelPolicyDefinitionName
+	case *v1beta1.WorkflowStepDefinition:
+		return oam.LabelWorkflowStepDefinitionName
+	}
+	return ""
+}
+
+// findDefinitionRevisionByNumber finds the DefinitionRevision assigned the revision number referred by the definition
+// name, e.g. `worker@v3`, among the revisions labeled by the name of the definition, in the namespaces searched by
+// GetDefinition. Nil is returned if the revision is not found or the reference is not a revision number.
+func findDefinitionRevisionByNumber(ctx context.Context, cli client.Reader, definitionName, nameLabel string) (*v1beta1.DefinitionRevision, error) {
+	defName, version, found := strings.Cut(definitionName, "@v")
+	if !found {
+		return nil, nil
+	}
+	revision, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	for _, ns := range []string{GetDefinitionNamespaceWithCtx(ctx), GetXDefinitionNamespaceWithCtx(ctx), oam.SystemDefinitionNamespace} {
+		revs := new(v1beta1.DefinitionRevisionList)
+		if err = cli.List(ctx, revs, client.InNamespace(ns), client.MatchingLabels{nameLabel: defName}); err != nil {
+			return nil, err
+		}
+		for i := range revs.Items {
+			for _, number := range DefinitionRevisionNumbers(&revs.Items[i]) {
+				if number == revision {
+					return &revs.Items[i], nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// DefinitionRevisionNumbers returns the sorted revision numbers assigned to the DefinitionRevision, which are the
+// revision number of its spec and the ones recorded by the annotation oam.AnnotationRevisionNumbers
+func DefinitionRevisionNumbers(defRev *v1beta1.DefinitionRevision) []int64 {
+	numbers := []int64{defRev.Spec.Revision}
+	for _, value := range strings.Split(defRev.GetAnnotations()[oam.AnnotationRevisionNumbers], ",") {
+		number, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		duplicated := false
+		for _, n := range numbers {
+			duplicated = duplicated || n == number
+		}
+		if !duplicated {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
 // ConvertDefinitionRevName can help convert definition type defined in Application to DefinitionRevision Name
 // e.g., worker@v1.3.1 will be convert to worker-v1.3.1
 func ConvertDefinitionRevName(definitionName string) (string, error) {
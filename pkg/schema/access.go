@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	velaerrors "github.com/oam-dev/kubevela/pkg/utils/errors"
+)
+
+// ValidateParameterAccessLevels validates the access levels declared by the +access tag and marked by
+// FixOpenAPISchema, which must be one of basic, advanced and internal.
+func ValidateParameterAccessLevels(schema *openapi3.Schema) error {
+	var errs velaerrors.ErrorList
+	walkParameters("parameter", schema, func(path string, param *openapi3.Schema) {
+		level, ok := param.Extensions[appfile.AccessLevelExtension]
+		if !ok {
+			return
+		}
+		switch level {
+		case appfile.AccessLevelBasic, appfile.AccessLevelAdvanced, appfile.AccessLevelInternal:
+		default:
+			errs = append(errs, fmt.Errorf("%s has invalid access level %v, the valid levels are: %s, %s, %s", path, level,
+				appfile.AccessLevelBasic, appfile.AccessLevelAdvanced, appfile.AccessLevelInternal))
+		}
+	})
+	if errs.HasError() {
+		return errs
+	}
+	return nil
+}
+
+// RemoveInternalParameters removes the parameters of the internal access level from the schema, along with their
+// names in the required parameters of the objects declaring them.
+func RemoveInternalParameters(schema *openapi3.Schema) {
+	walkParameters("parameter", schema, func(_ string, param *openapi3.Schema) {
+		for name, prop := range param.Properties {
+			if prop == nil || prop.Value == nil || prop.Value.Extensions[appfile.AccessLevelExtension] != appfile.AccessLevelInternal {
+				continue
+			}
+			delete(param.Properties, name)
+			required := param.Required[:0]
+			for _, r := range param.Required {
+				if r != name {
+					required = append(required, r)
+				}
+			}
+			param.Required = required
+		}
+	})
+}
+
+// walkParameters calls fn with the schema and its nested parameter schemas, the parents before their children
+func walkParameters(path string, schema *openapi3.Schema, fn func(path string, param *openapi3.Schema)) {
+	if schema == nil {
+		return
+	}
+	fn(path, schema)
+	if schema.Items != nil {
+		walkParameters(path+"[]", schema.Items.Value, fn)
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if prop := schema.Properties[name]; prop != nil {
+			walkParameters(path+"."+name, prop.Value, fn)
+		}
+	}
+}
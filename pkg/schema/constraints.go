@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/ast/astutil"
+	"cuelang.org/go/cue/token"
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/oam-dev/kubevela/pkg/cue/process"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// genOpenAPIWithLiftedDefaults generates the OpenAPI schema of the template parameter after
+// dropping the marked defaults that are disjoined with constraints, such as `*3 | int & >=1`.
+// The cue openapi encoder fails on numeric bounds that carry a default, so the constraints are
+// translated on their own and the defaults are set back from the original parameter afterwards.
+func genOpenAPIWithLiftedDefaults(template cue.Value) (*openapi3.Schema, error) {
+	param := template.LookupPath(cue.ParsePath(process.ParameterFieldName))
+	var f *ast.File
+	switch n := param.Syntax(cue.Docs(true), cue.Optional(true), cue.Definitions(true), cue.Attributes(true)).(type) {
+	case *ast.File:
+		f = n
+	case *ast.StructLit:
+		f = &ast.File{Decls: n.Elts}
+	default:
+		return nil, fmt.Errorf("unexpected syntax %T of the parameter", n)
+	}
+	lifted := template.Context().BuildFile(astutil.Apply(f, liftDefault, nil).(*ast.File))
+	if lifted.Err() != nil {
+		return nil, lifted.Err()
+	}
+	data, err := common.GenOpenAPI(template.Context().CompileString("{}").FillPath(cue.ParsePath(process.ParameterFieldName), lifted))
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ConvertOpenAPISchema2SwaggerObject(data)
+	if err != nil {
+		return nil, err
+	}
+	setSchemaDefaults(param, schema)
+	return schema, nil
+}
+
+// liftDefault replaces a disjunction holding a marked default and otherwise only constraints
+// with the disjunction of the constraints. Disjunctions of literals are enums and kept as is.
+func liftDefault(c astutil.Cursor) bool {
+	expr, ok := c.Node().(*ast.BinaryExpr)
+	if !ok || expr.Op != token.OR {
+		return true
+	}
+	var kept []ast.Expr
+	hasDefault := false
+	for _, d := range disjuncts(nil, expr) {
+		if u, ok := d.(*ast.UnaryExpr); ok && u.Op == token.MUL {
+			hasDefault = true
+			continue
+		}
+		if isLiteral(d) {
+			return true
+		}
+		kept = append(kept, d)
+	}
+	if hasDefault && len(kept) > 0 {
+		c.Replace(ast.NewBinExpr(token.OR, kept...))
+	}
+	return true
+}
+
+func disjuncts(a []ast.Expr, expr ast.Expr) []ast.Expr {
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		if e.Op == token.OR {
+			return disjuncts(disjuncts(a, e.X), e.Y)
+		}
+	case *ast.ParenExpr:
+		return disjuncts(a, e.X)
+	}
+	return append(a, expr)
+}
+
+func isLiteral(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.UnaryExpr:
+		_, ok := e.X.(*ast.BasicLit)
+		return ok && (e.Op == token.SUB || e.Op == token.ADD)
+	}
+	return false
+}
+
+// setSchemaDefaults sets the defaults of the given cue value onto the schema where it has none.
+func setSchemaDefaults(v cue.Value, schema *openapi3.Schema) {
+	if schema == nil {
+		return
+	}
+	if d, ok := v.Default(); ok && d.IsConcrete() && schema.Default == nil {
+		if b, err := d.MarshalJSON(); err == nil {
+			var def interface{}
+			if err = json.Unmarshal(b, &def); err == nil {
+				if l, isList := def.([]interface{}); !isList || len(l) > 0 {
+					schema.Default = def
+				}
+			}
+		}
+	}
+	switch {
+	case len(schema.Properties) > 0:
+		iter, err := v.Fields(cue.Optional(true))
+		if err != nil {
+			return
+		}
+		for iter.Next() {
+			if prop, ok := schema.Properties[iter.Selector().Unquoted()]; ok && prop != nil {
+				setSchemaDefaults(iter.Value(), prop.Value)
+			}
+		}
+	case schema.Items != nil:
+		setSchemaDefaults(v.LookupPath(cue.MakePath(cue.AnyIndex)), schema.Items.Value)
+	}
+}
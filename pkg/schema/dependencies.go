@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	velaerrors "github.com/oam-dev/kubevela/pkg/utils/errors"
+)
+
+// ApplyParameterDependencies validates the parameter dependencies declared by the +requires and +conflicts tags and
+// marked by FixOpenAPISchema, and expresses them in the allOf of the object declaring the parameters, so that the
+// clients validating against the schema enforce them:
+//   - `a` requires `b`: {"anyOf": [{"not": {"required": ["a"]}}, {"required": ["b"]}]}
+//   - `a` conflicts with `b`: {"not": {"required": ["a", "b"]}}
+//
+// A dependency on the parameter itself or on a parameter not declared by the same object is an error, so is a
+// parameter both required and conflicting.
+func ApplyParameterDependencies(schema *openapi3.Schema) error {
+	var errs velaerrors.ErrorList
+	applyParameterDependencies("parameter", schema, &errs)
+	if errs.HasError() {
+		return errs
+	}
+	return nil
+}
+
+func applyParameterDependencies(path string, schema *openapi3.Schema, errs *velaerrors.ErrorList) {
+	if schema == nil {
+		return
+	}
+	if schema.Items != nil {
+		applyParameterDependencies(path+"[]", schema.Items.Value, errs)
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop := schema.Properties[name]
+		if prop == nil || prop.Value == nil {
+			continue
+		}
+		applyParameterDependencies(path+"."+name, prop.Value, errs)
+		requires := dependencyExtension(prop.Value, appfile.RequiresExtension)
+		conflicts := dependencyExtension(prop.Value, appfile.ConflictsExtension)
+		if len(requires) == 0 && len(conflicts) == 0 {
+			continue
+		}
+		valid := true
+		required := map[string]bool{}
+		check := func(deps []string, conflicting bool) {
+			for _, dep := range deps {
+				switch _, declared := schema.Properties[dep]; {
+				case dep == name:
+					*errs = append(*errs, fmt.Errorf("%s.%s cannot depend on itself", path, name))
+				case !declared:
+					*errs = append(*errs, fmt.Errorf("%s.%s depends on undeclared parameter %s", path, name, dep))
+				case conflicting && required[dep]:
+					*errs = append(*errs, fmt.Errorf("%s.%s both requires and conflicts with %s", path, name, dep))
+				default:
+					required[dep] = !conflicting
+					continue
+				}
+				valid = false
+			}
+		}
+		check(requires, false)
+		check(conflicts, true)
+		if !valid {
+			continue
+		}
+		if len(requires) != 0 {
+			schema.AllOf = append(schema.AllOf, openapi3.NewSchemaRef("", &openapi3.Schema{AnyOf: openapi3.SchemaRefs{
+				openapi3.NewSchemaRef("", &openapi3.Schema{Not: openapi3.NewSchemaRef("", &openapi3.Schema{Required: []string{name}})}),
+				openapi3.NewSchemaRef("", &openapi3.Schema{Required: requires}),
+			}}))
+		}
+		for _, dep := range conflicts {
+			schema.AllOf = append(schema.AllOf, openapi3.NewSchemaRef("", &openapi3.Schema{
+				Not: openapi3.NewSchemaRef("", &openapi3.Schema{Required: []string{name, dep}}),
+			}))
+		}
+	}
+}
+
+// dependencyExtension returns the parameters of the dependency extension, which are set by FixOpenAPISchema or
+// decoded from JSON
+func dependencyExtension(schema *openapi3.Schema, extension string) []string {
+	switch deps := schema.Extensions[extension].(type) {
+	case []string:
+		return deps
+	case []interface{}:
+		names := make([]string, 0, len(deps))
+		for _, dep := range deps {
+			names = append(names, strings.TrimSpace(fmt.Sprint(dep)))
+		}
+		return names
+	}
+	return nil
+}
@@ -23,6 +23,9 @@ import (
 	"strings"
 
 	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/parser"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/kubevela/pkg/cue/cuex"
 	"github.com/kubevela/workflow/pkg/cue/model/value"
@@ -50,8 +53,14 @@ const ErrGenerateOpenAPIV2JSONSchemaForCapability = "cannot generate OpenAPI v3
 
 // ParsePropertiesToSchema parse the properties in cue script to the openapi schema
 func ParsePropertiesToSchema(ctx context.Context, s string, templateFieldPath ...string) (*openapi3.Schema, error) {
+	return ParsePropertiesToSchemaWithImports(ctx, s, nil, templateFieldPath...)
+}
+
+// ParsePropertiesToSchemaWithImports parse the properties in cue script to the openapi schema,
+// the given packages can be imported by the cue script besides the packages registered in the compiler
+func ParsePropertiesToSchemaWithImports(ctx context.Context, s string, imports []*build.Instance, templateFieldPath ...string) (*openapi3.Schema, error) {
 	t := s + "\n" + BaseTemplate
-	val, err := providers.Compiler.Get().CompileStringWithOptions(ctx, t, cuex.DisableResolveProviderFunctions{})
+	val, err := compileWithImports(ctx, t, imports)
 	if err != nil {
 		return nil, err
 	}
@@ -64,18 +73,44 @@ func ParsePropertiesToSchema(ctx context.Context, s string, templateFieldPath ..
 			return nil, fmt.Errorf("%w cue script: %s", template.Err(), s)
 		}
 	}
+	var schema *openapi3.Schema
 	data, err := common.GenOpenAPI(template)
-	if err != nil {
-		return nil, err
+	if err == nil {
+		schema, err = ConvertOpenAPISchema2SwaggerObject(data)
+	} else if lifted, liftErr := genOpenAPIWithLiftedDefaults(template); liftErr == nil {
+		schema, err = lifted, nil
 	}
-	schema, err := ConvertOpenAPISchema2SwaggerObject(data)
 	if err != nil {
 		return nil, err
 	}
 	FixOpenAPISchema("", schema)
+	if err = ValidateParameterAccessLevels(schema); err != nil {
+		return nil, err
+	}
+	if err = ApplyParameterDependencies(schema); err != nil {
+		return nil, err
+	}
 	return schema, nil
 }
 
+func compileWithImports(ctx context.Context, t string, imports []*build.Instance) (cue.Value, error) {
+	compiler := providers.Compiler.Get()
+	if len(imports) == 0 {
+		return compiler.CompileStringWithOptions(ctx, t, cuex.DisableResolveProviderFunctions{})
+	}
+	bi := build.NewContext().NewInstance("", nil)
+	bi.Imports = append(compiler.GetImports(), imports...)
+	f, err := parser.ParseFile("-", t, parser.ParseComments)
+	if err != nil {
+		return cue.Value{}, err
+	}
+	if err = bi.AddSyntax(f); err != nil {
+		return cue.Value{}, err
+	}
+	val := cuecontext.New().BuildInstance(bi)
+	return val, val.Err()
+}
+
 // ConvertOpenAPISchema2SwaggerObject converts OpenAPI v2 JSON schema to Swagger Object
 func ConvertOpenAPISchema2SwaggerObject(data []byte) (*openapi3.Schema, error) {
 	swagger, err := openapi3.NewLoader().LoadFromData(data)
@@ -90,7 +125,8 @@ func ConvertOpenAPISchema2SwaggerObject(data []byte) (*openapi3.Schema, error) {
 	return schemaRef.Value, nil
 }
 
-// FixOpenAPISchema fixes tainted `description` filed, missing of title `field`.
+// FixOpenAPISchema fixes tainted `description` filed, missing of title `field`, and marks the deprecated parameters,
+// the access levels and the parameter dependencies.
 func FixOpenAPISchema(name string, schema *openapi3.Schema) {
 	t := schema.Type
 	switch t {
@@ -108,7 +144,35 @@ func FixOpenAPISchema(name string, schema *openapi3.Schema) {
 		schema.Title = name
 	}
 
-	description := schema.Description
+	description, deprecated, message := appfile.ExtractDeprecatedTag(schema.Description)
+	if deprecated {
+		schema.Deprecated = true
+		if message != "" {
+			if schema.Extensions == nil {
+				schema.Extensions = map[string]interface{}{}
+			}
+			schema.Extensions[appfile.DeprecationMessageExtension] = message
+		}
+	}
+	description, level := appfile.ExtractAccessTag(description)
+	if level != "" {
+		if schema.Extensions == nil {
+			schema.Extensions = map[string]interface{}{}
+		}
+		schema.Extensions[appfile.AccessLevelExtension] = level
+	}
+	description, requires, conflicts := appfile.ExtractDependencyTags(description)
+	if len(requires) != 0 || len(conflicts) != 0 {
+		if schema.Extensions == nil {
+			schema.Extensions = map[string]interface{}{}
+		}
+		if len(requires) != 0 {
+			schema.Extensions[appfile.RequiresExtension] = requires
+		}
+		if len(conflicts) != 0 {
+			schema.Extensions[appfile.ConflictsExtension] = conflicts
+		}
+	}
 	if strings.Contains(description, appfile.UsageTag) {
 		description = strings.Split(description, appfile.UsageTag)[1]
 	}
@@ -17,13 +17,16 @@ limitations under the License.
 package schema
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/oam-dev/kubevela/pkg/appfile"
 	"github.com/oam-dev/kubevela/pkg/cue/process"
 )
 
@@ -59,3 +62,173 @@ func TestFixOpenAPISchema(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePropertiesToSchemaConstraints(t *testing.T) {
+	schema, err := ParsePropertiesToSchema(context.Background(), `
+parameter: {
+	mode:     *"a" | "b" | "c"
+	image:    =~"^[a-z0-9./:-]+$"
+	tags: [...=~"^v[0-9]+$"]
+	cpu:      number & >0 & <=64
+	weight:   int & >=0 & <100
+	// +usage=Number of replicas
+	replicas: *3 | int & >=1 & <=10
+	port?:    *8080 | int & >0 & <65536
+	ratio:    *0.5 | >=0 & <=1
+	probes: [...{
+		period: *10 | int & >=1
+	}]
+}
+`)
+	require.NoError(t, err)
+	props := schema.Properties
+
+	require.Equal(t, []interface{}{"a", "b", "c"}, props["mode"].Value.Enum)
+	require.Equal(t, "a", props["mode"].Value.Default)
+	require.Equal(t, "^[a-z0-9./:-]+$", props["image"].Value.Pattern)
+	require.Equal(t, "^v[0-9]+$", props["tags"].Value.Items.Value.Pattern)
+
+	cpu := props["cpu"].Value
+	require.Equal(t, float64(0), *cpu.Min)
+	require.True(t, cpu.ExclusiveMin)
+	require.Equal(t, float64(64), *cpu.Max)
+	require.False(t, cpu.ExclusiveMax)
+
+	weight := props["weight"].Value
+	require.Equal(t, "integer", weight.Type)
+	require.Equal(t, float64(0), *weight.Min)
+	require.Equal(t, float64(100), *weight.Max)
+	require.True(t, weight.ExclusiveMax)
+
+	replicas := props["replicas"].Value
+	require.Equal(t, "integer", replicas.Type)
+	require.Equal(t, float64(1), *replicas.Min)
+	require.Equal(t, float64(10), *replicas.Max)
+	require.Equal(t, float64(3), replicas.Default)
+	require.Equal(t, "Number of replicas", replicas.Description)
+
+	port := props["port"].Value
+	require.Equal(t, float64(0), *port.Min)
+	require.True(t, port.ExclusiveMin)
+	require.Equal(t, float64(65536), *port.Max)
+	require.True(t, port.ExclusiveMax)
+	require.Equal(t, float64(8080), port.Default)
+
+	ratio := props["ratio"].Value
+	require.Equal(t, float64(0), *ratio.Min)
+	require.Equal(t, float64(1), *ratio.Max)
+	require.Equal(t, 0.5, ratio.Default)
+
+	period := props["probes"].Value.Items.Value.Properties["period"].Value
+	require.Equal(t, float64(1), *period.Min)
+	require.Equal(t, float64(10), period.Default)
+}
+
+func TestParameterDependencies(t *testing.T) {
+	schema, err := ParsePropertiesToSchema(context.Background(), `
+parameter: {
+	// +usage=Enable TLS
+	// +requires=cert,key
+	// +conflicts=insecure
+	tls?:      bool
+	cert?:     string
+	key?:      string
+	insecure?: bool
+	volumes?: [...{
+		name: string
+		// +conflicts=hostPath
+		configMap?: string
+		hostPath?:  string
+	}]
+}
+`)
+	require.NoError(t, err)
+	tls := schema.Properties["tls"].Value
+	require.Equal(t, "Enable TLS", tls.Description)
+	require.Equal(t, []string{"cert", "key"}, tls.Extensions[appfile.RequiresExtension])
+	require.Equal(t, []string{"insecure"}, tls.Extensions[appfile.ConflictsExtension])
+	require.Len(t, schema.AllOf, 2)
+
+	// the dependencies survive the JSON round trip of the stored schema and are enforced by the clients
+	data, err := schema.MarshalJSON()
+	require.NoError(t, err)
+	stored := openapi3.NewSchema()
+	require.NoError(t, stored.UnmarshalJSON(data))
+	valid := []map[string]interface{}{
+		{},
+		{"tls": true, "cert": "c", "key": "k"},
+		{"insecure": true},
+		{"volumes": []interface{}{map[string]interface{}{"name": "a", "configMap": "c"}}},
+	}
+	for _, v := range valid {
+		require.NoError(t, stored.VisitJSON(v), "%v", v)
+	}
+	invalid := []map[string]interface{}{
+		{"tls": true, "cert": "c"},
+		{"tls": true, "cert": "c", "key": "k", "insecure": true},
+		{"volumes": []interface{}{map[string]interface{}{"name": "a", "configMap": "c", "hostPath": "/"}}},
+	}
+	for _, v := range invalid {
+		require.Error(t, stored.VisitJSON(v), "%v", v)
+	}
+}
+
+func TestInvalidParameterDependencies(t *testing.T) {
+	cases := map[string]struct {
+		parameter string
+		err       string
+	}{
+		"undeclared": {
+			parameter: "// +requires=cert\ntls?: bool",
+			err:       "parameter.tls depends on undeclared parameter cert",
+		},
+		"itself": {
+			parameter: "// +conflicts=tls\ntls?: bool",
+			err:       "parameter.tls cannot depend on itself",
+		},
+		"both": {
+			parameter: "// +requires=cert\n// +conflicts=cert\ntls?: bool\ncert?: string",
+			err:       "parameter.tls both requires and conflicts with cert",
+		},
+		"nested": {
+			parameter: "volumes?: [...{\n// +requires=path\nname: string\n}]",
+			err:       "parameter.volumes[].name depends on undeclared parameter path",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParsePropertiesToSchema(context.Background(), "parameter: {\n"+tc.parameter+"\n}")
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestParameterAccessLevels(t *testing.T) {
+	schema, err := ParsePropertiesToSchema(context.Background(), `
+parameter: {
+	// +usage=The image
+	// +access=basic
+	image: string
+	// +access=internal
+	revision: string
+	volumes?: [...{
+		// +access=advanced
+		name: string
+	}]
+}
+`)
+	require.NoError(t, err)
+	image := schema.Properties["image"].Value
+	require.Equal(t, "The image", image.Description)
+	require.Equal(t, appfile.AccessLevelBasic, image.Extensions[appfile.AccessLevelExtension])
+	volume := schema.Properties["volumes"].Value.Items.Value
+	require.Equal(t, appfile.AccessLevelAdvanced, volume.Properties["name"].Value.Extensions[appfile.AccessLevelExtension])
+
+	RemoveInternalParameters(schema)
+	require.NotContains(t, schema.Properties, "revision")
+	require.Equal(t, []string{"image"}, schema.Required)
+	require.Contains(t, volume.Properties, "name")
+
+	_, err = ParsePropertiesToSchema(context.Background(), "parameter: {\nvolumes?: [...{\n// +access=hidden\nname: string\n}]\n}")
+	require.ErrorContains(t, err, "parameter.volumes[].name has invalid access level hidden, the valid levels are: basic, advanced, internal")
+}
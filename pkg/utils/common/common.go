@@ -36,7 +36,9 @@ import (
 	"cuelang.org/go/cue/cuecontext"
 	"cuelang.org/go/encoding/openapi"
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/oam-dev/terraform-config-inspect/tfconfig"
 	kruise "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruisev1alpha1 "github.com/openkruise/rollouts/api/v1alpha1"
@@ -370,6 +372,62 @@ func ParseTerraformVariables(configuration string) (map[string]*tfconfig.Variabl
 	return mod.Variables, mod.Outputs, nil
 }
 
+// ParseTerraformModuleCalls parses the module calls of the Terraform configuration by the module names
+func ParseTerraformModuleCalls(configuration string) (map[string]*tfconfig.ModuleCall, error) {
+	p := hclparse.NewParser()
+	hclFile, diagnostic := p.ParseHCL([]byte(configuration), "")
+	if diagnostic != nil {
+		return nil, errors.New(diagnostic.Error())
+	}
+	mod := tfconfig.Module{ModuleCalls: map[string]*tfconfig.ModuleCall{}}
+	diagnostic = tfconfig.LoadModuleFromFile(hclFile, &mod)
+	if diagnostic != nil {
+		return nil, errors.New(diagnostic.Error())
+	}
+	return mod.ModuleCalls, nil
+}
+
+// terraformReferencingBlocks are the blocks of a Terraform configuration whose variable references are parsed by
+// ParseTerraformVariableReferences
+var terraformReferencingBlocks = map[string]bool{"resource": true, "data": true, "module": true, "output": true, "locals": true}
+
+// ParseTerraformVariableReferences parses the names of the variables referenced by var.<name> in the resources, the
+// data sources, the modules, the outputs and the locals of the Terraform configuration
+func ParseTerraformVariableReferences(configuration string) (map[string]bool, error) {
+	p := hclparse.NewParser()
+	hclFile, diagnostic := p.ParseHCL([]byte(configuration), "")
+	if diagnostic != nil {
+		return nil, errors.New(diagnostic.Error())
+	}
+	body, ok := hclFile.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, errors.New("the Terraform configuration is not in the native syntax")
+	}
+	references := map[string]bool{}
+	for _, block := range body.Blocks {
+		if terraformReferencingBlocks[block.Type] {
+			collectTerraformVariableReferences(block.Body, references)
+		}
+	}
+	return references, nil
+}
+
+func collectTerraformVariableReferences(body *hclsyntax.Body, references map[string]bool) {
+	for _, attr := range body.Attributes {
+		for _, traversal := range attr.Expr.Variables() {
+			if traversal.RootName() != "var" || len(traversal) < 2 {
+				continue
+			}
+			if step, ok := traversal[1].(hcl.TraverseAttr); ok {
+				references[step.Name] = true
+			}
+		}
+	}
+	for _, block := range body.Blocks {
+		collectTerraformVariableReferences(block.Body, references)
+	}
+}
+
 // GenerateUnstructuredObj generate UnstructuredObj
 func GenerateUnstructuredObj(name, ns string, gvk schema.GroupVersionKind) *unstructured.Unstructured {
 	u := &unstructured.Unstructured{}
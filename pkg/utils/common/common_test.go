@@ -493,6 +493,42 @@ variable "mapVar" {
 	assert.True(t, intVarExisted)
 }
 
+func TestParseTerraformVariableReferences(t *testing.T) {
+	configuration := `
+resource "alicloud_oss_bucket" "bucket" {
+  bucket = var.bucket
+  acl    = "${var.acl}-${var.suffix}"
+  tags   = { for k, v in var.tags : k => v }
+
+  lifecycle_rule {
+    enabled = var.lifecycle_enabled
+  }
+}
+
+module "rds" {
+  source        = "terraform-alicloud-modules/rds/alicloud"
+  instance_name = var.instance_name
+}
+
+output "BUCKET_NAME" {
+  value = "${alicloud_oss_bucket.bucket.id}.${var.region}"
+}
+
+variable "bucket" {
+  type    = string
+  default = var.unused
+}`
+
+	references, err := ParseTerraformVariableReferences(configuration)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"bucket": true, "acl": true, "suffix": true, "tags": true, "lifecycle_enabled": true, "instance_name": true, "region": true,
+	}, references)
+
+	_, err = ParseTerraformVariableReferences(`resource "x" {`)
+	assert.Error(t, err)
+}
+
 func TestRefineParameterInstance(t *testing.T) {
 	// test #parameter exists: mock issues in #1939 & #2062
 	s := `parameter: #parameter
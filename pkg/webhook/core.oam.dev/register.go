@@ -32,7 +32,7 @@ func Register(mgr manager.Manager, args controller.Args) {
 	application.RegisterValidatingHandler(mgr, args)
 	application.RegisterMutatingHandler(mgr)
 	componentdefinition.RegisterMutatingHandler(mgr, args)
-	componentdefinition.RegisterValidatingHandler(mgr)
+	componentdefinition.RegisterValidatingHandler(mgr, args)
 	traitdefinition.RegisterValidatingHandler(mgr, args)
 	policydefinition.RegisterValidatingHandler(mgr)
 	server := mgr.GetWebhookServer()
@@ -23,6 +23,7 @@ import (
 	"net/http"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -34,6 +35,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
 	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
@@ -81,8 +83,8 @@ func (h *MutatingHandler) Mutate(obj *v1beta1.ComponentDefinition) error {
 
 	// If the Type field is not empty, it means that ComponentDefinition refers to an existing WorkloadDefinition
 	if obj.Spec.Workload.Type != types.AutoDetectWorkloadDefinition && (obj.Spec.Workload.Type != "" && obj.Spec.Workload.Definition == (common.WorkloadGVK{})) {
-		workloadDef := new(v1beta1.WorkloadDefinition)
-		return h.Client.Get(context.TODO(), client.ObjectKey{Name: obj.Spec.Workload.Type, Namespace: obj.Namespace}, workloadDef)
+		// If only Type field exists, fill Definition field according to Type.
+		return h.fillWorkloadDefinition(obj)
 	}
 
 	if obj.Spec.Workload.Definition != (common.WorkloadGVK{}) {
@@ -96,6 +98,11 @@ func (h *MutatingHandler) Mutate(obj *v1beta1.ComponentDefinition) error {
 			obj.Spec.Workload.Type = defRef.Name
 		}
 
+		// The componentDefinition declares that it will never be consumed through the workloadDefinition
+		if obj.GetAnnotations()[oam.AnnotationSkipWorkloadConversion] == "true" {
+			return nil
+		}
+
 		workloadDef := new(v1beta1.WorkloadDefinition)
 		err = h.Client.Get(context.TODO(), client.ObjectKey{Name: defRef.Name, Namespace: obj.Namespace}, workloadDef)
 		if err != nil {
@@ -121,6 +128,40 @@ func (h *MutatingHandler) Mutate(obj *v1beta1.ComponentDefinition) error {
 	return nil
 }
 
+// fillWorkloadDefinition resolves the workload type of the ComponentDefinition into its GVK. The type is either the
+// name of an existing WorkloadDefinition or the name of a resource in the format of <resource plurals>.<group>
+func (h *MutatingHandler) fillWorkloadDefinition(obj *v1beta1.ComponentDefinition) error {
+	defRef := common.DefinitionReference{Name: obj.Spec.Workload.Type}
+	workloadDef := new(v1beta1.WorkloadDefinition)
+	err := h.Client.Get(context.TODO(), client.ObjectKey{Name: obj.Spec.Workload.Type, Namespace: obj.Namespace}, workloadDef)
+	existing := err == nil
+	switch {
+	case existing:
+		defRef = workloadDef.Spec.Reference
+	case !apierrors.IsNotFound(err):
+		return err
+	}
+	gvk, err := util.GetGVKFromDefinition(h.Client.RESTMapper(), defRef)
+	if err != nil {
+		// the type refers to an existing workloadDefinition whose resource can't be resolved yet, e.g. its CRD
+		// is not installed, so the definition is left empty
+		if existing {
+			return nil
+		}
+		return fmt.Errorf("cannot resolve the workload type %s referenced by componentDefinition, it's neither a workloadDefinition nor a known resource: %w", obj.Spec.Workload.Type, err)
+	}
+	// The type and the definition must represent the same resource, so the definition of a workloadDefinition
+	// named differently from the resource it refers to is left empty.
+	if gvk.Kind == "" || defRef.Name != obj.Spec.Workload.Type {
+		return nil
+	}
+	obj.Spec.Workload.Definition = common.WorkloadGVK{
+		APIVersion: metav1.GroupVersion{Group: gvk.Group, Version: gvk.Version}.String(),
+		Kind:       gvk.Kind,
+	}
+	return nil
+}
+
 var _ admission.DecoderInjector = &MutatingHandler{}
 
 // InjectDecoder injects the decoder into the ComponentDefinitionMutatingHandler
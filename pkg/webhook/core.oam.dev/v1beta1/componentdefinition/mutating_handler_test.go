@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package componentdefinition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func newMutatingHandler() *MutatingHandler {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	cli := fake.NewClientBuilder().WithScheme(velacommon.Scheme).WithRESTMapper(mapper).Build()
+	return &MutatingHandler{Client: cli, AutoGenWorkloadDef: true}
+}
+
+func TestMutateSkipWorkloadConversion(t *testing.T) {
+	newDef := func(annotations map[string]string) *v1beta1.ComponentDefinition {
+		return &v1beta1.ComponentDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: annotations},
+			Spec: v1beta1.ComponentDefinitionSpec{
+				Workload: common.WorkloadTypeDescriptor{
+					Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"},
+				},
+			},
+		}
+	}
+	key := client.ObjectKey{Namespace: "default", Name: "deployments.apps"}
+
+	h := newMutatingHandler()
+	def := newDef(map[string]string{oam.AnnotationSkipWorkloadConversion: "true"})
+	require.NoError(t, h.Mutate(def))
+	require.Equal(t, "deployments.apps", def.Spec.Workload.Type)
+	require.True(t, apierrors.IsNotFound(h.Client.Get(context.Background(), key, &v1beta1.WorkloadDefinition{})))
+
+	h.AutoGenWorkloadDef = false
+	require.NoError(t, h.Mutate(newDef(map[string]string{oam.AnnotationSkipWorkloadConversion: "true"})))
+
+	h.AutoGenWorkloadDef = true
+	require.NoError(t, h.Mutate(newDef(nil)))
+	require.NoError(t, h.Client.Get(context.Background(), key, &v1beta1.WorkloadDefinition{}))
+}
+
+func TestMutateWorkloadTypeAndDefinition(t *testing.T) {
+	newDef := func(workload common.WorkloadTypeDescriptor) *v1beta1.ComponentDefinition {
+		return &v1beta1.ComponentDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       v1beta1.ComponentDefinitionSpec{Workload: workload},
+		}
+	}
+	deployment := common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}
+
+	// type refers to a resource
+	h := newMutatingHandler()
+	def := newDef(common.WorkloadTypeDescriptor{Type: "deployments.apps"})
+	require.NoError(t, h.Mutate(def))
+	require.Equal(t, deployment, def.Spec.Workload.Definition)
+
+	// type refers to a workloadDefinition
+	require.NoError(t, h.Client.Create(context.Background(), &v1beta1.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "default"},
+		Spec:       v1beta1.WorkloadDefinitionSpec{Reference: common.DefinitionReference{Name: "deployments.apps", Version: "v1"}},
+	}))
+	require.NoError(t, h.Client.Create(context.Background(), &v1beta1.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployments.apps", Namespace: "default"},
+		Spec:       v1beta1.WorkloadDefinitionSpec{Reference: common.DefinitionReference{Name: "deployments.apps", Version: "v1"}},
+	}))
+	def = newDef(common.WorkloadTypeDescriptor{Type: "deployments.apps"})
+	require.NoError(t, h.Mutate(def))
+	require.Equal(t, deployment, def.Spec.Workload.Definition)
+	require.NoError(t, ValidateWorkload(h.Client.RESTMapper(), def))
+	// the definition of an aliased workloadDefinition is not filled, which would mismatch the type
+	def = newDef(common.WorkloadTypeDescriptor{Type: "deploy"})
+	require.NoError(t, h.Mutate(def))
+	require.Equal(t, common.WorkloadGVK{}, def.Spec.Workload.Definition)
+
+	// definition only
+	def = newDef(common.WorkloadTypeDescriptor{Definition: deployment})
+	require.NoError(t, h.Mutate(def))
+	require.Equal(t, "deployments.apps", def.Spec.Workload.Type)
+
+	// type refers to a workloadDefinition of a resource not installed yet
+	require.NoError(t, h.Client.Create(context.Background(), &v1beta1.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "crontabs.example.com", Namespace: "default"},
+		Spec:       v1beta1.WorkloadDefinitionSpec{Reference: common.DefinitionReference{Name: "crontabs.example.com", Version: "v1"}},
+	}))
+	def = newDef(common.WorkloadTypeDescriptor{Type: "crontabs.example.com"})
+	require.NoError(t, h.Mutate(def))
+	require.Equal(t, common.WorkloadGVK{}, def.Spec.Workload.Definition)
+
+	// unresolvable
+	def = newDef(common.WorkloadTypeDescriptor{Type: "unknown"})
+	err := h.Mutate(def)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot resolve the workload type unknown")
+	def = newDef(common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "example.com/v1", Kind: "Unknown"}})
+	require.Error(t, h.Mutate(def))
+}
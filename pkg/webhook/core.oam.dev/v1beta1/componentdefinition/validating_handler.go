@@ -20,8 +20,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
+	"cuelang.org/go/cue/parser"
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -31,6 +37,9 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 	webhookutils "github.com/oam-dev/kubevela/pkg/webhook/utils"
@@ -43,8 +52,20 @@ type ValidatingHandler struct {
 	// Decoder decodes object
 	Decoder *admission.Decoder
 	Client  client.Client
+	// RequireParameterDescriptions rejects the definitions whose top-level parameters have no description
+	RequireParameterDescriptions bool
+	// AllowedFamilies are the families allowed in the family label of the definitions, any family is allowed if it's empty
+	AllowedFamilies []string
+	// AllowedCUEImports are the packages the CUE templates of the definitions are allowed to import
+	AllowedCUEImports []string
+	// AllowedCUEImportsConfigMap is the ConfigMap listing more allowed CUE imports in its imports key, any import is
+	// allowed if it's not set and AllowedCUEImports is empty
+	AllowedCUEImportsConfigMap *client.ObjectKey
 }
 
+// AllowedCUEImportsKey is the key of the allowed CUE imports in the data of the allowlist ConfigMap
+const AllowedCUEImportsKey = "imports"
+
 var _ inject.Client = &ValidatingHandler{}
 
 // InjectClient injects the client into the ApplicationValidateHandler
@@ -74,6 +95,12 @@ func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) a
 		if err != nil {
 			return admission.Denied(err.Error())
 		}
+		if err = ValidateSchematic(obj); err != nil {
+			return admission.Denied(err.Error())
+		}
+		if err = ValidateFamily(obj, h.AllowedFamilies); err != nil {
+			return admission.Denied(err.Error())
+		}
 
 		// validate cueTemplate
 		if obj.Spec.Schematic != nil && obj.Spec.Schematic.CUE != nil {
@@ -81,6 +108,21 @@ func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) a
 			if err != nil {
 				return admission.Denied(err.Error())
 			}
+			allowed, enabled, err := h.allowedCUEImports(ctx)
+			if err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			if enabled {
+				if err = ValidateCUEImports(obj, allowed); err != nil {
+					return admission.Denied(err.Error())
+				}
+			}
+		}
+
+		if h.RequireParameterDescriptions {
+			if err = ValidateParameterDescriptions(ctx, h.Client, obj); err != nil {
+				return admission.Denied(err.Error())
+			}
 		}
 
 		revisionName := obj.GetAnnotations()[oam.AnnotationDefinitionRevisionName]
@@ -103,10 +145,46 @@ func (h *ValidatingHandler) InjectDecoder(d *admission.Decoder) error {
 	return nil
 }
 
+// allowedCUEImports returns the allowed CUE imports from both the handler and the allowlist ConfigMap, and whether
+// the imports should be checked at all. A missing ConfigMap contributes no imports.
+func (h *ValidatingHandler) allowedCUEImports(ctx context.Context) ([]string, bool, error) {
+	allowed := append([]string{}, h.AllowedCUEImports...)
+	if h.AllowedCUEImportsConfigMap == nil {
+		return allowed, len(allowed) != 0, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := h.Client.Get(ctx, *h.AllowedCUEImportsConfigMap, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return allowed, true, nil
+		}
+		return nil, false, fmt.Errorf("failed to get the allowed CUE imports from ConfigMap %s: %w", h.AllowedCUEImportsConfigMap, err)
+	}
+	for _, line := range strings.Split(cm.Data[AllowedCUEImportsKey], "\n") {
+		for _, path := range strings.Split(line, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				allowed = append(allowed, path)
+			}
+		}
+	}
+	return allowed, true, nil
+}
+
 // RegisterValidatingHandler will register ComponentDefinition validation to webhook
-func RegisterValidatingHandler(mgr manager.Manager) {
+func RegisterValidatingHandler(mgr manager.Manager, args controller.Args) {
+	handler := &ValidatingHandler{
+		RequireParameterDescriptions: args.DefRequireParameterDescriptions,
+		AllowedFamilies:              args.DefAllowedFamilies,
+		AllowedCUEImports:            args.DefAllowedCUEImports,
+	}
+	if args.DefAllowedCUEImportsConfigMap != "" {
+		namespace, name, found := strings.Cut(args.DefAllowedCUEImportsConfigMap, "/")
+		if !found {
+			namespace, name = oam.SystemDefinitionNamespace, args.DefAllowedCUEImportsConfigMap
+		}
+		handler.AllowedCUEImportsConfigMap = &client.ObjectKey{Namespace: namespace, Name: name}
+	}
 	server := mgr.GetWebhookServer()
-	server.Register("/validating-core-oam-dev-v1beta1-componentdefinitions", &webhook.Admission{Handler: &ValidatingHandler{}})
+	server.Register("/validating-core-oam-dev-v1beta1-componentdefinitions", &webhook.Admission{Handler: handler})
 }
 
 // ValidateWorkload validates whether the Workload field is valid
@@ -129,3 +207,111 @@ func ValidateWorkload(mapper meta.RESTMapper, cd *v1beta1.ComponentDefinition) e
 	}
 	return nil
 }
+
+// ValidateSchematic validates that at most one schematic is set in the ComponentDefinition
+// and that the schematic doesn't contradict the workload type
+func ValidateSchematic(cd *v1beta1.ComponentDefinition) error {
+	schematic := cd.Spec.Schematic
+	if schematic == nil {
+		return nil
+	}
+	if fields := utils.SchematicFields(schematic); len(fields) > 1 {
+		return fmt.Errorf("only one schematic can be set in ComponentDefinition %s, but got conflicting fields: %s", cd.Name, strings.Join(fields, ", "))
+	}
+
+	// The workload of a Terraform component is always the Terraform Configuration, it can't be auto detected.
+	if schematic.Terraform != nil && cd.Spec.Workload.Type == types.AutoDetectWorkloadDefinition {
+		return fmt.Errorf("the workload type %s of ComponentDefinition %s conflicts with its schematic, conflicting fields: spec.workload.type, spec.schematic.terraform", types.AutoDetectWorkloadDefinition, cd.Name)
+	}
+	if schematic.JSONSchema != nil {
+		if _, err := utils.GetOpenAPISchemaFromJSONSchema(schematic.JSONSchema); err != nil {
+			return fmt.Errorf("invalid spec.schematic.jsonSchema of ComponentDefinition %s: %w", cd.Name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateFamily validates that the family label of the ComponentDefinition is one of the allowed families. The
+// definitions without the label are not checked, and any family is allowed if the allowed families are empty.
+func ValidateFamily(cd *v1beta1.ComponentDefinition, allowedFamilies []string) error {
+	family, ok := cd.GetLabels()[oam.LabelDefinitionFamily]
+	if !ok || len(allowedFamilies) == 0 {
+		return nil
+	}
+	for _, allowed := range allowedFamilies {
+		if family == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("the family %q of ComponentDefinition %s in label %s is unknown, the allowed families are: %s",
+		family, cd.Name, oam.LabelDefinitionFamily, strings.Join(allowedFamilies, ", "))
+}
+
+// ValidateCUEImports validates that the CUE template of the ComponentDefinition only imports the allowed packages. An
+// allowed package ending with /* allows all the packages under that path. The definitions without CUE schematic are
+// not checked.
+func ValidateCUEImports(cd *v1beta1.ComponentDefinition, allowedImports []string) error {
+	schematic := cd.Spec.Schematic
+	if schematic == nil || schematic.CUE == nil {
+		return nil
+	}
+	f, err := parser.ParseFile("-", schematic.CUE.Template, parser.ImportsOnly)
+	if err != nil {
+		return fmt.Errorf("failed to parse the CUE template of ComponentDefinition %s: %w", cd.Name, err)
+	}
+	var disallowed []string
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return fmt.Errorf("invalid import %s in the CUE template of ComponentDefinition %s: %w", spec.Path.Value, cd.Name, err)
+		}
+		if !isCUEImportAllowed(path, allowedImports) {
+			disallowed = append(disallowed, path)
+		}
+	}
+	if len(disallowed) != 0 {
+		sort.Strings(disallowed)
+		return fmt.Errorf("the CUE template of ComponentDefinition %s imports packages that are not allowed: %s, the allowed imports are: %s",
+			cd.Name, strings.Join(disallowed, ", "), strings.Join(allowedImports, ", "))
+	}
+	return nil
+}
+
+func isCUEImportAllowed(path string, allowedImports []string) bool {
+	for _, allowed := range allowedImports {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if path == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateParameterDescriptions generates the parameter schema of the ComponentDefinition and validates that every
+// top-level parameter has a description. The definitions without schematic and the remote Terraform configurations
+// are not checked.
+func ValidateParameterDescriptions(ctx context.Context, cli client.Client, cd *v1beta1.ComponentDefinition) error {
+	schematic := cd.Spec.Schematic
+	if schematic == nil || (schematic.Terraform != nil && schematic.Terraform.Type == "remote") {
+		return nil
+	}
+	def := utils.NewCapabilityComponentDef(cd)
+	jsonSchema, err := def.GenerateOpenAPISchema(ctx, cli, cd.Namespace, cd.Name)
+	if err != nil {
+		return err
+	}
+	undocumented, err := utils.FindUndocumentedParameters(jsonSchema)
+	if err != nil {
+		return fmt.Errorf("invalid parameter schema of ComponentDefinition %s: %w", cd.Name, err)
+	}
+	if len(undocumented) != 0 {
+		return fmt.Errorf("the parameters of ComponentDefinition %s must have descriptions, but these parameters are missing descriptions: %s",
+			cd.Name, strings.Join(undocumented, ", "))
+	}
+	return nil
+}
@@ -26,18 +26,23 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/require"
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	core "github.com/oam-dev/kubevela/apis/core.oam.dev"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
 )
 
 var handler ValidatingHandler
@@ -231,3 +236,262 @@ var _ = Describe("Test ComponentDefinition validating handler", func() {
 		})
 	})
 })
+
+func TestValidateSchematic(t *testing.T) {
+	cueSchematic := &common.CUE{Template: "output: {}"}
+	tfSchematic := &common.Terraform{Configuration: `variable "name" {}`}
+	jsonSchematic := &common.JSONSchema{Schema: `{"type":"object","properties":{"port":{"type":"integer"}}}`}
+	testCases := map[string]struct {
+		workloadType string
+		schematic    *common.Schematic
+		errFields    []string
+	}{
+		"no schematic": {
+			workloadType: "deployments.apps",
+		},
+		"cue only": {
+			schematic: &common.Schematic{CUE: cueSchematic},
+		},
+		"cue with auto detected workload": {
+			workloadType: types.AutoDetectWorkloadDefinition,
+			schematic:    &common.Schematic{CUE: cueSchematic},
+		},
+		"terraform only": {
+			workloadType: "configurations.terraform.core.oam.dev",
+			schematic:    &common.Schematic{Terraform: tfSchematic},
+		},
+		"cue and terraform": {
+			schematic: &common.Schematic{CUE: cueSchematic, Terraform: tfSchematic},
+			errFields: []string{"spec.schematic.cue", "spec.schematic.terraform"},
+		},
+		"json schema only": {
+			schematic: &common.Schematic{JSONSchema: jsonSchematic},
+		},
+		"cue and json schema": {
+			schematic: &common.Schematic{CUE: cueSchematic, JSONSchema: jsonSchematic},
+			errFields: []string{"spec.schematic.cue", "spec.schematic.jsonSchema"},
+		},
+		"malformed json schema": {
+			schematic: &common.Schematic{JSONSchema: &common.JSONSchema{Schema: `{"type":"object","properties":{"port":{"type":"int"}}}`}},
+			errFields: []string{"spec.schematic.jsonSchema"},
+		},
+		"terraform with auto detected workload": {
+			workloadType: types.AutoDetectWorkloadDefinition,
+			schematic:    &common.Schematic{Terraform: tfSchematic},
+			errFields:    []string{"spec.workload.type", "spec.schematic.terraform"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			def := &v1beta1.ComponentDefinition{}
+			def.Name = "test"
+			def.Spec.Workload.Type = tc.workloadType
+			def.Spec.Schematic = tc.schematic
+			err := ValidateSchematic(def)
+			if len(tc.errFields) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, field := range tc.errFields {
+				require.Contains(t, err.Error(), field)
+			}
+		})
+	}
+}
+
+func TestValidateParameterDescriptions(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, core.AddToScheme(s))
+	d, err := admission.NewDecoder(s)
+	require.NoError(t, err)
+	testCases := map[string]struct {
+		template string
+		require  bool
+		denied   string
+	}{
+		"documented": {
+			template: `
+parameter: {
+	// +usage=The image of the container
+	image: string
+	// +usage=The number of replicas
+	replicas: *1 | int
+}
+output: {}
+`,
+			require: true,
+		},
+		"undocumented": {
+			template: `
+parameter: {
+	// +usage=The image of the container
+	image: string
+	replicas: *1 | int
+	env?: [...{
+		// +usage=The name of the variable
+		name: string
+	}]
+}
+output: {}
+`,
+			require: true,
+			denied:  "these parameters are missing descriptions: env, replicas",
+		},
+		"not required": {
+			template: "parameter: {\n\timage: string\n}\noutput: {}\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			def := v1beta1.ComponentDefinition{
+				TypeMeta:   metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.ComponentDefinitionKind},
+				ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+				Spec: v1beta1.ComponentDefinitionSpec{
+					Workload:  common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+					Schematic: &common.Schematic{CUE: &common.CUE{Template: tc.template}},
+				},
+			}
+			raw, err := json.Marshal(def)
+			require.NoError(t, err)
+			h := &ValidatingHandler{Decoder: d, Client: fake.NewClientBuilder().Build(), RequireParameterDescriptions: tc.require}
+			resp := h.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Resource:  metav1.GroupVersionResource{Group: v1beta1.Group, Version: v1beta1.Version, Resource: "componentdefinitions"},
+				Object:    runtime.RawExtension{Raw: raw},
+			}})
+			if tc.denied == "" {
+				require.True(t, resp.Allowed, resp.Result.Reason)
+				return
+			}
+			require.False(t, resp.Allowed)
+			require.Contains(t, string(resp.Result.Reason), tc.denied)
+		})
+	}
+}
+
+func TestValidateFamily(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, core.AddToScheme(s))
+	d, err := admission.NewDecoder(s)
+	require.NoError(t, err)
+	testCases := map[string]struct {
+		labels  map[string]string
+		allowed []string
+		denied  string
+	}{
+		"allowed family": {
+			labels:  map[string]string{oam.LabelDefinitionFamily: "database"},
+			allowed: []string{"database", "gateway"},
+		},
+		"unknown family": {
+			labels:  map[string]string{oam.LabelDefinitionFamily: "queue"},
+			allowed: []string{"database", "gateway"},
+			denied:  `the family "queue" of ComponentDefinition worker in label definition.oam.dev/family is unknown, the allowed families are: database, gateway`,
+		},
+		"without family": {
+			allowed: []string{"database"},
+		},
+		"any family": {
+			labels: map[string]string{oam.LabelDefinitionFamily: "queue"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			def := v1beta1.ComponentDefinition{
+				TypeMeta:   metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.ComponentDefinitionKind},
+				ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default", Labels: tc.labels},
+				Spec: v1beta1.ComponentDefinitionSpec{
+					Workload:  common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+					Schematic: &common.Schematic{CUE: &common.CUE{Template: "output: {}"}},
+				},
+			}
+			raw, err := json.Marshal(def)
+			require.NoError(t, err)
+			h := &ValidatingHandler{Decoder: d, Client: fake.NewClientBuilder().Build(), AllowedFamilies: tc.allowed}
+			resp := h.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Resource:  metav1.GroupVersionResource{Group: v1beta1.Group, Version: v1beta1.Version, Resource: "componentdefinitions"},
+				Object:    runtime.RawExtension{Raw: raw},
+			}})
+			if tc.denied == "" {
+				require.True(t, resp.Allowed, resp.Result.Reason)
+				return
+			}
+			require.False(t, resp.Allowed)
+			require.Contains(t, string(resp.Result.Reason), tc.denied)
+		})
+	}
+}
+
+func TestValidateCUEImports(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, core.AddToScheme(s))
+	require.NoError(t, scheme.AddToScheme(s))
+	d, err := admission.NewDecoder(s)
+	require.NoError(t, err)
+	template := `
+import (
+	"strings"
+	"math"
+	"encoding/json"
+)
+output: {}
+`
+	cmKey := client.ObjectKey{Namespace: "vela-system", Name: "cue-imports"}
+	testCases := map[string]struct {
+		allowed   []string
+		configMap *client.ObjectKey
+		imports   string
+		denied    string
+	}{
+		"allowed imports only": {
+			allowed: []string{"strings", "math", "encoding/*"},
+		},
+		"disallowed import": {
+			allowed: []string{"strings", "encoding/json"},
+			denied:  "the CUE template of ComponentDefinition worker imports packages that are not allowed: math, the allowed imports are: strings, encoding/json",
+		},
+		"any import": {},
+		"allowed by configmap": {
+			allowed:   []string{"strings"},
+			configMap: &cmKey,
+			imports:   "math,\nencoding/json\n",
+		},
+		"missing configmap": {
+			allowed:   []string{"strings"},
+			configMap: &client.ObjectKey{Namespace: "vela-system", Name: "missing"},
+			denied:    "imports packages that are not allowed: encoding/json, math",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			def := v1beta1.ComponentDefinition{
+				TypeMeta:   metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.ComponentDefinitionKind},
+				ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+				Spec: v1beta1.ComponentDefinitionSpec{
+					Workload:  common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+					Schematic: &common.Schematic{CUE: &common.CUE{Template: template}},
+				},
+			}
+			raw, err := json.Marshal(def)
+			require.NoError(t, err)
+			cli := fake.NewClientBuilder().WithScheme(s).WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: cmKey.Namespace, Name: cmKey.Name},
+				Data:       map[string]string{AllowedCUEImportsKey: tc.imports},
+			}).Build()
+			h := &ValidatingHandler{Decoder: d, Client: cli, AllowedCUEImports: tc.allowed, AllowedCUEImportsConfigMap: tc.configMap}
+			resp := h.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Resource:  metav1.GroupVersionResource{Group: v1beta1.Group, Version: v1beta1.Version, Resource: "componentdefinitions"},
+				Object:    runtime.RawExtension{Raw: raw},
+			}})
+			if tc.denied == "" {
+				require.True(t, resp.Allowed, resp.Result.Reason)
+				return
+			}
+			require.False(t, resp.Allowed)
+			require.Contains(t, string(resp.Result.Reason), tc.denied)
+		})
+	}
+}
@@ -50,6 +50,7 @@ import (
 	commontype "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
+	controllerutils "github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/cue/process"
 	pkgdef "github.com/oam-dev/kubevela/pkg/definition"
 	"github.com/oam-dev/kubevela/pkg/definition/gen_sdk"
@@ -91,6 +92,7 @@ func DefinitionCommandGroup(c common.Args, order string, ioStreams util.IOStream
 		NewDefinitionDelCommand(c),
 		NewDefinitionInitCommand(c),
 		NewDefinitionValidateCommand(c),
+		NewDefinitionLintCommand(c),
 		NewDefinitionDocGenCommand(c, ioStreams),
 		NewCapabilityShowCommand(c, "", ioStreams),
 		NewDefinitionGenAPICommand(c),
@@ -1084,6 +1086,55 @@ func validateSingleCueFile(fileName string, fileData []byte, c common.Args) (str
 	return fmt.Sprintf("Validation %s succeed.\n", fileName), nil
 }
 
+// NewDefinitionLintCommand create the `vela def lint` command to help user find the common issues of the component
+// definition before applying it
+func NewDefinitionLintCommand(c common.Args) *cobra.Command {
+	var schemaSizeThreshold int
+	cmd := &cobra.Command{
+		Use:   "lint DEFINITION_FILE",
+		Short: "Lint ComponentDefinition.",
+		Long: "Lint the ComponentDefinition in YAML or CUE file for the common issues before applying it, including conflicting schematics, " +
+			"invalid parameter schema, parameters missing descriptions, deprecated parameters, oversized schema and unused parameters.\n" +
+			"* The cluster is only needed to resolve the CUE packages referred by the definition, the checks needing them are skipped without cluster.",
+		Example: "# Command below will lint the my-def.yaml file.\n" +
+			"> vela def lint my-def.yaml\n" +
+			"# Lint every file provided\n" +
+			"> vela def lint my-def1.cue my-def2.yaml",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				klog.Infof("ignore kubernetes cluster, unable to get kubernetes client: %s", err.Error())
+			}
+			opts := controllerutils.LintOptions{SchemaSizeThreshold: schemaSizeThreshold}
+			var errCount int
+			for _, file := range args {
+				findings, err := controllerutils.LintComponentDefinitionFile(cmd.Context(), k8sClient, file, opts)
+				if err != nil {
+					return err
+				}
+				if len(findings) == 0 {
+					cmd.Printf("%s: no issues found\n", file)
+					continue
+				}
+				for _, finding := range findings {
+					cmd.Printf("%s: %s\n", file, finding)
+					if finding.Severity == controllerutils.LintSeverityError {
+						errCount++
+					}
+				}
+			}
+			if errCount != 0 {
+				return errors.Errorf("%d error(s) found", errCount)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&schemaSizeThreshold, "schema-size-threshold", controllerutils.DefaultLintSchemaSizeThreshold,
+		"Specify the size in bytes of the parameter schema above which it's reported as oversized, the check is disabled if it's not positive.")
+	return cmd
+}
+
 // NewDefinitionGenAPICommand create the `vela def gen-api` command to help user generate Go code from the definition
 func NewDefinitionGenAPICommand(c common.Args) *cobra.Command {
 	meta := gen_sdk.GenMeta{}
@@ -30,10 +30,12 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
 	"github.com/oam-dev/kubevela/pkg/utils/system"
 	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
@@ -61,6 +63,7 @@ const (
 var webSite bool
 var generateDocOnly bool
 var showFormat string
+var showFromSchema bool
 
 // NewCapabilityShowCommand shows the reference doc for a component type or trait
 func NewCapabilityShowCommand(c common.Args, order string, ioStreams cmdutil.IOStreams) *cobra.Command {
@@ -87,6 +90,8 @@ func NewCapabilityShowCommand(c common.Args, order string, ioStreams cmdutil.IOS
 > vela show
 8. Generate all docs and start a doc server
 > vela show --web
+9. Generate the markdown tables of the parameters from the schema stored by the controller
+> vela show webservice --format markdown --from-schema -n vela-system
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
@@ -113,6 +118,9 @@ func NewCapabilityShowCommand(c common.Args, order string, ioStreams cmdutil.IOS
 			if webSite || generateDocOnly {
 				return startReferenceDocsSite(ctx, namespace, c, ioStreams, capabilityName)
 			}
+			if showFromSchema && (showFormat == "md" || showFormat == "markdown") {
+				return ShowSchemaMarkdown(ctx, c, ioStreams, capabilityName, namespace, int64(ver))
+			}
 			if path != "" || showFormat == "md" || showFormat == "markdown" {
 				return ShowReferenceMarkdown(ctx, c, ioStreams, capabilityName, path, location, i18nPath, namespace, int64(ver))
 			}
@@ -126,6 +134,7 @@ func NewCapabilityShowCommand(c common.Args, order string, ioStreams cmdutil.IOS
 
 	cmd.Flags().BoolVarP(&webSite, "web", "", false, "start web doc site")
 	cmd.Flags().StringVarP(&showFormat, "format", "", "", "specify format of output data, by default it's a pretty human readable format, you can specify markdown(md)")
+	cmd.Flags().BoolVarP(&showFromSchema, "from-schema", "", false, "generate the markdown tables of the parameters from the OpenAPI schema stored by the controller for the component rather than from the definition, it requires the markdown format")
 	cmd.Flags().StringVarP(&revision, "revision", "r", "", "Get the specified revision of a definition. Use def get to list revisions.")
 	cmd.Flags().StringVarP(&path, "path", "p", "", "Specify the path for of the doc generated from definition.")
 	cmd.Flags().StringVarP(&location, "location", "l", "", "specify the location for of the doc generated from definition, now supported options 'zh', 'en'. ")
@@ -471,6 +480,37 @@ func ShowReferenceMarkdown(ctx context.Context, c common.Args, ioStreams cmdutil
 	return nil
 }
 
+// ShowSchemaMarkdown shows the parameters of the component in "markdown" format, which are generated from the OpenAPI
+// schema stored by the controller rather than from the definition
+func ShowSchemaMarkdown(ctx context.Context, c common.Args, ioStreams cmdutil.IOStreams, componentName, ns string, rev int64) error {
+	cli, err := c.GetClient()
+	if err != nil {
+		return err
+	}
+	name := componentName
+	if rev > 0 {
+		name = fmt.Sprintf("%s-v%d", componentName, rev)
+	}
+	store := &utils.ConfigMapSchemaStore{Client: cli}
+	data, err := store.Get(ctx, ns, name)
+	if errors.Is(err, utils.ErrSchemaNotStored) && ns != types.DefaultKubeVelaNS {
+		data, err = store.Get(ctx, types.DefaultKubeVelaNS, name)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the schema of component %s", componentName)
+	}
+	schema := openapi3.NewSchema()
+	if err := schema.UnmarshalJSON(data); err != nil {
+		return errors.Wrapf(err, "failed to parse the schema of component %s", componentName)
+	}
+	doc, err := docgen.GenerateMarkdownDocument("", schema)
+	if err != nil {
+		return err
+	}
+	ioStreams.Infof("## %s\n\n%s", componentName, doc)
+	return nil
+}
+
 func genRefParser(capabilityNameOrPath, ns, location, i18nPath string, rev int64) (docgen.ParseReference, error) {
 	ref := docgen.ParseReference{}
 	if location != "" {
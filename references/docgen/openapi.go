@@ -18,7 +18,9 @@ package docgen
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -75,3 +77,130 @@ func GenerateConsoleDocument(title string, schema *openapi3.Schema) (string, err
 
 	return buffer.String(), nil
 }
+
+// GenerateMarkdownDocument generates the Markdown document of the parameters from the OpenAPI v3 schema, e.g. the one
+// stored by the controller. The parameters are listed in a table of their name, type, whether it's required, default
+// and description. The nested objects, including the items of arrays, are listed in the sub-tables linked from the
+// type, which are titled by the path of the parameter.
+func GenerateMarkdownDocument(title string, schema *openapi3.Schema) (string, error) {
+	var buffer = &bytes.Buffer{}
+	writeMarkdownTable(buffer, title, schema)
+	return buffer.String(), nil
+}
+
+// markdownSubTable is a nested object listed in its own table
+type markdownSubTable struct {
+	title  string
+	schema *openapi3.Schema
+}
+
+func writeMarkdownTable(buffer *bytes.Buffer, title string, schema *openapi3.Schema) {
+	if schema == nil || len(schema.Properties) == 0 {
+		return
+	}
+	if title != "" {
+		buffer.WriteString("### " + title + "\n\n")
+	}
+	buffer.WriteString("| Name | Type | Required | Default | Description |\n")
+	buffer.WriteString("| ---- | ---- | -------- | ------- | ----------- |\n")
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	var subTables []markdownSubTable
+	for _, name := range names {
+		prop := schema.Properties[name]
+		if prop == nil || prop.Value == nil {
+			continue
+		}
+		path := name
+		if title != "" {
+			path = title + "." + name
+		}
+		typ, sub := markdownType(path, prop.Value)
+		if sub != nil {
+			subTables = append(subTables, markdownSubTable{title: path, schema: sub})
+		}
+		buffer.WriteString(fmt.Sprintf("| %s | %s | %t | %s | %s |\n", name, typ, required[name],
+			markdownCell(markdownDefault(prop.Value.Default)), markdownCell(prop.Value.Description)))
+	}
+	for _, sub := range subTables {
+		buffer.WriteString("\n")
+		writeMarkdownTable(buffer, sub.title, sub.schema)
+	}
+}
+
+// markdownType returns the type of the parameter shown in the table, and the object listed in the sub-table if any
+func markdownType(path string, schema *openapi3.Schema) (string, *openapi3.Schema) {
+	if len(schema.Enum) > 0 {
+		var options []string
+		for _, option := range schema.Enum {
+			options = append(options, markdownDefault(option))
+		}
+		return markdownCell(strings.Join(options, " or ")), nil
+	}
+	switch {
+	case len(schema.Properties) > 0:
+		return fmt.Sprintf("[object](#%s)", markdownAnchor(path)), schema
+	case schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil:
+		items := schema.Items.Value
+		if len(items.Properties) > 0 {
+			return fmt.Sprintf("[[]object](#%s)", markdownAnchor(path)), items
+		}
+		typ, _ := markdownType(path, items)
+		return "[]" + typ, nil
+	case schema.Type == "object" && schema.AdditionalProperties.Schema != nil && schema.AdditionalProperties.Schema.Value != nil:
+		typ, _ := markdownType(path, schema.AdditionalProperties.Schema.Value)
+		return "map[string]" + typ, nil
+	case schema.Type == "" && len(schema.OneOf)+len(schema.AnyOf) > 0:
+		var types []string
+		for _, ref := range append(schema.OneOf, schema.AnyOf...) {
+			if ref != nil && ref.Value != nil {
+				typ, _ := markdownType(path, ref.Value)
+				types = append(types, typ)
+			}
+		}
+		return strings.Join(types, " or "), nil
+	case schema.Type == "":
+		return "any", nil
+	}
+	return schema.Type, nil
+}
+
+// markdownDefault formats the value like in the YAML of applications, the strings are quoted to tell from the numbers
+func markdownDefault(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// markdownCell escapes the text to fit in a cell of the Markdown table
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// markdownAnchor is the anchor of the title generated by the Markdown renderers, i.e. the lower-cased title without
+// the punctuations
+func markdownAnchor(title string) string {
+	var anchor strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			anchor.WriteRune(r)
+		case r == ' ':
+			anchor.WriteRune('-')
+		}
+	}
+	return anchor.String()
+}
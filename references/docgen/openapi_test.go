@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docgen
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateMarkdownDocument(t *testing.T) {
+	cases := map[string]struct {
+		schema   string
+		expected string
+	}{
+		"flat": {
+			schema: `{"type":"object","required":["image"],"properties":{
+				"image":{"type":"string","description":"Which image would you like to use"},
+				"replicas":{"type":"integer","default":1,"description":"Number of replicas"},
+				"policy":{"type":"string","enum":["Always","Never"],"default":"Always"},
+				"cmd":{"type":"array","items":{"type":"string"},"description":"Commands to run\nin the container | shell"}}}`,
+			expected: `| Name | Type | Required | Default | Description |
+| ---- | ---- | -------- | ------- | ----------- |
+| cmd | []string | false |  | Commands to run<br>in the container \| shell |
+| image | string | true |  | Which image would you like to use |
+| policy | "Always" or "Never" | false | "Always" |  |
+| replicas | integer | false | 1 | Number of replicas |
+`,
+		},
+		"nested": {
+			schema: `{"type":"object","properties":{
+				"resources":{"type":"object","required":["cpu"],"properties":{
+					"cpu":{"type":"string","default":"100m"},
+					"limits":{"type":"object","properties":{"memory":{"type":"string"}}}}},
+				"ports":{"type":"array","items":{"type":"object","required":["port"],"properties":{
+					"port":{"type":"integer"},"expose":{"type":"boolean","default":false}}}},
+				"labels":{"type":"object","additionalProperties":{"type":"string"}}}}`,
+			expected: `| Name | Type | Required | Default | Description |
+| ---- | ---- | -------- | ------- | ----------- |
+| labels | map[string]string | false |  |  |
+| ports | [[]object](#ports) | false |  |  |
+| resources | [object](#resources) | false |  |  |
+
+### ports
+
+| Name | Type | Required | Default | Description |
+| ---- | ---- | -------- | ------- | ----------- |
+| expose | boolean | false | false |  |
+| port | integer | true |  |  |
+
+### resources
+
+| Name | Type | Required | Default | Description |
+| ---- | ---- | -------- | ------- | ----------- |
+| cpu | string | true | "100m" |  |
+| limits | [object](#resourceslimits) | false |  |  |
+
+### resources.limits
+
+| Name | Type | Required | Default | Description |
+| ---- | ---- | -------- | ------- | ----------- |
+| memory | string | false |  |  |
+`,
+		},
+		"no parameter": {
+			schema:   `{"type":"object"}`,
+			expected: "",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			schema := openapi3.NewSchema()
+			require.NoError(t, schema.UnmarshalJSON([]byte(tc.schema)))
+			doc, err := GenerateMarkdownDocument("", schema)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, doc)
+		})
+	}
+}